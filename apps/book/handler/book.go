@@ -1,13 +1,13 @@
 package handler
 
 import (
-	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/yanshicheng/ikube-gin-starter/apps/book"
-	"github.com/yanshicheng/ikube-gin-starter/apps/book/logic"
 	"github.com/yanshicheng/ikube-gin-starter/apps/book/model"
-	"github.com/yanshicheng/ikube-gin-starter/common/response"
-	"github.com/yanshicheng/ikube-gin-starter/common/types"
+	"github.com/yanshicheng/ikube-gin-starter/apps/book/service"
+	"github.com/yanshicheng/ikube-gin-starter/common/crud"
+	// swagger 注释中引用的响应类型
+	_ "github.com/yanshicheng/ikube-gin-starter/common/types"
 	"github.com/yanshicheng/ikube-gin-starter/global"
 	"github.com/yanshicheng/ikube-gin-starter/router"
 	"go.uber.org/zap"
@@ -16,8 +16,9 @@ import (
 var handler = &BookHandler{}
 
 type BookHandler struct {
-	l   *zap.Logger
-	svc *logic.BookService
+	l    *zap.Logger
+	svc  service.BookService
+	crud *crud.Handler[model.Book]
 }
 
 func (h *BookHandler) Name() string {
@@ -27,7 +28,8 @@ func (h *BookHandler) Name() string {
 // Config 配置函数，在这里注入依赖，并且初始化实例，供其他函数使用。
 func (h *BookHandler) Config() {
 	h.l = global.L.Named(book.AppBook).Named("handler")
-	h.svc = router.GetLogic(book.AppBook).(*logic.BookService)
+	router.Inject(&h.svc)
+	h.crud = crud.NewHandler[model.Book](h.svc, h.l, crud.Hooks[model.Book]{})
 }
 
 // PublicRegistry 注册公开接口
@@ -37,132 +39,86 @@ func (h *BookHandler) PublicRegistry(r gin.IRouter) {
 
 // AuthRegistry 注册认证接口
 func (h *BookHandler) AuthRegistry(r gin.IRouter) {
-	// 分组路由
-	group := r.Group("v1/book-shelf")
+
+}
+
+// VersionRegistry 按 API 版本注册接口
+func (h *BookHandler) VersionRegistry(v *router.Versions) {
+	// 书籍的增删改查使用通用处理函数，按数据权限范围过滤
+	group := v.Auth("v1").Group("book-shelf/book")
 	{
-		// group.GET("/list", h.List)
-		group.POST("/book", h.create)
-		group.GET("/book", h.list)
-		group.GET("/book/:id", h.get)
-		group.DELETE("/book/:id", h.delete)
-		group.PUT("/book/:id", h.put)
+		group.POST("", h.create)
+		group.GET("", h.list)
+		group.GET("/:id", h.get)
+		group.PUT("/:id", h.put)
+		group.DELETE("/:id", h.delete)
 	}
 }
 
-// GetPostListHandler2 书籍管理接口
-// @Summary 创建书籍接口
-// @Description 创建书籍接口
+// @Summary 创建书籍
+// @Description 创建书籍
 // @Tags 书籍管理
 // @Accept application/json
 // @Produce application/json
-// @Param req body  model.Book true "书籍名称" example "My Book"
-// @Example request:
-//
-//	{
-//	  "Title": "My Book",
-//	  "PageNumber": 300,
-//	  "Desc": "This is a great book",
-//	  "Meta": {
-//	    "details": "additional details here"
-//	  }
-//	}
-//
-// @success 200 {object} types.Data{data=model.Book} "desc"
-// @Router /book-shelf/book [post]
+// @Security ApiKeyAuth
+// @Param req body model.Book true "书籍信息"
+// @Success 200 {object} types.Data[string]{Data=model.Book}
+// @Router /v1/book-shelf/book [post]
 func (h *BookHandler) create(c *gin.Context) {
-	// @Security ApiKeyAuth
-
-	var b model.Book
-	// 绑定参数
-	if err := c.ShouldBindJSON(&b); err != nil {
-		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
-		response.FailedParam(c, err)
-		return
-	}
-	h.l.Debug(fmt.Sprintf("绑定数据: %+v", b))
-	// 调用业务逻辑
-	if err := h.svc.Create(&b); err != nil {
-		response.FailedStr(c, err.Error())
-	} else {
-		h.l.Debug(fmt.Sprintf("创建成功: %+v", b))
-		response.SuccessMap(c, b)
-	}
-
+	h.crud.Create(c)
 }
 
+// @Summary 查询书籍列表
+// @Description 分页查询书籍列表，filter 参数格式为 字段:操作:值，可重复
+// @Tags 书籍管理
+// @Produce application/json
+// @Security ApiKeyAuth
+// @Param PageNumber query int false "页码"
+// @Param PageSize query int false "每页数量"
+// @Param Sort query string false "排序" Enums(ASC, DESC)
+// @Param filter query []string false "过滤条件，如 Title:like:Go" collectionFormat(multi)
+// @Success 200 {object} types.Data[string]{Data=types.QueryResponse{Data=[]model.Book}}
+// @Router /v1/book-shelf/book [get]
 func (h *BookHandler) list(c *gin.Context) {
-	var query model.GetQuery
-	if err := c.ShouldBindQuery(&query); err != nil {
-		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
-		response.FailedParam(c, err)
-		return
-	}
-	h.l.Debug(fmt.Sprintf("query: %+v", query))
-	if s, err := h.svc.List(&query); err != nil {
-		response.FailedStr(c, err.Error())
-	} else {
-		h.l.Debug(fmt.Sprintf("查询成功: %+v", s))
-		response.SuccessSlice(c, s)
-	}
+	h.crud.List(c)
 }
 
-// @Summary 获取用户信息
-// @Description 获取当前用户的详细信息
-// @Accept  json
-// @Produce  json
-// @Security types.SearchId
-// @Success 200 {object} model.Book
-// @Router /api/user/info [get]
-func (h *BookHandler) delete(c *gin.Context) {
-	var id types.SearchId
-	if err := c.ShouldBindUri(&id); err != nil {
-		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
-		response.FailedParam(c, err)
-		return
-	}
-	if err := h.svc.Delete(id); err != nil {
-		response.FailedStr(c, err.Error())
-	} else {
-		h.l.Debug(fmt.Sprintf("删除成功: %+v", id))
-		response.SuccessMap(c, "")
-	}
-
+// @Summary 查询书籍详情
+// @Description 查询书籍详情
+// @Tags 书籍管理
+// @Produce application/json
+// @Security ApiKeyAuth
+// @Param id path int true "书籍ID"
+// @Success 200 {object} types.Data[string]{Data=model.Book}
+// @Router /v1/book-shelf/book/{id} [get]
+func (h *BookHandler) get(c *gin.Context) {
+	h.crud.Get(c)
 }
 
-func (h *BookHandler) get(ctx *gin.Context) {
-	var id types.SearchId
-	if err := ctx.ShouldBindUri(&id); err != nil {
-		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
-		response.FailedParam(ctx, err)
-		return
-	}
-	if s, err := h.svc.Get(id); err != nil {
-		response.FailedStr(ctx, err.Error())
-	} else {
-		h.l.Debug(fmt.Sprintf("查询成功: %+v", s))
-		response.SuccessMap(ctx, s)
-	}
+// @Summary 修改书籍
+// @Description 修改书籍的全部字段
+// @Tags 书籍管理
+// @Accept application/json
+// @Produce application/json
+// @Security ApiKeyAuth
+// @Param id path int true "书籍ID"
+// @Param req body model.Book true "书籍信息"
+// @Success 200 {object} types.Data[string]{Data=model.Book}
+// @Router /v1/book-shelf/book/{id} [put]
+func (h *BookHandler) put(c *gin.Context) {
+	h.crud.Update(c)
 }
 
-func (h *BookHandler) put(ctx *gin.Context) {
-	var id types.SearchId
-	if err := ctx.ShouldBindUri(&id); err != nil {
-		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
-		response.FailedParam(ctx, err)
-		return
-	}
-	var b model.Book
-	if err := ctx.ShouldBindJSON(&b); err != nil {
-		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
-		response.FailedParam(ctx, err)
-		return
-	}
-	if err := h.svc.Update(id, &b); err != nil {
-		response.FailedStr(ctx, err.Error())
-	} else {
-		h.l.Debug(fmt.Sprintf("更新成功: %+v", b))
-		response.SuccessMap(ctx, b)
-	}
+// @Summary 删除书籍
+// @Description 删除书籍
+// @Tags 书籍管理
+// @Produce application/json
+// @Security ApiKeyAuth
+// @Param id path int true "书籍ID"
+// @Success 200 {object} types.Data[string]
+// @Router /v1/book-shelf/book/{id} [delete]
+func (h *BookHandler) delete(c *gin.Context) {
+	h.crud.Delete(c)
 }
 
 func init() {
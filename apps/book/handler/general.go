@@ -1,5 +0,0 @@
-package handler
-
-var (
-	name = "logic"
-)
@@ -1,5 +0,0 @@
-package logic
-
-var (
-	name = "logic"
-)
@@ -2,13 +2,10 @@ package service
 
 import (
 	"github.com/yanshicheng/ikube-gin-starter/apps/book/model"
-	"github.com/yanshicheng/ikube-gin-starter/common/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/crud"
 )
 
+// BookService 书籍只有基础的增删改查，使用通用实现
 type BookService interface {
-	Create(*model.Book) error
-	Update(types.SearchId, *model.Book) error
-	Delete(types.SearchId) error
-	Get(types.SearchId) (*model.Book, error)
-	List(*model.GetQuery) (*types.QueryResponse, error)
+	crud.Store[model.Book]
 }
@@ -1,5 +0,0 @@
-package sql
-
-var (
-	name = "sql"
-)
@@ -1,6 +1,17 @@
 package portal
 
 const (
-	AppName         = "portal"
-	AppOrganization = "organization"
+	AppName           = "portal"
+	AppOrganization   = "organization"
+	AppAuth           = "auth"
+	AppAccount        = "account"
+	AppPermission     = "permission"
+	AppMenu           = "menu"
+	AppApplication    = "application"
+	AppRole           = "role"
+	AppServiceAccount = "service-account"
+	AppLdap           = "ldap"
+	AppOtp            = "otp"
+	AppSession        = "session"
+	AppAudit          = "audit"
 )
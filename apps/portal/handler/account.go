@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/model"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/service"
+	types2 "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/response"
+	"github.com/yanshicheng/ikube-gin-starter/common/types"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/router"
+	"go.uber.org/zap"
+)
+
+var _ router.GinService = (*AccountHandler)(nil)
+var accountHandler = &AccountHandler{}
+
+type AccountHandler struct {
+	l   *zap.Logger
+	svc service.AccountService
+}
+
+func (h *AccountHandler) PublicRegistry(r gin.IRouter) {
+
+}
+
+// AuthRegistry 注册认证接口
+func (h *AccountHandler) AuthRegistry(r gin.IRouter) {
+	// 分组路由
+	group := r.Group(fmt.Sprintf("%s/%s", portal.AppName, portal.AppAccount))
+	{
+		group.GET("/", h.list)
+		group.GET("/:id", h.get)
+		group.POST("/", h.create)
+		group.PUT("/:id", h.put)
+		group.DELETE("/:id", h.delete)
+		group.PUT("/:id/freeze", h.freeze)
+		group.PUT("/:id/disable", h.disable)
+		group.PUT("/:id/leave", h.leave)
+		group.PUT("/:id/password", h.resetPassword)
+	}
+}
+
+func (h *AccountHandler) get(c *gin.Context) {
+	var id types.SearchId
+	if err := c.ShouldBindUri(&id); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if s, err := h.svc.Get(c, id); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessMap(c, s)
+	}
+}
+
+func (h *AccountHandler) list(c *gin.Context) {
+	var search types2.AccountSearch
+	if err := c.ShouldBindQuery(&search); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	h.l.Debug(fmt.Sprintf("查询参数: %+v", search))
+	if s, err := h.svc.List(c, search); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessSlice(c, s)
+	}
+}
+
+func (h *AccountHandler) create(c *gin.Context) {
+	var req types2.AccountCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if account, err := h.svc.Create(c, req); err != nil {
+		h.l.Error(fmt.Sprintf("数据创建失败: %s", err))
+		response.FailedError(c, err)
+	} else {
+		h.l.Debug(fmt.Sprintf("创建成功: %+v", account))
+		response.SuccessMap(c, account)
+	}
+}
+
+func (h *AccountHandler) put(c *gin.Context) {
+	var id types.SearchId
+	if err := c.ShouldBindUri(&id); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	var req types2.AccountUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if account, err := h.svc.Put(c, id, req); err != nil {
+		h.l.Error(fmt.Sprintf("数据更新失败: %s", err))
+		response.FailedError(c, err)
+	} else {
+		h.l.Debug(fmt.Sprintf("更新成功: %+v", account))
+		response.SuccessMap(c, account)
+	}
+}
+
+func (h *AccountHandler) delete(c *gin.Context) {
+	var id types.SearchId
+	if err := c.ShouldBindUri(&id); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if err := h.svc.Delete(c, id); err != nil {
+		h.l.Error(fmt.Sprintf("数据删除失败: %s", err))
+		response.FailedError(c, err)
+	} else {
+		h.l.Debug(fmt.Sprintf("删除成功: %+v", id))
+		response.SuccessMap(c, nil)
+	}
+}
+
+func (h *AccountHandler) resetPassword(c *gin.Context) {
+	var id types.SearchId
+	if err := c.ShouldBindUri(&id); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	var req types2.AccountPasswordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if account, err := h.svc.ResetPassword(c, id, req); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessMap(c, account)
+	}
+}
+
+func (h *AccountHandler) freeze(c *gin.Context) {
+	h.status(c, h.svc.Freeze)
+}
+
+func (h *AccountHandler) disable(c *gin.Context) {
+	h.status(c, h.svc.Disable)
+}
+
+func (h *AccountHandler) leave(c *gin.Context) {
+	h.status(c, h.svc.Leave)
+}
+
+// status 冻结、禁用、离职操作的通用处理
+func (h *AccountHandler) status(c *gin.Context, fn func(*gin.Context, types.SearchId, bool) (*model.Account, error)) {
+	var id types.SearchId
+	if err := c.ShouldBindUri(&id); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	var req types2.AccountStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if account, err := fn(c, id, *req.Value); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessMap(c, account)
+	}
+}
+
+func (h *AccountHandler) Name() string {
+	return fmt.Sprintf("%s.%s", portal.AppName, portal.AppAccount)
+}
+
+// Config 配置函数，在这里注入依赖，并且初始化实例，供其他函数使用。
+func (h *AccountHandler) Config() {
+	h.l = global.L.Named(portal.AppName).Named(portal.AppAccount).Named("handler")
+	router.Inject(&h.svc)
+}
+
+func init() {
+	router.RegistryGinRouter(accountHandler)
+}
@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/model"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/service"
+	types2 "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/response"
+	"github.com/yanshicheng/ikube-gin-starter/common/types"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/router"
+	"go.uber.org/zap"
+)
+
+var _ router.GinService = (*ApplicationHandler)(nil)
+var applicationHandler = &ApplicationHandler{}
+
+type ApplicationHandler struct {
+	l   *zap.Logger
+	svc service.ApplicationService
+}
+
+func (h *ApplicationHandler) PublicRegistry(r gin.IRouter) {
+
+}
+
+// AuthRegistry 注册认证接口
+func (h *ApplicationHandler) AuthRegistry(r gin.IRouter) {
+	// 分组路由
+	group := r.Group(fmt.Sprintf("%s/%s", portal.AppName, portal.AppApplication))
+	{
+		group.GET("/", h.list)
+		group.GET("/mine", h.mine)
+		group.GET("/:id", h.get)
+		group.POST("/", h.create)
+		group.PUT("/:id", h.put)
+		group.DELETE("/:id", h.delete)
+		group.GET("/:id/accounts", h.accounts)
+		group.POST("/:id/accounts", h.grant)
+		group.DELETE("/:id/accounts", h.revoke)
+	}
+}
+
+func (h *ApplicationHandler) get(c *gin.Context) {
+	var id types.SearchId
+	if err := c.ShouldBindUri(&id); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if s, err := h.svc.Get(c, id); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessMap(c, s)
+	}
+}
+
+func (h *ApplicationHandler) list(c *gin.Context) {
+	var search types2.ApplicationSearch
+	if err := c.ShouldBindQuery(&search); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if s, err := h.svc.List(c, search); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessMap(c, s)
+	}
+}
+
+func (h *ApplicationHandler) mine(c *gin.Context) {
+	if s, err := h.svc.Mine(c); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessSlice(c, s)
+	}
+}
+
+func (h *ApplicationHandler) create(c *gin.Context) {
+	var application model.Application
+	if err := c.ShouldBindJSON(&application); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if err := h.svc.Create(c, &application); err != nil {
+		h.l.Error(fmt.Sprintf("数据创建失败: %s", err))
+		response.FailedError(c, err)
+	} else {
+		h.l.Debug(fmt.Sprintf("创建成功: %+v", application))
+		response.SuccessMap(c, application)
+	}
+}
+
+func (h *ApplicationHandler) put(c *gin.Context) {
+	var id types.SearchId
+	if err := c.ShouldBindUri(&id); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	var application model.Application
+	if err := c.ShouldBindJSON(&application); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if newApplication, err := h.svc.Put(c, id, &application); err != nil {
+		h.l.Error(fmt.Sprintf("数据更新失败: %s", err))
+		response.FailedError(c, err)
+	} else {
+		h.l.Debug(fmt.Sprintf("更新成功: %+v", newApplication))
+		response.SuccessMap(c, newApplication)
+	}
+}
+
+func (h *ApplicationHandler) delete(c *gin.Context) {
+	var id types.SearchId
+	if err := c.ShouldBindUri(&id); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if err := h.svc.Delete(c, id); err != nil {
+		h.l.Error(fmt.Sprintf("数据删除失败: %s", err))
+		response.FailedError(c, err)
+	} else {
+		h.l.Debug(fmt.Sprintf("删除成功: %+v", id))
+		response.SuccessMap(c, nil)
+	}
+}
+
+func (h *ApplicationHandler) accounts(c *gin.Context) {
+	var id types.SearchId
+	if err := c.ShouldBindUri(&id); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	var search types2.ApplicationAccountSearch
+	if err := c.ShouldBindQuery(&search); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if s, err := h.svc.Accounts(c, id, search); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessMap(c, s)
+	}
+}
+
+func (h *ApplicationHandler) grant(c *gin.Context) {
+	h.assign(c, h.svc.Grant)
+}
+
+func (h *ApplicationHandler) revoke(c *gin.Context) {
+	h.assign(c, h.svc.Revoke)
+}
+
+// assign 授权和撤销操作的公共处理逻辑
+func (h *ApplicationHandler) assign(c *gin.Context, fn func(*gin.Context, types.SearchId, types2.ApplicationAccountRequest) error) {
+	var id types.SearchId
+	if err := c.ShouldBindUri(&id); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	var req types2.ApplicationAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if err := fn(c, id, req); err != nil {
+		h.l.Error(fmt.Sprintf("应用授权操作失败: %s", err))
+		response.FailedError(c, err)
+	} else {
+		response.SuccessMap(c, nil)
+	}
+}
+
+func (h *ApplicationHandler) Name() string {
+	return fmt.Sprintf("%s.%s", portal.AppName, portal.AppApplication)
+}
+
+// Config 配置函数，在这里注入依赖，并且初始化实例，供其他函数使用。
+func (h *ApplicationHandler) Config() {
+	h.l = global.L.Named(portal.AppName).Named(portal.AppApplication).Named("handler")
+	router.Inject(&h.svc)
+}
+
+func init() {
+	router.RegistryGinRouter(applicationHandler)
+}
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/service"
+	types2 "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/response"
+	"github.com/yanshicheng/ikube-gin-starter/common/types"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/router"
+	"go.uber.org/zap"
+)
+
+var _ router.GinService = (*AuditHandler)(nil)
+var auditHandler = &AuditHandler{}
+
+type AuditHandler struct {
+	l   *zap.Logger
+	svc service.AuditService
+}
+
+func (h *AuditHandler) PublicRegistry(r gin.IRouter) {
+
+}
+
+func (h *AuditHandler) AuthRegistry(r gin.IRouter) {
+	group := r.Group(fmt.Sprintf("%s/%s", portal.AppName, portal.AppAudit))
+	{
+		group.GET("/", h.list)
+		group.GET("/:id", h.get)
+	}
+}
+
+func (h *AuditHandler) list(c *gin.Context) {
+	var search types2.AuditSearch
+	if err := c.ShouldBindQuery(&search); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if s, err := h.svc.List(c, search); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessMap(c, s)
+	}
+}
+
+func (h *AuditHandler) get(c *gin.Context) {
+	var id types.SearchId
+	if err := c.ShouldBindUri(&id); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if s, err := h.svc.Get(c, id); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessMap(c, s)
+	}
+}
+
+func (h *AuditHandler) Name() string {
+	return fmt.Sprintf("%s.%s", portal.AppName, portal.AppAudit)
+}
+
+// Config 配置函数，在这里注入依赖，并且初始化实例，供其他函数使用。
+func (h *AuditHandler) Config() {
+	h.l = global.L.Named(portal.AppName).Named(portal.AppAudit).Named("handler")
+	router.Inject(&h.svc)
+}
+
+func init() {
+	router.RegistryGinRouter(auditHandler)
+}
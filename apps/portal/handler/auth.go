@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/service"
+	types2 "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/auth"
+	"github.com/yanshicheng/ikube-gin-starter/common/response"
+	"github.com/yanshicheng/ikube-gin-starter/common/types"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/router"
+	"go.uber.org/zap"
+)
+
+var _ router.GinService = (*AuthHandler)(nil)
+var authHandler = &AuthHandler{}
+
+type AuthHandler struct {
+	l   *zap.Logger
+	svc service.AuthService
+}
+
+// PublicRegistry 注册公开接口
+func (h *AuthHandler) PublicRegistry(r gin.IRouter) {
+	group := r.Group(fmt.Sprintf("%s/%s", portal.AppName, portal.AppAuth))
+	{
+		group.POST("/login", h.login)
+		group.POST("/refresh", h.refresh)
+		group.POST("/password/expired", h.expiredPassword)
+		group.GET("/oidc/login", h.oidcLogin)
+		group.GET("/oidc/callback", h.oidcCallback)
+	}
+}
+
+// AuthRegistry 注册认证接口
+func (h *AuthHandler) AuthRegistry(r gin.IRouter) {
+	group := r.Group(fmt.Sprintf("%s/%s", portal.AppName, portal.AppAuth))
+	{
+		group.POST("/logout", h.logout)
+		group.POST("/revoke/:id", h.revoke)
+		group.PUT("/password", h.changePassword)
+	}
+}
+
+func (h *AuthHandler) login(c *gin.Context) {
+	var req types2.LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if tokens, err := h.svc.Login(c, req); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessMap(c, tokens)
+	}
+}
+
+func (h *AuthHandler) refresh(c *gin.Context) {
+	var req types2.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if tokens, err := h.svc.Refresh(c, req); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessMap(c, tokens)
+	}
+}
+
+func (h *AuthHandler) logout(c *gin.Context) {
+	var req types2.LogoutRequest
+	// 请求体可以为空
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+			response.FailedParam(c, err)
+			return
+		}
+	}
+	if err := h.svc.Logout(c, req); err != nil {
+		response.FailedError(c, err)
+	} else {
+		h.l.Debug(fmt.Sprintf("注销成功: %d", auth.GetAccountId(c)))
+		response.SuccessMap(c, nil)
+	}
+}
+
+func (h *AuthHandler) revoke(c *gin.Context) {
+	var id types.SearchId
+	if err := c.ShouldBindUri(&id); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if err := h.svc.Revoke(c, id); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessMap(c, nil)
+	}
+}
+
+func (h *AuthHandler) changePassword(c *gin.Context) {
+	var req types2.PasswordChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if err := h.svc.ChangePassword(c, req); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessMap(c, nil)
+	}
+}
+
+func (h *AuthHandler) expiredPassword(c *gin.Context) {
+	var req types2.PasswordExpiredRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if err := h.svc.ExpiredPassword(c, req); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessMap(c, nil)
+	}
+}
+
+func (h *AuthHandler) oidcLogin(c *gin.Context) {
+	if s, err := h.svc.OidcLogin(c); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessMap(c, s)
+	}
+}
+
+func (h *AuthHandler) oidcCallback(c *gin.Context) {
+	var req types2.OidcCallbackRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if tokens, err := h.svc.OidcCallback(c, req); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessMap(c, tokens)
+	}
+}
+
+func (h *AuthHandler) Name() string {
+	return fmt.Sprintf("%s.%s", portal.AppName, portal.AppAuth)
+}
+
+// Config 配置函数，在这里注入依赖，并且初始化实例，供其他函数使用。
+func (h *AuthHandler) Config() {
+	h.l = global.L.Named(portal.AppName).Named(portal.AppAuth).Named("handler")
+	router.Inject(&h.svc)
+}
+
+func init() {
+	router.RegistryGinRouter(authHandler)
+}
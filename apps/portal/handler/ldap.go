@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/service"
+	types2 "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/response"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/router"
+	"go.uber.org/zap"
+)
+
+var _ router.GinService = (*LdapHandler)(nil)
+var ldapHandler = &LdapHandler{}
+
+type LdapHandler struct {
+	l   *zap.Logger
+	svc service.LdapSyncService
+}
+
+func (h *LdapHandler) PublicRegistry(r gin.IRouter) {
+
+}
+
+// AuthRegistry 注册认证接口
+func (h *LdapHandler) AuthRegistry(r gin.IRouter) {
+	group := r.Group(fmt.Sprintf("%s/%s", portal.AppName, portal.AppLdap))
+	{
+		group.POST("/sync", h.sync)
+	}
+}
+
+func (h *LdapHandler) sync(c *gin.Context) {
+	var req types2.LdapSyncRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if report, err := h.svc.Sync(c, req.DryRun); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessMap(c, report)
+	}
+}
+
+func (h *LdapHandler) Name() string {
+	return fmt.Sprintf("%s.%s", portal.AppName, portal.AppLdap)
+}
+
+// Config 配置函数，在这里注入依赖，并且初始化实例，供其他函数使用。
+func (h *LdapHandler) Config() {
+	h.l = global.L.Named(portal.AppName).Named(portal.AppLdap).Named("handler")
+	router.Inject(&h.svc)
+}
+
+func init() {
+	router.RegistryGinRouter(ldapHandler)
+}
@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/model"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/service"
+	types2 "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/response"
+	"github.com/yanshicheng/ikube-gin-starter/common/types"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/router"
+	"go.uber.org/zap"
+)
+
+var _ router.GinService = (*MenuHandler)(nil)
+var menuHandler = &MenuHandler{}
+
+type MenuHandler struct {
+	l   *zap.Logger
+	svc service.MenuService
+}
+
+func (h *MenuHandler) PublicRegistry(r gin.IRouter) {
+
+}
+
+// AuthRegistry 注册认证接口
+func (h *MenuHandler) AuthRegistry(r gin.IRouter) {
+	// 分组路由
+	group := r.Group(fmt.Sprintf("%s/%s", portal.AppName, portal.AppMenu))
+	{
+		group.GET("/", h.list)
+		group.GET("/mine", h.mine)
+		group.GET("/:id", h.get)
+		group.POST("/", h.create)
+		group.PUT("/:id", h.put)
+		group.DELETE("/:id", h.delete)
+	}
+}
+
+func (h *MenuHandler) get(c *gin.Context) {
+	var id types.SearchId
+	if err := c.ShouldBindUri(&id); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if s, err := h.svc.Get(c, id); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessMap(c, s)
+	}
+}
+
+func (h *MenuHandler) list(c *gin.Context) {
+	var search types2.MenuSearch
+	if err := c.ShouldBindQuery(&search); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if s, err := h.svc.List(c, search); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessSlice(c, s)
+	}
+}
+
+func (h *MenuHandler) mine(c *gin.Context) {
+	var search types2.MenuSearch
+	if err := c.ShouldBindQuery(&search); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if s, err := h.svc.Mine(c, search); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessSlice(c, s)
+	}
+}
+
+func (h *MenuHandler) create(c *gin.Context) {
+	var menu model.Menu
+	if err := c.ShouldBindJSON(&menu); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if err := h.svc.Create(c, &menu); err != nil {
+		h.l.Error(fmt.Sprintf("数据创建失败: %s", err))
+		response.FailedError(c, err)
+	} else {
+		h.l.Debug(fmt.Sprintf("创建成功: %+v", menu))
+		response.SuccessMap(c, menu)
+	}
+}
+
+func (h *MenuHandler) put(c *gin.Context) {
+	var id types.SearchId
+	if err := c.ShouldBindUri(&id); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	var menu model.Menu
+	if err := c.ShouldBindJSON(&menu); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if newMenu, err := h.svc.Put(c, id, &menu); err != nil {
+		h.l.Error(fmt.Sprintf("数据更新失败: %s", err))
+		response.FailedError(c, err)
+	} else {
+		h.l.Debug(fmt.Sprintf("更新成功: %+v", newMenu))
+		response.SuccessMap(c, newMenu)
+	}
+}
+
+func (h *MenuHandler) delete(c *gin.Context) {
+	var id types.SearchId
+	if err := c.ShouldBindUri(&id); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if err := h.svc.Delete(c, id); err != nil {
+		h.l.Error(fmt.Sprintf("数据删除失败: %s", err))
+		response.FailedError(c, err)
+	} else {
+		h.l.Debug(fmt.Sprintf("删除成功: %+v", id))
+		response.SuccessMap(c, nil)
+	}
+}
+
+func (h *MenuHandler) Name() string {
+	return fmt.Sprintf("%s.%s", portal.AppName, portal.AppMenu)
+}
+
+// Config 配置函数，在这里注入依赖，并且初始化实例，供其他函数使用。
+func (h *MenuHandler) Config() {
+	h.l = global.L.Named(portal.AppName).Named(portal.AppMenu).Named("handler")
+	router.Inject(&h.svc)
+}
+
+func init() {
+	router.RegistryGinRouter(menuHandler)
+}
@@ -4,8 +4,8 @@ import (
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/yanshicheng/ikube-gin-starter/apps/portal"
-	"github.com/yanshicheng/ikube-gin-starter/apps/portal/logic"
 	"github.com/yanshicheng/ikube-gin-starter/apps/portal/model"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/service"
 	types2 "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
 	"github.com/yanshicheng/ikube-gin-starter/common/response"
 	"github.com/yanshicheng/ikube-gin-starter/common/types"
@@ -19,7 +19,7 @@ var handler = &OrganizationHandler{}
 
 type OrganizationHandler struct {
 	l   *zap.Logger
-	svc *logic.OrganizationLogic
+	svc service.OrganizationService
 }
 
 func (h *OrganizationHandler) PublicRegistry(r gin.IRouter) {
@@ -36,6 +36,7 @@ func (h *OrganizationHandler) AuthRegistry(r gin.IRouter) {
 		group.POST("/", h.create)
 		group.PUT("/:id", h.put)
 		group.DELETE("/:id", h.delete)
+		group.PUT("/:id/move", h.move)
 	}
 }
 func (h *OrganizationHandler) get(c *gin.Context) {
@@ -54,18 +55,20 @@ func (h *OrganizationHandler) get(c *gin.Context) {
 	}
 
 }
+
+// list 查询机构树，按机构的 filter 标签过滤，如 filter=name:like:运维
 func (h *OrganizationHandler) list(c *gin.Context) {
-	var search types2.OrganizationSearch
-	if err := c.ShouldBindQuery(&search); err != nil {
+	var query types.Filters
+	if err := c.ShouldBindQuery(&query); err != nil {
 		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
 		response.FailedParam(c, err)
 		return
 	}
-	h.l.Debug(fmt.Sprintf("查询参数: %v", search))
+	h.l.Debug(fmt.Sprintf("查询参数: %v", query))
 
-	if s, err := h.svc.List(c, search); err != nil {
+	if s, err := h.svc.List(c, query); err != nil {
 		h.l.Error(fmt.Sprintf("数据查询失败: %s", err))
-		response.FailedStr(c, err.Error())
+		response.FailedError(c, err)
 	} else {
 		response.SuccessSlice(c, s)
 	}
@@ -109,6 +112,28 @@ func (h *OrganizationHandler) put(c *gin.Context) {
 	}
 
 }
+func (h *OrganizationHandler) move(c *gin.Context) {
+	var id types.SearchId
+	if err := c.ShouldBindUri(&id); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	var req types2.OrganizationMoveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if newOrg, err := h.svc.Move(c, id, req); err != nil {
+		h.l.Error(fmt.Sprintf("机构移动失败: %s", err))
+		response.FailedError(c, err)
+	} else {
+		h.l.Debug(fmt.Sprintf("移动成功: %+v", newOrg))
+		response.SuccessMap(c, newOrg)
+	}
+}
+
 func (h *OrganizationHandler) delete(c *gin.Context) {
 	var id types.SearchId
 	if err := c.ShouldBindUri(&id); err != nil {
@@ -133,7 +158,7 @@ func (h *OrganizationHandler) Name() string {
 // Config 配置函数，在这里注入依赖，并且初始化实例，供其他函数使用。
 func (h *OrganizationHandler) Config() {
 	h.l = global.L.Named(portal.AppName).Named(portal.AppOrganization).Named("handler")
-	h.svc = router.GetLogic(h.Name()).(*logic.OrganizationLogic)
+	router.Inject(&h.svc)
 }
 
 func init() {
@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/service"
+	types2 "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/response"
+	"github.com/yanshicheng/ikube-gin-starter/common/types"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/router"
+	"go.uber.org/zap"
+)
+
+var _ router.GinService = (*OtpHandler)(nil)
+var otpHandler = &OtpHandler{}
+
+type OtpHandler struct {
+	l   *zap.Logger
+	svc service.OtpService
+}
+
+// PublicRegistry 注册公开接口，两步登录的第二步
+func (h *OtpHandler) PublicRegistry(r gin.IRouter) {
+	group := r.Group(fmt.Sprintf("%s/%s", portal.AppName, portal.AppOtp))
+	{
+		group.POST("/login", h.login)
+		group.POST("/login/enroll", h.loginEnroll)
+	}
+}
+
+// AuthRegistry 注册认证接口，mine 下为当前账号的自助操作
+func (h *OtpHandler) AuthRegistry(r gin.IRouter) {
+	group := r.Group(fmt.Sprintf("%s/%s", portal.AppName, portal.AppOtp))
+	{
+		group.GET("/mine", h.status)
+		group.POST("/mine/enroll", h.enroll)
+		group.POST("/mine/enable", h.enable)
+		group.POST("/mine/disable", h.disable)
+		group.POST("/mine/recovery-codes", h.recoveryCodes)
+		group.DELETE("/:id", h.reset)
+	}
+}
+
+func (h *OtpHandler) status(c *gin.Context) {
+	if s, err := h.svc.Status(c); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessMap(c, s)
+	}
+}
+
+func (h *OtpHandler) enroll(c *gin.Context) {
+	if s, err := h.svc.Enroll(c); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessMap(c, s)
+	}
+}
+
+func (h *OtpHandler) enable(c *gin.Context) {
+	var req types2.OtpCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if s, err := h.svc.Enable(c, req); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessMap(c, s)
+	}
+}
+
+func (h *OtpHandler) disable(c *gin.Context) {
+	var req types2.OtpCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if err := h.svc.Disable(c, req); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessMap(c, nil)
+	}
+}
+
+func (h *OtpHandler) recoveryCodes(c *gin.Context) {
+	var req types2.OtpCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if s, err := h.svc.RecoveryCodes(c, req); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessMap(c, s)
+	}
+}
+
+func (h *OtpHandler) reset(c *gin.Context) {
+	var id types.SearchId
+	if err := c.ShouldBindUri(&id); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if err := h.svc.Reset(c, id); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessMap(c, nil)
+	}
+}
+
+func (h *OtpHandler) loginEnroll(c *gin.Context) {
+	var req types2.OtpChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if s, err := h.svc.LoginEnroll(c, req); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessMap(c, s)
+	}
+}
+
+func (h *OtpHandler) login(c *gin.Context) {
+	var req types2.OtpLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if tokens, err := h.svc.Login(c, req); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessMap(c, tokens)
+	}
+}
+
+func (h *OtpHandler) Name() string {
+	return fmt.Sprintf("%s.%s", portal.AppName, portal.AppOtp)
+}
+
+// Config 配置函数，在这里注入依赖，并且初始化实例，供其他函数使用。
+func (h *OtpHandler) Config() {
+	h.l = global.L.Named(portal.AppName).Named(portal.AppOtp).Named("handler")
+	router.Inject(&h.svc)
+}
+
+func init() {
+	router.RegistryGinRouter(otpHandler)
+}
@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/model"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/service"
+	types2 "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/response"
+	"github.com/yanshicheng/ikube-gin-starter/common/types"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/router"
+	"go.uber.org/zap"
+)
+
+var _ router.GinService = (*RoleHandler)(nil)
+var roleHandler = &RoleHandler{}
+
+type RoleHandler struct {
+	l   *zap.Logger
+	svc service.RoleService
+}
+
+func (h *RoleHandler) PublicRegistry(r gin.IRouter) {
+
+}
+
+// AuthRegistry 注册认证接口
+func (h *RoleHandler) AuthRegistry(r gin.IRouter) {
+	// 分组路由
+	group := r.Group(fmt.Sprintf("%s/%s", portal.AppName, portal.AppRole))
+	{
+		group.GET("/", h.list)
+		group.GET("/:id", h.get)
+		group.POST("/", h.create)
+		group.PUT("/:id", h.put)
+		group.DELETE("/:id", h.delete)
+		group.GET("/:id/menus", h.menus)
+		group.PUT("/:id/menus", h.putMenus)
+		group.GET("/:id/accounts", h.accounts)
+		group.PUT("/:id/accounts", h.putAccounts)
+	}
+	// 账号生效的角色，资源归属于账号
+	r.GET(fmt.Sprintf("%s/%s/:id/roles", portal.AppName, portal.AppAccount), h.accountRoles)
+}
+
+func (h *RoleHandler) get(c *gin.Context) {
+	var id types.SearchId
+	if err := c.ShouldBindUri(&id); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if s, err := h.svc.Get(c, id); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessMap(c, s)
+	}
+}
+
+func (h *RoleHandler) list(c *gin.Context) {
+	var search types2.RoleSearch
+	if err := c.ShouldBindQuery(&search); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if s, err := h.svc.List(c, search); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessMap(c, s)
+	}
+}
+
+func (h *RoleHandler) create(c *gin.Context) {
+	var role model.Role
+	if err := c.ShouldBindJSON(&role); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if err := h.svc.Create(c, &role); err != nil {
+		h.l.Error(fmt.Sprintf("数据创建失败: %s", err))
+		response.FailedError(c, err)
+	} else {
+		h.l.Debug(fmt.Sprintf("创建成功: %+v", role))
+		response.SuccessMap(c, role)
+	}
+}
+
+func (h *RoleHandler) put(c *gin.Context) {
+	var id types.SearchId
+	if err := c.ShouldBindUri(&id); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	var role model.Role
+	if err := c.ShouldBindJSON(&role); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if newRole, err := h.svc.Put(c, id, &role); err != nil {
+		h.l.Error(fmt.Sprintf("数据更新失败: %s", err))
+		response.FailedError(c, err)
+	} else {
+		h.l.Debug(fmt.Sprintf("更新成功: %+v", newRole))
+		response.SuccessMap(c, newRole)
+	}
+}
+
+func (h *RoleHandler) delete(c *gin.Context) {
+	var id types.SearchId
+	if err := c.ShouldBindUri(&id); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if err := h.svc.Delete(c, id); err != nil {
+		h.l.Error(fmt.Sprintf("数据删除失败: %s", err))
+		response.FailedError(c, err)
+	} else {
+		h.l.Debug(fmt.Sprintf("删除成功: %+v", id))
+		response.SuccessMap(c, nil)
+	}
+}
+
+func (h *RoleHandler) menus(c *gin.Context) {
+	h.ids(c, h.svc.Menus)
+}
+
+func (h *RoleHandler) accounts(c *gin.Context) {
+	h.ids(c, h.svc.Accounts)
+}
+
+// ids 查询角色关联ID的公共处理逻辑
+func (h *RoleHandler) ids(c *gin.Context, fn func(*gin.Context, types.SearchId) ([]uint, error)) {
+	var id types.SearchId
+	if err := c.ShouldBindUri(&id); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if s, err := fn(c, id); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessSlice(c, s)
+	}
+}
+
+func (h *RoleHandler) putMenus(c *gin.Context) {
+	var id types.SearchId
+	if err := c.ShouldBindUri(&id); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	var req types2.RoleMenuRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if s, err := h.svc.PutMenus(c, id, req); err != nil {
+		h.l.Error(fmt.Sprintf("更新角色菜单失败: %s", err))
+		response.FailedError(c, err)
+	} else {
+		response.SuccessSlice(c, s)
+	}
+}
+
+func (h *RoleHandler) putAccounts(c *gin.Context) {
+	var id types.SearchId
+	if err := c.ShouldBindUri(&id); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	var req types2.RoleAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if s, err := h.svc.PutAccounts(c, id, req); err != nil {
+		h.l.Error(fmt.Sprintf("更新角色账号失败: %s", err))
+		response.FailedError(c, err)
+	} else {
+		response.SuccessSlice(c, s)
+	}
+}
+
+func (h *RoleHandler) accountRoles(c *gin.Context) {
+	var id types.SearchId
+	if err := c.ShouldBindUri(&id); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	var search types2.AccountRoleSearch
+	if err := c.ShouldBindQuery(&search); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if s, err := h.svc.AccountRoles(c, id, search); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessSlice(c, s)
+	}
+}
+
+func (h *RoleHandler) Name() string {
+	return fmt.Sprintf("%s.%s", portal.AppName, portal.AppRole)
+}
+
+// Config 配置函数，在这里注入依赖，并且初始化实例，供其他函数使用。
+func (h *RoleHandler) Config() {
+	h.l = global.L.Named(portal.AppName).Named(portal.AppRole).Named("handler")
+	router.Inject(&h.svc)
+}
+
+func init() {
+	router.RegistryGinRouter(roleHandler)
+}
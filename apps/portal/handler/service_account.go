@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/model"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/service"
+	types2 "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/response"
+	"github.com/yanshicheng/ikube-gin-starter/common/types"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/router"
+	"go.uber.org/zap"
+)
+
+var _ router.GinService = (*ServiceAccountHandler)(nil)
+var serviceAccountHandler = &ServiceAccountHandler{}
+
+type ServiceAccountHandler struct {
+	l   *zap.Logger
+	svc service.ServiceAccountService
+}
+
+func (h *ServiceAccountHandler) PublicRegistry(r gin.IRouter) {
+
+}
+
+// AuthRegistry 注册认证接口
+func (h *ServiceAccountHandler) AuthRegistry(r gin.IRouter) {
+	// 分组路由
+	group := r.Group(fmt.Sprintf("%s/%s", portal.AppName, portal.AppServiceAccount))
+	{
+		group.GET("/", h.list)
+		group.GET("/:id", h.get)
+		group.POST("/", h.create)
+		group.PUT("/:id", h.put)
+		group.DELETE("/:id", h.delete)
+		group.GET("/:id/keys", h.keys)
+		group.POST("/:id/keys", h.issueKey)
+		group.POST("/:id/keys/:keyId/rotate", h.rotateKey)
+		group.DELETE("/:id/keys/:keyId", h.revokeKey)
+	}
+}
+
+func (h *ServiceAccountHandler) get(c *gin.Context) {
+	var id types.SearchId
+	if err := c.ShouldBindUri(&id); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if s, err := h.svc.Get(c, id); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessMap(c, s)
+	}
+}
+
+func (h *ServiceAccountHandler) list(c *gin.Context) {
+	var search types2.ServiceAccountSearch
+	if err := c.ShouldBindQuery(&search); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if s, err := h.svc.List(c, search); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessMap(c, s)
+	}
+}
+
+func (h *ServiceAccountHandler) create(c *gin.Context) {
+	var serviceAccount model.ServiceAccount
+	if err := c.ShouldBindJSON(&serviceAccount); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if err := h.svc.Create(c, &serviceAccount); err != nil {
+		h.l.Error(fmt.Sprintf("数据创建失败: %s", err))
+		response.FailedError(c, err)
+	} else {
+		h.l.Debug(fmt.Sprintf("创建成功: %+v", serviceAccount))
+		response.SuccessMap(c, serviceAccount)
+	}
+}
+
+func (h *ServiceAccountHandler) put(c *gin.Context) {
+	var id types.SearchId
+	if err := c.ShouldBindUri(&id); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	var serviceAccount model.ServiceAccount
+	if err := c.ShouldBindJSON(&serviceAccount); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if newServiceAccount, err := h.svc.Put(c, id, &serviceAccount); err != nil {
+		h.l.Error(fmt.Sprintf("数据更新失败: %s", err))
+		response.FailedError(c, err)
+	} else {
+		h.l.Debug(fmt.Sprintf("更新成功: %+v", newServiceAccount))
+		response.SuccessMap(c, newServiceAccount)
+	}
+}
+
+func (h *ServiceAccountHandler) delete(c *gin.Context) {
+	var id types.SearchId
+	if err := c.ShouldBindUri(&id); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if err := h.svc.Delete(c, id); err != nil {
+		h.l.Error(fmt.Sprintf("数据删除失败: %s", err))
+		response.FailedError(c, err)
+	} else {
+		h.l.Debug(fmt.Sprintf("删除成功: %+v", id))
+		response.SuccessMap(c, nil)
+	}
+}
+
+func (h *ServiceAccountHandler) keys(c *gin.Context) {
+	var id types.SearchId
+	if err := c.ShouldBindUri(&id); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if s, err := h.svc.Keys(c, id); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessSlice(c, s)
+	}
+}
+
+func (h *ServiceAccountHandler) issueKey(c *gin.Context) {
+	var id types.SearchId
+	if err := c.ShouldBindUri(&id); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	var req types2.ApiKeyCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if s, err := h.svc.IssueKey(c, id, req); err != nil {
+		h.l.Error(fmt.Sprintf("签发 api key 失败: %s", err))
+		response.FailedError(c, err)
+	} else {
+		h.l.Info(fmt.Sprintf("签发 api key 成功, serviceAccount: %d, keyId: %s", id.Id, s.KeyId))
+		response.SuccessMap(c, s)
+	}
+}
+
+func (h *ServiceAccountHandler) rotateKey(c *gin.Context) {
+	var id types2.ApiKeySearchId
+	if err := c.ShouldBindUri(&id); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if s, err := h.svc.RotateKey(c, id); err != nil {
+		h.l.Error(fmt.Sprintf("轮换 api key 失败: %s", err))
+		response.FailedError(c, err)
+	} else {
+		h.l.Info(fmt.Sprintf("轮换 api key 成功, serviceAccount: %d, keyId: %s", id.Id, s.KeyId))
+		response.SuccessMap(c, s)
+	}
+}
+
+func (h *ServiceAccountHandler) revokeKey(c *gin.Context) {
+	var id types2.ApiKeySearchId
+	if err := c.ShouldBindUri(&id); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if err := h.svc.RevokeKey(c, id); err != nil {
+		h.l.Error(fmt.Sprintf("吊销 api key 失败: %s", err))
+		response.FailedError(c, err)
+	} else {
+		h.l.Info(fmt.Sprintf("吊销 api key 成功: %+v", id))
+		response.SuccessMap(c, nil)
+	}
+}
+
+func (h *ServiceAccountHandler) Name() string {
+	return fmt.Sprintf("%s.%s", portal.AppName, portal.AppServiceAccount)
+}
+
+// Config 配置函数，在这里注入依赖，并且初始化实例，供其他函数使用。
+func (h *ServiceAccountHandler) Config() {
+	h.l = global.L.Named(portal.AppName).Named(portal.AppServiceAccount).Named("handler")
+	router.Inject(&h.svc)
+}
+
+func init() {
+	router.RegistryGinRouter(serviceAccountHandler)
+}
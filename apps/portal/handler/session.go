@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/service"
+	types2 "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/response"
+	"github.com/yanshicheng/ikube-gin-starter/common/types"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/router"
+	"go.uber.org/zap"
+)
+
+var _ router.GinService = (*SessionHandler)(nil)
+var sessionHandler = &SessionHandler{}
+
+type SessionHandler struct {
+	l   *zap.Logger
+	svc service.SessionService
+}
+
+func (h *SessionHandler) PublicRegistry(r gin.IRouter) {
+
+}
+
+// AuthRegistry 注册认证接口，mine 下为当前账号的自助操作
+func (h *SessionHandler) AuthRegistry(r gin.IRouter) {
+	group := r.Group(fmt.Sprintf("%s/%s", portal.AppName, portal.AppSession))
+	{
+		group.GET("/mine", h.mine)
+		group.DELETE("/mine", h.revokeMineOthers)
+		group.DELETE("/mine/:sessionId", h.revokeMine)
+		group.GET("/:id", h.list)
+		group.DELETE("/:id", h.revokeAll)
+	}
+}
+
+func (h *SessionHandler) mine(c *gin.Context) {
+	if s, err := h.svc.Mine(c); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessSlice(c, s)
+	}
+}
+
+func (h *SessionHandler) revokeMine(c *gin.Context) {
+	var req types2.SessionSearchId
+	if err := c.ShouldBindUri(&req); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if err := h.svc.RevokeMine(c, req); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessMap(c, nil)
+	}
+}
+
+func (h *SessionHandler) revokeMineOthers(c *gin.Context) {
+	if s, err := h.svc.RevokeMineOthers(c); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessMap(c, s)
+	}
+}
+
+func (h *SessionHandler) list(c *gin.Context) {
+	var id types.SearchId
+	if err := c.ShouldBindUri(&id); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if s, err := h.svc.List(c, id); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessSlice(c, s)
+	}
+}
+
+func (h *SessionHandler) revokeAll(c *gin.Context) {
+	var id types.SearchId
+	if err := c.ShouldBindUri(&id); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if s, err := h.svc.RevokeAll(c, id); err != nil {
+		response.FailedError(c, err)
+	} else {
+		response.SuccessMap(c, s)
+	}
+}
+
+func (h *SessionHandler) Name() string {
+	return fmt.Sprintf("%s.%s", portal.AppName, portal.AppSession)
+}
+
+// Config 配置函数，在这里注入依赖，并且初始化实例，供其他函数使用。
+func (h *SessionHandler) Config() {
+	h.l = global.L.Named(portal.AppName).Named(portal.AppSession).Named("handler")
+	router.Inject(&h.svc)
+}
+
+func init() {
+	router.RegistryGinRouter(sessionHandler)
+}
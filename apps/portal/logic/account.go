@@ -0,0 +1,307 @@
+package logic
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/model"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/service"
+	types2 "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/auth"
+	"github.com/yanshicheng/ikube-gin-starter/common/errorx"
+	"github.com/yanshicheng/ikube-gin-starter/common/filter"
+	"github.com/yanshicheng/ikube-gin-starter/common/sql"
+	"github.com/yanshicheng/ikube-gin-starter/common/types"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/router"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 接口检查
+var _ service.AccountService = (*AccountLogic)(nil)
+
+var accountLogic = &AccountLogic{}
+
+type AccountLogic struct {
+	l  *zap.Logger
+	db *gorm.DB
+}
+
+func (a *AccountLogic) Get(c *gin.Context, id types.SearchId) (*model.Account, error) {
+	var account model.Account
+	if err := a.db.WithContext(c).Scopes(auth.DataScopeFilter(c, &model.Account{})).
+		Where("id = ?", id.Id).First(&account).Error; err != nil {
+		a.l.Error(fmt.Sprintf("查询账号信息失败, id: %d, error: %s", id.Id, err.Error()))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errorx.NewCodeError(errorx.ErrDataNotFound, "账号不存在")
+		}
+		return nil, fmt.Errorf("查询账号信息失败")
+	}
+	return &account, nil
+}
+
+func (a *AccountLogic) List(c *gin.Context, search types2.AccountSearch) (*types.QueryResponse, error) {
+	db := a.db.WithContext(c).Model(&model.Account{}).Scopes(auth.DataScopeFilter(c, &model.Account{}))
+	if search.Account != "" {
+		db = db.Where(filter.Prefix("account", search.Account))
+	}
+	if search.UserName != "" {
+		db = db.Where(filter.Prefix("user_name", search.UserName))
+	}
+	if search.OrganizationId != 0 {
+		db = db.Where("organization_id = ?", search.OrganizationId)
+	}
+	if search.IsFrozen != nil {
+		db = db.Where("is_frozen = ?", *search.IsFrozen)
+	}
+	if search.IsDisabled != nil {
+		db = db.Where("is_disabled = ?", *search.IsDisabled)
+	}
+	if search.IsLeave != nil {
+		db = db.Where("is_leave = ?", *search.IsLeave)
+	}
+	scope, err := filter.Scope(&model.Account{}, search.Filter)
+	if err != nil {
+		return nil, err
+	}
+	db = db.Scopes(scope).Order(fmt.Sprintf("id %s", search.Sort))
+	var accounts []model.Account
+	resp, err := sql.GetPageResponse(db, search.Pagination, &accounts)
+	if err != nil {
+		a.l.Error(fmt.Sprintf("查询账号列表失败, error: %s", err.Error()))
+		return nil, fmt.Errorf("查询账号列表失败")
+	}
+	return resp, nil
+}
+
+func (a *AccountLogic) Create(c *gin.Context, req types2.AccountCreateRequest) (*model.Account, error) {
+	account := model.Account{
+		UserName:       req.UserName,
+		Account:        req.Account,
+		Icon:           req.Icon,
+		Mobile:         req.Mobile,
+		Email:          req.Email,
+		WorkNumber:     req.WorkNumber,
+		HireDate:       req.HireDate,
+		Position:       req.Position,
+		OrganizationId: req.OrganizationId,
+	}
+	if err := a.checkUnique(c, &account); err != nil {
+		return nil, err
+	}
+	if err := a.checkOrganization(c, account.OrganizationId); err != nil {
+		return nil, err
+	}
+	hash, err := hashPassword(req.Password)
+	if err != nil {
+		a.l.Error(fmt.Sprintf("密码加密失败, account: %s, error: %s", req.Account, err.Error()))
+		return nil, fmt.Errorf("密码加密失败")
+	}
+	now := time.Now()
+	account.Password = hash
+	account.PasswordChangedAt = &now
+	err = a.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&account).Error; err != nil {
+			return err
+		}
+		return recordPassword(tx, account.ID, hash)
+	})
+	if err != nil {
+		a.l.Error(fmt.Sprintf("创建账号失败, account: %s, error: %s", req.Account, err.Error()))
+		return nil, errorx.NewCodeError(errorx.ErrDataCreation, "创建账号失败")
+	}
+	return &account, nil
+}
+
+func (a *AccountLogic) Put(c *gin.Context, id types.SearchId, req types2.AccountUpdateRequest) (*model.Account, error) {
+	account, err := a.Get(c, id)
+	if err != nil {
+		return nil, err
+	}
+	organizationChanged := account.OrganizationId != req.OrganizationId
+	account.UserName = req.UserName
+	account.Icon = req.Icon
+	account.Mobile = req.Mobile
+	account.Email = req.Email
+	account.WorkNumber = req.WorkNumber
+	account.HireDate = req.HireDate
+	account.Position = req.Position
+	account.OrganizationId = req.OrganizationId
+	if err := a.checkUnique(c, account); err != nil {
+		return nil, err
+	}
+	if err := a.checkOrganization(c, account.OrganizationId); err != nil {
+		return nil, err
+	}
+	if err := a.db.WithContext(c).Model(account).Select(
+		"user_name", "icon", "mobile", "email", "work_number", "hire_date", "position", "organization_id",
+	).Updates(account).Error; err != nil {
+		a.l.Error(fmt.Sprintf("更新账号信息失败, id: %d, error: %s", id.Id, err.Error()))
+		return nil, fmt.Errorf("更新账号信息失败")
+	}
+	// 所属机构变化会影响账号的数据权限范围
+	if organizationChanged {
+		if err := auth.ClearPermissionCache(c, account.ID); err != nil {
+			a.l.Error(fmt.Sprintf("清除权限缓存失败, id: %d, error: %s", id.Id, err.Error()))
+		}
+	}
+	return account, nil
+}
+
+func (a *AccountLogic) Delete(c *gin.Context, id types.SearchId) error {
+	account, err := a.Get(c, id)
+	if err != nil {
+		return err
+	}
+	if account.ID == auth.GetAccountId(c) {
+		return errorx.NewCodeError(errorx.ErrBusinessLogic, "不允许删除当前登录账号")
+	}
+	// 删除账号同时删除关联的角色和应用
+	err = a.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("account_id = ?", account.ID).Delete(&model.RoleAccount{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("account_id = ?", account.ID).Delete(&model.AccountApplication{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("account_id = ?", account.ID).Delete(&model.PasswordHistory{}).Error; err != nil {
+			return err
+		}
+		if err := deleteAccountOtp(tx, account.ID); err != nil {
+			return err
+		}
+		return tx.Delete(account).Error
+	})
+	if err != nil {
+		a.l.Error(fmt.Sprintf("删除账号失败, id: %d, error: %s", id.Id, err.Error()))
+		return errorx.NewCodeError(errorx.ErrDataDeletion, "删除账号失败")
+	}
+	a.revoke(c, account.ID)
+	return nil
+}
+
+// ResetPassword 管理员重置密码，账号强制下线并且下次登录前需要修改密码
+func (a *AccountLogic) ResetPassword(c *gin.Context, id types.SearchId, req types2.AccountPasswordResetRequest) (*model.Account, error) {
+	account, err := a.Get(c, id)
+	if err != nil {
+		return nil, err
+	}
+	// 请求绑定时账号未知，填充账号后重新校验密码策略
+	req.Account = account.Account
+	if err := binding.Validator.ValidateStruct(&req); err != nil {
+		return nil, err
+	}
+	hash, err := hashPassword(req.Password)
+	if err != nil {
+		a.l.Error(fmt.Sprintf("密码加密失败, id: %d, error: %s", id.Id, err.Error()))
+		return nil, fmt.Errorf("密码加密失败")
+	}
+	if err := a.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		return savePassword(tx, account, hash, true)
+	}); err != nil {
+		a.l.Error(fmt.Sprintf("重置密码失败, id: %d, error: %s", id.Id, err.Error()))
+		return nil, fmt.Errorf("重置密码失败")
+	}
+	a.l.Info(fmt.Sprintf("重置密码成功, id: %d, operator: %d", id.Id, auth.GetAccountId(c)))
+	a.revoke(c, account.ID)
+	return account, nil
+}
+
+func (a *AccountLogic) Freeze(c *gin.Context, id types.SearchId, value bool) (*model.Account, error) {
+	return a.updateStatus(c, id, "is_frozen", value)
+}
+
+func (a *AccountLogic) Disable(c *gin.Context, id types.SearchId, value bool) (*model.Account, error) {
+	return a.updateStatus(c, id, "is_disabled", value)
+}
+
+func (a *AccountLogic) Leave(c *gin.Context, id types.SearchId, value bool) (*model.Account, error) {
+	return a.updateStatus(c, id, "is_leave", value)
+}
+
+// updateStatus 修改账号状态，冻结、禁用、离职时由 Account 的更新钩子强制下线
+func (a *AccountLogic) updateStatus(c *gin.Context, id types.SearchId, column string, value bool) (*model.Account, error) {
+	account, err := a.Get(c, id)
+	if err != nil {
+		return nil, err
+	}
+	if value && account.ID == auth.GetAccountId(c) {
+		return nil, errorx.NewCodeError(errorx.ErrBusinessLogic, "不允许修改当前登录账号状态")
+	}
+	if err := a.db.WithContext(c).Model(account).Update(column, value).Error; err != nil {
+		a.l.Error(fmt.Sprintf("修改账号状态失败, id: %d, %s: %t, error: %s", id.Id, column, value, err.Error()))
+		return nil, fmt.Errorf("修改账号状态失败")
+	}
+	a.l.Info(fmt.Sprintf("修改账号状态成功, id: %d, %s: %t, operator: %d", id.Id, column, value, auth.GetAccountId(c)))
+	return account, nil
+}
+
+// revoke 强制账号下线，失败只记录日志
+func (a *AccountLogic) revoke(c *gin.Context, accountId uint) {
+	if err := auth.RevokeAccount(c, accountId); err != nil {
+		a.l.Warn(fmt.Sprintf("账号强制下线失败, id: %d, error: %s", accountId, err.Error()))
+	}
+}
+
+// checkUnique 检查账号、手机号、邮箱、工号是否已被其他账号使用
+func (a *AccountLogic) checkUnique(c *gin.Context, account *model.Account) error {
+	var exist model.Account
+	err := a.db.WithContext(c).Where("id <> ?", account.ID).
+		Where(a.db.Where("account = ?", account.Account).
+			Or("mobile = ?", account.Mobile).
+			Or("email = ?", account.Email).
+			Or("work_number = ?", account.WorkNumber)).
+		First(&exist).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		a.l.Error(fmt.Sprintf("查询账号信息失败, error: %s", err.Error()))
+		return fmt.Errorf("查询账号信息失败")
+	}
+	switch {
+	case exist.Account == account.Account:
+		return errorx.NewCodeError(errorx.ErrDataConflict, "账号已存在")
+	case exist.Mobile == account.Mobile:
+		return errorx.NewCodeError(errorx.ErrDataConflict, "手机号已存在")
+	case exist.Email == account.Email:
+		return errorx.NewCodeError(errorx.ErrDataConflict, "邮箱已存在")
+	default:
+		return errorx.NewCodeError(errorx.ErrDataConflict, "工号已存在")
+	}
+}
+
+// checkOrganization 检查组织是否存在
+func (a *AccountLogic) checkOrganization(c *gin.Context, organizationId uint) error {
+	var count int64
+	// 只允许使用数据权限范围内的机构
+	if err := a.db.WithContext(c).Model(&model.Organization{}).Scopes(auth.DataScopeFilter(c, &model.Organization{})).
+		Where("id = ?", organizationId).Count(&count).Error; err != nil {
+		a.l.Error(fmt.Sprintf("查询机构信息失败, id: %d, error: %s", organizationId, err.Error()))
+		return fmt.Errorf("查询机构信息失败")
+	}
+	if count == 0 {
+		return errorx.NewCodeError(errorx.ErrDataNotFound, "组织不存在")
+	}
+	return nil
+}
+
+// 只需要保证 全局对象Config和全局Logger已经加载完成
+func (a *AccountLogic) Config() {
+	a.l = global.L.Named(portal.AppName).Named(portal.AppAccount).Named("logic")
+	a.db = global.DB.GetDb()
+}
+
+func (a *AccountLogic) Name() string {
+	return fmt.Sprintf("%s.%s", portal.AppName, portal.AppAccount)
+}
+
+func init() {
+	// 注册
+	router.RegistryService[service.AccountService](accountLogic)
+}
@@ -0,0 +1,236 @@
+package logic_test
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/logic"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/model"
+	types2 "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/auth"
+	"github.com/yanshicheng/ikube-gin-starter/common/errorx"
+	"github.com/yanshicheng/ikube-gin-starter/common/types"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/jwt"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/testenv"
+	"gorm.io/gorm"
+)
+
+func testContext() *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/", nil)
+	return c
+}
+
+// accountContext 以账号身份发起请求的上下文
+func accountContext(accountId uint) *gin.Context {
+	c := testContext()
+	auth.SetClaims(c, &jwt.Claims{AccountId: accountId})
+	return c
+}
+
+func errorCode(err error) errorx.ErrorCode {
+	var codeErr *errorx.CodeError
+	if errors.As(err, &codeErr) {
+		return codeErr.Code
+	}
+	return 0
+}
+
+func newAccountLogic(t *testing.T) (*logic.AccountLogic, *gorm.DB) {
+	testenv.Setup(t)
+	db := testenv.DB(t, &model.Account{}, &model.Organization{}, &model.PasswordHistory{},
+		&model.RoleAccount{}, &model.AccountApplication{}, &model.AccountOtp{}, &model.OtpRecoveryCode{})
+	testenv.Redis(t)
+	a := &logic.AccountLogic{}
+	a.Config()
+	return a, db
+}
+
+// seedAccount 直接写入账号，手机号、邮箱和工号由 name 生成
+func seedAccount(t *testing.T, db *gorm.DB, name string, organizationId uint) *model.Account {
+	account := &model.Account{UserName: name, Account: name, Mobile: name, Email: name + "@local", WorkNumber: name,
+		HireDate: time.Now(), OrganizationId: organizationId}
+	assert.NoError(t, db.Create(account).Error)
+	return account
+}
+
+// accountNames 查询账号列表，返回账号名称
+func accountNames(t *testing.T, a *logic.AccountLogic, c *gin.Context, search types2.AccountSearch) []string {
+	search.Pagination = types.Pagination{PageNumber: 1, PageSize: 10, Sort: "ASC"}
+	resp, err := a.List(c, search)
+	if !assert.NoError(t, err, "查询账号列表应该成功") {
+		return nil
+	}
+	names := make([]string, 0)
+	for _, account := range *resp.Data.(*[]model.Account) {
+		names = append(names, account.Account)
+	}
+	return names
+}
+
+func TestAccountCheckUnique(t *testing.T) {
+	a, db := newAccountLogic(t)
+	c := accountContext(1)
+	auth.SetDataScope(c, &auth.DataScope{All: true})
+	assert.NoError(t, db.Create(&model.Organization{Name: "root", Level: 1}).Error)
+	zhangsan := seedAccount(t, db, "zhangsan", 1)
+	lisi := seedAccount(t, db, "lisi", 1)
+
+	req := types2.AccountCreateRequest{UserName: "wangwu", Account: "wangwu", Password: "Passw0rd!", Mobile: "wangwu",
+		Email: "wangwu@local", WorkNumber: "wangwu", HireDate: time.Now(), Position: 1, OrganizationId: 1}
+	tests := []struct {
+		name   string
+		modify func(req *types2.AccountCreateRequest)
+		msg    string
+	}{
+		{"账号", func(req *types2.AccountCreateRequest) { req.Account = zhangsan.Account }, "账号已存在"},
+		{"手机号", func(req *types2.AccountCreateRequest) { req.Mobile = zhangsan.Mobile }, "手机号已存在"},
+		{"邮箱", func(req *types2.AccountCreateRequest) { req.Email = zhangsan.Email }, "邮箱已存在"},
+		{"工号", func(req *types2.AccountCreateRequest) { req.WorkNumber = zhangsan.WorkNumber }, "工号已存在"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := req
+			tt.modify(&req)
+			_, err := a.Create(c, req)
+			if assert.Equal(t, errorx.ErrDataConflict, errorCode(err), "%s重复时应该返回数据冲突", tt.name) {
+				assert.Equal(t, tt.msg, err.Error())
+			}
+		})
+	}
+	created, err := a.Create(c, req)
+	assert.NoError(t, err, "不重复时应该创建成功")
+
+	// 修改时排除账号自身
+	update := types2.AccountUpdateRequest{UserName: "李四", Mobile: lisi.Mobile, Email: lisi.Email, WorkNumber: lisi.WorkNumber,
+		HireDate: time.Now(), Position: 1, OrganizationId: 1}
+	_, err = a.Put(c, types.SearchId{Id: lisi.ID}, update)
+	assert.NoError(t, err, "保留自身的手机号、邮箱和工号时应该修改成功")
+	update.Email = created.Email
+	_, err = a.Put(c, types.SearchId{Id: lisi.ID}, update)
+	if assert.Equal(t, errorx.ErrDataConflict, errorCode(err), "使用其他账号的邮箱时应该返回数据冲突") {
+		assert.Equal(t, "邮箱已存在", err.Error())
+	}
+}
+
+func TestAccountDataScope(t *testing.T) {
+	a, db := newAccountLogic(t)
+	zhangsan := seedAccount(t, db, "zhangsan", 1)
+	lisi := seedAccount(t, db, "lisi", 2)
+	seedAccount(t, db, "wangwu", 3)
+
+	tests := []struct {
+		name  string
+		scope *auth.DataScope
+		names []string
+	}{
+		{"全部数据", &auth.DataScope{All: true}, []string{"zhangsan", "lisi", "wangwu"}},
+		{"机构数据", &auth.DataScope{OrganizationIds: []uint{1, 2}}, []string{"zhangsan", "lisi"}},
+		{"本人数据", &auth.DataScope{Self: true, AccountId: lisi.ID}, []string{"lisi"}},
+		{"机构和本人数据", &auth.DataScope{Self: true, AccountId: lisi.ID, OrganizationIds: []uint{1}}, []string{"zhangsan", "lisi"}},
+		{"没有数据权限范围", nil, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := testContext()
+			if tt.scope != nil {
+				auth.SetDataScope(c, tt.scope)
+			}
+			assert.Equal(t, tt.names, accountNames(t, a, c, types2.AccountSearch{}), "列表应该按数据权限过滤")
+		})
+	}
+
+	// 范围外的账号查询不到，也不能修改状态
+	c := testContext()
+	auth.SetDataScope(c, &auth.DataScope{OrganizationIds: []uint{2}})
+	_, err := a.Get(c, types.SearchId{Id: lisi.ID})
+	assert.NoError(t, err, "范围内的账号应该可以查询")
+	_, err = a.Get(c, types.SearchId{Id: zhangsan.ID})
+	assert.Equal(t, errorx.ErrDataNotFound, errorCode(err), "范围外的账号应该查询不到")
+	_, err = a.Freeze(c, types.SearchId{Id: zhangsan.ID}, true)
+	assert.Equal(t, errorx.ErrDataNotFound, errorCode(err), "范围外的账号不能冻结")
+	assert.False(t, findAccountById(t, db, zhangsan.ID).IsFrozen, "范围外的账号不应该被冻结")
+}
+
+func TestAccountSelfStatus(t *testing.T) {
+	a, db := newAccountLogic(t)
+	zhangsan := seedAccount(t, db, "zhangsan", 1)
+	c := accountContext(zhangsan.ID)
+	auth.SetDataScope(c, &auth.DataScope{All: true})
+	id := types.SearchId{Id: zhangsan.ID}
+
+	tests := []struct {
+		name   string
+		update func(*gin.Context, types.SearchId, bool) (*model.Account, error)
+	}{
+		{"冻结", a.Freeze},
+		{"禁用", a.Disable},
+		{"离职", a.Leave},
+	}
+	for _, tt := range tests {
+		_, err := tt.update(c, id, true)
+		assert.Equal(t, errorx.ErrBusinessLogic, errorCode(err), "不允许%s当前登录账号", tt.name)
+		_, err = tt.update(c, id, false)
+		assert.NoError(t, err, "解除%s不受限制", tt.name)
+	}
+	account := findAccountById(t, db, zhangsan.ID)
+	assert.False(t, account.IsFrozen || account.IsDisabled || account.IsLeave, "当前登录账号的状态不应该被修改")
+	assert.Equal(t, errorx.ErrBusinessLogic, errorCode(a.Delete(c, id)), "不允许删除当前登录账号")
+}
+
+func TestAccountStatusRevokeSessions(t *testing.T) {
+	a, db := newAccountLogic(t)
+	operator := seedAccount(t, db, "admin", 1)
+	c := accountContext(operator.ID)
+	auth.SetDataScope(c, &auth.DataScope{All: true})
+
+	tests := []struct {
+		name    string
+		account string
+		update  func(*gin.Context, types.SearchId, bool) (*model.Account, error)
+	}{
+		{"冻结", "zhangsan", a.Freeze},
+		{"禁用", "lisi", a.Disable},
+		{"离职", "wangwu", a.Leave},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			account := seedAccount(t, db, tt.account, 1)
+			sessionId, err := auth.CreateSession(c, account.ID, "127.0.0.1", "")
+			assert.NoError(t, err)
+			claims := &jwt.Claims{AccountId: account.ID, SessionId: sessionId}
+			revoked, err := auth.IsBlacklisted(c, claims)
+			assert.NoError(t, err)
+			assert.False(t, revoked, "修改状态前 token 应该有效")
+
+			_, err = tt.update(c, types.SearchId{Id: account.ID}, true)
+			assert.NoError(t, err, "%s应该成功", tt.name)
+			sessions, err := auth.ListSessions(c, account.ID, "")
+			assert.NoError(t, err)
+			assert.Empty(t, sessions, "%s后应该删除账号的全部会话", tt.name)
+			claims.SessionId = ""
+			revoked, err = auth.IsBlacklisted(c, claims)
+			assert.NoError(t, err)
+			assert.True(t, revoked, "%s前签发的 token 应该失效", tt.name)
+
+			// 解除状态不会强制下线
+			_, err = auth.CreateSession(c, account.ID, "127.0.0.1", "")
+			assert.NoError(t, err)
+			_, err = tt.update(c, types.SearchId{Id: account.ID}, false)
+			assert.NoError(t, err)
+			sessions, err = auth.ListSessions(c, account.ID, "")
+			assert.NoError(t, err)
+			assert.Len(t, sessions, 1, "解除%s不应该删除会话", tt.name)
+		})
+	}
+}
+
+func findAccountById(t *testing.T, db *gorm.DB, id uint) *model.Account {
+	var account model.Account
+	assert.NoError(t, db.First(&account, id).Error)
+	return &account
+}
@@ -0,0 +1,320 @@
+package logic
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/model"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/service"
+	types2 "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/auth"
+	"github.com/yanshicheng/ikube-gin-starter/common/errorx"
+	"github.com/yanshicheng/ikube-gin-starter/common/filter"
+	"github.com/yanshicheng/ikube-gin-starter/common/sql"
+	"github.com/yanshicheng/ikube-gin-starter/common/types"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/router"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 接口检查
+var _ service.ApplicationService = (*ApplicationLogic)(nil)
+
+var applicationLogic = &ApplicationLogic{}
+
+type ApplicationLogic struct {
+	l  *zap.Logger
+	db *gorm.DB
+}
+
+func (a *ApplicationLogic) Get(c *gin.Context, id types.SearchId) (*model.Application, error) {
+	var application model.Application
+	if err := a.db.WithContext(c).Where("id = ?", id.Id).First(&application).Error; err != nil {
+		a.l.Error(fmt.Sprintf("查询应用信息失败, id: %d, error: %s", id.Id, err.Error()))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errorx.NewCodeError(errorx.ErrDataNotFound, "应用不存在")
+		}
+		return nil, fmt.Errorf("查询应用信息失败")
+	}
+	return &application, nil
+}
+
+func (a *ApplicationLogic) List(c *gin.Context, search types2.ApplicationSearch) (*types.QueryResponse, error) {
+	db := a.db.WithContext(c).Model(&model.Application{})
+	if search.Name != "" {
+		db = db.Where(filter.Prefix("name", search.Name))
+	}
+	scope, err := filter.Scope(&model.Application{}, search.Filter)
+	if err != nil {
+		return nil, err
+	}
+	db = db.Scopes(scope).Order(fmt.Sprintf("id %s", search.Sort))
+	var applications []model.Application
+	resp, err := sql.GetPageResponse(db, search.Pagination, &applications)
+	if err != nil {
+		a.l.Error(fmt.Sprintf("查询应用列表失败, error: %s", err.Error()))
+		return nil, fmt.Errorf("查询应用列表失败")
+	}
+	return resp, nil
+}
+
+// Mine 返回当前账号可以访问的应用，超级管理员返回全部应用
+func (a *ApplicationLogic) Mine(c *gin.Context) ([]model.Application, error) {
+	accountId := auth.GetAccountId(c)
+	roles, err := queryAccountRoles(a.db.WithContext(c), accountId, 0)
+	if err != nil {
+		a.l.Error(fmt.Sprintf("查询账号角色失败, id: %d, error: %s", accountId, err.Error()))
+		return nil, fmt.Errorf("查询账号角色失败")
+	}
+	applicationTable := (&model.Application{}).TableName()
+	db := a.db.WithContext(c).Model(&model.Application{})
+	if !isSuperRole(roles) {
+		db = db.Joins(fmt.Sprintf("JOIN %s aa ON aa.application_id = %s.id AND aa.deleted_at IS NULL",
+			(&model.AccountApplication{}).TableName(), applicationTable)).
+			Where("aa.account_id = ?", accountId)
+	}
+	applications := make([]model.Application, 0)
+	if err := db.Order(fmt.Sprintf("%s.id ASC", applicationTable)).Find(&applications).Error; err != nil {
+		a.l.Error(fmt.Sprintf("查询账号应用失败, id: %d, error: %s", accountId, err.Error()))
+		return nil, fmt.Errorf("查询账号应用失败")
+	}
+	return applications, nil
+}
+
+func (a *ApplicationLogic) Create(c *gin.Context, application *model.Application) error {
+	application.ID = 0
+	if err := a.checkUnique(c, application); err != nil {
+		return err
+	}
+	if err := a.db.WithContext(c).Create(application).Error; err != nil {
+		a.l.Error(fmt.Sprintf("创建应用失败, error: %s", err.Error()))
+		return errorx.NewCodeError(errorx.ErrDataCreation, fmt.Sprintf("创建应用失败: %s", err.Error()))
+	}
+	return nil
+}
+
+func (a *ApplicationLogic) Put(c *gin.Context, id types.SearchId, application *model.Application) (*model.Application, error) {
+	oldApplication, err := a.Get(c, id)
+	if err != nil {
+		return nil, err
+	}
+	application.ID = oldApplication.ID
+	if err := a.checkUnique(c, application); err != nil {
+		return nil, err
+	}
+	// 修改操作
+	oldApplication.Name = application.Name
+	oldApplication.Path = application.Path
+	oldApplication.Icon = application.Icon
+	oldApplication.Desc = application.Desc
+	// 保存
+	if err := a.db.WithContext(c).Save(oldApplication).Error; err != nil {
+		a.l.Error(fmt.Sprintf("更新应用信息失败, id: %d, error: %s", id.Id, err.Error()))
+		return nil, fmt.Errorf("更新应用信息失败")
+	}
+	return oldApplication, nil
+}
+
+func (a *ApplicationLogic) Delete(c *gin.Context, id types.SearchId) error {
+	application, err := a.Get(c, id)
+	if err != nil {
+		return err
+	}
+	// 应用下存在菜单或角色时不允许删除
+	var count int64
+	if err := a.db.WithContext(c).Model(&model.Menu{}).Where("application_id = ?", application.ID).Count(&count).Error; err != nil {
+		a.l.Error(fmt.Sprintf("查询应用菜单失败, id: %d, error: %s", id.Id, err.Error()))
+		return fmt.Errorf("查询应用菜单失败")
+	}
+	if count > 0 {
+		return errorx.NewCodeError(errorx.ErrBusinessLogic, "应用下存在菜单")
+	}
+	if err := a.db.WithContext(c).Model(&model.Role{}).Where("application_id = ?", application.ID).Count(&count).Error; err != nil {
+		a.l.Error(fmt.Sprintf("查询应用角色失败, id: %d, error: %s", id.Id, err.Error()))
+		return fmt.Errorf("查询应用角色失败")
+	}
+	if count > 0 {
+		return errorx.NewCodeError(errorx.ErrBusinessLogic, "应用下存在角色")
+	}
+	// 删除应用同时删除账号授权
+	err = a.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("application_id = ?", application.ID).Delete(&model.AccountApplication{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(application).Error
+	})
+	if err != nil {
+		a.l.Error(fmt.Sprintf("删除应用失败, id: %d, error: %s", id.Id, err.Error()))
+		return errorx.NewCodeError(errorx.ErrDataDeletion, "删除应用失败")
+	}
+	return nil
+}
+
+// Accounts 分页查询已授权访问应用的账号
+func (a *ApplicationLogic) Accounts(c *gin.Context, id types.SearchId, search types2.ApplicationAccountSearch) (*types.QueryResponse, error) {
+	if _, err := a.Get(c, id); err != nil {
+		return nil, err
+	}
+	accountTable := (&model.Account{}).TableName()
+	db := a.db.WithContext(c).Model(&model.Account{}).
+		Joins(fmt.Sprintf("JOIN %s aa ON aa.account_id = %s.id AND aa.deleted_at IS NULL",
+			(&model.AccountApplication{}).TableName(), accountTable)).
+		Where("aa.application_id = ?", id.Id).
+		Scopes(auth.DataScopeFilter(c, &model.Account{}))
+	if search.Account != "" {
+		db = db.Where(filter.Prefix(accountTable+".account", search.Account))
+	}
+	db = db.Order(fmt.Sprintf("%s.id %s", accountTable, search.Sort))
+	var accounts []model.Account
+	resp, err := sql.GetPageResponse(db, search.Pagination, &accounts)
+	if err != nil {
+		a.l.Error(fmt.Sprintf("查询应用账号失败, id: %d, error: %s", id.Id, err.Error()))
+		return nil, fmt.Errorf("查询应用账号失败")
+	}
+	return resp, nil
+}
+
+// Grant 授权账号访问应用，已授权的账号忽略
+func (a *ApplicationLogic) Grant(c *gin.Context, id types.SearchId, req types2.ApplicationAccountRequest) error {
+	if _, err := a.getManaged(c, id); err != nil {
+		return err
+	}
+	accountIds := uniqueIds(req.AccountIds)
+	if err := a.checkAccounts(c, accountIds); err != nil {
+		return err
+	}
+	var exists []uint
+	if err := a.db.WithContext(c).Model(&model.AccountApplication{}).
+		Where("application_id = ? AND account_id IN ?", id.Id, accountIds).
+		Pluck("account_id", &exists).Error; err != nil {
+		a.l.Error(fmt.Sprintf("查询应用授权失败, id: %d, error: %s", id.Id, err.Error()))
+		return fmt.Errorf("查询应用授权失败")
+	}
+	existMap := make(map[uint]bool, len(exists))
+	for _, accountId := range exists {
+		existMap[accountId] = true
+	}
+	records := make([]model.AccountApplication, 0, len(accountIds))
+	for _, accountId := range accountIds {
+		if !existMap[accountId] {
+			records = append(records, model.AccountApplication{AccountId: accountId, ApplicationId: id.Id})
+		}
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	if err := a.db.WithContext(c).Create(&records).Error; err != nil {
+		a.l.Error(fmt.Sprintf("授权应用失败, id: %d, error: %s", id.Id, err.Error()))
+		return errorx.NewCodeError(errorx.ErrDataCreation, "授权应用失败")
+	}
+	return nil
+}
+
+// Revoke 撤销账号访问应用的授权
+func (a *ApplicationLogic) Revoke(c *gin.Context, id types.SearchId, req types2.ApplicationAccountRequest) error {
+	if _, err := a.getManaged(c, id); err != nil {
+		return err
+	}
+	var records []model.AccountApplication
+	if err := a.db.WithContext(c).Where("application_id = ? AND account_id IN ?", id.Id, uniqueIds(req.AccountIds)).
+		Find(&records).Error; err != nil {
+		a.l.Error(fmt.Sprintf("查询应用授权失败, id: %d, error: %s", id.Id, err.Error()))
+		return fmt.Errorf("查询应用授权失败")
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	// 逐条删除，保证钩子函数能够拿到账号并清除权限缓存
+	if err := a.db.WithContext(c).Unscoped().Delete(&records).Error; err != nil {
+		a.l.Error(fmt.Sprintf("撤销应用授权失败, id: %d, error: %s", id.Id, err.Error()))
+		return errorx.NewCodeError(errorx.ErrDataDeletion, "撤销应用授权失败")
+	}
+	return nil
+}
+
+// getManaged 查询当前账号可以管理授权的应用，非超级管理员只能管理当前请求所属的应用
+func (a *ApplicationLogic) getManaged(c *gin.Context, id types.SearchId) (*model.Application, error) {
+	scope, err := applicationScope(c, "id")
+	if err != nil {
+		a.l.Error(fmt.Sprintf("加载账号权限失败, id: %d, error: %s", auth.GetAccountId(c), err.Error()))
+		return nil, fmt.Errorf("加载账号权限失败")
+	}
+	var application model.Application
+	if err := a.db.WithContext(c).Scopes(scope).Where("id = ?", id.Id).First(&application).Error; err != nil {
+		a.l.Error(fmt.Sprintf("查询应用信息失败, id: %d, error: %s", id.Id, err.Error()))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errorx.NewCodeError(errorx.ErrDataNotFound, "应用不存在")
+		}
+		return nil, fmt.Errorf("查询应用信息失败")
+	}
+	return &application, nil
+}
+
+// checkUnique 检查应用名称、路径、图标是否已被使用
+func (a *ApplicationLogic) checkUnique(c *gin.Context, application *model.Application) error {
+	var exist model.Application
+	err := a.db.WithContext(c).Where("id <> ?", application.ID).
+		Where(a.db.Where("name = ?", application.Name).
+			Or("path = ?", application.Path).
+			Or("icon = ?", application.Icon)).
+		First(&exist).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		a.l.Error(fmt.Sprintf("查询应用信息失败, error: %s", err.Error()))
+		return fmt.Errorf("查询应用信息失败")
+	}
+	switch {
+	case exist.Name == application.Name:
+		return errorx.NewCodeError(errorx.ErrDataConflict, "应用名称已存在")
+	case exist.Path == application.Path:
+		return errorx.NewCodeError(errorx.ErrDataConflict, "应用路径已存在")
+	default:
+		return errorx.NewCodeError(errorx.ErrDataConflict, "应用图标已存在")
+	}
+}
+
+// checkAccounts 检查账号是否全部存在
+func (a *ApplicationLogic) checkAccounts(c *gin.Context, accountIds []uint) error {
+	var count int64
+	if err := a.db.WithContext(c).Model(&model.Account{}).Where("id IN ?", accountIds).Count(&count).Error; err != nil {
+		a.l.Error(fmt.Sprintf("查询账号信息失败, error: %s", err.Error()))
+		return fmt.Errorf("查询账号信息失败")
+	}
+	if int(count) != len(accountIds) {
+		return errorx.NewCodeError(errorx.ErrDataNotFound, "账号不存在")
+	}
+	return nil
+}
+
+// uniqueIds 去除重复的 ID，保持原有顺序
+func uniqueIds(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+// 只需要保证 全局对象Config和全局Logger已经加载完成
+func (a *ApplicationLogic) Config() {
+	a.l = global.L.Named(portal.AppName).Named(portal.AppApplication).Named("logic")
+	a.db = global.DB.GetDb()
+}
+
+func (a *ApplicationLogic) Name() string {
+	return fmt.Sprintf("%s.%s", portal.AppName, portal.AppApplication)
+}
+
+func init() {
+	// 注册
+	router.RegistryService[service.ApplicationService](applicationLogic)
+}
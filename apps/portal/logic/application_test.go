@@ -0,0 +1,56 @@
+package logic_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/logic"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/model"
+	types2 "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/auth"
+	"github.com/yanshicheng/ikube-gin-starter/common/errorx"
+	"github.com/yanshicheng/ikube-gin-starter/common/types"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/jwt"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/testenv"
+)
+
+func TestApplicationGrantCrossApplication(t *testing.T) {
+	testenv.Setup(t)
+	db := testenv.DB(t, &model.Application{}, &model.Account{}, &model.AccountApplication{})
+	authorizer := enableRbac(t, auth.PermissionSet{})
+	a := &logic.ApplicationLogic{}
+	a.Config()
+
+	apps := []*model.Application{{Name: "portal", Path: "portal", Icon: "portal"}, {Name: "book", Path: "book", Icon: "book"}}
+	assert.NoError(t, db.Create(&apps).Error)
+	account := &model.Account{UserName: "张三", Account: "zhangsan", Mobile: "13800000001", Email: "zhangsan@example.com", WorkNumber: "001"}
+	assert.NoError(t, db.Create(account).Error)
+	assert.NoError(t, db.Create(&model.AccountApplication{AccountId: account.ID, ApplicationId: apps[0].ID}).Error)
+	req := types2.ApplicationAccountRequest{AccountIds: []uint{account.ID}}
+	granted := func(applicationId uint) bool {
+		var count int64
+		assert.NoError(t, db.Model(&model.AccountApplication{}).
+			Where("account_id = ? AND application_id = ?", account.ID, applicationId).Count(&count).Error)
+		return count > 0
+	}
+
+	// 在应用 book 下不能管理应用 portal 的授权
+	c := testContext()
+	auth.SetClaims(c, &jwt.Claims{AccountId: 2})
+	c.Request.Header.Set(auth.ApplicationHeader, "2")
+	err := a.Revoke(c, types.SearchId{Id: apps[0].ID}, req)
+	assert.Equal(t, errorx.ErrDataNotFound, errorCode(err), "不能撤销其他应用的授权")
+	assert.True(t, granted(apps[0].ID), "其他应用的授权不应该被撤销")
+
+	// 只能管理当前应用的授权
+	assert.NoError(t, a.Grant(c, types.SearchId{Id: apps[1].ID}, req), "可以授权访问当前应用")
+	assert.True(t, granted(apps[1].ID))
+	c.Request.Header.Set(auth.ApplicationHeader, "1")
+	err = a.Grant(c, types.SearchId{Id: apps[1].ID}, req)
+	assert.Equal(t, errorx.ErrDataNotFound, errorCode(err), "不能授权访问其他应用")
+
+	// 超级管理员可以管理所有应用的授权
+	authorizer.perms.Super = true
+	assert.NoError(t, a.Revoke(c, types.SearchId{Id: apps[1].ID}, req), "超级管理员可以撤销其他应用的授权")
+	assert.False(t, granted(apps[1].ID))
+}
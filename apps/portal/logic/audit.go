@@ -0,0 +1,143 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/service"
+	types2 "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/audit"
+	"github.com/yanshicheng/ikube-gin-starter/common/errorx"
+	"github.com/yanshicheng/ikube-gin-starter/common/filter"
+	"github.com/yanshicheng/ikube-gin-starter/common/sql"
+	"github.com/yanshicheng/ikube-gin-starter/common/types"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/router"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 审计日志由 common/audit 的回调写入，这里提供查询和过期清理。
+
+// 接口检查
+var _ service.AuditService = (*AuditLogic)(nil)
+var _ router.Lifecycle = (*AuditLogic)(nil)
+
+var auditLogic = &AuditLogic{}
+
+type AuditLogic struct {
+	l        *zap.Logger
+	db       *gorm.DB
+	schedule schedule
+}
+
+func (a *AuditLogic) List(c *gin.Context, search types2.AuditSearch) (*types.QueryResponse, error) {
+	db := a.db.WithContext(c).Model(&audit.Log{})
+	if search.AccountId != 0 {
+		db = db.Where("account_id = ?", search.AccountId)
+	}
+	if search.Account != "" {
+		db = db.Where(filter.Prefix("account", search.Account))
+	}
+	if search.Action != "" {
+		db = db.Where("action = ?", search.Action)
+	}
+	if search.EntityType != "" {
+		db = db.Where("entity_type = ?", search.EntityType)
+	}
+	if search.EntityId != "" {
+		db = db.Where("entity_id = ?", search.EntityId)
+	}
+	if search.Route != "" {
+		db = db.Where(filter.Prefix("route", search.Route))
+	}
+	if search.Ip != "" {
+		db = db.Where("ip = ?", search.Ip)
+	}
+	if search.Start != nil {
+		db = db.Where("created_at >= ?", *search.Start)
+	}
+	if search.End != nil {
+		db = db.Where("created_at < ?", *search.End)
+	}
+	scope, err := filter.Scope(&audit.Log{}, search.Filter)
+	if err != nil {
+		return nil, err
+	}
+	db = db.Scopes(scope).Order(fmt.Sprintf("id %s", search.Sort))
+	var logs []audit.Log
+	resp, err := sql.GetPageResponse(db, search.Pagination, &logs)
+	if err != nil {
+		a.l.Error(fmt.Sprintf("查询审计日志失败, error: %s", err.Error()))
+		return nil, fmt.Errorf("查询审计日志失败")
+	}
+	return resp, nil
+}
+
+func (a *AuditLogic) Get(c *gin.Context, id types.SearchId) (*audit.Log, error) {
+	var log audit.Log
+	if err := a.db.WithContext(c).Where("id = ?", id.Id).First(&log).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errorx.NewCodeError(errorx.ErrDataNotFound, "审计日志不存在")
+		}
+		a.l.Error(fmt.Sprintf("查询审计日志失败, id: %d, error: %s", id.Id, err.Error()))
+		return nil, fmt.Errorf("查询审计日志失败")
+	}
+	return &log, nil
+}
+
+// Purge 删除超过保留天数的审计日志，返回删除的数量
+func (a *AuditLogic) Purge(ctx context.Context, retention int) (int64, error) {
+	if retention <= 0 {
+		return 0, nil
+	}
+	before := time.Now().AddDate(0, 0, -retention)
+	result := a.db.WithContext(ctx).Where("created_at < ?", before).Delete(&audit.Log{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("清理审计日志失败: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// 只需要保证 全局对象Config和全局Logger已经加载完成
+func (a *AuditLogic) Config() {
+	a.l = global.L.Named(portal.AppName).Named(portal.AppAudit).Named("logic")
+	a.db = global.DB.GetDb()
+}
+
+// Start 配置了保留天数时定时清理过期的审计日志
+func (a *AuditLogic) Start(ctx context.Context) error {
+	cfg := global.C.Audit
+	if !cfg.Enable || cfg.Retention <= 0 || cfg.Interval <= 0 {
+		return nil
+	}
+	a.schedule.start(time.Duration(cfg.Interval)*time.Second, func(ctx context.Context) {
+		n, err := a.Purge(ctx, global.C.Audit.Retention)
+		if err != nil {
+			a.l.Error(err.Error())
+			return
+		}
+		if n > 0 {
+			a.l.Info(fmt.Sprintf("清理过期审计日志, count: %d", n))
+		}
+	})
+	return nil
+}
+
+// Stop 停止定时清理
+func (a *AuditLogic) Stop(ctx context.Context) error {
+	return a.schedule.stop(ctx)
+}
+
+func (a *AuditLogic) Name() string {
+	return fmt.Sprintf("%s.%s", portal.AppName, portal.AppAudit)
+}
+
+func init() {
+	// 注册
+	router.RegistryService[service.AuditService](auditLogic)
+}
@@ -0,0 +1,43 @@
+package logic_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/logic"
+	types2 "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/audit"
+	"github.com/yanshicheng/ikube-gin-starter/common/types"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/testenv"
+)
+
+func TestAuditListSearch(t *testing.T) {
+	testenv.Setup(t)
+	db := testenv.DB(t, &audit.Log{})
+	a := &logic.AuditLogic{}
+	a.Config()
+	logs := []*audit.Log{
+		{Account: "ops_1", Route: "/api/v1/portal/role", Action: "create", EntityType: "role"},
+		{Account: "opsX1", Route: "/api/v1/portal/role/:id", Action: "update", EntityType: "role"},
+		{Account: "dev", Route: "/api/v1/portal/account", Action: "delete", EntityType: "account"},
+	}
+	assert.NoError(t, db.Create(logs).Error)
+
+	accounts := func(search types2.AuditSearch) []string {
+		search.Pagination = types.Pagination{PageNumber: 1, PageSize: 10, Sort: "ASC"}
+		resp, err := a.List(testContext(), search)
+		if !assert.NoError(t, err, "查询审计日志应该成功") {
+			return nil
+		}
+		accounts := make([]string, 0)
+		for _, log := range *resp.Data.(*[]audit.Log) {
+			accounts = append(accounts, log.Account)
+		}
+		return accounts
+	}
+	assert.Equal(t, []string{"ops_1"}, accounts(types2.AuditSearch{Account: "ops_"}), "账号中的 _ 应该按普通字符匹配")
+	assert.Equal(t, []string{"ops_1", "opsX1"}, accounts(types2.AuditSearch{Route: "/api/v1/portal/role"}), "应该按路由前缀匹配")
+	assert.Empty(t, accounts(types2.AuditSearch{Route: "%"}), "路由中的 % 应该按普通字符匹配")
+	assert.Equal(t, []string{"opsX1", "dev"}, accounts(types2.AuditSearch{Filters: types.Filters{Filter: []string{"action:in:update,delete"}}}),
+		"应该按 filter 参数过滤")
+}
@@ -0,0 +1,306 @@
+package logic
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/model"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/service"
+	types2 "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/auth"
+	"github.com/yanshicheng/ikube-gin-starter/common/errorx"
+	"github.com/yanshicheng/ikube-gin-starter/common/types"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/jwt"
+	"github.com/yanshicheng/ikube-gin-starter/router"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 接口检查
+var _ service.AuthService = (*AuthLogic)(nil)
+
+var authLogic = &AuthLogic{}
+
+type AuthLogic struct {
+	l  *zap.Logger
+	db *gorm.DB
+}
+
+func (a *AuthLogic) Login(c *gin.Context, req types2.LoginRequest) (*types2.TokenResponse, error) {
+	var account model.Account
+	if err := a.db.WithContext(c).Where("account = ?", req.Account).First(&account).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			comparePassword(nil, req.Password)
+			a.l.Info(fmt.Sprintf("登录失败，账号不存在, account: %s", req.Account))
+			return nil, errorx.NewCodeError(errorx.ErrLoginInvalid, "账号或密码错误")
+		}
+		a.l.Error(fmt.Sprintf("查询账号信息失败, account: %s, error: %s", req.Account, err.Error()))
+		return nil, errorx.NewCodeError(errorx.ErrDatabase, "查询账号信息失败")
+	}
+	if !comparePassword(&account, req.Password) {
+		a.l.Info(fmt.Sprintf("登录失败，密码错误, account: %s", req.Account))
+		return nil, errorx.NewCodeError(errorx.ErrLoginInvalid, "账号或密码错误")
+	}
+	if err := checkAccountStatus(&account); err != nil {
+		return nil, err
+	}
+	if err := checkPasswordExpired(&account); err != nil {
+		a.l.Info(fmt.Sprintf("登录失败，需要修改密码, account: %s", req.Account))
+		return nil, err
+	}
+	// 需要两步验证时返回验证凭据，由 OtpLogic.Login 完成登录
+	if err := otpLogic.challenge(c, &account, otpPurposeLogin); err != nil {
+		return nil, err
+	}
+	a.l.Info(fmt.Sprintf("登录成功, account: %s", req.Account))
+	return a.issueTokens(c, &account, "")
+}
+
+func (a *AuthLogic) Refresh(c *gin.Context, req types2.RefreshRequest) (*types2.TokenResponse, error) {
+	claims, err := global.J.ParseToken(req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, errorx.NewCodeError(errorx.ErrLoginExpired, "登录已过期，请重新登录")
+		}
+		return nil, errorx.NewCodeError(errorx.ErrTokenRefresh, "refresh token 无效")
+	}
+	if claims.TokenType != jwt.TokenTypeRefresh {
+		return nil, errorx.NewCodeError(errorx.ErrTokenRefresh, "refresh token 类型错误")
+	}
+	blacklisted, err := auth.IsBlacklisted(c, claims)
+	if err != nil {
+		a.l.Error(err.Error())
+		return nil, errorx.NewCodeError(errorx.ErrTokenRefresh, "token 刷新失败")
+	}
+	if blacklisted {
+		a.l.Warn(fmt.Sprintf("使用已失效的 refresh token, accountId: %d, jti: %s", claims.AccountId, claims.ID))
+		return nil, errorx.NewCodeError(errorx.ErrTokenBlacklisted, "token 已失效，请重新登录")
+	}
+	// 重新加载账号，确保账号状态仍然有效
+	var account model.Account
+	if err := a.db.WithContext(c).Where("id = ?", claims.AccountId).First(&account).Error; err != nil {
+		a.l.Error(fmt.Sprintf("查询账号信息失败, id: %d, error: %s", claims.AccountId, err.Error()))
+		return nil, errorx.NewCodeError(errorx.ErrLoginInvalid, "账号不存在")
+	}
+	if err := checkAccountStatus(&account); err != nil {
+		return nil, err
+	}
+	// refresh token 轮换，旧的 refresh token 只能使用一次，并发请求中只有一个可以占用成功
+	claimed, err := auth.ClaimToken(c, claims)
+	if err != nil {
+		a.l.Error(err.Error())
+		return nil, errorx.NewCodeError(errorx.ErrTokenRefresh, "token 刷新失败")
+	}
+	if !claimed {
+		a.l.Warn(fmt.Sprintf("refresh token 重复使用, accountId: %d, jti: %s", claims.AccountId, claims.ID))
+		return nil, errorx.NewCodeError(errorx.ErrTokenBlacklisted, "token 已失效，请重新登录")
+	}
+	// 刷新后仍属于同一个会话
+	if err := auth.RefreshSession(c, claims, c.ClientIP()); err != nil {
+		if errors.Is(err, auth.ErrSessionNotFound) {
+			a.l.Warn(fmt.Sprintf("会话已删除, accountId: %d, session: %s", claims.AccountId, claims.SessionId))
+			return nil, errorx.NewCodeError(errorx.ErrTokenBlacklisted, "token 已失效，请重新登录")
+		}
+		a.l.Error(err.Error())
+		return nil, errorx.NewCodeError(errorx.ErrTokenRefresh, "token 刷新失败")
+	}
+	return a.issueTokens(c, &account, claims.SessionId)
+}
+
+func (a *AuthLogic) Logout(c *gin.Context, req types2.LogoutRequest) error {
+	claims, ok := auth.GetClaims(c)
+	if !ok {
+		return errorx.NewCodeError(errorx.ErrTokenMissing, "未登录")
+	}
+	if err := auth.AddBlacklist(c, claims); err != nil {
+		a.l.Error(err.Error())
+		return fmt.Errorf("注销失败")
+	}
+	// 删除当前会话，同一会话的 refresh token 随之失效
+	if claims.SessionId != "" {
+		if err := auth.DeleteSession(c, claims.AccountId, claims.SessionId); err != nil && !errors.Is(err, auth.ErrSessionNotFound) {
+			a.l.Warn(fmt.Sprintf("删除会话失败, id: %d, error: %s", claims.AccountId, err.Error()))
+		}
+	}
+	if req.RefreshToken == "" {
+		return nil
+	}
+	refreshClaims, err := global.J.ParseToken(req.RefreshToken)
+	if err != nil {
+		// refresh token 已失效无需处理
+		return nil
+	}
+	if refreshClaims.TokenType != jwt.TokenTypeRefresh || refreshClaims.AccountId != claims.AccountId {
+		return errorx.NewCodeError(errorx.ErrTokenInvalid, "refresh token 不属于当前账号")
+	}
+	if err := auth.AddBlacklist(c, refreshClaims); err != nil {
+		a.l.Error(err.Error())
+		return fmt.Errorf("注销失败")
+	}
+	return nil
+}
+
+func (a *AuthLogic) Revoke(c *gin.Context, id types.SearchId) error {
+	var account model.Account
+	if err := a.db.WithContext(c).Where("id = ?", id.Id).First(&account).Error; err != nil {
+		a.l.Error(fmt.Sprintf("查询账号信息失败, id: %d, error: %s", id.Id, err.Error()))
+		return fmt.Errorf("查询账号信息失败")
+	}
+	if err := auth.RevokeAccount(c, account.ID); err != nil {
+		a.l.Error(err.Error())
+		return fmt.Errorf("强制下线失败")
+	}
+	a.l.Info(fmt.Sprintf("账号已被强制下线, id: %d, operator: %d", account.ID, auth.GetAccountId(c)))
+	return nil
+}
+
+// ChangePassword 修改当前登录账号的密码，修改成功后账号的所有 token 失效，需要重新登录
+func (a *AuthLogic) ChangePassword(c *gin.Context, req types2.PasswordChangeRequest) error {
+	var account model.Account
+	if err := a.db.WithContext(c).Where("id = ?", auth.GetAccountId(c)).First(&account).Error; err != nil {
+		a.l.Error(fmt.Sprintf("查询账号信息失败, id: %d, error: %s", auth.GetAccountId(c), err.Error()))
+		return fmt.Errorf("查询账号信息失败")
+	}
+	// 请求绑定时账号未知，填充账号后重新校验密码策略
+	req.Account = account.Account
+	if err := binding.Validator.ValidateStruct(&req); err != nil {
+		return err
+	}
+	if !comparePassword(&account, req.OldPassword) {
+		a.l.Info(fmt.Sprintf("修改密码失败，原密码错误, account: %s", account.Account))
+		return errorx.NewCodeError(errorx.ErrLoginInvalid, "账号或密码错误")
+	}
+	return a.changePassword(c, &account, req.NewPassword)
+}
+
+// ExpiredPassword 密码过期或被重置的账号无法登录，使用原密码修改密码后重新登录。
+// 只允许修改需要修改的密码，账号需要两步验证时先返回验证凭据，携带凭据和一次性密码再次请求。
+func (a *AuthLogic) ExpiredPassword(c *gin.Context, req types2.PasswordExpiredRequest) error {
+	var account model.Account
+	if err := a.db.WithContext(c).Where("account = ?", req.Account).First(&account).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			comparePassword(nil, req.OldPassword)
+			return errorx.NewCodeError(errorx.ErrLoginInvalid, "账号或密码错误")
+		}
+		a.l.Error(fmt.Sprintf("查询账号信息失败, account: %s, error: %s", req.Account, err.Error()))
+		return errorx.NewCodeError(errorx.ErrDatabase, "查询账号信息失败")
+	}
+	if !comparePassword(&account, req.OldPassword) {
+		a.l.Info(fmt.Sprintf("修改密码失败，原密码错误, account: %s", account.Account))
+		return errorx.NewCodeError(errorx.ErrLoginInvalid, "账号或密码错误")
+	}
+	if err := checkAccountStatus(&account); err != nil {
+		return err
+	}
+	if checkPasswordExpired(&account) == nil {
+		a.l.Info(fmt.Sprintf("修改密码失败，密码未过期, account: %s", account.Account))
+		return errorx.NewCodeError(errorx.ErrBusinessLogic, "密码未过期，请登录后修改密码")
+	}
+	if req.Challenge == "" {
+		if err := otpLogic.challenge(c, &account, otpPurposePassword); err != nil {
+			return err
+		}
+	} else if err := otpLogic.pass(c, &account, req.Challenge, otpPurposePassword, req.Code); err != nil {
+		return err
+	}
+	return a.changePassword(c, &account, req.NewPassword)
+}
+
+// changePassword 原密码已校验，检查密码历史后保存新密码
+func (a *AuthLogic) changePassword(c *gin.Context, account *model.Account, newPassword string) error {
+	if err := checkPasswordReuse(a.db.WithContext(c), account, newPassword); err != nil {
+		return err
+	}
+	hash, err := hashPassword(newPassword)
+	if err != nil {
+		a.l.Error(fmt.Sprintf("密码加密失败, id: %d, error: %s", account.ID, err.Error()))
+		return fmt.Errorf("密码加密失败")
+	}
+	if err := a.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		return savePassword(tx, account, hash, false)
+	}); err != nil {
+		a.l.Error(fmt.Sprintf("修改密码失败, id: %d, error: %s", account.ID, err.Error()))
+		return fmt.Errorf("修改密码失败")
+	}
+	a.l.Info(fmt.Sprintf("修改密码成功, account: %s", account.Account))
+	if err := auth.RevokeAccount(c, account.ID); err != nil {
+		a.l.Warn(fmt.Sprintf("账号强制下线失败, id: %d, error: %s", account.ID, err.Error()))
+	}
+	return nil
+}
+
+// issueTokens 签发 access token 和 refresh token，sessionId 为空时创建新的登录会话
+func (a *AuthLogic) issueTokens(c *gin.Context, account *model.Account, sessionId string) (*types2.TokenResponse, error) {
+	if sessionId == "" {
+		var err error
+		if sessionId, err = auth.CreateSession(c, account.ID, c.ClientIP(), c.Request.UserAgent()); err != nil {
+			a.l.Error(fmt.Sprintf("创建会话失败, id: %d, error: %s", account.ID, err.Error()))
+			return nil, fmt.Errorf("签发 token 失败")
+		}
+	}
+	revision, err := auth.TokenRevision(c, account.ID)
+	if err != nil {
+		a.l.Error(err.Error())
+		return nil, fmt.Errorf("签发 token 失败")
+	}
+	accessToken, err := global.J.GenerateToken(&jwt.Claims{
+		AccountId: account.ID,
+		Account:   account.Account,
+		UserName:  account.UserName,
+		SessionId: sessionId,
+		Revision:  revision,
+	})
+	if err != nil {
+		a.l.Error(fmt.Sprintf("签发 access token 失败, id: %d, error: %s", account.ID, err.Error()))
+		return nil, fmt.Errorf("签发 token 失败")
+	}
+	refreshToken, err := global.J.GenerateRefreshToken(&jwt.Claims{
+		AccountId: account.ID,
+		Account:   account.Account,
+		UserName:  account.UserName,
+		SessionId: sessionId,
+		Revision:  revision,
+	})
+	if err != nil {
+		a.l.Error(fmt.Sprintf("签发 refresh token 失败, id: %d, error: %s", account.ID, err.Error()))
+		return nil, fmt.Errorf("签发 token 失败")
+	}
+	return &types2.TokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(global.J.AccessExpire().Seconds()),
+	}, nil
+}
+
+// checkAccountStatus 检查账号是否允许登录
+func checkAccountStatus(account *model.Account) error {
+	switch {
+	case account.IsLeave:
+		return errorx.NewCodeError(errorx.ErrLoginInvalid, "账号已离职")
+	case account.IsDisabled:
+		return errorx.NewCodeError(errorx.ErrLoginInvalid, "账号已被禁用")
+	case account.IsFrozen:
+		return errorx.NewCodeError(errorx.ErrLoginInvalid, "账号已被冻结")
+	}
+	return nil
+}
+
+// 只需要保证 全局对象Config和全局Logger已经加载完成
+func (a *AuthLogic) Config() {
+	a.l = global.L.Named(portal.AppName).Named(portal.AppAuth).Named("logic")
+	a.db = global.DB.GetDb()
+}
+
+func (a *AuthLogic) Name() string {
+	return fmt.Sprintf("%s.%s", portal.AppName, portal.AppAuth)
+}
+
+func init() {
+	// 注册
+	router.RegistryService[service.AuthService](authLogic)
+}
@@ -0,0 +1,109 @@
+package logic_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/logic"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/model"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/service"
+	types2 "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/errorx"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/testenv"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/totp"
+	"github.com/yanshicheng/ikube-gin-starter/router"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// newAuthLogic 使用注册的认证和两步验证逻辑，两者通过包内变量互相调用
+func newAuthLogic(t *testing.T) (*logic.AuthLogic, *logic.OtpLogic, *gorm.DB) {
+	testenv.Setup(t)
+	db := testenv.DB(t, &model.Account{}, &model.PasswordHistory{}, &model.AccountOtp{}, &model.OtpRecoveryCode{},
+		&model.Role{}, &model.RoleAccount{})
+	testenv.Redis(t)
+	authSvc, err := router.Lookup[service.AuthService]()
+	assert.NoError(t, err, "认证逻辑应该已注册")
+	otpSvc, err := router.Lookup[service.OtpService]()
+	assert.NoError(t, err, "两步验证逻辑应该已注册")
+	a, o := authSvc.(*logic.AuthLogic), otpSvc.(*logic.OtpLogic)
+	a.Config()
+	o.Config()
+	return a, o, db
+}
+
+func createAccount(t *testing.T, db *gorm.DB, name, password string, mustChange bool) *model.Account {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	assert.NoError(t, err)
+	now := time.Now()
+	account := &model.Account{UserName: name, Account: name, Mobile: name, Email: name + "@local", WorkNumber: name,
+		Password: string(hash), PasswordChangedAt: &now, MustChangePassword: mustChange}
+	assert.NoError(t, db.Create(account).Error)
+	return account
+}
+
+func TestAuthLoginNotFound(t *testing.T) {
+	a, _, _ := newAuthLogic(t)
+	_, err := a.Login(testContext(), types2.LoginRequest{Account: "nobody", Password: "Passw0rd!"})
+	assert.Equal(t, errorx.ErrLoginInvalid, errorCode(err), "账号不存在时应该返回账号或密码错误")
+}
+
+func TestAuthExpiredPassword(t *testing.T) {
+	a, _, db := newAuthLogic(t)
+	c := testContext()
+	createAccount(t, db, "zhangsan", "Old-Passw0rd", false)
+	createAccount(t, db, "lisi", "Old-Passw0rd", true)
+
+	// 账号不存在和原密码错误返回相同的错误
+	err := a.ExpiredPassword(c, types2.PasswordExpiredRequest{Account: "nobody", OldPassword: "Old-Passw0rd", NewPassword: "New-Passw0rd"})
+	assert.Equal(t, errorx.ErrLoginInvalid, errorCode(err), "账号不存在时应该返回账号或密码错误")
+	err = a.ExpiredPassword(c, types2.PasswordExpiredRequest{Account: "lisi", OldPassword: "Wrong-Passw0rd", NewPassword: "New-Passw0rd"})
+	assert.Equal(t, errorx.ErrLoginInvalid, errorCode(err), "原密码错误时应该返回账号或密码错误")
+
+	// 密码未过期时不允许通过该接口修改
+	err = a.ExpiredPassword(c, types2.PasswordExpiredRequest{Account: "zhangsan", OldPassword: "Old-Passw0rd", NewPassword: "New-Passw0rd"})
+	assert.Equal(t, errorx.ErrBusinessLogic, errorCode(err), "密码未过期时应该拒绝修改")
+
+	// 被重置的密码可以修改，修改后不再需要修改密码
+	err = a.ExpiredPassword(c, types2.PasswordExpiredRequest{Account: "lisi", OldPassword: "Old-Passw0rd", NewPassword: "New-Passw0rd"})
+	assert.NoError(t, err, "被重置的密码应该可以修改")
+	lisi := findAccount(t, db, "lisi")
+	assert.False(t, lisi.MustChangePassword, "修改后不应该再要求修改密码")
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(lisi.Password), []byte("New-Passw0rd")), "新密码应该生效")
+}
+
+func TestAuthExpiredPasswordOtp(t *testing.T) {
+	a, o, db := newAuthLogic(t)
+	c := testContext()
+	account := createAccount(t, db, "zhangsan", "Old-Passw0rd", true)
+	secret, err := totp.GenerateSecret()
+	assert.NoError(t, err)
+	now := time.Now()
+	assert.NoError(t, db.Create(&model.AccountOtp{AccountId: account.ID, Secret: secret, EnabledAt: &now}).Error)
+	req := types2.PasswordExpiredRequest{Account: "zhangsan", OldPassword: "Old-Passw0rd", NewPassword: "New-Passw0rd"}
+
+	// 已启用两步验证时先返回验证凭据，不修改密码
+	err = a.ExpiredPassword(c, req)
+	assert.Equal(t, errorx.ErrOtpRequired, errorCode(err), "已启用两步验证时应该要求两步验证")
+	challenge := err.(*errorx.CodeError).Data.(*types2.OtpChallengeResponse).Challenge
+	assert.True(t, findAccount(t, db, "zhangsan").MustChangePassword, "两步验证完成前不应该修改密码")
+
+	// 修改密码的验证凭据不能用于登录
+	_, err = o.Login(c, types2.OtpLoginRequest{Challenge: challenge, Code: "000000"})
+	assert.Equal(t, errorx.ErrLoginExpired, errorCode(err), "修改密码的验证凭据不能用于登录")
+
+	// 验证码错误时不修改密码
+	req.Challenge, req.Code = challenge, "000000"
+	if code, _ := totp.Code(secret, totp.Step(time.Now())); code == req.Code {
+		req.Code = "111111"
+	}
+	err = a.ExpiredPassword(c, req)
+	assert.Equal(t, errorx.ErrLoginInvalid, errorCode(err), "验证码错误时应该拒绝修改")
+
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	assert.NoError(t, err)
+	req.Code = code
+	assert.NoError(t, a.ExpiredPassword(c, req), "两步验证通过后应该修改密码")
+	assert.False(t, findAccount(t, db, "zhangsan").MustChangePassword, "修改后不应该再要求修改密码")
+}
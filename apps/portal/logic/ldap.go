@@ -0,0 +1,550 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/model"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/service"
+	types2 "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/audit"
+	"github.com/yanshicheng/ikube-gin-starter/common/auth"
+	"github.com/yanshicheng/ikube-gin-starter/common/errorx"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/ldap"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/oidc"
+	"github.com/yanshicheng/ikube-gin-starter/router"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// 接口检查
+var _ service.LdapSyncService = (*LdapSyncLogic)(nil)
+var _ router.Lifecycle = (*LdapSyncLogic)(nil)
+
+var ldapSyncLogic = &LdapSyncLogic{}
+
+const (
+	LdapActionCreate  = "create"
+	LdapActionUpdate  = "update"
+	LdapActionLink    = "link"
+	LdapActionMissing = "missing"
+	LdapActionSkip    = "skip"
+)
+
+// errLdapDryRun 预览模式下用于回滚事务
+var errLdapDryRun = errors.New("ldap dry run")
+
+// LdapSyncLogic 将目录中的 OU 和用户同步为机构和账号。
+// 目录条目与本地数据通过外部身份表关联，目录中已删除的账号按配置标记为离职或禁用。
+type LdapSyncLogic struct {
+	l        *zap.Logger
+	db       *gorm.DB
+	mu       sync.Mutex
+	schedule schedule
+}
+
+// NewLdapSyncLogic 创建不带定时任务的同步实例，供命令行使用
+func NewLdapSyncLogic(db *gorm.DB, l *zap.Logger) *LdapSyncLogic {
+	return &LdapSyncLogic{l: l, db: db}
+}
+
+// Sync 执行一次同步。预览模式在事务中完成全部变更后回滚，报告与实际同步一致。
+func (s *LdapSyncLogic) Sync(ctx context.Context, dryRun bool) (*types2.LdapSyncReport, error) {
+	cfg := global.C.Ldap
+	if !cfg.Enable {
+		return nil, errorx.NewCodeError(errorx.ErrBusinessLogic, "未启用 ldap 同步")
+	}
+	if !s.mu.TryLock() {
+		return nil, errorx.NewCodeError(errorx.ErrBusinessLogic, "ldap 同步正在进行中")
+	}
+	defer s.mu.Unlock()
+
+	client, err := ldap.InitIkubeLdap(cfg.Url, cfg.BindDn, cfg.BindPassword, cfg.StartTls, cfg.InsecureSkipVerify, cfg.PageSize, cfg.Timeout)
+	if err != nil {
+		s.l.Error(fmt.Sprintf("初始化 ldap 客户端失败, error: %s", err.Error()))
+		return nil, fmt.Errorf("初始化 ldap 客户端失败")
+	}
+	attrs := cfg.Attributes
+	ous, err := client.Search(cfg.BaseDn, cfg.OrganizationFilter, []string{attrs.Organization, attrs.Desc})
+	if err != nil {
+		s.l.Error(err.Error())
+		return nil, fmt.Errorf("查询目录机构失败")
+	}
+	users, err := client.Search(cfg.BaseDn, cfg.UserFilter,
+		[]string{attrs.Uid, attrs.Account, attrs.UserName, attrs.Email, attrs.WorkNumber, attrs.Mobile})
+	if err != nil {
+		s.l.Error(err.Error())
+		return nil, fmt.Errorf("查询目录用户失败")
+	}
+
+	report := &types2.LdapSyncReport{
+		DryRun:        dryRun,
+		Organizations: make([]types2.LdapSyncItem, 0),
+		Accounts:      make([]types2.LdapSyncItem, 0),
+	}
+	db := s.db.WithContext(ctx)
+	if dryRun {
+		// 试运行的变更会回滚，账号不强制下线
+		db = db.Set(model.SkipSessionRevoke, true)
+	}
+	// 离职或禁用的账号由 Account 的更新钩子强制下线
+	err = db.Transaction(func(tx *gorm.DB) error {
+		orgIds, err := s.syncOrganizations(tx, ous, report)
+		if err != nil {
+			return err
+		}
+		if err := s.syncAccounts(tx, users, orgIds, report); err != nil {
+			return err
+		}
+		if dryRun {
+			return errLdapDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errLdapDryRun) {
+		s.l.Error(fmt.Sprintf("ldap 同步失败, error: %s", err.Error()))
+		var codeErr *errorx.CodeError
+		if errors.As(err, &codeErr) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("ldap 同步失败")
+	}
+	s.l.Info(fmt.Sprintf("ldap 同步完成, dryRun: %t, 机构变更: %d, 账号变更: %d",
+		dryRun, len(report.Organizations), len(report.Accounts)))
+	return report, nil
+}
+
+// syncOrganizations 按层级从上到下同步 OU，返回规范化 DN 到机构ID的映射
+func (s *LdapSyncLogic) syncOrganizations(tx *gorm.DB, entries []*ldap.Entry, report *types2.LdapSyncReport) (map[string]uint, error) {
+	cfg := global.C.Ldap
+	issuer := ldapIssuer()
+	identities, err := s.organizationIdentities(tx, issuer)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return ldap.Depth(entries[i].DN) < ldap.Depth(entries[j].DN)
+	})
+	orgLogic := &OrganizationLogic{l: s.l, db: tx}
+	base := ldap.NormalizeDN(cfg.BaseDn)
+	orgIds := map[string]uint{base: cfg.RootOrganizationId}
+	for _, e := range entries {
+		dn := ldap.NormalizeDN(e.DN)
+		if dn == base {
+			continue
+		}
+		name := truncate(e.Attr(cfg.Attributes.Organization), 32)
+		if name == "" {
+			report.Organizations = append(report.Organizations, types2.LdapSyncItem{
+				Action: LdapActionSkip, Dn: e.DN, Changes: []string{"缺少机构名称属性"}})
+			continue
+		}
+		desc := truncate(e.Attr(cfg.Attributes.Desc), 56)
+		parentId, ok := orgIds[ldap.ParentDN(e.DN)]
+		if !ok {
+			parentId = cfg.RootOrganizationId
+		}
+
+		var org model.Organization
+		identity, linked := identities[dn]
+		if linked {
+			err := tx.Where("id = ?", identity.OrganizationId).First(&org).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				// 机构已在本地删除，重新创建并关联
+				if err := tx.Unscoped().Delete(&identity).Error; err != nil {
+					return nil, err
+				}
+				linked = false
+			} else if err != nil {
+				return nil, err
+			}
+		}
+		if !linked {
+			org = model.Organization{Name: name, ParentId: parentId, Desc: desc}
+			if err := tx.Create(&org).Error; err != nil {
+				report.Organizations = append(report.Organizations, types2.LdapSyncItem{
+					Action: LdapActionSkip, Dn: e.DN, Name: name, Changes: []string{err.Error()}})
+				continue
+			}
+			if err := tx.Create(&model.OrganizationIdentity{OrganizationId: org.ID, Issuer: issuer, Subject: dn}).Error; err != nil {
+				return nil, err
+			}
+			orgIds[dn] = org.ID
+			report.Organizations = append(report.Organizations, types2.LdapSyncItem{Action: LdapActionCreate, Dn: e.DN, Name: name})
+			continue
+		}
+
+		orgIds[dn] = org.ID
+		var changes []string
+		updates := map[string]interface{}{}
+		if org.Name != name {
+			changes = append(changes, fmt.Sprintf("name: %s -> %s", org.Name, name))
+			updates["name"] = name
+		}
+		if org.Desc != desc {
+			changes = append(changes, fmt.Sprintf("desc: %s -> %s", org.Desc, desc))
+			updates["desc"] = desc
+		}
+		if len(updates) > 0 {
+			if err := tx.Model(&org).Updates(updates).Error; err != nil {
+				return nil, err
+			}
+		}
+		if org.ParentId != parentId {
+			if err := orgLogic.moveTree(tx, &org, parentId); err != nil {
+				report.Organizations = append(report.Organizations, types2.LdapSyncItem{
+					Action: LdapActionSkip, Dn: e.DN, Name: name, Changes: []string{err.Error()}})
+				continue
+			}
+			changes = append(changes, fmt.Sprintf("parentId: %d -> %d", org.ParentId, parentId))
+		}
+		if len(changes) > 0 {
+			report.Organizations = append(report.Organizations, types2.LdapSyncItem{
+				Action: LdapActionUpdate, Dn: e.DN, Name: name, Changes: changes})
+		}
+	}
+	return orgIds, nil
+}
+
+// syncAccounts 同步目录用户，目录中已删除的账号按配置标记为离职或禁用。
+// 目录用户按唯一标识属性关联本地账号，同名的本地账号只有在配置允许时才会关联。
+// 目录查询结果为空或缺失的已关联账号超过阈值时中止同步，避免目录异常导致账号被批量禁用。
+func (s *LdapSyncLogic) syncAccounts(tx *gorm.DB, entries []*ldap.Entry, orgIds map[string]uint, report *types2.LdapSyncReport) error {
+	cfg := global.C.Ldap
+	attrs := cfg.Attributes
+	issuer := ldapIssuer()
+	identities, err := s.accountIdentities(tx, issuer)
+	if err != nil {
+		return err
+	}
+	subjects := make(map[*ldap.Entry]string, len(entries))
+	seen := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		if subject := ldapSubject(e); subject != "" {
+			subjects[e] = subject
+			seen[subject] = true
+		}
+	}
+	if err := checkLdapMissing(identities, seen); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		name := e.Attr(attrs.Account)
+		if name == "" || len([]rune(name)) > 32 {
+			report.Accounts = append(report.Accounts, types2.LdapSyncItem{
+				Action: LdapActionSkip, Dn: e.DN, Name: name, Changes: []string{"账号属性为空或超过 32 个字符"}})
+			continue
+		}
+		subject, ok := subjects[e]
+		if !ok {
+			report.Accounts = append(report.Accounts, types2.LdapSyncItem{
+				Action: LdapActionSkip, Dn: e.DN, Name: name, Changes: []string{"缺少唯一标识属性且 DN 超过 191 个字符"}})
+			continue
+		}
+		target := model.Account{
+			Account:    name,
+			UserName:   truncate(e.Attr(attrs.UserName), 32),
+			Email:      truncate(e.Attr(attrs.Email), 36),
+			WorkNumber: truncate(e.Attr(attrs.WorkNumber), 24),
+			Mobile:     truncate(e.Attr(attrs.Mobile), 11),
+		}
+		if target.UserName == "" {
+			target.UserName = name
+		}
+		if orgId, ok := orgIds[ldap.ParentDN(e.DN)]; ok {
+			target.OrganizationId = orgId
+		} else {
+			target.OrganizationId = cfg.RootOrganizationId
+		}
+
+		action := LdapActionUpdate
+		var account model.Account
+		identity, linked := identities[subject]
+		found := false
+		if linked {
+			err := tx.Where("id = ?", identity.AccountId).First(&account).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				if err := tx.Unscoped().Delete(&identity).Error; err != nil {
+					return err
+				}
+				linked = false
+			} else if err != nil {
+				return err
+			} else {
+				found = true
+			}
+		}
+		if !found {
+			// 同名的本地账号只有在配置允许时才关联，否则跳过，避免目录账号接管本地账号
+			err := tx.Where("account = ?", name).First(&account).Error
+			if err == nil {
+				allowed, err := ldapLinkAllowed(tx, &account)
+				if err != nil {
+					return err
+				}
+				if !allowed {
+					report.Accounts = append(report.Accounts, types2.LdapSyncItem{
+						Action: LdapActionSkip, Dn: e.DN, Name: name, Changes: []string{"本地已存在同名账号，未允许关联"}})
+					continue
+				}
+				found = true
+				action = LdapActionLink
+			} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+		}
+
+		if !found {
+			if target.OrganizationId == 0 {
+				report.Accounts = append(report.Accounts, types2.LdapSyncItem{
+					Action: LdapActionSkip, Dn: e.DN, Name: name, Changes: []string{"无法确定账号所属机构"}})
+				continue
+			}
+			if err := s.createAccount(tx, &target); err != nil {
+				return err
+			}
+			account = target
+			action = LdapActionCreate
+		}
+		if !linked {
+			if err := tx.Create(&model.AccountIdentity{AccountId: account.ID, Issuer: issuer, Subject: subject}).Error; err != nil {
+				return err
+			}
+		}
+		if action == LdapActionCreate {
+			report.Accounts = append(report.Accounts, types2.LdapSyncItem{Action: action, Dn: e.DN, Name: name})
+			continue
+		}
+		changes, err := s.updateAccount(tx, &account, &target)
+		if err != nil {
+			return err
+		}
+		if len(changes) > 0 || action == LdapActionLink {
+			report.Accounts = append(report.Accounts, types2.LdapSyncItem{Action: action, Dn: e.DN, Name: name, Changes: changes})
+		}
+	}
+
+	// 目录中已删除的账号
+	for subject, identity := range identities {
+		if seen[subject] {
+			continue
+		}
+		var account model.Account
+		if err := tx.Where("id = ?", identity.AccountId).First(&account).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return err
+		}
+		updates := map[string]interface{}{}
+		var changes []string
+		if cfg.MissingLeave && !account.IsLeave {
+			updates["is_leave"] = true
+			changes = append(changes, "isLeave: false -> true")
+		}
+		if cfg.MissingDisable && !account.IsDisabled {
+			updates["is_disabled"] = true
+			changes = append(changes, "isDisabled: false -> true")
+		}
+		if len(updates) == 0 {
+			continue
+		}
+		if err := tx.Model(&account).Updates(updates).Error; err != nil {
+			return err
+		}
+		report.Accounts = append(report.Accounts, types2.LdapSyncItem{Action: LdapActionMissing, Name: account.Account, Changes: changes})
+	}
+	sort.SliceStable(report.Accounts, func(i, j int) bool {
+		return report.Accounts[i].Name < report.Accounts[j].Name
+	})
+	return nil
+}
+
+// ldapSubject 目录用户在外部身份表中的标识，优先使用唯一标识属性，未配置或缺失时使用规范化后的 DN
+func ldapSubject(e *ldap.Entry) string {
+	if attr := global.C.Ldap.Attributes.Uid; attr != "" {
+		if uid := e.Attr(attr); uid != "" {
+			return uid
+		}
+	}
+	if dn := ldap.NormalizeDN(e.DN); len(dn) <= 191 {
+		return dn
+	}
+	return ""
+}
+
+// checkLdapMissing 检查目录中缺失的已关联账号，查询结果为空，或缺失账号需要标记离职、禁用且比例超过阈值时中止同步
+func checkLdapMissing(identities map[string]model.AccountIdentity, seen map[string]bool) error {
+	if len(identities) == 0 {
+		return nil
+	}
+	if len(seen) == 0 {
+		return errorx.NewCodeError(errorx.ErrBusinessLogic, "目录中没有查询到用户，已中止同步，请检查 user_filter 配置")
+	}
+	// 缺失的账号不做处理时无需检查
+	if !global.C.Ldap.MissingLeave && !global.C.Ldap.MissingDisable {
+		return nil
+	}
+	missing := 0
+	for subject := range identities {
+		if !seen[subject] {
+			missing++
+		}
+	}
+	if threshold := global.C.Ldap.MissingThreshold; missing*100 > len(identities)*threshold {
+		return errorx.NewCodeError(errorx.ErrBusinessLogic, fmt.Sprintf(
+			"目录中缺失 %d/%d 个已关联账号，超过阈值 %d%%，已中止同步", missing, len(identities), threshold))
+	}
+	return nil
+}
+
+// ldapLinkAllowed 判断本地账号是否允许与目录中的同名账号关联，
+// 配置中列出的账号允许关联，配置 * 时允许关联除超级管理员以外的全部账号
+func ldapLinkAllowed(tx *gorm.DB, account *model.Account) (bool, error) {
+	wildcard := false
+	for _, name := range global.C.Ldap.LinkAccounts {
+		if name == account.Account {
+			return true, nil
+		}
+		wildcard = wildcard || name == "*"
+	}
+	if !wildcard {
+		return false, nil
+	}
+	roles, err := queryAccountRoles(tx, account.ID, 0)
+	if err != nil {
+		return false, err
+	}
+	return !isSuperRole(roles), nil
+}
+
+// updateAccount 更新目录中维护的账号字段，返回变更说明
+func (s *LdapSyncLogic) updateAccount(tx *gorm.DB, account, target *model.Account) ([]string, error) {
+	var changes []string
+	updates := map[string]interface{}{}
+	compare := func(column, old, new string) {
+		if old != new {
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", column, old, new))
+			updates[column] = new
+		}
+	}
+	compare("user_name", account.UserName, target.UserName)
+	compare("email", account.Email, target.Email)
+	compare("work_number", account.WorkNumber, target.WorkNumber)
+	compare("mobile", account.Mobile, target.Mobile)
+	organizationChanged := target.OrganizationId != 0 && account.OrganizationId != target.OrganizationId
+	if organizationChanged {
+		changes = append(changes, fmt.Sprintf("organization_id: %d -> %d", account.OrganizationId, target.OrganizationId))
+		updates["organization_id"] = target.OrganizationId
+	}
+	if len(updates) == 0 {
+		return nil, nil
+	}
+	if err := tx.Model(account).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	// 所属机构变化会影响账号的数据权限范围
+	if organizationChanged {
+		auth.ClearPermissionCacheAfterCommit(tx, account.ID)
+	}
+	return changes, nil
+}
+
+// createAccount 创建目录账号，密码随机生成，账号需要通过单点登录或重置密码后登录
+func (s *LdapSyncLogic) createAccount(tx *gorm.DB, account *model.Account) error {
+	secret, err := oidc.RandomString()
+	if err != nil {
+		return err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	account.Password = string(hash)
+	account.HireDate = time.Now()
+	return tx.Create(account).Error
+}
+
+func (s *LdapSyncLogic) organizationIdentities(tx *gorm.DB, issuer string) (map[string]model.OrganizationIdentity, error) {
+	var records []model.OrganizationIdentity
+	if err := tx.Where("issuer = ?", issuer).Find(&records).Error; err != nil {
+		return nil, err
+	}
+	identities := make(map[string]model.OrganizationIdentity, len(records))
+	for _, r := range records {
+		identities[r.Subject] = r
+	}
+	return identities, nil
+}
+
+func (s *LdapSyncLogic) accountIdentities(tx *gorm.DB, issuer string) (map[string]model.AccountIdentity, error) {
+	var records []model.AccountIdentity
+	if err := tx.Where("issuer = ?", issuer).Find(&records).Error; err != nil {
+		return nil, err
+	}
+	identities := make(map[string]model.AccountIdentity, len(records))
+	for _, r := range records {
+		identities[r.Subject] = r
+	}
+	return identities, nil
+}
+
+// ldapIssuer 目录条目在外部身份表中的来源标识
+func ldapIssuer() string {
+	return "ldap:" + ldap.NormalizeDN(global.C.Ldap.BaseDn)
+}
+
+// truncate 按字符截断，避免超出列长度
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
+
+// 只需要保证 全局对象Config和全局Logger已经加载完成
+func (s *LdapSyncLogic) Config() {
+	s.l = global.L.Named(portal.AppName).Named(portal.AppLdap).Named("logic")
+	s.db = global.DB.GetDb()
+}
+
+// Start 启用定时同步时启动后台任务
+func (s *LdapSyncLogic) Start(ctx context.Context) error {
+	if !global.C.Ldap.Enable || global.C.Ldap.Interval <= 0 {
+		return nil
+	}
+	s.schedule.start(time.Duration(global.C.Ldap.Interval)*time.Second, func(ctx context.Context) {
+		ctx = audit.WithActor(ctx, &audit.Actor{Account: "ldap"})
+		if _, err := s.Sync(ctx, false); err != nil {
+			s.l.Error(fmt.Sprintf("ldap 定时同步失败, error: %s", err.Error()))
+		}
+	})
+	return nil
+}
+
+// Stop 停止定时同步，等待正在进行的同步结束
+func (s *LdapSyncLogic) Stop(ctx context.Context) error {
+	return s.schedule.stop(ctx)
+}
+
+func (s *LdapSyncLogic) Name() string {
+	return fmt.Sprintf("%s.%s", portal.AppName, portal.AppLdap)
+}
+
+// Dependencies 同步 OU 时使用 OrganizationLogic 维护机构层级
+func (s *LdapSyncLogic) Dependencies() []string {
+	return []string{logic.Name()}
+}
+
+func init() {
+	// 注册
+	router.RegistryService[service.LdapSyncService](ldapSyncLogic)
+}
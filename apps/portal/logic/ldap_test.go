@@ -0,0 +1,192 @@
+package logic_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/logic"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/model"
+	types2 "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/errorx"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/ldap"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/ldap/ldaptest"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/testenv"
+	pkgtypes "github.com/yanshicheng/ikube-gin-starter/pkg/types"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const ldapBaseDn = "dc=ikubeops,dc=local"
+
+func ldapUser(uid, account, ou, mail string) *ldap.Entry {
+	return ldaptest.NewEntry("uid="+account+",ou="+ou+","+ldapBaseDn, "objectClass", "inetOrgPerson",
+		"entryUUID", uid, "uid", account, "cn", account, "mail", mail)
+}
+
+func ldapOu(ou string) *ldap.Entry {
+	return ldaptest.NewEntry("ou="+ou+","+ldapBaseDn, "objectClass", "organizationalUnit", "ou", ou)
+}
+
+func newLdapSync(t *testing.T) (*logic.LdapSyncLogic, *ldaptest.Server, *gorm.DB) {
+	testenv.Setup(t)
+	db := testenv.DB(t, &model.Account{}, &model.Organization{}, &model.AccountIdentity{}, &model.OrganizationIdentity{},
+		&model.Role{}, &model.RoleAccount{})
+	srv, err := ldaptest.NewServer("cn=admin,"+ldapBaseDn, "secret")
+	assert.NoError(t, err, "启动目录桩应该成功")
+	t.Cleanup(srv.Close)
+
+	ldapConfig, superRole := global.C.Ldap, global.C.Rbac.SuperRole
+	t.Cleanup(func() {
+		global.C.Ldap = ldapConfig
+		global.C.Rbac.SuperRole = superRole
+	})
+	global.C.Rbac.SuperRole = "admin"
+	global.C.Ldap = pkgtypes.NewLdapConfig()
+	global.C.Ldap.Enable = true
+	global.C.Ldap.Url = srv.URL
+	global.C.Ldap.BindDn = srv.BindDn
+	global.C.Ldap.BindPassword = srv.BindPassword
+	global.C.Ldap.BaseDn = ldapBaseDn
+	global.C.Ldap.MissingThreshold = 50
+	return logic.NewLdapSyncLogic(db, zap.NewNop()), srv, db
+}
+
+// reportActions 按账号名称汇总同步报告中的账号变更
+func reportActions(report *types2.LdapSyncReport) map[string]string {
+	actions := make(map[string]string, len(report.Accounts))
+	for _, item := range report.Accounts {
+		actions[item.Name] = item.Action
+	}
+	return actions
+}
+
+func findAccount(t *testing.T, db *gorm.DB, name string) *model.Account {
+	var account model.Account
+	assert.NoError(t, db.Where("account = ?", name).First(&account).Error, "账号 %s 应该存在", name)
+	return &account
+}
+
+func TestLdapSync(t *testing.T) {
+	s, srv, db := newLdapSync(t)
+	ctx := context.Background()
+
+	// 本地已有的超级管理员和普通账号与目录中的账号同名
+	admin := &model.Account{UserName: "admin", Account: "admin", Mobile: "1", Email: "admin@local", WorkNumber: "1"}
+	wangwu := &model.Account{UserName: "wangwu", Account: "wangwu", Mobile: "2", Email: "wangwu@local", WorkNumber: "2"}
+	assert.NoError(t, db.Create([]*model.Account{admin, wangwu}).Error)
+	role := &model.Role{Name: "admin", ApplicationId: 1}
+	assert.NoError(t, db.Create(role).Error)
+	assert.NoError(t, db.Create(&model.RoleAccount{RoleId: role.ID, AccountId: admin.ID}).Error)
+	global.C.Ldap.LinkAccounts = []string{"*"}
+
+	srv.SetEntries(
+		ldapOu("ops"),
+		ldapUser("u1", "zhangsan", "ops", "zhangsan@ikubeops.local"),
+		ldapUser("u2", "lisi", "ops", "lisi@ikubeops.local"),
+		ldapUser("u3", "wangwu", "ops", "wangwu@ikubeops.local"),
+		ldapUser("u4", "admin", "ops", "admin@ikubeops.local"),
+	)
+
+	// 试运行只生成报告，不写入数据
+	report, err := s.Sync(ctx, true)
+	assert.NoError(t, err, "试运行应该成功")
+	assert.True(t, report.DryRun)
+	assert.Equal(t, map[string]string{
+		"zhangsan": logic.LdapActionCreate,
+		"lisi":     logic.LdapActionCreate,
+		"wangwu":   logic.LdapActionLink,
+		"admin":    logic.LdapActionSkip,
+	}, reportActions(report), "试运行报告与预期不符")
+	var count int64
+	assert.NoError(t, db.Model(&model.Account{}).Count(&count).Error)
+	assert.Equal(t, int64(2), count, "试运行不应该创建账号")
+	assert.NoError(t, db.Model(&model.AccountIdentity{}).Count(&count).Error)
+	assert.Equal(t, int64(0), count, "试运行不应该创建关联")
+
+	// 创建账号并关联允许关联的同名账号，超级管理员不会被关联
+	report, err = s.Sync(ctx, false)
+	assert.NoError(t, err, "同步应该成功")
+	assert.Equal(t, logic.LdapActionCreate, reportActions(report)["zhangsan"], "目录中新增的账号应该被创建")
+	assert.Equal(t, logic.LdapActionSkip, reportActions(report)["admin"], "超级管理员不应该被关联")
+	assert.Equal(t, "wangwu@ikubeops.local", findAccount(t, db, "wangwu").Email, "关联的账号应该同步目录中的字段")
+	assert.Equal(t, "admin@local", findAccount(t, db, "admin").Email, "未关联的账号不应该被修改")
+
+	// DN 变化时按唯一标识关联，更新已关联的账号
+	srv.SetEntries(
+		ldapOu("ops"), ldapOu("dev"),
+		ldaptest.NewEntry("uid=zhangsan,ou=dev,"+ldapBaseDn, "objectClass", "inetOrgPerson",
+			"entryUUID", "u1", "uid", "zhangsan", "cn", "张三", "mail", "zs@ikubeops.local"),
+		ldapUser("u2", "lisi", "ops", "lisi@ikubeops.local"),
+		ldapUser("u3", "wangwu", "ops", "wangwu@ikubeops.local"),
+	)
+	report, err = s.Sync(ctx, false)
+	assert.NoError(t, err, "同步应该成功")
+	assert.Equal(t, logic.LdapActionUpdate, reportActions(report)["zhangsan"], "目录中修改的账号应该被更新")
+	zhangsan := findAccount(t, db, "zhangsan")
+	assert.Equal(t, "张三", zhangsan.UserName, "姓名应该被更新")
+	assert.Equal(t, "zs@ikubeops.local", zhangsan.Email, "邮箱应该被更新")
+	var dev model.Organization
+	assert.NoError(t, db.Where("name = ?", "dev").First(&dev).Error)
+	assert.Equal(t, dev.ID, zhangsan.OrganizationId, "所属机构应该随 DN 更新")
+
+	// 目录中删除的账号标记为离职和禁用
+	srv.SetEntries(
+		ldapOu("ops"), ldapOu("dev"),
+		ldaptest.NewEntry("uid=zhangsan,ou=dev,"+ldapBaseDn, "objectClass", "inetOrgPerson",
+			"entryUUID", "u1", "uid", "zhangsan", "cn", "张三", "mail", "zs@ikubeops.local"),
+		ldapUser("u3", "wangwu", "ops", "wangwu@ikubeops.local"),
+	)
+	report, err = s.Sync(ctx, false)
+	assert.NoError(t, err, "同步应该成功")
+	assert.Equal(t, map[string]string{"lisi": logic.LdapActionMissing}, reportActions(report), "只有 lisi 应该被标记")
+	lisi := findAccount(t, db, "lisi")
+	assert.True(t, lisi.IsLeave && lisi.IsDisabled, "目录中删除的账号应该被标记为离职和禁用")
+}
+
+func TestLdapSyncAbort(t *testing.T) {
+	s, srv, db := newLdapSync(t)
+	ctx := context.Background()
+	srv.SetEntries(
+		ldapOu("ops"),
+		ldapUser("u1", "zhangsan", "ops", "zhangsan@ikubeops.local"),
+		ldapUser("u2", "lisi", "ops", "lisi@ikubeops.local"),
+		ldapUser("u3", "wangwu", "ops", "wangwu@ikubeops.local"),
+	)
+	_, err := s.Sync(ctx, false)
+	assert.NoError(t, err, "同步应该成功")
+
+	disabled := func() int64 {
+		var count int64
+		assert.NoError(t, db.Model(&model.Account{}).Where("is_disabled = ?", true).Count(&count).Error)
+		return count
+	}
+
+	// 查询结果为空时中止同步
+	srv.SetEntries(ldapOu("ops"))
+	_, err = s.Sync(ctx, false)
+	assert.Equal(t, errorx.ErrBusinessLogic, errorCode(err), "查询结果为空时应该中止同步")
+	assert.Equal(t, int64(0), disabled(), "中止同步时不应该禁用账号")
+
+	// 缺失的账号超过阈值时中止同步
+	srv.SetEntries(ldapOu("ops"), ldapUser("u1", "zhangsan", "ops", "zhangsan@ikubeops.local"))
+	_, err = s.Sync(ctx, false)
+	assert.Equal(t, errorx.ErrBusinessLogic, errorCode(err), "缺失账号超过阈值时应该中止同步")
+	assert.Equal(t, int64(0), disabled(), "中止同步时不应该禁用账号")
+
+	// 未允许关联时同名的本地账号跳过
+	local := &model.Account{UserName: "zhaoliu", Account: "zhaoliu", Mobile: "4", Email: "zhaoliu@local", WorkNumber: "4"}
+	assert.NoError(t, db.Create(local).Error)
+	srv.SetEntries(
+		ldapOu("ops"),
+		ldapUser("u1", "zhangsan", "ops", "zhangsan@ikubeops.local"),
+		ldapUser("u2", "lisi", "ops", "lisi@ikubeops.local"),
+		ldapUser("u3", "wangwu", "ops", "wangwu@ikubeops.local"),
+		ldapUser("u4", "zhaoliu", "ops", "zhaoliu@ikubeops.local"),
+	)
+	report, err := s.Sync(ctx, false)
+	assert.NoError(t, err, "同步应该成功")
+	assert.Equal(t, logic.LdapActionSkip, reportActions(report)["zhaoliu"], "未允许关联的同名账号应该跳过")
+	assert.Equal(t, "zhaoliu@local", findAccount(t, db, "zhaoliu").Email, "未关联的账号不应该被修改")
+}
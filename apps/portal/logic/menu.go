@@ -0,0 +1,353 @@
+package logic
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/model"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/service"
+	types2 "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/auth"
+	"github.com/yanshicheng/ikube-gin-starter/common/errorx"
+	"github.com/yanshicheng/ikube-gin-starter/common/types"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/router"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 接口检查
+var _ service.MenuService = (*MenuLogic)(nil)
+
+var menuLogic = &MenuLogic{}
+
+type MenuLogic struct {
+	l  *zap.Logger
+	db *gorm.DB
+}
+
+func (m *MenuLogic) Get(c *gin.Context, id types.SearchId) (*model.Menu, error) {
+	var menu model.Menu
+	if err := m.db.WithContext(c).Where("id = ?", id.Id).First(&menu).Error; err != nil {
+		m.l.Error(fmt.Sprintf("查询菜单信息失败, id: %d, error: %s", id.Id, err.Error()))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errorx.NewCodeError(errorx.ErrDataNotFound, "菜单不存在")
+		}
+		return nil, fmt.Errorf("查询菜单信息失败")
+	}
+	return &menu, nil
+}
+
+// List 返回应用下完整的菜单树
+func (m *MenuLogic) List(c *gin.Context, search types2.MenuSearch) ([]*model.Menu, error) {
+	menus, err := m.applicationMenus(c, search.ApplicationId)
+	if err != nil {
+		return nil, err
+	}
+	return buildMenuTree(menus), nil
+}
+
+// Mine 返回当前账号在应用下可访问的菜单树，按 OrderNo 排序。
+// 账号必须已被授权访问该应用，菜单只取该应用下角色所关联的菜单。
+func (m *MenuLogic) Mine(c *gin.Context, search types2.MenuSearch) ([]*types2.MenuRoute, error) {
+	accountId := auth.GetAccountId(c)
+	roles, err := queryAccountRoles(m.db.WithContext(c), accountId, 0)
+	if err != nil {
+		m.l.Error(fmt.Sprintf("查询账号角色失败, id: %d, error: %s", accountId, err.Error()))
+		return nil, fmt.Errorf("查询账号角色失败")
+	}
+	super := isSuperRole(roles)
+	if !super {
+		ok, err := hasApplication(m.db.WithContext(c), accountId, search.ApplicationId)
+		if err != nil {
+			m.l.Error(fmt.Sprintf("查询账号应用授权失败, id: %d, error: %s", accountId, err.Error()))
+			return nil, fmt.Errorf("查询账号应用授权失败")
+		}
+		if !ok {
+			return nil, errorx.NewCodeError(errorx.ErrPermissionDenied, "无权访问该应用")
+		}
+	}
+	menus, err := m.applicationMenus(c, search.ApplicationId)
+	if err != nil {
+		return nil, err
+	}
+	if !super {
+		menus, err = m.grantedMenus(c, filterApplicationRoles(roles, search.ApplicationId), menus)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return toMenuRoutes(buildMenuTree(menus)), nil
+}
+
+func (m *MenuLogic) Create(c *gin.Context, menu *model.Menu) error {
+	if err := m.checkApplication(c, menu.ApplicationId); err != nil {
+		return err
+	}
+	if err := m.checkName(c, menu); err != nil {
+		return err
+	}
+	menu.ID = 0
+	if err := m.db.WithContext(c).Create(menu).Error; err != nil {
+		m.l.Error(fmt.Sprintf("创建菜单失败, error: %s", err.Error()))
+		return errorx.NewCodeError(errorx.ErrDataCreation, fmt.Sprintf("创建菜单失败: %s", err.Error()))
+	}
+	return nil
+}
+
+func (m *MenuLogic) Put(c *gin.Context, id types.SearchId, menu *model.Menu) (*model.Menu, error) {
+	oldMenu, err := m.getManaged(c, id)
+	if err != nil {
+		return nil, err
+	}
+	if menu.ApplicationId != oldMenu.ApplicationId {
+		return nil, errorx.NewCodeError(errorx.ErrBusinessLogic, "不允许修改菜单所属应用")
+	}
+	menu.ID = oldMenu.ID
+	if err := m.checkName(c, menu); err != nil {
+		return nil, err
+	}
+	// 修改父级时重新计算层级，存在子节点时不允许修改
+	if menu.ParentId != oldMenu.ParentId {
+		if err := m.checkParent(c, oldMenu, menu.ParentId); err != nil {
+			return nil, err
+		}
+		var count int64
+		if err := m.db.WithContext(c).Model(&model.Menu{}).Where("parent_id = ?", id.Id).Count(&count).Error; err != nil {
+			m.l.Error(fmt.Sprintf("查询子菜单失败, id: %d, error: %s", id.Id, err.Error()))
+			return nil, fmt.Errorf("查询子菜单失败")
+		}
+		if count > 0 {
+			return nil, errorx.NewCodeError(errorx.ErrBusinessLogic, "存在子菜单不允许修改 ParentId")
+		}
+		oldMenu.ParentId = menu.ParentId
+		if err := oldMenu.BeforeCreate(m.db.WithContext(c)); err != nil {
+			m.l.Error(fmt.Sprintf("计算菜单层级失败, id: %d, error: %s", id.Id, err.Error()))
+			return nil, errorx.NewCodeError(errorx.ErrBusinessLogic, fmt.Sprintf("修改父级失败: %s", err.Error()))
+		}
+	}
+	// 修改操作
+	oldMenu.Path = menu.Path
+	oldMenu.Name = menu.Name
+	oldMenu.Component = menu.Component
+	oldMenu.Redirect = menu.Redirect
+	oldMenu.Title = menu.Title
+	oldMenu.Icon = menu.Icon
+	oldMenu.Expanded = menu.Expanded
+	oldMenu.OrderNo = menu.OrderNo
+	oldMenu.Hidden = menu.Hidden
+	oldMenu.HiddenBreadcrumb = menu.HiddenBreadcrumb
+	oldMenu.Single = menu.Single
+	oldMenu.FrameSrc = menu.FrameSrc
+	oldMenu.FrameBlank = menu.FrameBlank
+	oldMenu.KeepAlive = menu.KeepAlive
+	// 保存
+	if err := m.db.WithContext(c).Save(oldMenu).Error; err != nil {
+		m.l.Error(fmt.Sprintf("更新菜单信息失败, id: %d, error: %s", id.Id, err.Error()))
+		return nil, fmt.Errorf("更新菜单信息失败")
+	}
+	return oldMenu, nil
+}
+
+func (m *MenuLogic) Delete(c *gin.Context, id types.SearchId) error {
+	menu, err := m.getManaged(c, id)
+	if err != nil {
+		return err
+	}
+	var count int64
+	if err := m.db.WithContext(c).Model(&model.Menu{}).Where("parent_id = ?", menu.ID).Count(&count).Error; err != nil {
+		m.l.Error(fmt.Sprintf("查询子菜单失败, id: %d, error: %s", id.Id, err.Error()))
+		return fmt.Errorf("查询子菜单失败")
+	}
+	if count > 0 {
+		return errorx.NewCodeError(errorx.ErrBusinessLogic, "菜单下存在子菜单")
+	}
+	// 删除菜单同时删除角色菜单关联
+	err = m.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("menu_id = ?", menu.ID).Delete(&model.RoleMenu{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(menu).Error
+	})
+	if err != nil {
+		m.l.Error(fmt.Sprintf("删除菜单失败, id: %d, error: %s", id.Id, err.Error()))
+		return errorx.NewCodeError(errorx.ErrDataDeletion, "删除菜单失败")
+	}
+	return nil
+}
+
+// getManaged 查询当前账号可以修改的菜单，非超级管理员只能修改当前请求所属应用下的菜单
+func (m *MenuLogic) getManaged(c *gin.Context, id types.SearchId) (*model.Menu, error) {
+	scope, err := applicationScope(c, "application_id")
+	if err != nil {
+		m.l.Error(fmt.Sprintf("加载账号权限失败, id: %d, error: %s", auth.GetAccountId(c), err.Error()))
+		return nil, fmt.Errorf("加载账号权限失败")
+	}
+	var menu model.Menu
+	if err := m.db.WithContext(c).Scopes(scope).Where("id = ?", id.Id).First(&menu).Error; err != nil {
+		m.l.Error(fmt.Sprintf("查询菜单信息失败, id: %d, error: %s", id.Id, err.Error()))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errorx.NewCodeError(errorx.ErrDataNotFound, "菜单不存在")
+		}
+		return nil, fmt.Errorf("查询菜单信息失败")
+	}
+	return &menu, nil
+}
+
+// applicationMenus 查询应用下的所有菜单，按 OrderNo 排序
+func (m *MenuLogic) applicationMenus(c *gin.Context, applicationId uint) ([]*model.Menu, error) {
+	var menus []*model.Menu
+	if err := m.db.WithContext(c).Where("application_id = ?", applicationId).
+		Order("order_no ASC").Order("id ASC").Find(&menus).Error; err != nil {
+		m.l.Error(fmt.Sprintf("查询菜单信息失败, applicationId: %d, error: %s", applicationId, err.Error()))
+		return nil, fmt.Errorf("查询菜单信息失败")
+	}
+	return menus, nil
+}
+
+// grantedMenus 过滤出角色被授权的菜单，并补齐其所有父级菜单
+func (m *MenuLogic) grantedMenus(c *gin.Context, roles []model.Role, menus []*model.Menu) ([]*model.Menu, error) {
+	if len(roles) == 0 {
+		return []*model.Menu{}, nil
+	}
+	roleIds := make([]uint, 0, len(roles))
+	for _, role := range roles {
+		roleIds = append(roleIds, role.ID)
+	}
+	var menuIds []uint
+	if err := m.db.WithContext(c).Model(&model.RoleMenu{}).Where("role_id IN ?", roleIds).
+		Pluck("menu_id", &menuIds).Error; err != nil {
+		m.l.Error(fmt.Sprintf("查询角色菜单失败, roles: %v, error: %s", roleIds, err.Error()))
+		return nil, fmt.Errorf("查询角色菜单失败")
+	}
+	menuMap := make(map[uint]*model.Menu, len(menus))
+	for _, menu := range menus {
+		menuMap[menu.ID] = menu
+	}
+	granted := make(map[uint]bool, len(menuIds))
+	for _, id := range menuIds {
+		menu, ok := menuMap[id]
+		if !ok {
+			continue
+		}
+		// 根据祖先路径补齐父级菜单，否则子菜单无法挂载
+		granted[menu.ID] = true
+		for _, ancestorId := range menu.AncestorIds() {
+			granted[ancestorId] = true
+		}
+	}
+	result := make([]*model.Menu, 0, len(granted))
+	for _, menu := range menus {
+		if granted[menu.ID] {
+			result = append(result, menu)
+		}
+	}
+	return result, nil
+}
+
+// checkApplication 检查应用是否存在，非超级管理员只能在当前请求所属应用下创建菜单
+func (m *MenuLogic) checkApplication(c *gin.Context, applicationId uint) error {
+	scope, err := applicationScope(c, "id")
+	if err != nil {
+		m.l.Error(fmt.Sprintf("加载账号权限失败, id: %d, error: %s", auth.GetAccountId(c), err.Error()))
+		return fmt.Errorf("加载账号权限失败")
+	}
+	var count int64
+	if err := m.db.WithContext(c).Model(&model.Application{}).Scopes(scope).Where("id = ?", applicationId).Count(&count).Error; err != nil {
+		m.l.Error(fmt.Sprintf("查询应用信息失败, id: %d, error: %s", applicationId, err.Error()))
+		return fmt.Errorf("查询应用信息失败")
+	}
+	if count == 0 {
+		return errorx.NewCodeError(errorx.ErrDataNotFound, "应用不存在")
+	}
+	return nil
+}
+
+// checkParent 检查新的父级菜单，父级不能是菜单自身，否则菜单树会出现循环。
+// 存在子菜单时不允许修改父级，因此父级不会是菜单的子孙菜单
+func (m *MenuLogic) checkParent(c *gin.Context, menu *model.Menu, parentId uint) error {
+	if parentId != 0 && parentId == menu.ID {
+		return errorx.NewCodeError(errorx.ErrBusinessLogic, "父级菜单不能是菜单自身")
+	}
+	return nil
+}
+
+// checkName 检查菜单唯一标识名称是否已被同一应用下的其他菜单使用
+func (m *MenuLogic) checkName(c *gin.Context, menu *model.Menu) error {
+	var count int64
+	if err := m.db.WithContext(c).Model(&model.Menu{}).
+		Where("name = ? AND application_id = ? AND id <> ?", menu.Name, menu.ApplicationId, menu.ID).Count(&count).Error; err != nil {
+		m.l.Error(fmt.Sprintf("查询菜单信息失败, name: %s, error: %s", menu.Name, err.Error()))
+		return fmt.Errorf("查询菜单信息失败")
+	}
+	if count > 0 {
+		return errorx.NewCodeError(errorx.ErrDataConflict, "菜单名称已存在")
+	}
+	return nil
+}
+
+// buildMenuTree 构建菜单树，子菜单保持传入的顺序
+func buildMenuTree(menus []*model.Menu) []*model.Menu {
+	menuMap := make(map[uint]*model.Menu, len(menus))
+	for _, menu := range menus {
+		menu.Children = make([]*model.Menu, 0)
+		menuMap[menu.ID] = menu
+	}
+	tree := make([]*model.Menu, 0)
+	for _, menu := range menus {
+		// 父级指向自身的数据无法挂载，跳过以免子菜单引用自身
+		if menu.ParentId == menu.ID {
+			continue
+		}
+		if parent, ok := menuMap[menu.ParentId]; ok && menu.ParentId != 0 {
+			parent.Children = append(parent.Children, menu)
+		} else if menu.ParentId == 0 {
+			tree = append(tree, menu)
+		}
+	}
+	return tree
+}
+
+// toMenuRoutes 将菜单树转换为前端路由结构
+func toMenuRoutes(menus []*model.Menu) []*types2.MenuRoute {
+	routes := make([]*types2.MenuRoute, 0, len(menus))
+	for _, menu := range menus {
+		routes = append(routes, &types2.MenuRoute{
+			Path:      menu.Path,
+			Name:      menu.Name,
+			Component: menu.Component,
+			Redirect:  menu.Redirect,
+			Meta: types2.MenuMeta{
+				Title:            menu.Title,
+				Icon:             menu.Icon,
+				Expanded:         menu.Expanded,
+				OrderNo:          menu.OrderNo,
+				Hidden:           menu.Hidden,
+				HiddenBreadcrumb: menu.HiddenBreadcrumb,
+				Single:           menu.Single,
+				FrameSrc:         menu.FrameSrc,
+				FrameBlank:       menu.FrameBlank,
+				KeepAlive:        menu.KeepAlive,
+			},
+			Children: toMenuRoutes(menu.Children),
+		})
+	}
+	return routes
+}
+
+// 只需要保证 全局对象Config和全局Logger已经加载完成
+func (m *MenuLogic) Config() {
+	m.l = global.L.Named(portal.AppName).Named(portal.AppMenu).Named("logic")
+	m.db = global.DB.GetDb()
+}
+
+func (m *MenuLogic) Name() string {
+	return fmt.Sprintf("%s.%s", portal.AppName, portal.AppMenu)
+}
+
+func init() {
+	// 注册
+	router.RegistryService[service.MenuService](menuLogic)
+}
@@ -0,0 +1,139 @@
+package logic_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/logic"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/model"
+	types2 "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/auth"
+	"github.com/yanshicheng/ikube-gin-starter/common/errorx"
+	"github.com/yanshicheng/ikube-gin-starter/common/types"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/jwt"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/testenv"
+	"gorm.io/gorm"
+)
+
+func newMenuLogic(t *testing.T) (*logic.MenuLogic, *gorm.DB) {
+	testenv.Setup(t)
+	db := testenv.DB(t, &model.Application{}, &model.Menu{}, &model.RoleMenu{})
+	m := &logic.MenuLogic{}
+	m.Config()
+	return m, db
+}
+
+func TestMenuNamePerApplication(t *testing.T) {
+	m, db := newMenuLogic(t)
+	c := testContext()
+	apps := []*model.Application{{Name: "portal", Path: "portal", Icon: "portal"}, {Name: "book", Path: "book", Icon: "book"}}
+	assert.NoError(t, db.Create(&apps).Error)
+
+	// 不同应用下可以使用相同的名称
+	portal := &model.Menu{Path: "/system", Name: "system", Component: "Layout", OrderNo: 1, ApplicationId: apps[0].ID}
+	assert.NoError(t, m.Create(c, portal))
+	book := &model.Menu{Path: "/system", Name: "system", Component: "Layout", OrderNo: 1, ApplicationId: apps[1].ID}
+	assert.NoError(t, m.Create(c, book), "不同应用下菜单名称可以重复")
+
+	// 同一应用下名称不能重复，修改时排除自身
+	err := m.Create(c, &model.Menu{Path: "/other", Name: "system", Component: "Layout", OrderNo: 2, ApplicationId: apps[0].ID})
+	assert.Equal(t, errorx.ErrDataConflict, errorCode(err), "同一应用下菜单名称不能重复")
+	other := &model.Menu{Path: "/other", Name: "other", Component: "Layout", OrderNo: 2, ApplicationId: apps[0].ID}
+	assert.NoError(t, m.Create(c, other))
+	update := *other
+	update.Name = "system"
+	_, err = m.Put(c, types.SearchId{Id: other.ID}, &update)
+	assert.Equal(t, errorx.ErrDataConflict, errorCode(err), "修改为同一应用下已存在的名称时应该返回数据冲突")
+	update.Name = "other"
+	_, err = m.Put(c, types.SearchId{Id: other.ID}, &update)
+	assert.NoError(t, err, "保留自身名称时应该修改成功")
+
+	// 数据库唯一索引同样按应用区分
+	assert.Error(t, db.Create(&model.Menu{Path: "/x", Name: "system", Component: "Layout", OrderNo: 3, ApplicationId: apps[1].ID}).Error,
+		"唯一索引应该拒绝同一应用下的重复名称")
+}
+
+func TestMenuPutParent(t *testing.T) {
+	m, db := newMenuLogic(t)
+	c := testContext()
+	app := &model.Application{Name: "portal", Path: "portal", Icon: "portal"}
+	assert.NoError(t, db.Create(app).Error)
+
+	root := &model.Menu{Path: "/system", Name: "system", Component: "Layout", OrderNo: 1, ApplicationId: app.ID}
+	assert.NoError(t, m.Create(c, root))
+	child := &model.Menu{Path: "menu", Name: "menu", Component: "Menu", OrderNo: 1, ParentId: root.ID, ApplicationId: app.ID}
+	assert.NoError(t, m.Create(c, child))
+
+	// 父级不能是菜单自身
+	update := *child
+	update.ParentId = child.ID
+	_, err := m.Put(c, types.SearchId{Id: child.ID}, &update)
+	assert.Equal(t, errorx.ErrBusinessLogic, errorCode(err), "父级为自身时应拒绝修改")
+
+	// 父级不能是菜单的子孙菜单
+	update = *root
+	update.ParentId = child.ID
+	_, err = m.Put(c, types.SearchId{Id: root.ID}, &update)
+	assert.Equal(t, errorx.ErrBusinessLogic, errorCode(err), "父级为子菜单时应拒绝修改")
+
+	// 移动到其他菜单下重新计算层级
+	other := &model.Menu{Path: "/other", Name: "other", Component: "Layout", OrderNo: 2, ApplicationId: app.ID}
+	assert.NoError(t, m.Create(c, other))
+	update = *child
+	update.ParentId = other.ID
+	moved, err := m.Put(c, types.SearchId{Id: child.ID}, &update)
+	assert.NoError(t, err)
+	assert.Equal(t, other.ID, moved.ParentId, "父级应修改成功")
+	assert.Equal(t, 2, moved.Level, "层级应重新计算")
+	assert.True(t, moved.IsDescendantOf(other.TreePath, other.ID), "祖先路径应重新计算")
+
+	menus, err := m.List(c, types2.MenuSearch{ApplicationId: app.ID})
+	assert.NoError(t, err)
+	assert.Len(t, menus, 2, "应只有两个根菜单")
+}
+
+func TestMenuCrossApplication(t *testing.T) {
+	m, db := newMenuLogic(t)
+	authorizer := enableRbac(t, auth.PermissionSet{})
+	apps := []*model.Application{{Name: "portal", Path: "portal", Icon: "portal"}, {Name: "book", Path: "book", Icon: "book"}}
+	assert.NoError(t, db.Create(&apps).Error)
+	menu := &model.Menu{Path: "/system", Name: "system", Component: "Layout", OrderNo: 1, ApplicationId: apps[0].ID}
+	assert.NoError(t, db.Create(menu).Error)
+	id := types.SearchId{Id: menu.ID}
+
+	// 在应用 book 下不能修改应用 portal 的菜单
+	c := testContext()
+	auth.SetClaims(c, &jwt.Claims{AccountId: 2})
+	c.Request.Header.Set(auth.ApplicationHeader, "2")
+	update := *menu
+	update.Title = "系统"
+	_, err := m.Put(c, id, &update)
+	assert.Equal(t, errorx.ErrDataNotFound, errorCode(err), "不能修改其他应用的菜单")
+	assert.Equal(t, errorx.ErrDataNotFound, errorCode(m.Delete(c, id)), "不能删除其他应用的菜单")
+	err = m.Create(c, &model.Menu{Path: "/other", Name: "other", Component: "Layout", ApplicationId: apps[0].ID})
+	assert.Equal(t, errorx.ErrDataNotFound, errorCode(err), "不能在其他应用下创建菜单")
+	assert.NoError(t, db.First(&model.Menu{}, menu.ID).Error, "其他应用的菜单不应该被删除")
+
+	// 在菜单所在应用下可以修改
+	c.Request.Header.Set(auth.ApplicationHeader, "1")
+	_, err = m.Put(c, id, &update)
+	assert.NoError(t, err, "可以修改当前应用的菜单")
+
+	// 超级管理员可以修改所有应用的菜单
+	authorizer.perms.Super = true
+	c.Request.Header.Set(auth.ApplicationHeader, "2")
+	assert.NoError(t, m.Delete(c, id), "超级管理员可以删除其他应用的菜单")
+}
+
+func TestMenuKeepAlive(t *testing.T) {
+	m, db := newMenuLogic(t)
+	c := testContext()
+	app := &model.Application{Name: "portal", Path: "portal", Icon: "portal"}
+	assert.NoError(t, db.Create(app).Error)
+
+	menu := &model.Menu{Path: "/system", Name: "system", Component: "Layout", OrderNo: 1, ApplicationId: app.ID, KeepAlive: false}
+	assert.NoError(t, m.Create(c, menu))
+	saved, err := m.Get(c, types.SearchId{Id: menu.ID})
+	assert.NoError(t, err)
+	assert.False(t, saved.KeepAlive, "创建时传入的 keepAlive false 应保存为 false")
+}
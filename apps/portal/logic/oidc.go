@@ -0,0 +1,280 @@
+package logic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/model"
+	types2 "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/errorx"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/oidc"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// oidcStateKeyPrefix 单点登录请求，按 state 存储 nonce 和 PKCE 校验码，只能使用一次
+const oidcStateKeyPrefix = "ikubeops:oidc:state:"
+
+// oidcLinkColumns 可用于关联已有账号的字段
+var oidcLinkColumns = map[string]string{
+	"email":       "email",
+	"work_number": "work_number",
+	"mobile":      "mobile",
+}
+
+type oidcState struct {
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+}
+
+// oidcProfile 根据声明映射得到的账号信息
+type oidcProfile struct {
+	Subject       string
+	Account       string
+	UserName      string
+	Email         string
+	EmailVerified bool
+	WorkNumber    string
+	Mobile        string
+	Organization  string
+}
+
+// OidcLogin 生成单点登录授权地址，state、nonce 和 PKCE 校验码保存在 redis 中
+func (a *AuthLogic) OidcLogin(c *gin.Context) (*types2.OidcLoginResponse, error) {
+	if err := checkOidc(); err != nil {
+		return nil, err
+	}
+	state, err := oidc.RandomString()
+	if err != nil {
+		a.l.Error(fmt.Sprintf("生成 oidc state 失败, error: %s", err.Error()))
+		return nil, fmt.Errorf("生成单点登录请求失败")
+	}
+	nonce, err := oidc.RandomString()
+	if err != nil {
+		a.l.Error(fmt.Sprintf("生成 oidc nonce 失败, error: %s", err.Error()))
+		return nil, fmt.Errorf("生成单点登录请求失败")
+	}
+	s := oidcState{Nonce: nonce, Verifier: oidc.GenerateVerifier()}
+	authUrl, err := global.O.AuthCodeURL(c, state, s.Nonce, s.Verifier)
+	if err != nil {
+		a.l.Error(fmt.Sprintf("生成 oidc 授权地址失败, error: %s", err.Error()))
+		return nil, fmt.Errorf("身份提供方不可用")
+	}
+	value, _ := json.Marshal(s)
+	expire := time.Duration(global.C.Oidc.StateExpire) * time.Second
+	if err := global.RDB.GetClient().Set(c, oidcStateKeyPrefix+state, value, expire).Err(); err != nil {
+		a.l.Error(fmt.Sprintf("保存 oidc state 失败, error: %s", err.Error()))
+		return nil, fmt.Errorf("生成单点登录请求失败")
+	}
+	return &types2.OidcLoginResponse{AuthUrl: authUrl, State: state}, nil
+}
+
+// OidcCallback 使用授权码完成单点登录，首次登录时关联或创建账号
+func (a *AuthLogic) OidcCallback(c *gin.Context, req types2.OidcCallbackRequest) (*types2.TokenResponse, error) {
+	if err := checkOidc(); err != nil {
+		return nil, err
+	}
+	// state 只能使用一次，读取后立即删除
+	pipe := global.RDB.GetClient().TxPipeline()
+	get := pipe.Get(c, oidcStateKeyPrefix+req.State)
+	pipe.Del(c, oidcStateKeyPrefix+req.State)
+	if _, err := pipe.Exec(c); err != nil && !errors.Is(err, goredis.Nil) {
+		a.l.Error(fmt.Sprintf("查询 oidc state 失败, error: %s", err.Error()))
+		return nil, fmt.Errorf("单点登录失败")
+	}
+	value, err := get.Bytes()
+	if err != nil {
+		a.l.Info(fmt.Sprintf("单点登录失败，state 无效或已过期, state: %s", req.State))
+		return nil, errorx.NewCodeError(errorx.ErrLoginExpired, "登录请求已过期，请重新登录")
+	}
+	var s oidcState
+	if err := json.Unmarshal(value, &s); err != nil {
+		a.l.Error(fmt.Sprintf("解析 oidc state 失败, error: %s", err.Error()))
+		return nil, fmt.Errorf("单点登录失败")
+	}
+	claims, err := global.O.Exchange(c, req.Code, s.Nonce, s.Verifier)
+	if err != nil {
+		a.l.Info(fmt.Sprintf("单点登录失败, error: %s", err.Error()))
+		return nil, errorx.NewCodeError(errorx.ErrLoginInvalid, "单点登录失败")
+	}
+	profile := newOidcProfile(claims)
+	if profile.Subject == "" {
+		return nil, errorx.NewCodeError(errorx.ErrLoginInvalid, "身份提供方未返回用户标识")
+	}
+	account, err := a.oidcAccount(c, profile)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAccountStatus(account); err != nil {
+		return nil, err
+	}
+	// 单点登录同样需要两步验证，由 OtpLogic.Login 完成登录
+	if err := otpLogic.challenge(c, account, otpPurposeLogin); err != nil {
+		return nil, err
+	}
+	a.l.Info(fmt.Sprintf("单点登录成功, account: %s, subject: %s", account.Account, profile.Subject))
+	return a.issueTokens(c, account, "")
+}
+
+// oidcAccount 查找外部用户关联的账号，未关联时按配置的字段关联已有账号，仍未找到时自动创建
+func (a *AuthLogic) oidcAccount(c *gin.Context, profile *oidcProfile) (*model.Account, error) {
+	db := a.db.WithContext(c)
+	issuer := global.O.Issuer()
+	var identity model.AccountIdentity
+	err := db.Where("issuer = ? AND subject = ?", issuer, profile.Subject).First(&identity).Error
+	if err == nil {
+		var account model.Account
+		if err := db.Where("id = ?", identity.AccountId).First(&account).Error; err != nil {
+			a.l.Error(fmt.Sprintf("查询关联账号失败, id: %d, error: %s", identity.AccountId, err.Error()))
+			return nil, errorx.NewCodeError(errorx.ErrLoginInvalid, "关联的账号不存在")
+		}
+		return &account, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		a.l.Error(fmt.Sprintf("查询外部身份失败, subject: %s, error: %s", profile.Subject, err.Error()))
+		return nil, errorx.NewCodeError(errorx.ErrDatabase, "查询外部身份失败")
+	}
+
+	account, err := a.oidcLinkAccount(c, profile)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if account == nil {
+			if account, err = a.oidcCreateAccount(tx, profile); err != nil {
+				return err
+			}
+		}
+		return tx.Create(&model.AccountIdentity{AccountId: account.ID, Issuer: issuer, Subject: profile.Subject}).Error
+	})
+	if err != nil {
+		var codeErr *errorx.CodeError
+		if errors.As(err, &codeErr) {
+			return nil, err
+		}
+		a.l.Error(fmt.Sprintf("关联外部身份失败, subject: %s, error: %s", profile.Subject, err.Error()))
+		return nil, errorx.NewCodeError(errorx.ErrDataCreation, "关联外部身份失败")
+	}
+	a.l.Info(fmt.Sprintf("外部身份已关联账号, account: %s, subject: %s", account.Account, profile.Subject))
+	return account, nil
+}
+
+// oidcLinkAccount 按配置的字段顺序查找已有账号，未验证的邮箱不用于关联
+func (a *AuthLogic) oidcLinkAccount(c *gin.Context, profile *oidcProfile) (*model.Account, error) {
+	values := map[string]string{
+		"email":       profile.Email,
+		"work_number": profile.WorkNumber,
+		"mobile":      profile.Mobile,
+	}
+	if !profile.EmailVerified {
+		values["email"] = ""
+	}
+	for _, field := range global.C.Oidc.LinkBy {
+		column, ok := oidcLinkColumns[field]
+		if !ok || values[field] == "" {
+			continue
+		}
+		var account model.Account
+		err := a.db.WithContext(c).Where(fmt.Sprintf("%s = ?", column), values[field]).First(&account).Error
+		if err == nil {
+			return &account, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			a.l.Error(fmt.Sprintf("查询账号信息失败, %s: %s, error: %s", field, values[field], err.Error()))
+			return nil, errorx.NewCodeError(errorx.ErrDatabase, "查询账号信息失败")
+		}
+	}
+	return nil, nil
+}
+
+// oidcCreateAccount 根据声明创建账号，密码随机生成，账号只能通过单点登录登录
+func (a *AuthLogic) oidcCreateAccount(tx *gorm.DB, profile *oidcProfile) (*model.Account, error) {
+	if !global.C.Oidc.AutoCreate {
+		return nil, errorx.NewCodeError(errorx.ErrLoginInvalid, "账号未开通，请联系管理员")
+	}
+	var count int64
+	if err := tx.Model(&model.Account{}).Where("account = ?", profile.Account).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, errorx.NewCodeError(errorx.ErrDataConflict, fmt.Sprintf("账号 %s 已存在，请联系管理员关联", profile.Account))
+	}
+	organizationId := global.C.Oidc.DefaultOrganizationId
+	if profile.Organization != "" {
+		var organization model.Organization
+		err := tx.Where("name = ?", profile.Organization).First(&organization).Error
+		if err == nil {
+			organizationId = organization.ID
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+	if organizationId == 0 {
+		return nil, errorx.NewCodeError(errorx.ErrLoginInvalid, "无法确定账号所属机构，请联系管理员")
+	}
+	secret, err := oidc.RandomString()
+	if err != nil {
+		return nil, err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	account := model.Account{
+		UserName:       profile.UserName,
+		Account:        profile.Account,
+		Password:       string(hash),
+		Email:          profile.Email,
+		WorkNumber:     profile.WorkNumber,
+		Mobile:         profile.Mobile,
+		HireDate:       time.Now(),
+		OrganizationId: organizationId,
+	}
+	if err := tx.Create(&account).Error; err != nil {
+		return nil, err
+	}
+	a.l.Info(fmt.Sprintf("单点登录自动创建账号, account: %s, subject: %s", account.Account, profile.Subject))
+	return &account, nil
+}
+
+// newOidcProfile 按配置的声明映射提取账号信息，账号和姓名缺失时依次使用邮箱前缀和 subject
+func newOidcProfile(claims oidc.Claims) *oidcProfile {
+	mapping := global.C.Oidc.Claims
+	profile := &oidcProfile{
+		Subject:       claims.Subject(),
+		Account:       claims.String(mapping.Account),
+		UserName:      claims.String(mapping.UserName),
+		Email:         claims.String(mapping.Email),
+		EmailVerified: claims.Bool("email_verified", false), // 未声明时视为未验证，不用于关联账号
+		WorkNumber:    claims.String(mapping.WorkNumber),
+		Mobile:        claims.String(mapping.Mobile),
+		Organization:  claims.String(mapping.Organization),
+	}
+	if profile.Account == "" && profile.Email != "" {
+		profile.Account = strings.SplitN(profile.Email, "@", 2)[0]
+	}
+	if profile.Account == "" {
+		profile.Account = profile.Subject
+	}
+	if profile.UserName == "" {
+		profile.UserName = profile.Account
+	}
+	return profile
+}
+
+// checkOidc 检查是否启用单点登录
+func checkOidc() error {
+	if !global.C.Oidc.Enable || global.O == nil {
+		return errorx.NewCodeError(errorx.ErrBusinessLogic, "未启用单点登录")
+	}
+	if global.RDB == nil {
+		return errorx.NewCodeError(errorx.ErrBusinessLogic, "单点登录依赖 redis，请先启用 redis 配置")
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package logic_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/logic"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/model"
+	types2 "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/errorx"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/oidc"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/oidc/oidctest"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/totp"
+	pkgtypes "github.com/yanshicheng/ikube-gin-starter/pkg/types"
+	"gorm.io/gorm"
+)
+
+// newOidcIdp 启动身份提供方桩并启用单点登录，使用默认的单点登录配置
+func newOidcIdp(t *testing.T, db *gorm.DB) *oidctest.Server {
+	assert.NoError(t, db.AutoMigrate(&model.AccountIdentity{}, &model.Organization{}))
+	idp, err := oidctest.NewServer("ikube", "secret")
+	assert.NoError(t, err, "启动身份提供方桩应该成功")
+	t.Cleanup(idp.Close)
+	oidcConfig := global.C.Oidc
+	t.Cleanup(func() {
+		global.C.Oidc = oidcConfig
+		global.O = nil
+	})
+	global.C.Oidc = pkgtypes.NewOidcConfig()
+	global.C.Oidc.Enable = true
+	global.O, err = oidc.InitIkubeOidc(idp.URL, "ikube", "secret", "http://localhost/callback", nil)
+	assert.NoError(t, err, "初始化 IkubeOidc 应该成功")
+	return idp
+}
+
+// oidcCallback 完成一次授权并回调，返回回调的结果
+func oidcCallback(t *testing.T, a *logic.AuthLogic, idp *oidctest.Server, c *gin.Context) (*types2.TokenResponse, error) {
+	login, err := a.OidcLogin(c)
+	assert.NoError(t, err, "生成授权地址应该成功")
+	code, state, err := idp.Authorize(login.AuthUrl)
+	assert.NoError(t, err, "授权应该成功")
+	return a.OidcCallback(c, types2.OidcCallbackRequest{Code: code, State: state})
+}
+
+func TestOidcUnverifiedEmail(t *testing.T) {
+	a, _, db := newAuthLogic(t)
+	idp := newOidcIdp(t, db)
+	// 不自动创建账号，未关联时直接拒绝登录
+	global.C.Oidc.AutoCreate = false
+	account := createAccount(t, db, "zhangsan", "Passw0rd!", false)
+
+	tests := []struct {
+		name   string
+		claims map[string]interface{}
+	}{
+		{"邮箱未验证", map[string]interface{}{"sub": "u-1001", "email": account.Email, "email_verified": false}},
+		{"未声明邮箱是否验证", map[string]interface{}{"sub": "u-1002", "email": account.Email}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp.SetClaims(tt.claims)
+			tokens, err := oidcCallback(t, a, idp, testContext())
+			assert.Nil(t, tokens, "不应该签发 token")
+			assert.Equal(t, errorx.ErrLoginInvalid, errorCode(err), "未验证的邮箱不应该关联已有账号")
+			var count int64
+			assert.NoError(t, db.Model(&model.AccountIdentity{}).Where("subject = ?", tt.claims["sub"]).Count(&count).Error)
+			assert.Equal(t, int64(0), count, "不应该记录外部身份")
+		})
+	}
+
+	// 已验证的邮箱关联已有账号
+	idp.SetClaims(map[string]interface{}{"sub": "u-1003", "email": account.Email, "email_verified": true})
+	tokens, err := oidcCallback(t, a, idp, testContext())
+	assert.NoError(t, err, "已验证的邮箱应该关联已有账号")
+	assert.NotEmpty(t, tokens.AccessToken, "应该签发 access token")
+	var identity model.AccountIdentity
+	assert.NoError(t, db.Where("subject = ?", "u-1003").First(&identity).Error)
+	assert.Equal(t, account.ID, identity.AccountId, "外部身份应该关联到邮箱对应的账号")
+}
+
+func TestOidcCallbackOtp(t *testing.T) {
+	a, o, db := newAuthLogic(t)
+	idp := newOidcIdp(t, db)
+
+	// 按已验证的邮箱关联已启用两步验证的账号
+	account := createAccount(t, db, "zhangsan", "Passw0rd!", false)
+	secret, err := totp.GenerateSecret()
+	assert.NoError(t, err)
+	now := time.Now()
+	assert.NoError(t, db.Create(&model.AccountOtp{AccountId: account.ID, Secret: secret, EnabledAt: &now}).Error)
+	idp.SetClaims(map[string]interface{}{"sub": "u-1001", "email": account.Email, "email_verified": true})
+
+	// 单点登录不直接签发 token，先返回验证凭据
+	c := testContext()
+	tokens, err := oidcCallback(t, a, idp, c)
+	assert.Nil(t, tokens, "两步验证完成前不应该签发 token")
+	assert.Equal(t, errorx.ErrOtpRequired, errorCode(err), "已启用两步验证时应该要求两步验证")
+	challenge := err.(*errorx.CodeError).Data.(*types2.OtpChallengeResponse).Challenge
+
+	otpCode, err := totp.Code(secret, totp.Step(time.Now()))
+	assert.NoError(t, err)
+	tokens, err = o.Login(c, types2.OtpLoginRequest{Challenge: challenge, Code: otpCode})
+	assert.NoError(t, err, "两步验证通过后应该登录成功")
+	assert.NotEmpty(t, tokens.AccessToken, "应该签发 access token")
+}
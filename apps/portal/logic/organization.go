@@ -1,17 +1,23 @@
 package logic
 
 import (
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/yanshicheng/ikube-gin-starter/apps/portal"
 	"github.com/yanshicheng/ikube-gin-starter/apps/portal/model"
 	"github.com/yanshicheng/ikube-gin-starter/apps/portal/service"
 	types2 "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/auth"
+	"github.com/yanshicheng/ikube-gin-starter/common/errorx"
+	"github.com/yanshicheng/ikube-gin-starter/common/filter"
+	cmodel "github.com/yanshicheng/ikube-gin-starter/common/model"
 	"github.com/yanshicheng/ikube-gin-starter/common/types"
 	"github.com/yanshicheng/ikube-gin-starter/global"
 	"github.com/yanshicheng/ikube-gin-starter/router"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"sort"
 )
 
 // 接口检查
@@ -27,192 +33,257 @@ type OrganizationLogic struct {
 func (o *OrganizationLogic) Get(c *gin.Context, search types2.OrganizationSearch) ([]*model.Organization, error) {
 	o.l.Info(fmt.Sprintf("查询机构信息, name: %s", search.Name))
 	var orgs []model.Organization
-	if err := o.db.WithContext(c).Where("name like ?", search.Name+"%").Find(&orgs).Error; err != nil {
+	if err := o.db.WithContext(c).Scopes(auth.DataScopeFilter(c, &model.Organization{})).
+		Where(filter.Prefix("name", search.Name)).Find(&orgs).Error; err != nil {
 		o.l.Error(fmt.Sprintf("查询机构信息失败, id: %s, error: %s", search.Name, err.Error()))
 		return nil, err
 	}
-	resultOrgs := make([]*model.Organization, 0)
-	for _, org := range orgs {
-		resultOrg, err := o.buildSingleParent(&org)
-		if err != nil {
-			o.l.Error(fmt.Sprintf("构建父级层级结构失败, id: %d, error: %s", org.ID, err.Error()))
-			return nil, err
+	ancestors, err := o.queryAncestors(c, orgs)
+	if err != nil {
+		return nil, err
+	}
+	// 每个机构单独构建一条从根节点到自身的链路
+	resultOrgs := make([]*model.Organization, 0, len(orgs))
+	for i := range orgs {
+		node := &orgs[i]
+		ids := node.AncestorIds()
+		for j := len(ids) - 1; j >= 0; j-- {
+			ancestor, ok := ancestors[ids[j]]
+			if !ok {
+				break
+			}
+			parent := *ancestor
+			parent.Children = []*model.Organization{node}
+			node = &parent
 		}
-		resultOrgs = append(resultOrgs, resultOrg)
+		resultOrgs = append(resultOrgs, node)
 	}
 	return resultOrgs, nil
 }
 
-// 递归获某个节点的所有父节点
-func (o *OrganizationLogic) buildSingleParent(org *model.Organization) (*model.Organization, error) {
-	if org.ParentId == 0 {
-		return org, nil // 如果没有父级，返回当前组织
+// queryAncestors 通过祖先路径一次查询出所有机构的祖先节点，超出数据权限范围的祖先不返回
+func (o *OrganizationLogic) queryAncestors(c *gin.Context, orgs []model.Organization) (map[uint]*model.Organization, error) {
+	idSet := make(map[uint]bool)
+	ids := make([]uint, 0)
+	for _, org := range orgs {
+		for _, id := range org.AncestorIds() {
+			if !idSet[id] {
+				idSet[id] = true
+				ids = append(ids, id)
+			}
+		}
 	}
-
-	var parent model.Organization
-	if err := o.db.Where("id = ?", org.ParentId).First(&parent).Error; err != nil {
+	ancestors := make(map[uint]*model.Organization, len(ids))
+	if len(ids) == 0 {
+		return ancestors, nil
+	}
+	var parents []*model.Organization
+	if err := o.db.WithContext(c).Scopes(auth.DataScopeFilter(c, &model.Organization{})).
+		Where("id IN ?", ids).Find(&parents).Error; err != nil {
+		o.l.Error(fmt.Sprintf("查询父级机构失败, error: %s", err.Error()))
 		return nil, err
 	}
-	// 将当前组织添加到父级的 Children 中
-	parent.Children = append(parent.Children, org)
-	// 判断是不是根节点
-	if parent.ParentId == 0 {
-		return &parent, nil
+	for _, parent := range parents {
+		ancestors[parent.ID] = parent
 	}
-	// 递归构建父级结构
-	parentOrg, err := o.buildSingleParent(&parent)
+	return ancestors, nil
+}
+
+// List 查询机构树，没有过滤条件时返回全部机构，否则返回匹配的机构和其祖先组成的树
+func (o *OrganizationLogic) List(c *gin.Context, query types.Filters) ([]*model.Organization, error) {
+	if len(query.Filter) == 0 {
+		orgTree, err := (&model.Organization{}).GetAllDescendants(o.db.WithContext(c).Scopes(auth.DataScopeFilter(c, &model.Organization{})))
+		if err != nil {
+			o.l.Error(fmt.Sprintf("查询机构信息失败, error: %s", err.Error()))
+			return nil, err
+		}
+		return orgTree, nil
+	}
+	scope, err := filter.Scope(&model.Organization{}, query.Filter)
 	if err != nil {
 		return nil, err
 	}
-
-	return parentOrg, nil
-}
-
-// 递归获某个节点的所有父节点
-func (o *OrganizationLogic) buildParentHierarchy(org *model.Organization, orgs []*model.Organization) ([]*model.Organization, error) {
-	// 先判断是否存在列表中
-	args, ok := o.recursiveAdd(org, orgs)
-	if ok {
-		return args, nil
+	var orgs []model.Organization
+	if err := o.db.WithContext(c).Scopes(auth.DataScopeFilter(c, &model.Organization{}), scope).
+		Find(&orgs).Error; err != nil {
+		o.l.Error(fmt.Sprintf("查询机构信息失败, filter: %v, error: %s", query.Filter, err.Error()))
+		return nil, err
 	}
-
-	var parent model.Organization
-	if err := o.db.Where("id = ?", org.ParentId).First(&parent).Error; err != nil {
+	o.l.Debug(fmt.Sprintf("查询机构信息: %+v, %d", orgs, len(orgs)))
+	ancestors, err := o.queryAncestors(c, orgs)
+	if err != nil {
 		return nil, err
 	}
-	// 将当前组织添加到父级的 Children 中
-	parent.Children = append(parent.Children, org)
-	args, ok = o.recursiveAdd(&parent, orgs)
-	if ok {
-		return args, nil
-	} else {
-		orgSlice, err := o.buildParentHierarchy(&parent, orgs)
-		if err != nil {
-			return nil, err
+	// 匹配的机构和其祖先合并为一棵树，按层级排序保证父节点先于子节点挂载
+	nodes := make([]*model.Organization, 0, len(orgs)+len(ancestors))
+	for _, ancestor := range ancestors {
+		nodes = append(nodes, ancestor)
+	}
+	for i := range orgs {
+		if _, ok := ancestors[orgs[i].ID]; !ok {
+			nodes = append(nodes, &orgs[i])
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Level != nodes[j].Level {
+			return nodes[i].Level < nodes[j].Level
 		}
-		return orgSlice, nil
+		return nodes[i].ID < nodes[j].ID
+	})
+	nodeMap := make(map[uint]*model.Organization, len(nodes))
+	for _, node := range nodes {
+		nodeMap[node.ID] = node
 	}
+	resultOrgs := []*model.Organization{}
+	for _, node := range nodes {
+		if parent, ok := nodeMap[node.ParentId]; ok {
+			parent.Children = append(parent.Children, node)
+		} else {
+			resultOrgs = append(resultOrgs, node)
+		}
+	}
+	o.l.Debug(fmt.Sprintf("查询机构信息resultOrg: %+v", resultOrgs))
+	return resultOrgs, nil
 }
 
-// 递归添加节点
-func (o *OrganizationLogic) recursiveAdd(org *model.Organization, orgs []*model.Organization) ([]*model.Organization, bool) {
-	// 判断当前是否为主节点
-	if org.ParentId == 0 {
-		for _, og := range orgs {
-			if og.ID == org.ID {
-				// 如果已经存在，则跳过
-				return orgs, true
-			}
+func (o *OrganizationLogic) Put(c *gin.Context, id types.SearchId, org *model.Organization) (*model.Organization, error) {
+	var oldOrg *model.Organization
+	err := o.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		// 先查询出来，数据权限范围外的机构按不存在处理
+		var err error
+		if oldOrg, err = o.first(c, tx, id.Id, "机构不存在"); err != nil {
+			return err
 		}
-		// 如果不存在，则添加
-		orgs = append(orgs, org)
-		return orgs, true
-	}
-	// 当前节点为子节点
-	for _, og := range orgs {
-		if og.ID == org.ParentId {
-			// 如果已经存在，则跳过
-			og.Children = append(og.Children, org)
-			return orgs, true
+		// 修改父级时整体移动子树
+		if org.ParentId != oldOrg.ParentId {
+			if err := o.move(c, tx, oldOrg, org.ParentId); err != nil {
+				return err
+			}
 		}
-		// 如果当前节点存在子节点，则递归添加
-		if og.Children != nil {
-			o.recursiveAdd(org, og.Children)
+		// 修改操作
+		oldOrg.Desc = org.Desc
+		oldOrg.Name = org.Name
+		// 保存
+		if err := tx.Save(oldOrg).Error; err != nil {
+			o.l.Error(fmt.Sprintf("更新机构信息失败, id: %d, error: %s", id.Id, err.Error()))
+			return fmt.Errorf("更新机构信息失败")
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	// 如果都不满足，则返回false
-	return orgs, false
+	return oldOrg, nil
 }
-func (o *OrganizationLogic) List(c *gin.Context, search types2.OrganizationSearch) ([]*model.Organization, error) {
-	if search.Name == "" {
-		var allOrgs []model.Organization
-		if err := o.db.WithContext(c).Find(&allOrgs).Error; err != nil {
-			o.l.Error(fmt.Sprintf("查询机构信息失败, error: %s", err.Error()))
-			return nil, err
+
+// Move 将机构及其所有子孙节点移动到新的父节点下，并重新计算层级
+func (o *OrganizationLogic) Move(c *gin.Context, id types.SearchId, req types2.OrganizationMoveRequest) (*model.Organization, error) {
+	var org *model.Organization
+	err := o.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if org, err = o.first(c, tx, id.Id, "机构不存在"); err != nil {
+			return err
 		}
-		orgMap := make(map[uint]*model.Organization, len(allOrgs))
-		for i := range allOrgs {
-			orgMap[allOrgs[i].ID] = &allOrgs[i]
+		return o.move(c, tx, org, *req.ParentId)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+// move 检查新的父级在数据权限范围内后移动子树
+func (o *OrganizationLogic) move(c *gin.Context, tx *gorm.DB, org *model.Organization, parentId uint) error {
+	if parentId == org.ParentId {
+		return nil
+	}
+	if parentId == 0 {
+		if err := checkRootScope(c); err != nil {
+			return err
 		}
-		// 构建树形结构
-		var orgTree []*model.Organization
-		for i := range allOrgs {
-			org := &allOrgs[i]
-			if org.ParentId != 0 {
-				if parent, ok := orgMap[uint(org.ParentId)]; ok {
-					if parent.Children == nil {
-						parent.Children = make([]*model.Organization, 0)
-					}
-					parent.Children = append(parent.Children, org)
-				}
-			} else {
-				orgTree = append(orgTree, org)
+	} else if _, err := o.first(c, tx, parentId, "父级机构不存在"); err != nil {
+		return err
+	}
+	return o.moveTree(tx, org, parentId)
+}
+
+// moveTree 在事务中移动子树：检查循环引用和层级限制，更新节点父级，并调整所有子孙节点的层级和祖先路径
+func (o *OrganizationLogic) moveTree(tx *gorm.DB, org *model.Organization, parentId uint) error {
+	if parentId == org.ParentId {
+		return nil
+	}
+	level := 1
+	var path cmodel.TreePath
+	path.SetParent(nil, 0)
+	if parentId != 0 {
+		var parent model.Organization
+		if err := tx.Where("id = ?", parentId).First(&parent).Error; err != nil {
+			o.l.Error(fmt.Sprintf("查询父级机构失败, id: %d, error: %s", parentId, err.Error()))
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errorx.NewCodeError(errorx.ErrDataNotFound, "父级机构不存在")
 			}
+			return fmt.Errorf("查询父级机构失败")
 		}
-		return orgTree, nil
-	} else {
-		var orgs []model.Organization
-		if err := o.db.WithContext(c).Where("name like ?", search.Name+"%").Find(&orgs).Error; err != nil {
-			o.l.Error(fmt.Sprintf("查询机构信息失败, id: %s, error: %s", search.Name, err.Error()))
-			return nil, err
+		// 新的父节点是自身或位于子树中会形成循环
+		if parent.ID == org.ID {
+			return errorx.NewCodeError(errorx.ErrBusinessLogic, "不能将机构移动到自身下")
 		}
-		o.l.Debug(fmt.Sprintf("查询机构信息: %+v, %d", orgs, len(orgs)))
-		resultOrgs := []*model.Organization{}
-		for _, org := range orgs {
-			o.l.Debug(fmt.Sprintf("查询机构信息org: %+v", org))
-			resOrgs, err := o.buildParentHierarchy(&org, resultOrgs)
-			if err != nil {
-				o.l.Error(fmt.Sprintf("构建父级层级结构失败, id: %d, error: %s", org.ID, err.Error()))
-				return nil, err
-			}
-			resultOrgs = make([]*model.Organization, len(resOrgs))
-			copy(resultOrgs, resOrgs)
+		if parent.IsDescendantOf(org.TreePath, org.ID) {
+			return errorx.NewCodeError(errorx.ErrBusinessLogic, "不能将机构移动到其子机构下")
 		}
-		o.l.Debug(fmt.Sprintf("查询机构信息resultOrg: %+v", resultOrgs))
-		return resultOrgs, nil
+		level = parent.Level + 1
+		path.SetParent(&parent.TreePath, parent.ID)
 	}
-}
-
-func (o *OrganizationLogic) Put(c *gin.Context, id types.SearchId, org *model.Organization) (*model.Organization, error) {
-	// 先查询出来
-	var oldOrg model.Organization
-	if err := o.db.WithContext(c).Where("id = ?", id.Id).First(&oldOrg).Error; err != nil {
-		o.l.Error(fmt.Sprintf("查询机构信息失败, id: %d, error: %s", id.Id, err.Error()))
-		return nil, fmt.Errorf("查询机构信息失败")
+	// 子树深度由子孙节点的最大层级得到
+	var maxLevel int
+	if err := tx.Model(&model.Organization{}).Scopes(cmodel.Descendants(org.TreePath, org.ID)).
+		Select("COALESCE(MAX(level), 0)").Scan(&maxLevel).Error; err != nil {
+		o.l.Error(fmt.Sprintf("查询子节点信息失败, id: %d, error: %s", org.ID, err.Error()))
+		return fmt.Errorf("查询子节点信息失败")
 	}
-	// 判断是否为主节点，如果是主节点则不允许修改 ParentId
-	if oldOrg.ParentId == 0 {
-		if org.ParentId != 0 {
-			o.l.Error(fmt.Sprintf("主节点不允许修改 ParentId, id: %d", id.Id))
-			return nil, fmt.Errorf("主节点不允许修改 ParentId")
-		}
+	depth := 0
+	if maxLevel > org.Level {
+		depth = maxLevel - org.Level
 	}
-	// 判断是否存在子节点
-	var childOrgs []model.Organization
-	if err := o.db.WithContext(c).Where("parent_id = ?", id.Id).Find(&childOrgs).Error; err != nil {
-		o.l.Error(fmt.Sprintf("查询子节点信息失败, id: %d, error: %s", id.Id, err.Error()))
-		return nil, fmt.Errorf("查询子节点信息失败")
+	if level+depth > model.OrganizationLevel {
+		return errorx.NewCodeError(errorx.ErrBusinessLogic, fmt.Sprintf("移动后机构层级超过 %d 级", model.OrganizationLevel))
 	}
-	if len(childOrgs) > 0 {
-		// 如果存在子节点，则不允许修改 ParentId
-		if org.ParentId != oldOrg.ParentId {
-			o.l.Error(fmt.Sprintf("存在子节点不允许修改 ParentId, id: %d", id.Id))
-			return nil, fmt.Errorf("存在子节点不允许修改 ParentId")
+	// 先按旧路径更新子孙节点层级，再替换路径前缀
+	if delta := level - org.Level; delta != 0 && depth > 0 {
+		if err := tx.Unscoped().Model(&model.Organization{}).Scopes(cmodel.Descendants(org.TreePath, org.ID)).
+			Update("level", gorm.Expr("level + ?", delta)).Error; err != nil {
+			o.l.Error(fmt.Sprintf("更新子节点层级失败, id: %d, error: %s", org.ID, err.Error()))
+			return fmt.Errorf("更新子节点层级失败")
 		}
 	}
-	// 修改操作
-	oldOrg.Desc = org.Desc
-	oldOrg.Name = org.Name
-	oldOrg.ParentId = org.ParentId
-	// 保存
-	if err := o.db.WithContext(c).Save(&oldOrg).Error; err != nil {
-		o.l.Error(fmt.Sprintf("更新机构信息失败, id: %d, error: %s", id.Id, err.Error()))
-		return nil, fmt.Errorf("更新机构信息失败")
+	if err := cmodel.MoveTreePath(tx.Model(&model.Organization{}), org.ChildrenPath(org.ID), path.ChildrenPath(org.ID)); err != nil {
+		o.l.Error(fmt.Sprintf("更新子节点路径失败, id: %d, error: %s", org.ID, err.Error()))
+		return fmt.Errorf("更新子节点路径失败")
+	}
+	if err := tx.Model(&model.Organization{}).Where("id = ?", org.ID).
+		Updates(map[string]interface{}{"parent_id": parentId, "level": level, "tree_path": path.TreePath}).Error; err != nil {
+		o.l.Error(fmt.Sprintf("移动机构失败, id: %d, error: %s", org.ID, err.Error()))
+		return fmt.Errorf("移动机构失败")
 	}
-	return &oldOrg, nil
+	// 机构层级变化会影响数据权限中子机构的范围
+	auth.ClearAllPermissionCacheAfterCommit(tx)
+	o.l.Debug(fmt.Sprintf("移动机构, id: %d, parentId: %d -> %d, level: %d -> %d, depth: %d",
+		org.ID, org.ParentId, parentId, org.Level, level, depth))
+	org.ParentId = parentId
+	org.Level = level
+	org.TreePath = path
+	return nil
 }
 
+// Create 创建机构，父级必须在数据权限范围内
 func (o *OrganizationLogic) Create(c *gin.Context, org *model.Organization) error {
+	if org.ParentId == 0 {
+		if err := checkRootScope(c); err != nil {
+			return err
+		}
+	} else if _, err := o.first(c, o.db.WithContext(c), org.ParentId, "父级机构不存在"); err != nil {
+		return err
+	}
 	if err := o.db.WithContext(c).Create(org).Error; err != nil {
 		o.l.Error(fmt.Sprintf("创建机构信息失败, error: %s", err.Error()))
 		return err
@@ -221,19 +292,18 @@ func (o *OrganizationLogic) Create(c *gin.Context, org *model.Organization) erro
 }
 
 func (o *OrganizationLogic) Delete(c *gin.Context, id types.SearchId) error {
-	// 删除机构首先查询出来
-	var org model.Organization
-	if err := o.db.WithContext(c).Where("id = ?", id.Id).First(&org).Error; err != nil {
-		o.l.Error(fmt.Sprintf("查询机构信息失败, id: %d, error: %s", id.Id, err.Error()))
-		return fmt.Errorf("查询机构信息失败")
+	// 删除机构首先查询出来，数据权限范围外的机构按不存在处理
+	org, err := o.first(c, o.db.WithContext(c), id.Id, "机构不存在")
+	if err != nil {
+		return err
 	}
 	// 判断是否有子节点
-	var childOrgs []model.Organization
-	if err := o.db.WithContext(c).Where("parent_id = ?", id.Id).Find(&childOrgs).Error; err != nil {
+	var count int64
+	if err := o.db.WithContext(c).Model(&model.Organization{}).Scopes(cmodel.Descendants(org.TreePath, org.ID)).Count(&count).Error; err != nil {
 		o.l.Error(fmt.Sprintf("查询机构信息失败, id: %d, error: %s", id.Id, err.Error()))
 		return fmt.Errorf("查询机构信息失败")
 	}
-	if len(childOrgs) > 0 {
+	if count > 0 {
 		o.l.Error(fmt.Sprintf("机构下存在子节点, id: %d", id.Id))
 		return fmt.Errorf("机构下存在子节点")
 	}
@@ -245,6 +315,27 @@ func (o *OrganizationLogic) Delete(c *gin.Context, id types.SearchId) error {
 	return nil
 }
 
+// first 按数据权限范围查询机构，范围外的机构按不存在处理，返回 notFound 错误信息
+func (o *OrganizationLogic) first(c *gin.Context, tx *gorm.DB, id uint, notFound string) (*model.Organization, error) {
+	var org model.Organization
+	if err := tx.Scopes(auth.DataScopeFilter(c, &model.Organization{})).Where("id = ?", id).First(&org).Error; err != nil {
+		o.l.Error(fmt.Sprintf("查询机构信息失败, id: %d, error: %s", id, err.Error()))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errorx.NewCodeError(errorx.ErrDataNotFound, notFound)
+		}
+		return nil, fmt.Errorf("查询机构信息失败")
+	}
+	return &org, nil
+}
+
+// checkRootScope 顶级机构不属于任何机构，只有不限制数据范围的账号可以创建或移动到顶级
+func checkRootScope(c *gin.Context) error {
+	if scope, ok := auth.GetDataScope(c); ok && scope.All {
+		return nil
+	}
+	return errorx.NewCodeError(errorx.ErrPermissionDenied, "没有权限操作顶级机构")
+}
+
 // 只需要保证 全局对象Config和全局Logger已经加载完成
 func (o *OrganizationLogic) Config() {
 	o.l = global.L.Named(portal.AppName).Named(portal.AppOrganization).Named("logic")
@@ -257,5 +348,5 @@ func (o *OrganizationLogic) Name() string {
 
 func init() {
 	// 注册
-	router.RegistryLogic(logic)
+	router.RegistryService[service.OrganizationService](logic)
 }
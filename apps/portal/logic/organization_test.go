@@ -0,0 +1,269 @@
+package logic_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/logic"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/model"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/service"
+	types2 "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/auth"
+	"github.com/yanshicheng/ikube-gin-starter/common/errorx"
+	"github.com/yanshicheng/ikube-gin-starter/common/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/validator"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/testenv"
+	"github.com/yanshicheng/ikube-gin-starter/router"
+	"gorm.io/gorm"
+)
+
+// orgNames 按树的先序遍历返回机构名称，子机构用 / 连接父机构
+func orgNames(orgs []*model.Organization, prefix string) []string {
+	names := make([]string, 0)
+	for _, org := range orgs {
+		name := prefix + org.Name
+		names = append(names, name)
+		names = append(names, orgNames(org.Children, name+"/")...)
+	}
+	return names
+}
+
+func TestOrganizationList(t *testing.T) {
+	testenv.Setup(t)
+	db := testenv.DB(t, &model.Organization{})
+	testenv.Redis(t)
+	svc, err := router.Lookup[service.OrganizationService]()
+	assert.NoError(t, err, "机构逻辑应该已注册")
+	svc.(interface{ Config() }).Config()
+
+	root := &model.Organization{Name: "总部"}
+	assert.NoError(t, db.Create(root).Error)
+	ops := &model.Organization{Name: "运维部", ParentId: root.ID}
+	assert.NoError(t, db.Create(ops).Error)
+	assert.NoError(t, db.Create(&model.Organization{Name: "运维一组", ParentId: ops.ID}).Error)
+	assert.NoError(t, db.Create(&model.Organization{Name: "研发部", ParentId: root.ID}).Error)
+
+	c := testContext()
+	auth.SetDataScope(c, &auth.DataScope{All: true})
+
+	// 没有过滤条件时返回整棵机构树
+	orgs, err := svc.List(c, types.Filters{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"总部", "总部/运维部", "总部/运维部/运维一组", "总部/研发部"}, orgNames(orgs, ""), "应该返回整棵机构树")
+
+	// 返回匹配的机构和其祖先
+	orgs, err = svc.List(c, types.Filters{Filter: []string{"name:like:运维"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"总部", "总部/运维部", "总部/运维部/运维一组"}, orgNames(orgs, ""), "应该返回匹配的机构和其祖先")
+	orgs, err = svc.List(c, types.Filters{Filter: []string{"name:like:运维", "level:eq:2"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"总部", "总部/运维部"}, orgNames(orgs, ""), "多个条件应该同时满足")
+
+	// 不允许过滤的字段返回参数错误
+	_, err = svc.List(c, types.Filters{Filter: []string{"Desc:eq:a"}})
+	assert.IsType(t, validator.FieldErrors{}, err, "不允许过滤的字段应该返回参数错误")
+}
+
+func TestOrganizationWriteScope(t *testing.T) {
+	testenv.Setup(t)
+	db := testenv.DB(t, &model.Organization{})
+	svc := &logic.OrganizationLogic{}
+	svc.Config()
+
+	root := &model.Organization{Name: "总部"}
+	assert.NoError(t, db.Create(root).Error)
+	ops := &model.Organization{Name: "运维部", ParentId: root.ID}
+	assert.NoError(t, db.Create(ops).Error)
+	team := &model.Organization{Name: "运维一组", ParentId: ops.ID}
+	assert.NoError(t, db.Create(team).Error)
+	dev := &model.Organization{Name: "研发部", ParentId: root.ID}
+	assert.NoError(t, db.Create(dev).Error)
+
+	// 只能访问运维部及其子机构
+	c := testContext()
+	auth.SetDataScope(c, &auth.DataScope{OrganizationIds: []uint{ops.ID, team.ID}})
+	parentId := func(id uint) *uint { return &id }
+	tests := []struct {
+		name string
+		call func() error
+		code errorx.ErrorCode
+	}{
+		{"修改范围外的机构", func() error {
+			_, err := svc.Put(c, types.SearchId{Id: dev.ID}, &model.Organization{Name: "研发中心", ParentId: dev.ParentId})
+			return err
+		}, errorx.ErrDataNotFound},
+		{"修改时移动到范围外的父级", func() error {
+			_, err := svc.Put(c, types.SearchId{Id: team.ID}, &model.Organization{Name: "运维一组", ParentId: dev.ID})
+			return err
+		}, errorx.ErrDataNotFound},
+		{"移动范围外的机构", func() error {
+			_, err := svc.Move(c, types.SearchId{Id: dev.ID}, types2.OrganizationMoveRequest{ParentId: parentId(ops.ID)})
+			return err
+		}, errorx.ErrDataNotFound},
+		{"移动到范围外的父级", func() error {
+			_, err := svc.Move(c, types.SearchId{Id: team.ID}, types2.OrganizationMoveRequest{ParentId: parentId(dev.ID)})
+			return err
+		}, errorx.ErrDataNotFound},
+		{"移动到顶级", func() error {
+			_, err := svc.Move(c, types.SearchId{Id: team.ID}, types2.OrganizationMoveRequest{ParentId: parentId(0)})
+			return err
+		}, errorx.ErrPermissionDenied},
+		{"在范围外的父级下创建", func() error {
+			return svc.Create(c, &model.Organization{Name: "研发一组", ParentId: dev.ID})
+		}, errorx.ErrDataNotFound},
+		{"创建顶级机构", func() error {
+			return svc.Create(c, &model.Organization{Name: "分公司"})
+		}, errorx.ErrPermissionDenied},
+		{"删除范围外的机构", func() error {
+			return svc.Delete(c, types.SearchId{Id: dev.ID})
+		}, errorx.ErrDataNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.code, errorCode(tt.call()), tt.name)
+		})
+	}
+	var orgs []model.Organization
+	assert.NoError(t, db.Order("id").Find(&orgs).Error)
+	assert.Len(t, orgs, 4, "范围外的操作不应该创建或删除机构")
+	assert.Equal(t, "研发部", orgs[3].Name, "范围外的机构不应该被修改")
+	assert.Equal(t, ops.ID, orgs[2].ParentId, "不应该移动到范围外的父级")
+
+	// 范围内的操作
+	assert.NoError(t, svc.Create(c, &model.Organization{Name: "运维二组", ParentId: ops.ID}), "可以在范围内的父级下创建")
+	_, err := svc.Put(c, types.SearchId{Id: team.ID}, &model.Organization{Name: "运维1组", ParentId: ops.ID})
+	assert.NoError(t, err, "可以修改范围内的机构")
+	assert.NoError(t, svc.Delete(c, types.SearchId{Id: team.ID}), "可以删除范围内的机构")
+}
+
+// newOrgTree 创建两棵机构树，返回按名称索引的机构:
+// 总部 > 运维部 > 运维一组 > 值班组，总部 > 研发部，分公司 > 一部 > 二部 > 三部 > 四部
+func newOrgTree(t *testing.T) (*logic.OrganizationLogic, *gorm.DB, map[string]*model.Organization) {
+	testenv.Setup(t)
+	db := testenv.DB(t, &model.Organization{})
+	o := &logic.OrganizationLogic{}
+	o.Config()
+	orgs := make(map[string]*model.Organization)
+	for _, node := range [][2]string{
+		{"总部", ""}, {"运维部", "总部"}, {"运维一组", "运维部"}, {"值班组", "运维一组"}, {"研发部", "总部"},
+		{"分公司", ""}, {"一部", "分公司"}, {"二部", "一部"}, {"三部", "二部"}, {"四部", "三部"},
+	} {
+		org := &model.Organization{Name: node[0]}
+		if parent, ok := orgs[node[1]]; ok {
+			org.ParentId = parent.ID
+		}
+		assert.NoError(t, db.Create(org).Error)
+		orgs[org.Name] = org
+	}
+	return o, db, orgs
+}
+
+func TestOrganizationMove(t *testing.T) {
+	tests := []struct {
+		name   string
+		org    string
+		parent string // 为空表示移动为根节点
+		code   errorx.ErrorCode
+		levels map[string]int // 移动后子树的层级
+	}{
+		{"移动到其他父级", "运维部", "研发部", 0, map[string]int{"运维部": 3, "运维一组": 4, "值班组": 5}},
+		{"移动到更浅的父级", "运维一组", "总部", 0, map[string]int{"运维一组": 2, "值班组": 3}},
+		{"移动为根节点", "运维部", "", 0, map[string]int{"运维部": 1, "运维一组": 2, "值班组": 3}},
+		{"叶子节点", "四部", "分公司", 0, map[string]int{"四部": 2}},
+		{"移动后刚好达到层级上限", "运维一组", "二部", 0, map[string]int{"运维一组": 4, "值班组": 5}},
+		{"移动到自身下", "运维部", "运维部", errorx.ErrBusinessLogic, nil},
+		{"移动到子机构下", "运维部", "运维一组", errorx.ErrBusinessLogic, nil},
+		{"移动到子孙机构下", "运维部", "值班组", errorx.ErrBusinessLogic, nil},
+		{"子树超过层级上限", "运维部", "三部", errorx.ErrBusinessLogic, nil},
+		{"节点超过层级上限", "研发部", "四部", errorx.ErrBusinessLogic, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, db, orgs := newOrgTree(t)
+			c := testContext()
+			auth.SetDataScope(c, &auth.DataScope{All: true})
+			var parentId uint
+			if tt.parent != "" {
+				parentId = orgs[tt.parent].ID
+			}
+			_, err := o.Move(c, types.SearchId{Id: orgs[tt.org].ID}, types2.OrganizationMoveRequest{ParentId: &parentId})
+			levels := make(map[string]int)
+			var all []model.Organization
+			assert.NoError(t, db.Find(&all).Error)
+			for _, org := range all {
+				levels[org.Name] = org.Level
+			}
+			if tt.code != 0 {
+				assert.Equal(t, tt.code, errorCode(err), "应该拒绝移动")
+				for name, org := range orgs {
+					assert.Equal(t, org.Level, levels[name], "拒绝移动时 %s 的层级不应该变化", name)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			for name, org := range orgs {
+				want, ok := tt.levels[name]
+				if !ok {
+					want = org.Level
+				}
+				assert.Equal(t, want, levels[name], "%s 的层级与预期不符", name)
+			}
+		})
+	}
+
+	// 父级不存在
+	o, _, orgs := newOrgTree(t)
+	c := testContext()
+	auth.SetDataScope(c, &auth.DataScope{All: true})
+	parentId := uint(999)
+	_, err := o.Move(c, types.SearchId{Id: orgs["运维部"].ID}, types2.OrganizationMoveRequest{ParentId: &parentId})
+	assert.Equal(t, errorx.ErrDataNotFound, errorCode(err), "父级不存在时应该返回数据未找到")
+}
+
+func TestOrganizationMoveTreePath(t *testing.T) {
+	o, db, orgs := newOrgTree(t)
+	c := testContext()
+	auth.SetDataScope(c, &auth.DataScope{All: true})
+	path := func(names ...string) string {
+		p := "/"
+		for _, name := range names {
+			p += fmt.Sprintf("%d/", orgs[name].ID)
+		}
+		return p
+	}
+	treePaths := func() map[string]string {
+		var all []model.Organization
+		assert.NoError(t, db.Unscoped().Find(&all).Error)
+		paths := make(map[string]string, len(all))
+		for _, org := range all {
+			paths[org.Name] = org.TreePath.TreePath
+		}
+		return paths
+	}
+	// 软删除的子孙节点同样更新路径
+	assert.NoError(t, db.Delete(orgs["值班组"]).Error)
+
+	moveTo := func(org, parent string) {
+		var parentId uint
+		if parent != "" {
+			parentId = orgs[parent].ID
+		}
+		moved, err := o.Move(c, types.SearchId{Id: orgs[org].ID}, types2.OrganizationMoveRequest{ParentId: &parentId})
+		assert.NoError(t, err)
+		assert.Equal(t, treePaths()[org], moved.TreePath.TreePath, "返回的机构应该是移动后的路径")
+	}
+	moveTo("运维部", "二部")
+	assert.Equal(t, map[string]string{
+		"总部": path(), "运维部": path("分公司", "一部", "二部"), "运维一组": path("分公司", "一部", "二部", "运维部"),
+		"值班组": path("分公司", "一部", "二部", "运维部", "运维一组"), "研发部": path("总部"),
+		"分公司": path(), "一部": path("分公司"), "二部": path("分公司", "一部"),
+		"三部": path("分公司", "一部", "二部"), "四部": path("分公司", "一部", "二部", "三部"),
+	}, treePaths(), "子树的路径前缀应该整体替换，其他机构不变")
+
+	moveTo("运维一组", "")
+	paths := treePaths()
+	assert.Equal(t, path(), paths["运维一组"], "移动为根节点后路径为根路径")
+	assert.Equal(t, path("运维一组"), paths["值班组"])
+	assert.Equal(t, path("分公司", "一部", "二部"), paths["运维部"], "原父级的路径不变")
+}
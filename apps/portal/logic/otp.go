@@ -0,0 +1,490 @@
+package logic
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/model"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/service"
+	types2 "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/auth"
+	"github.com/yanshicheng/ikube-gin-starter/common/errorx"
+	"github.com/yanshicheng/ikube-gin-starter/common/types"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/oidc"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/totp"
+	"github.com/yanshicheng/ikube-gin-starter/router"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 两步验证：账号启用或所属角色要求启用时，密码校验通过后不直接签发 token，
+// 而是返回保存在 redis 中的验证凭据，前端携带凭据和一次性密码完成第二步登录。
+
+const otpChallengeKeyPrefix = "ikubeops:otp:challenge:"
+
+// 验证凭据的用途，修改过期密码时生成的凭据不能用于登录
+const (
+	otpPurposeLogin    = "login"
+	otpPurposePassword = "password"
+)
+
+// 接口检查
+var _ service.OtpService = (*OtpLogic)(nil)
+
+var otpLogic = &OtpLogic{}
+
+type OtpLogic struct {
+	l  *zap.Logger
+	db *gorm.DB
+}
+
+func (o *OtpLogic) Status(c *gin.Context) (*types2.OtpStatusResponse, error) {
+	accountId := auth.GetAccountId(c)
+	record, err := o.get(c, accountId)
+	if err != nil {
+		return nil, err
+	}
+	enforced, err := o.enforced(c, accountId)
+	if err != nil {
+		return nil, err
+	}
+	resp := &types2.OtpStatusResponse{Enabled: record.Enabled(), Enforced: enforced}
+	if record.Enabled() {
+		resp.EnabledAt = record.EnabledAt
+		if err := o.db.WithContext(c).Model(&model.OtpRecoveryCode{}).
+			Where("account_id = ? AND used_at IS NULL", accountId).Count(&resp.RecoveryCodes).Error; err != nil {
+			o.l.Error(fmt.Sprintf("查询恢复码失败, id: %d, error: %s", accountId, err.Error()))
+			return nil, fmt.Errorf("查询两步验证状态失败")
+		}
+	}
+	return resp, nil
+}
+
+func (o *OtpLogic) Enroll(c *gin.Context) (*types2.OtpEnrollResponse, error) {
+	account, err := o.account(c, auth.GetAccountId(c))
+	if err != nil {
+		return nil, err
+	}
+	return o.enroll(c, account)
+}
+
+func (o *OtpLogic) Enable(c *gin.Context, req types2.OtpCodeRequest) (*types2.OtpRecoveryCodesResponse, error) {
+	codes, err := o.enable(c, auth.GetAccountId(c), req.Code)
+	if err != nil {
+		return nil, err
+	}
+	return &types2.OtpRecoveryCodesResponse{Codes: codes}, nil
+}
+
+// Disable 关闭两步验证，所属角色要求启用时不允许关闭
+func (o *OtpLogic) Disable(c *gin.Context, req types2.OtpCodeRequest) error {
+	accountId := auth.GetAccountId(c)
+	enforced, err := o.enforced(c, accountId)
+	if err != nil {
+		return err
+	}
+	if enforced {
+		return errorx.NewCodeError(errorx.ErrBusinessLogic, "所属角色要求启用两步验证，不允许关闭")
+	}
+	record, err := o.enabled(c, accountId)
+	if err != nil {
+		return err
+	}
+	if err := o.verify(c, record, req.Code); err != nil {
+		return err
+	}
+	if err := o.remove(c, accountId); err != nil {
+		return err
+	}
+	o.l.Info(fmt.Sprintf("关闭两步验证, id: %d", accountId))
+	return nil
+}
+
+// RecoveryCodes 重新生成恢复码，原有的恢复码全部失效
+func (o *OtpLogic) RecoveryCodes(c *gin.Context, req types2.OtpCodeRequest) (*types2.OtpRecoveryCodesResponse, error) {
+	accountId := auth.GetAccountId(c)
+	record, err := o.enabled(c, accountId)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.verify(c, record, req.Code); err != nil {
+		return nil, err
+	}
+	var codes []string
+	err = o.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		codes, err = replaceRecoveryCodes(tx, accountId)
+		return err
+	})
+	if err != nil {
+		o.l.Error(fmt.Sprintf("生成恢复码失败, id: %d, error: %s", accountId, err.Error()))
+		return nil, fmt.Errorf("生成恢复码失败")
+	}
+	return &types2.OtpRecoveryCodesResponse{Codes: codes}, nil
+}
+
+// Reset 管理员重置账号的两步验证，用于账号丢失验证器应用且没有可用恢复码的情况
+func (o *OtpLogic) Reset(c *gin.Context, id types.SearchId) error {
+	var account model.Account
+	if err := o.db.WithContext(c).Scopes(auth.DataScopeFilter(c, &model.Account{})).
+		Where("id = ?", id.Id).First(&account).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errorx.NewCodeError(errorx.ErrDataNotFound, "账号不存在")
+		}
+		o.l.Error(fmt.Sprintf("查询账号信息失败, id: %d, error: %s", id.Id, err.Error()))
+		return fmt.Errorf("查询账号信息失败")
+	}
+	if err := o.remove(c, account.ID); err != nil {
+		return err
+	}
+	o.l.Info(fmt.Sprintf("重置两步验证成功, id: %d, operator: %d", account.ID, auth.GetAccountId(c)))
+	return nil
+}
+
+// LoginEnroll 角色要求启用两步验证但账号尚未绑定时，登录过程中使用验证凭据生成密钥
+func (o *OtpLogic) LoginEnroll(c *gin.Context, req types2.OtpChallengeRequest) (*types2.OtpEnrollResponse, error) {
+	accountId, err := o.challengeAccount(c, req.Challenge, otpPurposeLogin, true)
+	if err != nil {
+		return nil, err
+	}
+	account, err := o.account(c, accountId)
+	if err != nil {
+		return nil, err
+	}
+	return o.enroll(c, account)
+}
+
+// Login 两步登录的第二步，账号尚未绑定时校验通过即完成绑定，并在 token 中返回恢复码
+func (o *OtpLogic) Login(c *gin.Context, req types2.OtpLoginRequest) (*types2.TokenResponse, error) {
+	accountId, err := o.challengeAccount(c, req.Challenge, otpPurposeLogin, true)
+	if err != nil {
+		return nil, err
+	}
+	account, err := o.account(c, accountId)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkAccountStatus(account); err != nil {
+		return nil, err
+	}
+	recoveryCodes, err := o.complete(c, account, req.Challenge, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	o.l.Info(fmt.Sprintf("登录成功, account: %s", account.Account))
+	tokens, err := authLogic.issueTokens(c, account, "")
+	if err != nil {
+		return nil, err
+	}
+	tokens.RecoveryCodes = recoveryCodes
+	return tokens, nil
+}
+
+// pass 校验验证凭据属于账号后完成两步验证，账号尚未绑定时完成绑定，恢复码可以登录后重新生成
+func (o *OtpLogic) pass(c *gin.Context, account *model.Account, challenge, purpose, code string) error {
+	accountId, err := o.challengeAccount(c, challenge, purpose, true)
+	if err != nil {
+		return err
+	}
+	if accountId != account.ID {
+		o.l.Warn(fmt.Sprintf("两步验证凭据与账号不匹配, account: %s", account.Account))
+		return errorx.NewCodeError(errorx.ErrLoginExpired, "登录请求已过期，请重新登录")
+	}
+	_, err = o.complete(c, account, challenge, code)
+	return err
+}
+
+// complete 校验一次性密码，账号尚未绑定时校验通过即完成绑定并返回恢复码，完成后验证凭据失效
+func (o *OtpLogic) complete(c *gin.Context, account *model.Account, challenge, code string) ([]string, error) {
+	record, err := o.get(c, account.ID)
+	if err != nil {
+		return nil, err
+	}
+	var recoveryCodes []string
+	if record.Enabled() {
+		if err := o.verify(c, record, code); err != nil {
+			o.l.Info(fmt.Sprintf("两步验证失败, account: %s", account.Account))
+			return nil, err
+		}
+	} else if recoveryCodes, err = o.enable(c, account.ID, code); err != nil {
+		return nil, err
+	}
+	if err := global.RDB.GetClient().Del(c, otpChallengeKeyPrefix+challenge).Err(); err != nil {
+		o.l.Warn(fmt.Sprintf("删除两步验证凭据失败, error: %s", err.Error()))
+	}
+	return recoveryCodes, nil
+}
+
+// challenge 密码校验通过后判断是否需要两步验证，需要时返回携带验证凭据的错误，purpose 为凭据的用途
+func (o *OtpLogic) challenge(c *gin.Context, account *model.Account, purpose string) error {
+	record, err := o.get(c, account.ID)
+	if err != nil {
+		return err
+	}
+	if !record.Enabled() {
+		enforced, err := o.enforced(c, account.ID)
+		if err != nil {
+			return err
+		}
+		if !enforced {
+			return nil
+		}
+	}
+	if global.RDB == nil {
+		o.l.Error("两步验证需要启用 redis")
+		return fmt.Errorf("两步验证不可用")
+	}
+	challenge, err := oidc.RandomString()
+	if err != nil {
+		o.l.Error(fmt.Sprintf("生成两步验证凭据失败, error: %s", err.Error()))
+		return fmt.Errorf("生成两步验证凭据失败")
+	}
+	expire := time.Duration(global.C.Otp.ChallengeExpire) * time.Second
+	key := otpChallengeKeyPrefix + challenge
+	pipe := global.RDB.GetClient().TxPipeline()
+	pipe.HSet(c, key, "account", account.ID, "purpose", purpose, "attempts", 0)
+	pipe.Expire(c, key, expire)
+	if _, err := pipe.Exec(c); err != nil {
+		o.l.Error(fmt.Sprintf("保存两步验证凭据失败, error: %s", err.Error()))
+		return fmt.Errorf("生成两步验证凭据失败")
+	}
+	o.l.Info(fmt.Sprintf("登录需要两步验证, account: %s", account.Account))
+	return errorx.NewCodeErrorData(errorx.ErrOtpRequired, "需要两步验证", &types2.OtpChallengeResponse{
+		Challenge: challenge,
+		Enrolled:  record.Enabled(),
+		ExpiresIn: int64(expire.Seconds()),
+	})
+}
+
+// challengeAccount 读取验证凭据对应的账号，purpose 不为空时凭据的用途必须一致，
+// attempt 为 true 时记录尝试次数，超过次数后凭据失效
+func (o *OtpLogic) challengeAccount(c *gin.Context, challenge, purpose string, attempt bool) (uint, error) {
+	if global.RDB == nil {
+		return 0, errorx.NewCodeError(errorx.ErrLoginExpired, "登录请求已过期，请重新登录")
+	}
+	key := otpChallengeKeyPrefix + challenge
+	client := global.RDB.GetClient()
+	values, err := client.HMGet(c, key, "account", "purpose").Result()
+	if err != nil {
+		o.l.Error(fmt.Sprintf("查询两步验证凭据失败, error: %s", err.Error()))
+		return 0, errorx.NewCodeError(errorx.ErrLoginExpired, "登录请求已过期，请重新登录")
+	}
+	account, _ := values[0].(string)
+	stored, _ := values[1].(string)
+	value, err := strconv.ParseUint(account, 10, 64)
+	if err != nil || (purpose != "" && stored != purpose) {
+		return 0, errorx.NewCodeError(errorx.ErrLoginExpired, "登录请求已过期，请重新登录")
+	}
+	if attempt {
+		attempts, err := client.HIncrBy(c, key, "attempts", 1).Result()
+		if err != nil {
+			o.l.Error(fmt.Sprintf("记录两步验证次数失败, error: %s", err.Error()))
+			return 0, fmt.Errorf("两步验证失败")
+		}
+		if limit := global.C.Otp.MaxAttempts; limit > 0 && attempts > int64(limit) {
+			client.Del(c, key)
+			o.l.Warn(fmt.Sprintf("两步验证失败次数过多, id: %d", value))
+			return 0, errorx.NewCodeError(errorx.ErrLoginExpired, "验证失败次数过多，请重新登录")
+		}
+	}
+	return uint(value), nil
+}
+
+// enroll 生成新的密钥，已启用时需要先关闭
+func (o *OtpLogic) enroll(c *gin.Context, account *model.Account) (*types2.OtpEnrollResponse, error) {
+	record, err := o.get(c, account.ID)
+	if err != nil {
+		return nil, err
+	}
+	if record.Enabled() {
+		return nil, errorx.NewCodeError(errorx.ErrBusinessLogic, "两步验证已启用")
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		o.l.Error(fmt.Sprintf("生成两步验证密钥失败, error: %s", err.Error()))
+		return nil, fmt.Errorf("生成两步验证密钥失败")
+	}
+	if record == nil {
+		err = o.db.WithContext(c).Create(&model.AccountOtp{AccountId: account.ID, Secret: secret}).Error
+	} else {
+		err = o.db.WithContext(c).Model(record).Updates(map[string]interface{}{"secret": secret, "last_step": 0}).Error
+	}
+	if err != nil {
+		o.l.Error(fmt.Sprintf("保存两步验证密钥失败, id: %d, error: %s", account.ID, err.Error()))
+		return nil, fmt.Errorf("生成两步验证密钥失败")
+	}
+	return &types2.OtpEnrollResponse{Secret: secret, Uri: totp.URI(global.C.Otp.Issuer, account.Account, secret)}, nil
+}
+
+// enable 使用待绑定密钥生成的密码完成绑定，返回新生成的恢复码
+func (o *OtpLogic) enable(c *gin.Context, accountId uint, code string) ([]string, error) {
+	record, err := o.get(c, accountId)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, errorx.NewCodeError(errorx.ErrBusinessLogic, "请先生成两步验证密钥")
+	}
+	if record.Enabled() {
+		return nil, errorx.NewCodeError(errorx.ErrBusinessLogic, "两步验证已启用")
+	}
+	step, ok := totp.Validate(record.Secret, code, time.Now(), global.C.Otp.Skew)
+	if !ok {
+		return nil, errorx.NewCodeError(errorx.ErrLoginInvalid, "验证码错误")
+	}
+	var codes []string
+	err = o.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(record).Updates(map[string]interface{}{"enabled_at": time.Now(), "last_step": step}).Error; err != nil {
+			return err
+		}
+		codes, err = replaceRecoveryCodes(tx, accountId)
+		return err
+	})
+	if err != nil {
+		o.l.Error(fmt.Sprintf("启用两步验证失败, id: %d, error: %s", accountId, err.Error()))
+		return nil, fmt.Errorf("启用两步验证失败")
+	}
+	o.l.Info(fmt.Sprintf("启用两步验证, id: %d", accountId))
+	return codes, nil
+}
+
+// verify 校验一次性密码或恢复码，同一个时间步的密码和已使用的恢复码不能再次使用
+func (o *OtpLogic) verify(c *gin.Context, record *model.AccountOtp, code string) error {
+	db := o.db.WithContext(c)
+	if step, ok := totp.Validate(record.Secret, code, time.Now(), global.C.Otp.Skew); ok {
+		// 条件更新，并发请求中只有一个可以使用同一个时间步
+		result := db.Model(&model.AccountOtp{}).Where("id = ? AND last_step < ?", record.ID, step).Update("last_step", step)
+		if result.Error != nil {
+			o.l.Error(fmt.Sprintf("更新两步验证状态失败, id: %d, error: %s", record.AccountId, result.Error.Error()))
+			return fmt.Errorf("两步验证失败")
+		}
+		if result.RowsAffected == 0 {
+			return errorx.NewCodeError(errorx.ErrLoginInvalid, "验证码已使用，请等待验证码刷新")
+		}
+		return nil
+	}
+	result := db.Model(&model.OtpRecoveryCode{}).
+		Where("account_id = ? AND hash = ? AND used_at IS NULL", record.AccountId, totp.HashRecoveryCode(code)).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		o.l.Error(fmt.Sprintf("更新恢复码失败, id: %d, error: %s", record.AccountId, result.Error.Error()))
+		return fmt.Errorf("两步验证失败")
+	}
+	if result.RowsAffected == 0 {
+		return errorx.NewCodeError(errorx.ErrLoginInvalid, "验证码错误")
+	}
+	o.l.Info(fmt.Sprintf("使用恢复码完成两步验证, id: %d", record.AccountId))
+	return nil
+}
+
+// get 查询账号的两步验证记录，不存在时返回 nil
+func (o *OtpLogic) get(c *gin.Context, accountId uint) (*model.AccountOtp, error) {
+	var record model.AccountOtp
+	if err := o.db.WithContext(c).Where("account_id = ?", accountId).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		o.l.Error(fmt.Sprintf("查询两步验证信息失败, id: %d, error: %s", accountId, err.Error()))
+		return nil, fmt.Errorf("查询两步验证信息失败")
+	}
+	return &record, nil
+}
+
+// enabled 查询已启用的两步验证记录
+func (o *OtpLogic) enabled(c *gin.Context, accountId uint) (*model.AccountOtp, error) {
+	record, err := o.get(c, accountId)
+	if err != nil {
+		return nil, err
+	}
+	if !record.Enabled() {
+		return nil, errorx.NewCodeError(errorx.ErrBusinessLogic, "两步验证未启用")
+	}
+	return record, nil
+}
+
+// enforced 账号是否拥有要求两步验证的角色
+func (o *OtpLogic) enforced(c *gin.Context, accountId uint) (bool, error) {
+	roles, err := queryAccountRoles(o.db.WithContext(c), accountId, 0)
+	if err != nil {
+		o.l.Error(fmt.Sprintf("查询账号角色失败, id: %d, error: %s", accountId, err.Error()))
+		return false, fmt.Errorf("查询账号角色失败")
+	}
+	for _, role := range roles {
+		if role.RequireOtp {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (o *OtpLogic) account(c *gin.Context, accountId uint) (*model.Account, error) {
+	var account model.Account
+	if err := o.db.WithContext(c).Where("id = ?", accountId).First(&account).Error; err != nil {
+		o.l.Error(fmt.Sprintf("查询账号信息失败, id: %d, error: %s", accountId, err.Error()))
+		return nil, errorx.NewCodeError(errorx.ErrLoginInvalid, "账号不存在")
+	}
+	return &account, nil
+}
+
+// remove 删除账号的两步验证密钥和恢复码
+func (o *OtpLogic) remove(c *gin.Context, accountId uint) error {
+	err := o.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		return deleteAccountOtp(tx, accountId)
+	})
+	if err != nil {
+		o.l.Error(fmt.Sprintf("删除两步验证信息失败, id: %d, error: %s", accountId, err.Error()))
+		return fmt.Errorf("删除两步验证信息失败")
+	}
+	return nil
+}
+
+// deleteAccountOtp 删除账号的两步验证密钥和恢复码，tx 应该在事务中
+func deleteAccountOtp(tx *gorm.DB, accountId uint) error {
+	if err := tx.Unscoped().Where("account_id = ?", accountId).Delete(&model.OtpRecoveryCode{}).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Where("account_id = ?", accountId).Delete(&model.AccountOtp{}).Error
+}
+
+// replaceRecoveryCodes 生成新的恢复码替换原有的恢复码，返回恢复码明文
+func replaceRecoveryCodes(tx *gorm.DB, accountId uint) ([]string, error) {
+	codes, err := totp.GenerateRecoveryCodes(global.C.Otp.RecoveryCodes)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Unscoped().Where("account_id = ?", accountId).Delete(&model.OtpRecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+	if len(codes) == 0 {
+		return codes, nil
+	}
+	records := make([]model.OtpRecoveryCode, 0, len(codes))
+	for _, code := range codes {
+		records = append(records, model.OtpRecoveryCode{AccountId: accountId, Hash: totp.HashRecoveryCode(code)})
+	}
+	return codes, tx.Create(&records).Error
+}
+
+// 只需要保证 全局对象Config和全局Logger已经加载完成
+func (o *OtpLogic) Config() {
+	o.l = global.L.Named(portal.AppName).Named(portal.AppOtp).Named("logic")
+	o.db = global.DB.GetDb()
+}
+
+func (o *OtpLogic) Name() string {
+	return fmt.Sprintf("%s.%s", portal.AppName, portal.AppOtp)
+}
+
+// Dependencies 两步验证完成后由 AuthLogic 签发 token
+func (o *OtpLogic) Dependencies() []string {
+	return []string{authLogic.Name()}
+}
+
+func init() {
+	// 注册
+	router.RegistryService[service.OtpService](otpLogic)
+}
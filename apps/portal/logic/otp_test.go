@@ -0,0 +1,209 @@
+package logic_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/model"
+	types2 "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/errorx"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/totp"
+)
+
+// otpCode 生成当前时间偏移 offset 个时间步的一次性密码
+func otpCode(t *testing.T, secret string, offset int64) string {
+	code, err := totp.Code(secret, totp.Step(time.Now())+offset)
+	assert.NoError(t, err)
+	return code
+}
+
+// invalidCode 返回允许偏移范围内都不合法的一次性密码
+func invalidCode(t *testing.T, secret string) string {
+	for i := 0; ; i++ {
+		code := fmt.Sprintf("%06d", i)
+		if _, ok := totp.Validate(secret, code, time.Now(), global.C.Otp.Skew+1); !ok {
+			return code
+		}
+	}
+}
+
+// otpChallenge 密码登录，返回两步验证凭据
+func otpChallenge(t *testing.T, err error) *types2.OtpChallengeResponse {
+	if !assert.Equal(t, errorx.ErrOtpRequired, errorCode(err), "应该要求两步验证") {
+		t.FailNow()
+	}
+	return err.(*errorx.CodeError).Data.(*types2.OtpChallengeResponse)
+}
+
+func TestOtpEnrollEnable(t *testing.T) {
+	_, o, db := newAuthLogic(t)
+	account := createAccount(t, db, "zhangsan", "Passw0rd!", false)
+	c := accountContext(account.ID)
+
+	_, err := o.Enable(c, types2.OtpCodeRequest{Code: "000000"})
+	assert.Equal(t, errorx.ErrBusinessLogic, errorCode(err), "未生成密钥时不能启用")
+
+	enroll, err := o.Enroll(c)
+	assert.NoError(t, err, "生成密钥应该成功")
+	assert.Contains(t, enroll.Uri, enroll.Secret, "绑定地址应该包含密钥")
+	status, err := o.Status(c)
+	assert.NoError(t, err)
+	assert.False(t, status.Enabled, "绑定完成前不应该启用")
+
+	_, err = o.Enable(c, types2.OtpCodeRequest{Code: invalidCode(t, enroll.Secret)})
+	assert.Equal(t, errorx.ErrLoginInvalid, errorCode(err), "验证码错误时不能启用")
+
+	codes, err := o.Enable(c, types2.OtpCodeRequest{Code: otpCode(t, enroll.Secret, 0)})
+	assert.NoError(t, err, "验证码正确时应该启用")
+	assert.Len(t, codes.Codes, global.C.Otp.RecoveryCodes, "启用后应该返回恢复码")
+	status, err = o.Status(c)
+	assert.NoError(t, err)
+	assert.True(t, status.Enabled, "应该已启用")
+	assert.Equal(t, int64(global.C.Otp.RecoveryCodes), status.RecoveryCodes, "恢复码数量与预期不符")
+
+	_, err = o.Enroll(c)
+	assert.Equal(t, errorx.ErrBusinessLogic, errorCode(err), "已启用时不能重新生成密钥")
+}
+
+func TestOtpReplay(t *testing.T) {
+	_, o, db := newAuthLogic(t)
+	account := createAccount(t, db, "zhangsan", "Passw0rd!", false)
+	c := accountContext(account.ID)
+	enroll, err := o.Enroll(c)
+	assert.NoError(t, err)
+	code := otpCode(t, enroll.Secret, 0)
+	_, err = o.Enable(c, types2.OtpCodeRequest{Code: code})
+	assert.NoError(t, err)
+
+	// 启用时使用过的时间步不能再次使用
+	_, err = o.RecoveryCodes(c, types2.OtpCodeRequest{Code: code})
+	if assert.Equal(t, errorx.ErrLoginInvalid, errorCode(err), "同一个时间步的验证码不能再次使用") {
+		assert.Contains(t, err.Error(), "验证码已使用")
+	}
+
+	// 之后的时间步可以使用，使用后同样失效
+	step := totp.Step(time.Now()) + 1
+	next, err := totp.Code(enroll.Secret, step)
+	assert.NoError(t, err)
+	_, err = o.RecoveryCodes(c, types2.OtpCodeRequest{Code: next})
+	assert.NoError(t, err, "新的时间步应该可以使用")
+	_, err = o.RecoveryCodes(c, types2.OtpCodeRequest{Code: next})
+	assert.Equal(t, errorx.ErrLoginInvalid, errorCode(err), "使用过的时间步不能再次使用")
+
+	// 早于已使用时间步的验证码同样不能使用
+	err = o.Disable(c, types2.OtpCodeRequest{Code: code})
+	assert.Equal(t, errorx.ErrLoginInvalid, errorCode(err), "早于已使用时间步的验证码不能使用")
+	var record model.AccountOtp
+	assert.NoError(t, db.Where("account_id = ?", account.ID).First(&record).Error)
+	assert.Equal(t, step, record.LastStep, "应该记录最后使用的时间步")
+}
+
+func TestOtpRecoveryCode(t *testing.T) {
+	a, o, db := newAuthLogic(t)
+	account := createAccount(t, db, "zhangsan", "Passw0rd!", false)
+	enroll, err := o.Enroll(accountContext(account.ID))
+	assert.NoError(t, err)
+	codes, err := o.Enable(accountContext(account.ID), types2.OtpCodeRequest{Code: otpCode(t, enroll.Secret, 0)})
+	assert.NoError(t, err)
+	recovery := codes.Codes[0]
+
+	c := testContext()
+	login := func() string {
+		_, err := a.Login(c, types2.LoginRequest{Account: "zhangsan", Password: "Passw0rd!"})
+		return otpChallenge(t, err).Challenge
+	}
+	tokens, err := o.Login(c, types2.OtpLoginRequest{Challenge: login(), Code: recovery})
+	assert.NoError(t, err, "恢复码应该可以完成两步验证")
+	assert.NotEmpty(t, tokens.AccessToken, "应该签发 access token")
+
+	_, err = o.Login(c, types2.OtpLoginRequest{Challenge: login(), Code: recovery})
+	assert.Equal(t, errorx.ErrLoginInvalid, errorCode(err), "恢复码只能使用一次")
+	status, err := o.Status(accountContext(account.ID))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(codes.Codes)-1), status.RecoveryCodes, "使用后剩余恢复码应该减少")
+
+	// 重新生成后原有的恢复码全部失效
+	regenerated, err := o.RecoveryCodes(accountContext(account.ID), types2.OtpCodeRequest{Code: codes.Codes[1]})
+	assert.NoError(t, err, "恢复码应该可以用于重新生成恢复码")
+	assert.NotContains(t, regenerated.Codes, codes.Codes[2])
+	_, err = o.Login(c, types2.OtpLoginRequest{Challenge: login(), Code: codes.Codes[2]})
+	assert.Equal(t, errorx.ErrLoginInvalid, errorCode(err), "重新生成后原有的恢复码应该失效")
+}
+
+func TestOtpMaxAttempts(t *testing.T) {
+	a, o, db := newAuthLogic(t)
+	account := createAccount(t, db, "zhangsan", "Passw0rd!", false)
+	secret, err := totp.GenerateSecret()
+	assert.NoError(t, err)
+	now := time.Now()
+	assert.NoError(t, db.Create(&model.AccountOtp{AccountId: account.ID, Secret: secret, EnabledAt: &now}).Error)
+	maxAttempts := global.C.Otp.MaxAttempts
+	t.Cleanup(func() { global.C.Otp.MaxAttempts = maxAttempts })
+	global.C.Otp.MaxAttempts = 3
+
+	c := testContext()
+	_, err = a.Login(c, types2.LoginRequest{Account: "zhangsan", Password: "Passw0rd!"})
+	challenge := otpChallenge(t, err).Challenge
+	for i := 0; i < global.C.Otp.MaxAttempts; i++ {
+		_, err = o.Login(c, types2.OtpLoginRequest{Challenge: challenge, Code: invalidCode(t, secret)})
+		assert.Equal(t, errorx.ErrLoginInvalid, errorCode(err), "次数限制内应该返回验证码错误")
+	}
+	// 超过次数后凭据失效，正确的验证码也不能使用
+	_, err = o.Login(c, types2.OtpLoginRequest{Challenge: challenge, Code: otpCode(t, secret, 0)})
+	assert.Equal(t, errorx.ErrLoginExpired, errorCode(err), "超过次数后凭据应该失效")
+	_, err = o.Login(c, types2.OtpLoginRequest{Challenge: challenge, Code: otpCode(t, secret, 0)})
+	assert.Equal(t, errorx.ErrLoginExpired, errorCode(err), "失效的凭据不能再次使用")
+
+	// 登录过程中生成密钥同样计入次数
+	_, err = a.Login(c, types2.LoginRequest{Account: "zhangsan", Password: "Passw0rd!"})
+	challenge = otpChallenge(t, err).Challenge
+	for i := 0; i < global.C.Otp.MaxAttempts; i++ {
+		_, err = o.LoginEnroll(c, types2.OtpChallengeRequest{Challenge: challenge})
+		assert.Equal(t, errorx.ErrBusinessLogic, errorCode(err), "已启用时不能重新生成密钥")
+	}
+	_, err = o.LoginEnroll(c, types2.OtpChallengeRequest{Challenge: challenge})
+	assert.Equal(t, errorx.ErrLoginExpired, errorCode(err), "超过次数后凭据应该失效")
+}
+
+func TestOtpRoleEnforced(t *testing.T) {
+	a, o, db := newAuthLogic(t)
+	account := createAccount(t, db, "zhangsan", "Passw0rd!", false)
+	c := testContext()
+	login := types2.LoginRequest{Account: "zhangsan", Password: "Passw0rd!"}
+
+	// 所属角色不要求两步验证时直接登录
+	role := &model.Role{Name: "ops", ApplicationId: 1}
+	assert.NoError(t, db.Create(role).Error)
+	assert.NoError(t, db.Create(&model.RoleAccount{RoleId: role.ID, AccountId: account.ID}).Error)
+	tokens, err := a.Login(c, login)
+	assert.NoError(t, err, "未要求两步验证时应该直接登录")
+	assert.NotEmpty(t, tokens.AccessToken)
+
+	// 角色要求两步验证时，未绑定的账号在登录过程中完成绑定
+	assert.NoError(t, db.Model(role).Update("require_otp", true).Error)
+	_, err = a.Login(c, login)
+	challenge := otpChallenge(t, err)
+	assert.False(t, challenge.Enrolled, "账号尚未绑定")
+	enroll, err := o.LoginEnroll(c, types2.OtpChallengeRequest{Challenge: challenge.Challenge})
+	assert.NoError(t, err, "登录过程中应该可以生成密钥")
+	tokens, err = o.Login(c, types2.OtpLoginRequest{Challenge: challenge.Challenge, Code: otpCode(t, enroll.Secret, 0)})
+	assert.NoError(t, err, "验证通过后应该完成绑定并登录")
+	assert.NotEmpty(t, tokens.AccessToken, "应该签发 access token")
+	assert.Len(t, tokens.RecoveryCodes, global.C.Otp.RecoveryCodes, "完成绑定时应该返回恢复码")
+
+	// 角色要求时不能关闭，也会在状态中体现
+	status, err := o.Status(accountContext(account.ID))
+	assert.NoError(t, err)
+	assert.True(t, status.Enabled && status.Enforced, "应该已启用且被角色要求")
+	err = o.Disable(accountContext(account.ID), types2.OtpCodeRequest{Code: otpCode(t, enroll.Secret, 1)})
+	assert.Equal(t, errorx.ErrBusinessLogic, errorCode(err), "角色要求时不能关闭两步验证")
+
+	// 修改密码的验证凭据不能用于登录过程中生成密钥
+	assert.NoError(t, db.Model(&model.Account{}).Where("id = ?", account.ID).Update("must_change_password", true).Error)
+	err = a.ExpiredPassword(c, types2.PasswordExpiredRequest{Account: "zhangsan", OldPassword: "Passw0rd!", NewPassword: "New-Passw0rd"})
+	_, err = o.LoginEnroll(c, types2.OtpChallengeRequest{Challenge: otpChallenge(t, err).Challenge})
+	assert.Equal(t, errorx.ErrLoginExpired, errorCode(err), "修改密码的验证凭据不能用于生成密钥")
+}
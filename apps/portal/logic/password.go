@@ -0,0 +1,104 @@
+package logic
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/model"
+	"github.com/yanshicheng/ikube-gin-starter/common/errorx"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// 账号密码的公共处理，密码规则由请求结构体上的 password_* 校验器检查，这里只处理密码历史和有效期。
+
+// dummyPasswordHash 账号不存在时用于比较的密文，与真实账号一样执行一次 bcrypt 比较，避免通过响应时间判断账号是否存在
+const dummyPasswordHash = "$2a$10$Q82Vp.q/SSmrwrflneUdjOfMJwnAczG5u1XzBgfSW685Ugo6msyli"
+
+// comparePassword 校验账号密码，account 为 nil 表示账号不存在，同样执行一次比较后返回失败
+func comparePassword(account *model.Account, password string) bool {
+	if account == nil {
+		_ = bcrypt.CompareHashAndPassword([]byte(dummyPasswordHash), []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(account.Password), []byte(password)) == nil
+}
+
+// hashPassword bcrypt 加密密码
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// checkPasswordReuse 新密码不能与当前密码和最近 N 次使用过的密码相同
+func checkPasswordReuse(db *gorm.DB, account *model.Account, password string) error {
+	if bcrypt.CompareHashAndPassword([]byte(account.Password), []byte(password)) == nil {
+		return errorx.NewCodeError(errorx.ErrBusinessLogic, "新密码不能与当前密码相同")
+	}
+	n := global.C.Password.History
+	if n <= 0 {
+		return nil
+	}
+	var histories []model.PasswordHistory
+	if err := db.Where("account_id = ?", account.ID).Order("id DESC").Limit(n).Find(&histories).Error; err != nil {
+		return fmt.Errorf("查询密码历史失败: %w", err)
+	}
+	for _, h := range histories {
+		if bcrypt.CompareHashAndPassword([]byte(h.Password), []byte(password)) == nil {
+			return errorx.NewCodeError(errorx.ErrBusinessLogic, fmt.Sprintf("新密码不能与最近 %d 次使用过的密码相同", n))
+		}
+	}
+	return nil
+}
+
+// savePassword 保存新密码密文并记录密码历史，mustChange 表示下次登录前需要修改密码。
+// 密码历史只保留最近 N 条，tx 应该在事务中。
+func savePassword(tx *gorm.DB, account *model.Account, hash string, mustChange bool) error {
+	now := time.Now()
+	if err := tx.Model(account).Updates(map[string]interface{}{
+		"password":             hash,
+		"password_changed_at":  now,
+		"must_change_password": mustChange,
+	}).Error; err != nil {
+		return err
+	}
+	account.Password = hash
+	account.PasswordChangedAt = &now
+	account.MustChangePassword = mustChange
+	return recordPassword(tx, account.ID, hash)
+}
+
+// recordPassword 记录密码历史并清理超出保留数量的记录
+func recordPassword(tx *gorm.DB, accountId uint, hash string) error {
+	if err := tx.Create(&model.PasswordHistory{AccountId: accountId, Password: hash}).Error; err != nil {
+		return err
+	}
+	keep := global.C.Password.History
+	if keep < 1 {
+		keep = 1
+	}
+	var ids []uint
+	if err := tx.Model(&model.PasswordHistory{}).Where("account_id = ?", accountId).
+		Order("id DESC").Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	if len(ids) <= keep {
+		return nil
+	}
+	return tx.Unscoped().Where("id IN ?", ids[keep:]).Delete(&model.PasswordHistory{}).Error
+}
+
+// checkPasswordExpired 登录时检查密码是否需要修改
+func checkPasswordExpired(account *model.Account) error {
+	if account.MustChangePassword {
+		return errorx.NewCodeError(errorx.ErrPasswordExpired, "密码已被重置，请修改密码后重新登录")
+	}
+	if account.PasswordExpired(global.C.Password.MaxAge, time.Now()) {
+		return errorx.NewCodeError(errorx.ErrPasswordExpired, "密码已过期，请修改密码后重新登录")
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/model"
+	"github.com/yanshicheng/ikube-gin-starter/common/auth"
+	cmodel "github.com/yanshicheng/ikube-gin-starter/common/model"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/router"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 接口检查
+var _ auth.Authorizer = (*PermissionLogic)(nil)
+
+var permissionLogic = &PermissionLogic{}
+
+// PermissionLogic 根据 RoleAccount、Role、Upms 加载账号权限
+type PermissionLogic struct {
+	l  *zap.Logger
+	db *gorm.DB
+}
+
+// LoadPermissions 加载账号在应用下的权限，只加载该应用下角色的权限，且账号必须已被授权访问该应用。
+// 超级管理员角色不区分应用；未指定应用或未被授权时返回空权限，不合并其他应用的权限。
+func (p *PermissionLogic) LoadPermissions(ctx context.Context, accountId, applicationId uint) (*auth.PermissionSet, error) {
+	roles, err := queryAccountRoles(p.db.WithContext(ctx), accountId, 0)
+	if err != nil {
+		p.l.Error(fmt.Sprintf("查询账号角色失败, id: %d, error: %s", accountId, err.Error()))
+		return nil, fmt.Errorf("查询账号角色失败")
+	}
+	perms := &auth.PermissionSet{
+		Permissions: make([]auth.Permission, 0),
+		DataScope:   auth.DataScope{Self: true, AccountId: accountId},
+	}
+	if isSuperRole(roles) {
+		perms.Super = true
+		perms.DataScope = auth.DataScope{All: true}
+		return perms, nil
+	}
+	if applicationId == 0 {
+		return perms, nil
+	}
+	ok, err := hasApplication(p.db.WithContext(ctx), accountId, applicationId)
+	if err != nil {
+		p.l.Error(fmt.Sprintf("查询账号应用授权失败, id: %d, error: %s", accountId, err.Error()))
+		return nil, fmt.Errorf("查询账号应用授权失败")
+	}
+	if !ok {
+		return perms, nil
+	}
+	roles = filterApplicationRoles(roles, applicationId)
+	if len(roles) == 0 {
+		return perms, nil
+	}
+	if perms.DataScope, err = p.loadDataScope(ctx, accountId, roles); err != nil {
+		return nil, err
+	}
+	roleIds := make([]uint, 0, len(roles))
+	for _, role := range roles {
+		roleIds = append(roleIds, role.ID)
+	}
+	var upms []model.Upms
+	if err := p.db.WithContext(ctx).Where("role_id IN ?", roleIds).Find(&upms).Error; err != nil {
+		p.l.Error(fmt.Sprintf("查询角色权限失败, id: %d, error: %s", accountId, err.Error()))
+		return nil, fmt.Errorf("查询角色权限失败")
+	}
+	for _, u := range upms {
+		perms.Permissions = append(perms.Permissions, auth.Permission{
+			Resource: u.Resource,
+			Action:   u.Type.String(),
+		})
+	}
+	p.l.Debug(fmt.Sprintf("加载账号权限, id: %d, roles: %v, permissions: %d", accountId, roleIds, len(perms.Permissions)))
+	return perms, nil
+}
+
+// loadDataScope 合并角色的数据权限范围，本机构及子机构通过祖先路径一次查询得到，未设置或未知的范围不授予任何数据
+func (p *PermissionLogic) loadDataScope(ctx context.Context, accountId uint, roles []model.Role) (auth.DataScope, error) {
+	scope := auth.DataScope{AccountId: accountId}
+	var account *model.Account
+	getAccount := func() (*model.Account, error) {
+		if account != nil {
+			return account, nil
+		}
+		account = &model.Account{}
+		if err := p.db.WithContext(ctx).Select("id", "organization_id").Where("id = ?", accountId).First(account).Error; err != nil {
+			p.l.Error(fmt.Sprintf("查询账号信息失败, id: %d, error: %s", accountId, err.Error()))
+			return nil, fmt.Errorf("查询账号信息失败")
+		}
+		return account, nil
+	}
+	orgSet := make(map[uint]bool)
+	addOrgs := func(ids ...uint) {
+		for _, id := range ids {
+			if !orgSet[id] {
+				orgSet[id] = true
+				scope.OrganizationIds = append(scope.OrganizationIds, id)
+			}
+		}
+	}
+	for _, role := range roles {
+		switch role.DataScope {
+		case model.DataScopeAll:
+			return auth.DataScope{All: true}, nil
+		case model.DataScopeSelf:
+			scope.Self = true
+		case model.DataScopeCustom:
+			addOrgs(role.DataOrganizationIds...)
+		case model.DataScopeOrg, model.DataScopeOrgAndChildren:
+			acc, err := getAccount()
+			if err != nil {
+				return scope, err
+			}
+			addOrgs(acc.OrganizationId)
+			if role.DataScope == model.DataScopeOrg {
+				continue
+			}
+			var org model.Organization
+			if err := p.db.WithContext(ctx).Where("id = ?", acc.OrganizationId).First(&org).Error; err != nil {
+				p.l.Error(fmt.Sprintf("查询账号机构失败, id: %d, error: %s", accountId, err.Error()))
+				return scope, fmt.Errorf("查询账号机构失败")
+			}
+			var ids []uint
+			if err := p.db.WithContext(ctx).Model(&model.Organization{}).
+				Scopes(cmodel.Descendants(org.TreePath, org.ID)).Pluck("id", &ids).Error; err != nil {
+				p.l.Error(fmt.Sprintf("查询子机构失败, id: %d, error: %s", org.ID, err.Error()))
+				return scope, fmt.Errorf("查询子机构失败")
+			}
+			addOrgs(ids...)
+		}
+	}
+	return scope, nil
+}
+
+// queryAccountRoles 查询账号拥有的角色，applicationId 不为 0 时只返回该应用下的角色
+func queryAccountRoles(db *gorm.DB, accountId, applicationId uint) ([]model.Role, error) {
+	var roles []model.Role
+	roleTable := (&model.Role{}).TableName()
+	query := db.Model(&model.Role{}).
+		Joins(fmt.Sprintf("JOIN %s ra ON ra.role_id = %s.id AND ra.deleted_at IS NULL", (&model.RoleAccount{}).TableName(), roleTable)).
+		Where("ra.account_id = ?", accountId)
+	if applicationId != 0 {
+		query = query.Where(fmt.Sprintf("%s.application_id = ?", roleTable), applicationId)
+	}
+	err := query.Find(&roles).Error
+	return roles, err
+}
+
+// filterApplicationRoles 过滤出应用下的角色
+func filterApplicationRoles(roles []model.Role, applicationId uint) []model.Role {
+	result := make([]model.Role, 0, len(roles))
+	for _, role := range roles {
+		if role.ApplicationId == applicationId {
+			result = append(result, role)
+		}
+	}
+	return result
+}
+
+// hasApplication 判断账号是否被授权访问应用
+func hasApplication(db *gorm.DB, accountId, applicationId uint) (bool, error) {
+	var count int64
+	err := db.Model(&model.AccountApplication{}).
+		Where("account_id = ? AND application_id = ?", accountId, applicationId).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// applicationScope 限定只能操作当前请求所属应用的数据，column 为应用ID所在的列。
+// 超级管理员和未启用 rbac 时不限制，api key 只能操作签发时所在应用的数据。
+func applicationScope(c *gin.Context, column string) (func(db *gorm.DB) *gorm.DB, error) {
+	applicationId := auth.GetApplicationId(c)
+	if _, ok := auth.GetApiKey(c); !ok {
+		if !global.C.Rbac.Enable {
+			return func(db *gorm.DB) *gorm.DB { return db }, nil
+		}
+		perms, err := auth.GetPermissions(c, auth.GetAccountId(c), applicationId)
+		if err != nil {
+			return nil, err
+		}
+		if perms.Super {
+			return func(db *gorm.DB) *gorm.DB { return db }, nil
+		}
+	}
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(fmt.Sprintf("%s = ?", column), applicationId)
+	}, nil
+}
+
+// isSuperRole 判断角色中是否包含超级管理员角色
+func isSuperRole(roles []model.Role) bool {
+	for _, role := range roles {
+		if role.Name == global.C.Rbac.SuperRole {
+			return true
+		}
+	}
+	return false
+}
+
+// 只需要保证 全局对象Config和全局Logger已经加载完成
+func (p *PermissionLogic) Config() {
+	p.l = global.L.Named(portal.AppName).Named(portal.AppPermission).Named("logic")
+	p.db = global.DB.GetDb()
+	// 注册为全局权限加载实现，供权限中间件使用
+	auth.RegistryAuthorizer(p)
+}
+
+func (p *PermissionLogic) Name() string {
+	return fmt.Sprintf("%s.%s", portal.AppName, portal.AppPermission)
+}
+
+func init() {
+	// 注册
+	router.RegistryLogic(permissionLogic)
+}
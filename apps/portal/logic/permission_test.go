@@ -0,0 +1,108 @@
+package logic_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/logic"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/model"
+	"github.com/yanshicheng/ikube-gin-starter/common/auth"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/testenv"
+)
+
+// staticAuthorizer 返回固定的权限
+type staticAuthorizer struct {
+	perms auth.PermissionSet
+}
+
+func (s *staticAuthorizer) LoadPermissions(context.Context, uint, uint) (*auth.PermissionSet, error) {
+	perms := s.perms
+	return &perms, nil
+}
+
+// enableRbac 启用 rbac 并注册固定权限的加载实现，测试结束后恢复
+func enableRbac(t *testing.T, perms auth.PermissionSet) *staticAuthorizer {
+	enable := global.C.Rbac.Enable
+	previous := auth.GetAuthorizer()
+	t.Cleanup(func() {
+		global.C.Rbac.Enable = enable
+		auth.RegistryAuthorizer(previous)
+	})
+	authorizer := &staticAuthorizer{perms: perms}
+	global.C.Rbac.Enable = true
+	auth.RegistryAuthorizer(authorizer)
+	return authorizer
+}
+
+func TestLoadPermissionsByApplication(t *testing.T) {
+	testenv.Setup(t)
+	db := testenv.DB(t, &model.Account{}, &model.Organization{}, &model.Role{}, &model.RoleAccount{},
+		&model.Upms{}, &model.AccountApplication{})
+	previous := auth.GetAuthorizer()
+	t.Cleanup(func() { auth.RegistryAuthorizer(previous) })
+	p := &logic.PermissionLogic{}
+	p.Config()
+	ctx := context.Background()
+
+	// 账号在应用 1 和应用 2 下各有一个角色，只被授权访问应用 1
+	roles := []*model.Role{
+		{Name: "app1", ApplicationId: 1, DataScope: model.DataScopeAll},
+		{Name: "app2", ApplicationId: 2, DataScope: model.DataScopeAll},
+	}
+	for _, role := range roles {
+		assert.NoError(t, db.Create(role).Error)
+		assert.NoError(t, db.Create(&model.RoleAccount{RoleId: role.ID, AccountId: 1}).Error)
+		assert.NoError(t, db.Create(&model.Upms{Name: role.Name, RoleId: role.ID, Resource: "/" + role.Name + "/*", Type: model.WriteAction}).Error)
+	}
+	assert.NoError(t, db.Create(&model.AccountApplication{AccountId: 1, ApplicationId: 1}).Error)
+
+	perms, err := p.LoadPermissions(ctx, 1, 1)
+	assert.NoError(t, err)
+	assert.True(t, perms.Allow("/app1/book", auth.ActionWrite), "应该拥有已授权应用下角色的权限")
+	assert.False(t, perms.Allow("/app2/book", auth.ActionRead), "不应该合并其他应用下角色的权限")
+
+	perms, err = p.LoadPermissions(ctx, 1, 2)
+	assert.NoError(t, err)
+	assert.Empty(t, perms.Permissions, "未被授权访问的应用不应该有权限")
+
+	perms, err = p.LoadPermissions(ctx, 1, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, perms.Permissions, "未指定应用时不应该有权限")
+}
+
+func TestLoadDataScopeDefault(t *testing.T) {
+	testenv.Setup(t)
+	db := testenv.DB(t, &model.Account{}, &model.Organization{}, &model.Role{}, &model.RoleAccount{},
+		&model.Upms{}, &model.AccountApplication{}, &model.Application{})
+	p := &logic.PermissionLogic{}
+	p.Config()
+	r := &logic.RoleLogic{}
+	r.Config()
+	ctx := context.Background()
+	app := &model.Application{Name: "portal", Path: "portal", Icon: "portal"}
+	assert.NoError(t, db.Create(app).Error)
+	assert.NoError(t, db.Create(&model.AccountApplication{AccountId: 1, ApplicationId: app.ID}).Error)
+
+	// 未指定数据权限范围的角色默认为本人数据
+	role := &model.Role{Name: "ops", ApplicationId: app.ID}
+	assert.NoError(t, r.Create(testContext(), role))
+	assert.Equal(t, model.DataScopeSelf, role.DataScope, "创建角色时默认为本人数据")
+	assert.NoError(t, db.Create(&model.RoleAccount{RoleId: role.ID, AccountId: 1}).Error)
+	perms, err := p.LoadPermissions(ctx, 1, app.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, auth.DataScope{Self: true, AccountId: 1}, perms.DataScope, "默认只能访问本人数据")
+
+	// 数据库中的默认值同样为本人数据
+	raw := &model.Role{Name: "raw", ApplicationId: app.ID}
+	assert.NoError(t, db.Create(raw).Error)
+	assert.NoError(t, db.First(raw, raw.ID).Error)
+	assert.Equal(t, model.DataScopeSelf, raw.DataScope, "数据库默认值应该为本人数据")
+
+	// 空的数据权限范围不授予任何数据
+	assert.NoError(t, db.Model(&model.Role{}).Where("id = ?", role.ID).Update("data_scope", "").Error)
+	perms, err = p.LoadPermissions(ctx, 1, app.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, auth.DataScope{AccountId: 1}, perms.DataScope, "空的数据权限范围不应该视为全部数据")
+}
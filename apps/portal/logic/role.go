@@ -0,0 +1,426 @@
+package logic
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/model"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/service"
+	types2 "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/auth"
+	"github.com/yanshicheng/ikube-gin-starter/common/errorx"
+	"github.com/yanshicheng/ikube-gin-starter/common/filter"
+	"github.com/yanshicheng/ikube-gin-starter/common/sql"
+	"github.com/yanshicheng/ikube-gin-starter/common/types"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/router"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 接口检查
+var _ service.RoleService = (*RoleLogic)(nil)
+
+var roleLogic = &RoleLogic{}
+
+type RoleLogic struct {
+	l  *zap.Logger
+	db *gorm.DB
+}
+
+// Get 查询角色，非超级管理员只能查询当前请求所属应用下的角色
+func (r *RoleLogic) Get(c *gin.Context, id types.SearchId) (*model.Role, error) {
+	scope, err := r.applicationScope(c, "application_id")
+	if err != nil {
+		return nil, err
+	}
+	var role model.Role
+	if err := r.db.WithContext(c).Scopes(scope).Where("id = ?", id.Id).First(&role).Error; err != nil {
+		r.l.Error(fmt.Sprintf("查询角色信息失败, id: %d, error: %s", id.Id, err.Error()))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errorx.NewCodeError(errorx.ErrDataNotFound, "角色不存在")
+		}
+		return nil, fmt.Errorf("查询角色信息失败")
+	}
+	return &role, nil
+}
+
+func (r *RoleLogic) List(c *gin.Context, search types2.RoleSearch) (*types.QueryResponse, error) {
+	scope, err := r.applicationScope(c, "application_id")
+	if err != nil {
+		return nil, err
+	}
+	db := r.db.WithContext(c).Model(&model.Role{}).Scopes(scope)
+	if search.Name != "" {
+		db = db.Where(filter.Prefix("name", search.Name))
+	}
+	if search.ApplicationId != 0 {
+		db = db.Where("application_id = ?", search.ApplicationId)
+	}
+	filters, err := filter.Scope(&model.Role{}, search.Filter)
+	if err != nil {
+		return nil, err
+	}
+	db = db.Scopes(filters).Order(fmt.Sprintf("id %s", search.Sort))
+	var roles []model.Role
+	resp, err := sql.GetPageResponse(db, search.Pagination, &roles)
+	if err != nil {
+		r.l.Error(fmt.Sprintf("查询角色列表失败, error: %s", err.Error()))
+		return nil, fmt.Errorf("查询角色列表失败")
+	}
+	return resp, nil
+}
+
+func (r *RoleLogic) Create(c *gin.Context, role *model.Role) error {
+	role.ID = 0
+	if err := r.checkApplication(c, role.ApplicationId); err != nil {
+		return err
+	}
+	if err := r.checkName(c, role); err != nil {
+		return err
+	}
+	if err := r.checkDataScope(c, role); err != nil {
+		return err
+	}
+	if err := r.db.WithContext(c).Create(role).Error; err != nil {
+		r.l.Error(fmt.Sprintf("创建角色失败, error: %s", err.Error()))
+		return errorx.NewCodeError(errorx.ErrDataCreation, "创建角色失败")
+	}
+	return nil
+}
+
+func (r *RoleLogic) Put(c *gin.Context, id types.SearchId, role *model.Role) (*model.Role, error) {
+	oldRole, err := r.Get(c, id)
+	if err != nil {
+		return nil, err
+	}
+	if role.ApplicationId != oldRole.ApplicationId {
+		return nil, errorx.NewCodeError(errorx.ErrBusinessLogic, "不允许修改角色所属应用")
+	}
+	role.ID = oldRole.ID
+	if err := r.checkName(c, role); err != nil {
+		return nil, err
+	}
+	if err := r.checkDataScope(c, role); err != nil {
+		return nil, err
+	}
+	// 修改操作
+	oldRole.Name = role.Name
+	oldRole.DataScope = role.DataScope
+	oldRole.DataOrganizationIds = role.DataOrganizationIds
+	oldRole.RequireOtp = role.RequireOtp
+	// 保存
+	if err := r.db.WithContext(c).Save(oldRole).Error; err != nil {
+		r.l.Error(fmt.Sprintf("更新角色信息失败, id: %d, error: %s", id.Id, err.Error()))
+		return nil, fmt.Errorf("更新角色信息失败")
+	}
+	return oldRole, nil
+}
+
+// Delete 删除角色，同时删除角色的菜单、账号和权限关联
+func (r *RoleLogic) Delete(c *gin.Context, id types.SearchId) error {
+	role, err := r.Get(c, id)
+	if err != nil {
+		return err
+	}
+	err = r.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		// 先删除角色，钩子函数需要根据角色账号关联清除权限缓存
+		if err := tx.Delete(role).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("role_id = ?", role.ID).Delete(&model.RoleMenu{}).Error; err != nil {
+			return err
+		}
+		var roleAccounts []model.RoleAccount
+		if err := tx.Where("role_id = ?", role.ID).Find(&roleAccounts).Error; err != nil {
+			return err
+		}
+		if len(roleAccounts) > 0 {
+			if err := tx.Unscoped().Delete(&roleAccounts).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Where("role_id = ?", role.ID).Delete(&model.Upms{RoleId: role.ID}).Error
+	})
+	if err != nil {
+		r.l.Error(fmt.Sprintf("删除角色失败, id: %d, error: %s", id.Id, err.Error()))
+		return errorx.NewCodeError(errorx.ErrDataDeletion, "删除角色失败")
+	}
+	return nil
+}
+
+// Menus 查询角色关联的菜单ID
+func (r *RoleLogic) Menus(c *gin.Context, id types.SearchId) ([]uint, error) {
+	if _, err := r.Get(c, id); err != nil {
+		return nil, err
+	}
+	menuIds := make([]uint, 0)
+	if err := r.db.WithContext(c).Model(&model.RoleMenu{}).Where("role_id = ?", id.Id).
+		Order("menu_id ASC").Pluck("menu_id", &menuIds).Error; err != nil {
+		r.l.Error(fmt.Sprintf("查询角色菜单失败, id: %d, error: %s", id.Id, err.Error()))
+		return nil, fmt.Errorf("查询角色菜单失败")
+	}
+	return menuIds, nil
+}
+
+// PutMenus 使用请求中的菜单整体替换角色的菜单，菜单必须属于角色所在应用
+func (r *RoleLogic) PutMenus(c *gin.Context, id types.SearchId, req types2.RoleMenuRequest) ([]uint, error) {
+	role, err := r.Get(c, id)
+	if err != nil {
+		return nil, err
+	}
+	menuIds := uniqueIds(req.MenuIds)
+	if len(menuIds) > 0 {
+		var count int64
+		if err := r.db.WithContext(c).Model(&model.Menu{}).
+			Where("id IN ? AND application_id = ?", menuIds, role.ApplicationId).
+			Count(&count).Error; err != nil {
+			r.l.Error(fmt.Sprintf("查询菜单信息失败, error: %s", err.Error()))
+			return nil, fmt.Errorf("查询菜单信息失败")
+		}
+		if int(count) != len(menuIds) {
+			return nil, errorx.NewCodeError(errorx.ErrDataNotFound, "菜单不存在或不属于角色所在应用")
+		}
+	}
+	err = r.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		var olds []model.RoleMenu
+		if err := tx.Where("role_id = ?", role.ID).Find(&olds).Error; err != nil {
+			return err
+		}
+		exists := make([]uint, 0, len(olds))
+		for _, old := range olds {
+			exists = append(exists, old.MenuId)
+		}
+		added, removed := diffIds(exists, menuIds)
+		if len(removed) > 0 {
+			if err := tx.Unscoped().Where("role_id = ? AND menu_id IN ?", role.ID, removed).
+				Delete(&model.RoleMenu{}).Error; err != nil {
+				return err
+			}
+		}
+		if len(added) > 0 {
+			records := make([]model.RoleMenu, 0, len(added))
+			for _, menuId := range added {
+				records = append(records, model.RoleMenu{RoleId: role.ID, MenuId: menuId})
+			}
+			return tx.Create(&records).Error
+		}
+		return nil
+	})
+	if err != nil {
+		r.l.Error(fmt.Sprintf("更新角色菜单失败, id: %d, error: %s", id.Id, err.Error()))
+		return nil, fmt.Errorf("更新角色菜单失败")
+	}
+	return menuIds, nil
+}
+
+// Accounts 查询角色关联的账号ID
+func (r *RoleLogic) Accounts(c *gin.Context, id types.SearchId) ([]uint, error) {
+	if _, err := r.Get(c, id); err != nil {
+		return nil, err
+	}
+	accountIds := make([]uint, 0)
+	if err := r.db.WithContext(c).Model(&model.RoleAccount{}).Where("role_id = ?", id.Id).
+		Order("account_id ASC").Pluck("account_id", &accountIds).Error; err != nil {
+		r.l.Error(fmt.Sprintf("查询角色账号失败, id: %d, error: %s", id.Id, err.Error()))
+		return nil, fmt.Errorf("查询角色账号失败")
+	}
+	return accountIds, nil
+}
+
+// PutAccounts 使用请求中的账号整体替换角色的账号，账号必须已被授权访问角色所在应用，变更的账号会清除权限缓存
+func (r *RoleLogic) PutAccounts(c *gin.Context, id types.SearchId, req types2.RoleAccountRequest) ([]uint, error) {
+	role, err := r.Get(c, id)
+	if err != nil {
+		return nil, err
+	}
+	accountIds := uniqueIds(req.AccountIds)
+	if len(accountIds) > 0 {
+		var count int64
+		if err := r.db.WithContext(c).Model(&model.Account{}).Where("id IN ?", accountIds).Count(&count).Error; err != nil {
+			r.l.Error(fmt.Sprintf("查询账号信息失败, error: %s", err.Error()))
+			return nil, fmt.Errorf("查询账号信息失败")
+		}
+		if int(count) != len(accountIds) {
+			return nil, errorx.NewCodeError(errorx.ErrDataNotFound, "账号不存在")
+		}
+		var granted []uint
+		if err := r.db.WithContext(c).Model(&model.AccountApplication{}).
+			Where("account_id IN ? AND application_id = ?", accountIds, role.ApplicationId).
+			Pluck("account_id", &granted).Error; err != nil {
+			r.l.Error(fmt.Sprintf("查询账号应用授权失败, error: %s", err.Error()))
+			return nil, fmt.Errorf("查询账号应用授权失败")
+		}
+		if ungranted, _ := diffIds(granted, accountIds); len(ungranted) > 0 {
+			return nil, errorx.NewCodeErrorData(errorx.ErrBusinessLogic, "账号未被授权访问角色所在应用", ungranted)
+		}
+	}
+	err = r.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		var olds []model.RoleAccount
+		if err := tx.Where("role_id = ?", role.ID).Find(&olds).Error; err != nil {
+			return err
+		}
+		exists := make([]uint, 0, len(olds))
+		for _, old := range olds {
+			exists = append(exists, old.AccountId)
+		}
+		added, removed := diffIds(exists, accountIds)
+		if len(removed) > 0 {
+			removedMap := make(map[uint]bool, len(removed))
+			for _, accountId := range removed {
+				removedMap[accountId] = true
+			}
+			records := make([]model.RoleAccount, 0, len(removed))
+			for _, old := range olds {
+				if removedMap[old.AccountId] {
+					records = append(records, old)
+				}
+			}
+			// 逐条删除，保证钩子函数能够拿到账号并清除权限缓存
+			if err := tx.Unscoped().Delete(&records).Error; err != nil {
+				return err
+			}
+		}
+		if len(added) > 0 {
+			records := make([]model.RoleAccount, 0, len(added))
+			for _, accountId := range added {
+				records = append(records, model.RoleAccount{RoleId: role.ID, AccountId: accountId})
+			}
+			return tx.Create(&records).Error
+		}
+		return nil
+	})
+	if err != nil {
+		r.l.Error(fmt.Sprintf("更新角色账号失败, id: %d, error: %s", id.Id, err.Error()))
+		return nil, fmt.Errorf("更新角色账号失败")
+	}
+	return accountIds, nil
+}
+
+// AccountRoles 查询账号生效的角色
+func (r *RoleLogic) AccountRoles(c *gin.Context, id types.SearchId, search types2.AccountRoleSearch) ([]model.Role, error) {
+	var count int64
+	if err := r.db.WithContext(c).Model(&model.Account{}).Where("id = ?", id.Id).Count(&count).Error; err != nil {
+		r.l.Error(fmt.Sprintf("查询账号信息失败, id: %d, error: %s", id.Id, err.Error()))
+		return nil, fmt.Errorf("查询账号信息失败")
+	}
+	if count == 0 {
+		return nil, errorx.NewCodeError(errorx.ErrDataNotFound, "账号不存在")
+	}
+	roles, err := queryAccountRoles(r.db.WithContext(c), id.Id, search.ApplicationId)
+	if err != nil {
+		r.l.Error(fmt.Sprintf("查询账号角色失败, id: %d, error: %s", id.Id, err.Error()))
+		return nil, fmt.Errorf("查询账号角色失败")
+	}
+	if roles == nil {
+		roles = make([]model.Role, 0)
+	}
+	return roles, nil
+}
+
+// checkApplication 检查应用是否存在，非超级管理员只能在当前请求所属应用下创建角色
+func (r *RoleLogic) checkApplication(c *gin.Context, applicationId uint) error {
+	scope, err := r.applicationScope(c, "id")
+	if err != nil {
+		return err
+	}
+	var count int64
+	if err := r.db.WithContext(c).Model(&model.Application{}).Scopes(scope).Where("id = ?", applicationId).Count(&count).Error; err != nil {
+		r.l.Error(fmt.Sprintf("查询应用信息失败, id: %d, error: %s", applicationId, err.Error()))
+		return fmt.Errorf("查询应用信息失败")
+	}
+	if count == 0 {
+		return errorx.NewCodeError(errorx.ErrDataNotFound, "应用不存在")
+	}
+	return nil
+}
+
+// checkName 检查角色名称在应用下是否已被使用。
+// 超级管理员按角色名称判断且不区分应用，超级管理员角色名称全局只能使用一次
+func (r *RoleLogic) checkName(c *gin.Context, role *model.Role) error {
+	var count int64
+	query := r.db.WithContext(c).Model(&model.Role{}).Where("name = ? AND id <> ?", role.Name, role.ID)
+	if role.Name != global.C.Rbac.SuperRole {
+		query = query.Where("application_id = ?", role.ApplicationId)
+	}
+	if err := query.Count(&count).Error; err != nil {
+		r.l.Error(fmt.Sprintf("查询角色信息失败, error: %s", err.Error()))
+		return fmt.Errorf("查询角色信息失败")
+	}
+	if count > 0 {
+		return errorx.NewCodeError(errorx.ErrDataConflict, "角色名称已存在")
+	}
+	return nil
+}
+
+// checkDataScope 检查数据权限范围，未指定时默认为本人数据，自定义范围必须指定存在的机构
+func (r *RoleLogic) checkDataScope(c *gin.Context, role *model.Role) error {
+	if role.DataScope == "" {
+		role.DataScope = model.DataScopeSelf
+	}
+	if role.DataScope != model.DataScopeCustom {
+		role.DataOrganizationIds = nil
+		return nil
+	}
+	role.DataOrganizationIds = uniqueIds(role.DataOrganizationIds)
+	if len(role.DataOrganizationIds) == 0 {
+		return errorx.NewCodeError(errorx.ErrParamParse, "自定义数据权限必须指定机构")
+	}
+	var count int64
+	if err := r.db.WithContext(c).Model(&model.Organization{}).Where("id IN ?", role.DataOrganizationIds).Count(&count).Error; err != nil {
+		r.l.Error(fmt.Sprintf("查询机构信息失败, error: %s", err.Error()))
+		return fmt.Errorf("查询机构信息失败")
+	}
+	if int(count) != len(role.DataOrganizationIds) {
+		return errorx.NewCodeError(errorx.ErrDataNotFound, "机构不存在")
+	}
+	return nil
+}
+
+// applicationScope 加载当前账号的权限，限定只能操作当前请求所属应用的数据
+func (r *RoleLogic) applicationScope(c *gin.Context, column string) (func(db *gorm.DB) *gorm.DB, error) {
+	scope, err := applicationScope(c, column)
+	if err != nil {
+		r.l.Error(fmt.Sprintf("加载账号权限失败, id: %d, error: %s", auth.GetAccountId(c), err.Error()))
+		return nil, fmt.Errorf("加载账号权限失败")
+	}
+	return scope, nil
+}
+
+// diffIds 对比现有ID和目标ID，返回需要新增和需要删除的ID
+func diffIds(exists, targets []uint) (added, removed []uint) {
+	existMap := make(map[uint]bool, len(exists))
+	for _, id := range exists {
+		existMap[id] = true
+	}
+	targetMap := make(map[uint]bool, len(targets))
+	for _, id := range targets {
+		targetMap[id] = true
+		if !existMap[id] {
+			added = append(added, id)
+		}
+	}
+	for _, id := range exists {
+		if !targetMap[id] {
+			removed = append(removed, id)
+		}
+	}
+	return added, removed
+}
+
+// 只需要保证 全局对象Config和全局Logger已经加载完成
+func (r *RoleLogic) Config() {
+	r.l = global.L.Named(portal.AppName).Named(portal.AppRole).Named("logic")
+	r.db = global.DB.GetDb()
+}
+
+func (r *RoleLogic) Name() string {
+	return fmt.Sprintf("%s.%s", portal.AppName, portal.AppRole)
+}
+
+func init() {
+	// 注册
+	router.RegistryService[service.RoleService](roleLogic)
+}
@@ -0,0 +1,168 @@
+package logic_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/logic"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/model"
+	types2 "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/auth"
+	"github.com/yanshicheng/ikube-gin-starter/common/errorx"
+	"github.com/yanshicheng/ikube-gin-starter/common/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/validator"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/jwt"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/testenv"
+)
+
+func TestRoleNamePerApplication(t *testing.T) {
+	testenv.Setup(t)
+	db := testenv.DB(t, &model.Application{}, &model.Role{}, &model.Organization{})
+	superRole := global.C.Rbac.SuperRole
+	t.Cleanup(func() { global.C.Rbac.SuperRole = superRole })
+	global.C.Rbac.SuperRole = "admin"
+	r := &logic.RoleLogic{}
+	r.Config()
+	c := testContext()
+	apps := []*model.Application{{Name: "portal", Path: "portal", Icon: "portal"}, {Name: "book", Path: "book", Icon: "book"}}
+	assert.NoError(t, db.Create(&apps).Error)
+
+	// 不同应用下可以使用相同的角色名称
+	assert.NoError(t, r.Create(c, &model.Role{Name: "ops", ApplicationId: apps[0].ID}))
+	assert.NoError(t, r.Create(c, &model.Role{Name: "ops", ApplicationId: apps[1].ID}), "不同应用下角色名称可以重复")
+	err := r.Create(c, &model.Role{Name: "ops", ApplicationId: apps[0].ID})
+	assert.Equal(t, errorx.ErrDataConflict, errorCode(err), "同一应用下角色名称不能重复")
+
+	// 超级管理员角色名称全局只能使用一次
+	assert.NoError(t, r.Create(c, &model.Role{Name: "admin", ApplicationId: apps[0].ID}))
+	err = r.Create(c, &model.Role{Name: "admin", ApplicationId: apps[1].ID})
+	assert.Equal(t, errorx.ErrDataConflict, errorCode(err), "超级管理员角色名称不能在其他应用下使用")
+}
+
+func TestRolePutAccountsRequireGrant(t *testing.T) {
+	testenv.Setup(t)
+	db := testenv.DB(t, &model.Account{}, &model.Role{}, &model.RoleAccount{}, &model.AccountApplication{})
+	r := &logic.RoleLogic{}
+	r.Config()
+	c := testContext()
+	accounts := []*model.Account{
+		{UserName: "张三", Account: "zhangsan", Mobile: "13800000001", Email: "zhangsan@example.com", WorkNumber: "001"},
+		{UserName: "李四", Account: "lisi", Mobile: "13800000002", Email: "lisi@example.com", WorkNumber: "002"},
+	}
+	assert.NoError(t, db.Create(&accounts).Error)
+	role := &model.Role{Name: "ops", ApplicationId: 1, DataScope: model.DataScopeAll}
+	assert.NoError(t, db.Create(role).Error)
+	assert.NoError(t, db.Create(&model.AccountApplication{AccountId: accounts[0].ID, ApplicationId: 1}).Error)
+
+	// 未被授权访问角色所在应用的账号不能分配角色
+	_, err := r.PutAccounts(c, types.SearchId{Id: role.ID}, types2.RoleAccountRequest{AccountIds: []uint{accounts[0].ID, accounts[1].ID}})
+	assert.Equal(t, errorx.ErrBusinessLogic, errorCode(err), "未授权应用的账号应拒绝分配")
+	ids, err := r.Accounts(c, types.SearchId{Id: role.ID})
+	assert.NoError(t, err)
+	assert.Empty(t, ids, "拒绝分配时不应修改角色账号")
+
+	ids, err = r.PutAccounts(c, types.SearchId{Id: role.ID}, types2.RoleAccountRequest{AccountIds: []uint{accounts[0].ID}})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{accounts[0].ID}, ids, "已授权应用的账号应分配成功")
+}
+
+func TestRoleCrossApplication(t *testing.T) {
+	testenv.Setup(t)
+	db := testenv.DB(t, &model.Application{}, &model.Account{}, &model.Role{}, &model.RoleAccount{},
+		&model.RoleMenu{}, &model.AccountApplication{}, &model.Upms{})
+	authorizer := enableRbac(t, auth.PermissionSet{})
+	r := &logic.RoleLogic{}
+	r.Config()
+
+	apps := []*model.Application{{Name: "portal", Path: "portal", Icon: "portal"}, {Name: "book", Path: "book", Icon: "book"}}
+	assert.NoError(t, db.Create(&apps).Error)
+	account := &model.Account{UserName: "张三", Account: "zhangsan", Mobile: "13800000001", Email: "zhangsan@example.com", WorkNumber: "001"}
+	assert.NoError(t, db.Create(account).Error)
+	assert.NoError(t, db.Create(&model.AccountApplication{AccountId: account.ID, ApplicationId: apps[0].ID}).Error)
+	superRole := &model.Role{Name: global.C.Rbac.SuperRole, ApplicationId: apps[0].ID, DataScope: model.DataScopeAll}
+	assert.NoError(t, db.Create(superRole).Error)
+	id := types.SearchId{Id: superRole.ID}
+
+	// 在应用 book 下操作应用 portal 的角色
+	c := testContext()
+	auth.SetClaims(c, &jwt.Claims{AccountId: 2})
+	c.Request.Header.Set(auth.ApplicationHeader, "2")
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"查询", func() error { _, err := r.Get(c, id); return err }},
+		{"修改", func() error {
+			_, err := r.Put(c, id, &model.Role{Name: "ops", ApplicationId: apps[0].ID, DataScope: model.DataScopeAll})
+			return err
+		}},
+		{"分配菜单", func() error { _, err := r.PutMenus(c, id, types2.RoleMenuRequest{}); return err }},
+		{"分配账号", func() error {
+			_, err := r.PutAccounts(c, id, types2.RoleAccountRequest{AccountIds: []uint{account.ID}})
+			return err
+		}},
+		{"删除", func() error { return r.Delete(c, id) }},
+		{"创建", func() error { return r.Create(c, &model.Role{Name: "ops", ApplicationId: apps[0].ID}) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, errorx.ErrDataNotFound, errorCode(tt.call()), "不能操作其他应用的角色")
+		})
+	}
+	var count int64
+	assert.NoError(t, db.Model(&model.RoleAccount{}).Where("role_id = ?", superRole.ID).Count(&count).Error)
+	assert.Equal(t, int64(0), count, "其他应用的角色不应该被分配账号")
+	assert.NoError(t, db.First(&model.Role{}, superRole.ID).Error, "其他应用的角色不应该被删除")
+	resp, err := r.List(c, types2.RoleSearch{Pagination: types.Pagination{PageNumber: 1, PageSize: 10, Sort: "ASC"}})
+	assert.NoError(t, err)
+	assert.Empty(t, *resp.Data.(*[]model.Role), "列表不应该返回其他应用的角色")
+
+	// 在角色所在应用下可以操作
+	c.Request.Header.Set(auth.ApplicationHeader, "1")
+	_, err = r.PutAccounts(c, id, types2.RoleAccountRequest{AccountIds: []uint{account.ID}})
+	assert.NoError(t, err, "可以操作当前应用的角色")
+
+	// 超级管理员可以操作所有应用的角色
+	authorizer.perms.Super = true
+	c.Request.Header.Set(auth.ApplicationHeader, "2")
+	_, err = r.Get(c, id)
+	assert.NoError(t, err, "超级管理员可以操作其他应用的角色")
+}
+
+func TestRoleListSearch(t *testing.T) {
+	testenv.Setup(t)
+	db := testenv.DB(t, &model.Role{})
+	r := &logic.RoleLogic{}
+	r.Config()
+	roles := []*model.Role{
+		{Name: "ops_admin", ApplicationId: 1, DataScope: model.DataScopeAll},
+		{Name: "opsXadmin", ApplicationId: 1, DataScope: model.DataScopeSelf},
+		{Name: "ops", ApplicationId: 2, DataScope: model.DataScopeSelf, RequireOtp: true},
+	}
+	assert.NoError(t, db.Create(roles).Error)
+
+	names := func(search types2.RoleSearch) []string {
+		search.Pagination = types.Pagination{PageNumber: 1, PageSize: 10, Sort: "ASC"}
+		resp, err := r.List(testContext(), search)
+		if !assert.NoError(t, err, "查询角色列表应该成功") {
+			return nil
+		}
+		names := make([]string, 0)
+		for _, role := range *resp.Data.(*[]model.Role) {
+			names = append(names, role.Name)
+		}
+		return names
+	}
+	assert.Equal(t, []string{"ops_admin"}, names(types2.RoleSearch{Name: "ops_"}), "名称中的 _ 应该按普通字符匹配")
+	assert.Equal(t, []string{"ops_admin", "opsXadmin", "ops"}, names(types2.RoleSearch{Name: "ops"}), "应该按名称前缀匹配")
+	assert.Empty(t, names(types2.RoleSearch{Name: "%"}), "名称中的 % 应该按普通字符匹配")
+	assert.Equal(t, []string{"opsXadmin", "ops"}, names(types2.RoleSearch{Filters: types.Filters{Filter: []string{"dataScope:eq:self"}}}),
+		"应该按 filter 参数过滤")
+	assert.Equal(t, []string{"ops"}, names(types2.RoleSearch{Filters: types.Filters{Filter: []string{"requireOtp:eq:true", "name:like:ops"}}}),
+		"多个过滤条件应该同时满足")
+
+	_, err := r.List(testContext(), types2.RoleSearch{Pagination: types.Pagination{PageNumber: 1, PageSize: 10, Sort: "ASC"},
+		Filters: types.Filters{Filter: []string{"dataOrganizationIds:eq:1"}}})
+	assert.IsType(t, validator.FieldErrors{}, err, "未声明的过滤字段应该返回参数错误")
+}
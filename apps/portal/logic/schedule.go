@@ -0,0 +1,47 @@
+package logic
+
+import (
+	"context"
+	"time"
+)
+
+// schedule 后台定时任务，由 logic 的 Start 启动、Stop 停止
+type schedule struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// start 每隔 interval 执行一次 fn，fn 使用的 ctx 在停止时取消
+func (s *schedule) start(interval time.Duration, fn func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				fn(ctx)
+			}
+		}
+	}()
+}
+
+// stop 停止任务并等待正在执行的任务结束，ctx 超时后不再等待
+func (s *schedule) stop(ctx context.Context) error {
+	if s.cancel == nil {
+		return nil
+	}
+	s.cancel()
+	s.cancel = nil
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,364 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/model"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/service"
+	types2 "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/auth"
+	"github.com/yanshicheng/ikube-gin-starter/common/errorx"
+	"github.com/yanshicheng/ikube-gin-starter/common/filter"
+	"github.com/yanshicheng/ikube-gin-starter/common/sql"
+	"github.com/yanshicheng/ikube-gin-starter/common/types"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/apikey"
+	"github.com/yanshicheng/ikube-gin-starter/router"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 接口检查
+var _ service.ServiceAccountService = (*ServiceAccountLogic)(nil)
+var _ auth.ApiKeyValidator = (*ServiceAccountLogic)(nil)
+
+var serviceAccountLogic = &ServiceAccountLogic{}
+
+type ServiceAccountLogic struct {
+	l  *zap.Logger
+	db *gorm.DB
+}
+
+func (s *ServiceAccountLogic) Get(c *gin.Context, id types.SearchId) (*model.ServiceAccount, error) {
+	var serviceAccount model.ServiceAccount
+	if err := s.db.WithContext(c).Where("id = ?", id.Id).First(&serviceAccount).Error; err != nil {
+		s.l.Error(fmt.Sprintf("查询服务账号信息失败, id: %d, error: %s", id.Id, err.Error()))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errorx.NewCodeError(errorx.ErrDataNotFound, "服务账号不存在")
+		}
+		return nil, fmt.Errorf("查询服务账号信息失败")
+	}
+	return &serviceAccount, nil
+}
+
+func (s *ServiceAccountLogic) List(c *gin.Context, search types2.ServiceAccountSearch) (*types.QueryResponse, error) {
+	db := s.db.WithContext(c).Model(&model.ServiceAccount{})
+	if search.Name != "" {
+		db = db.Where(filter.Prefix("name", search.Name))
+	}
+	scope, err := filter.Scope(&model.ServiceAccount{}, search.Filter)
+	if err != nil {
+		return nil, err
+	}
+	db = db.Scopes(scope).Order(fmt.Sprintf("id %s", search.Sort))
+	var serviceAccounts []model.ServiceAccount
+	resp, err := sql.GetPageResponse(db, search.Pagination, &serviceAccounts)
+	if err != nil {
+		s.l.Error(fmt.Sprintf("查询服务账号列表失败, error: %s", err.Error()))
+		return nil, fmt.Errorf("查询服务账号列表失败")
+	}
+	return resp, nil
+}
+
+func (s *ServiceAccountLogic) Create(c *gin.Context, serviceAccount *model.ServiceAccount) error {
+	serviceAccount.ID = 0
+	if err := s.checkName(c, serviceAccount); err != nil {
+		return err
+	}
+	if err := s.db.WithContext(c).Create(serviceAccount).Error; err != nil {
+		s.l.Error(fmt.Sprintf("创建服务账号失败, error: %s", err.Error()))
+		return errorx.NewCodeError(errorx.ErrDataCreation, fmt.Sprintf("创建服务账号失败: %s", err.Error()))
+	}
+	return nil
+}
+
+func (s *ServiceAccountLogic) Put(c *gin.Context, id types.SearchId, serviceAccount *model.ServiceAccount) (*model.ServiceAccount, error) {
+	oldServiceAccount, err := s.Get(c, id)
+	if err != nil {
+		return nil, err
+	}
+	serviceAccount.ID = oldServiceAccount.ID
+	if err := s.checkName(c, serviceAccount); err != nil {
+		return nil, err
+	}
+	// 修改操作，禁用后该服务账号的所有 key 立即失效
+	oldServiceAccount.Name = serviceAccount.Name
+	oldServiceAccount.Desc = serviceAccount.Desc
+	oldServiceAccount.IsDisabled = serviceAccount.IsDisabled
+	if err := s.db.WithContext(c).Save(oldServiceAccount).Error; err != nil {
+		s.l.Error(fmt.Sprintf("更新服务账号信息失败, id: %d, error: %s", id.Id, err.Error()))
+		return nil, fmt.Errorf("更新服务账号信息失败")
+	}
+	return oldServiceAccount, nil
+}
+
+// Delete 删除服务账号，同时吊销其所有 key
+func (s *ServiceAccountLogic) Delete(c *gin.Context, id types.SearchId) error {
+	serviceAccount, err := s.Get(c, id)
+	if err != nil {
+		return err
+	}
+	err = s.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.ApiKey{}).
+			Where("service_account_id = ? AND revoked_at IS NULL", serviceAccount.ID).
+			Update("revoked_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Delete(serviceAccount).Error
+	})
+	if err != nil {
+		s.l.Error(fmt.Sprintf("删除服务账号失败, id: %d, error: %s", id.Id, err.Error()))
+		return errorx.NewCodeError(errorx.ErrDataDeletion, "删除服务账号失败")
+	}
+	return nil
+}
+
+// Keys 查询服务账号的所有 key，包括已吊销的 key
+func (s *ServiceAccountLogic) Keys(c *gin.Context, id types.SearchId) ([]model.ApiKey, error) {
+	if _, err := s.Get(c, id); err != nil {
+		return nil, err
+	}
+	keys := make([]model.ApiKey, 0)
+	if err := s.db.WithContext(c).Where("service_account_id = ?", id.Id).Order("id DESC").Find(&keys).Error; err != nil {
+		s.l.Error(fmt.Sprintf("查询服务账号 key 失败, id: %d, error: %s", id.Id, err.Error()))
+		return nil, fmt.Errorf("查询服务账号 key 失败")
+	}
+	return keys, nil
+}
+
+// IssueKey 为服务账号签发新的 key，完整的 key 只在响应中返回一次
+func (s *ServiceAccountLogic) IssueKey(c *gin.Context, id types.SearchId, req types2.ApiKeyCreateRequest) (*types2.ApiKeyIssueResponse, error) {
+	serviceAccount, err := s.Get(c, id)
+	if err != nil {
+		return nil, err
+	}
+	scopes := make([]auth.Permission, 0, len(req.Scopes))
+	for _, scope := range req.Scopes {
+		scopes = append(scopes, auth.Permission{Resource: scope.Resource, Action: scope.Action})
+	}
+	scope, applicationId, err := s.checkScopes(c, scopes)
+	if err != nil {
+		return nil, err
+	}
+	apiKey := model.ApiKey{
+		ServiceAccountId: serviceAccount.ID,
+		Name:             req.Name,
+		Scopes:           scopes,
+		ApplicationId:    applicationId,
+		DataScope:        scope,
+	}
+	if req.ExpiresIn > 0 {
+		t := time.Now().Add(time.Duration(req.ExpiresIn) * time.Second)
+		apiKey.ExpiresAt = &t
+	}
+	return s.issue(s.db.WithContext(c), apiKey)
+}
+
+// RotateKey 轮换 key，吊销旧 key 并签发名称、授权范围和有效期相同的新 key
+func (s *ServiceAccountLogic) RotateKey(c *gin.Context, id types2.ApiKeySearchId) (*types2.ApiKeyIssueResponse, error) {
+	old, err := s.getKey(c, id)
+	if err != nil {
+		return nil, err
+	}
+	if old.RevokedAt != nil {
+		return nil, errorx.NewCodeError(errorx.ErrBusinessLogic, "api key 已吊销")
+	}
+	// 签发人的权限可能已经变化，轮换时同样检查授权范围，数据范围和应用按当前签发人重新记录
+	scope, applicationId, err := s.checkScopes(c, old.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	apiKey := model.ApiKey{
+		ServiceAccountId: old.ServiceAccountId,
+		Name:             old.Name,
+		Scopes:           old.Scopes,
+		ApplicationId:    applicationId,
+		DataScope:        scope,
+	}
+	if old.ExpiresAt != nil {
+		t := time.Now().Add(old.ExpiresAt.Sub(old.CreatedAt))
+		apiKey.ExpiresAt = &t
+	}
+	var resp *types2.ApiKeyIssueResponse
+	err = s.db.WithContext(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(old).Update("revoked_at", time.Now()).Error; err != nil {
+			return err
+		}
+		resp, err = s.issue(tx, apiKey)
+		return err
+	})
+	if err != nil {
+		s.l.Error(fmt.Sprintf("轮换 api key 失败, id: %d, error: %s", id.KeyId, err.Error()))
+		return nil, errorx.NewCodeError(errorx.ErrDataCreation, "轮换 api key 失败")
+	}
+	return resp, nil
+}
+
+// RevokeKey 吊销 key，吊销后立即失效，记录保留用于审计
+func (s *ServiceAccountLogic) RevokeKey(c *gin.Context, id types2.ApiKeySearchId) error {
+	key, err := s.getKey(c, id)
+	if err != nil {
+		return err
+	}
+	if key.RevokedAt != nil {
+		return nil
+	}
+	if err := s.db.WithContext(c).Model(key).Update("revoked_at", time.Now()).Error; err != nil {
+		s.l.Error(fmt.Sprintf("吊销 api key 失败, id: %d, error: %s", id.KeyId, err.Error()))
+		return fmt.Errorf("吊销 api key 失败")
+	}
+	return nil
+}
+
+// ValidateApiKey 校验 api key，并按配置的间隔更新最近使用时间
+func (s *ServiceAccountLogic) ValidateApiKey(ctx context.Context, key string) (*auth.ApiKeyPrincipal, error) {
+	keyId, err := apikey.Parse(global.C.ApiKey.Prefix, key)
+	if err != nil {
+		return nil, auth.ErrApiKeyInvalid
+	}
+	db := s.db.WithContext(ctx)
+	var apiKey model.ApiKey
+	if err := db.Where("key_id = ?", keyId).First(&apiKey).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, auth.ErrApiKeyInvalid
+		}
+		return nil, fmt.Errorf("查询 api key 失败: %w", err)
+	}
+	if !apikey.Verify(key, apiKey.Hash) || apiKey.RevokedAt != nil {
+		return nil, auth.ErrApiKeyInvalid
+	}
+	now := time.Now()
+	if apiKey.ExpiresAt != nil && !apiKey.ExpiresAt.After(now) {
+		return nil, auth.ErrApiKeyExpired
+	}
+	var serviceAccount model.ServiceAccount
+	if err := db.Where("id = ?", apiKey.ServiceAccountId).First(&serviceAccount).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, auth.ErrApiKeyInvalid
+		}
+		return nil, fmt.Errorf("查询服务账号失败: %w", err)
+	}
+	if serviceAccount.IsDisabled {
+		return nil, auth.ErrApiKeyInvalid
+	}
+	// 最近使用时间只需要大致准确，按间隔更新，避免每次请求都写库
+	interval := time.Duration(global.C.ApiKey.LastUsedInterval) * time.Second
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= interval {
+		if err := db.Model(&apiKey).UpdateColumn("last_used_at", now).Error; err != nil {
+			s.l.Warn(fmt.Sprintf("更新 api key 使用时间失败, id: %d, error: %s", apiKey.ID, err.Error()))
+		}
+	}
+	return &auth.ApiKeyPrincipal{
+		KeyId:            apiKey.ID,
+		ServiceAccountId: serviceAccount.ID,
+		Name:             serviceAccount.Name,
+		Scopes:           apiKey.Scopes,
+		ApplicationId:    apiKey.ApplicationId,
+		DataScope:        apiKey.DataScope,
+	}, nil
+}
+
+// issue 生成 key 并保存摘要
+func (s *ServiceAccountLogic) issue(db *gorm.DB, apiKey model.ApiKey) (*types2.ApiKeyIssueResponse, error) {
+	key, keyId, err := apikey.Generate(global.C.ApiKey.Prefix)
+	if err != nil {
+		s.l.Error(fmt.Sprintf("生成 api key 失败, error: %s", err.Error()))
+		return nil, fmt.Errorf("生成 api key 失败")
+	}
+	apiKey.KeyId = keyId
+	apiKey.Hash = apikey.Hash(key)
+	if err := db.Create(&apiKey).Error; err != nil {
+		s.l.Error(fmt.Sprintf("保存 api key 失败, error: %s", err.Error()))
+		return nil, errorx.NewCodeError(errorx.ErrDataCreation, "保存 api key 失败")
+	}
+	return &types2.ApiKeyIssueResponse{ApiKey: apiKey, Key: key}, nil
+}
+
+// checkScopes 检查 key 的授权范围不能超过签发人自身的权限，超级管理员和未启用 rbac 时不限制。
+// 通过 api key 签发时，不能超过该 key 的授权范围。
+// 返回签发人的数据权限范围和所在的应用，key 访问数据时不能超过签发人的数据范围。
+func (s *ServiceAccountLogic) checkScopes(c *gin.Context, scopes []auth.Permission) (*auth.DataScope, uint, error) {
+	applicationId := auth.GetApplicationId(c)
+	var perms *auth.PermissionSet
+	var scope *auth.DataScope
+	if principal, ok := auth.GetApiKey(c); ok {
+		perms = &auth.PermissionSet{Permissions: principal.Scopes}
+		scope = principal.DataScope
+	} else {
+		if !global.C.Rbac.Enable {
+			return &auth.DataScope{All: true}, applicationId, nil
+		}
+		accountId := auth.GetAccountId(c)
+		var err error
+		if perms, err = auth.GetPermissions(c, accountId, applicationId); err != nil {
+			s.l.Error(fmt.Sprintf("加载账号权限失败, id: %d, error: %s", accountId, err.Error()))
+			return nil, 0, fmt.Errorf("加载账号权限失败")
+		}
+		if perms.Super {
+			scope = &auth.DataScope{All: true}
+		} else {
+			dataScope := perms.DataScope
+			dataScope.OrganizationIds = append([]uint(nil), perms.DataScope.OrganizationIds...)
+			scope = &dataScope
+		}
+	}
+	denied := make([]auth.Permission, 0)
+	for _, scope := range scopes {
+		// 授权范围作为资源校验，通配的范围只有在签发人拥有更大或相同的通配权限时才允许
+		if !perms.Allow(scope.Resource, scope.Action) {
+			denied = append(denied, scope)
+		}
+	}
+	if len(denied) > 0 {
+		return nil, 0, errorx.NewCodeErrorData(errorx.ErrPermissionDenied, "api key 授权范围超出当前账号的权限", denied)
+	}
+	return scope, applicationId, nil
+}
+
+// getKey 查询服务账号下的 key
+func (s *ServiceAccountLogic) getKey(c *gin.Context, id types2.ApiKeySearchId) (*model.ApiKey, error) {
+	var apiKey model.ApiKey
+	if err := s.db.WithContext(c).Where("id = ? AND service_account_id = ?", id.KeyId, id.Id).First(&apiKey).Error; err != nil {
+		s.l.Error(fmt.Sprintf("查询 api key 失败, id: %d, error: %s", id.KeyId, err.Error()))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errorx.NewCodeError(errorx.ErrDataNotFound, "api key 不存在")
+		}
+		return nil, fmt.Errorf("查询 api key 失败")
+	}
+	return &apiKey, nil
+}
+
+// checkName 检查服务账号名称是否已被使用
+func (s *ServiceAccountLogic) checkName(c *gin.Context, serviceAccount *model.ServiceAccount) error {
+	var count int64
+	if err := s.db.WithContext(c).Model(&model.ServiceAccount{}).
+		Where("id <> ? AND name = ?", serviceAccount.ID, serviceAccount.Name).Count(&count).Error; err != nil {
+		s.l.Error(fmt.Sprintf("查询服务账号信息失败, error: %s", err.Error()))
+		return fmt.Errorf("查询服务账号信息失败")
+	}
+	if count > 0 {
+		return errorx.NewCodeError(errorx.ErrDataConflict, "服务账号名称已存在")
+	}
+	return nil
+}
+
+// 只需要保证 全局对象Config和全局Logger已经加载完成
+func (s *ServiceAccountLogic) Config() {
+	s.l = global.L.Named(portal.AppName).Named(portal.AppServiceAccount).Named("logic")
+	s.db = global.DB.GetDb()
+	auth.RegistryApiKeyValidator(s)
+}
+
+func (s *ServiceAccountLogic) Name() string {
+	return fmt.Sprintf("%s.%s", portal.AppName, portal.AppServiceAccount)
+}
+
+func init() {
+	// 注册
+	router.RegistryService[service.ServiceAccountService](serviceAccountLogic)
+}
@@ -0,0 +1,148 @@
+package logic_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/logic"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/model"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/service"
+	types2 "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/auth"
+	"github.com/yanshicheng/ikube-gin-starter/common/errorx"
+	"github.com/yanshicheng/ikube-gin-starter/common/middleware"
+	"github.com/yanshicheng/ikube-gin-starter/common/types"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/jwt"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/testenv"
+	"github.com/yanshicheng/ikube-gin-starter/router"
+)
+
+func TestIssueKeyScopes(t *testing.T) {
+	testenv.Setup(t)
+	db := testenv.DB(t, &model.ServiceAccount{}, &model.ApiKey{})
+	authorizer := enableRbac(t, auth.PermissionSet{Permissions: []auth.Permission{
+		{Resource: "/portal/account/*", Action: auth.ActionWrite},
+		{Resource: "/book/*", Action: auth.ActionRead},
+	}})
+	s := &logic.ServiceAccountLogic{}
+	s.Config()
+	t.Cleanup(func() { auth.RegistryApiKeyValidator(nil) })
+
+	c := testContext()
+	auth.SetClaims(c, &jwt.Claims{AccountId: 1})
+	c.Request.Header.Set(auth.ApplicationHeader, "1")
+	serviceAccount := &model.ServiceAccount{Name: "ci"}
+	assert.NoError(t, db.Create(serviceAccount).Error)
+	id := types.SearchId{Id: serviceAccount.ID}
+	issue := func(scopes ...types2.ApiKeyScope) (*types2.ApiKeyIssueResponse, error) {
+		return s.IssueKey(c, id, types2.ApiKeyCreateRequest{Name: "deploy", Scopes: scopes})
+	}
+
+	tests := []struct {
+		name  string
+		scope types2.ApiKeyScope
+		allow bool
+	}{
+		{"权限内的资源", types2.ApiKeyScope{Resource: "/portal/account/", Action: auth.ActionWrite}, true},
+		{"权限内的通配资源", types2.ApiKeyScope{Resource: "/portal/account/*", Action: auth.ActionRead}, true},
+		{"只读权限不能签发写权限", types2.ApiKeyScope{Resource: "/book/book", Action: auth.ActionWrite}, false},
+		{"更大的通配范围", types2.ApiKeyScope{Resource: "/portal/*", Action: auth.ActionRead}, false},
+		{"全部资源", types2.ApiKeyScope{Resource: "*", Action: auth.ActionRead}, false},
+		{"没有权限的资源", types2.ApiKeyScope{Resource: "/portal/role/", Action: auth.ActionRead}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := issue(tt.scope)
+			if tt.allow {
+				assert.NoError(t, err, tt.name)
+			} else {
+				assert.Equal(t, errorx.ErrPermissionDenied, errorCode(err), tt.name)
+			}
+		})
+	}
+
+	// 签发人权限收回后不能再轮换 key
+	resp, err := issue(types2.ApiKeyScope{Resource: "/book/*", Action: auth.ActionRead})
+	assert.NoError(t, err)
+	authorizer.perms.Permissions = authorizer.perms.Permissions[:1]
+	_, err = s.RotateKey(c, types2.ApiKeySearchId{Id: serviceAccount.ID, KeyId: resp.ID})
+	assert.Equal(t, errorx.ErrPermissionDenied, errorCode(err), "权限收回后轮换 key 应该被拒绝")
+
+	// 超级管理员不限制授权范围
+	authorizer.perms.Super = true
+	_, err = s.RotateKey(c, types2.ApiKeySearchId{Id: serviceAccount.ID, KeyId: resp.ID})
+	assert.NoError(t, err, "超级管理员可以轮换任意授权范围的 key")
+}
+
+func TestApiKeyDataScope(t *testing.T) {
+	testenv.Setup(t)
+	gin.SetMode(gin.TestMode)
+	db := testenv.DB(t, &model.ServiceAccount{}, &model.ApiKey{}, &model.Account{})
+	// 签发人只能访问机构 1 的账号
+	authorizer := enableRbac(t, auth.PermissionSet{
+		Permissions: []auth.Permission{{Resource: "/portal/account/", Action: auth.ActionRead}},
+		DataScope:   auth.DataScope{OrganizationIds: []uint{1}},
+	})
+	s := &logic.ServiceAccountLogic{}
+	s.Config()
+	t.Cleanup(func() { auth.RegistryApiKeyValidator(nil) })
+	accounts, err := router.Lookup[service.AccountService]()
+	assert.NoError(t, err, "账号逻辑应该已注册")
+	accounts.(interface{ Config() }).Config()
+
+	for i, name := range []string{"zhangsan", "lisi"} {
+		assert.NoError(t, db.Create(&model.Account{UserName: name, Account: name, Mobile: name, Email: name + "@local",
+			WorkNumber: name, OrganizationId: uint(i + 1)}).Error)
+	}
+	serviceAccount := &model.ServiceAccount{Name: "ci"}
+	assert.NoError(t, db.Create(serviceAccount).Error)
+
+	c := testContext()
+	auth.SetClaims(c, &jwt.Claims{AccountId: 1})
+	c.Request.Header.Set(auth.ApplicationHeader, "2")
+	resp, err := s.IssueKey(c, types.SearchId{Id: serviceAccount.ID}, types2.ApiKeyCreateRequest{Name: "deploy",
+		Scopes: []types2.ApiKeyScope{{Resource: "/portal/account/", Action: auth.ActionRead}}})
+	assert.NoError(t, err, "权限内的授权范围应该签发成功")
+	principal, err := s.ValidateApiKey(context.Background(), resp.Key)
+	assert.NoError(t, err, "新签发的 key 应该有效")
+	assert.Equal(t, uint(2), principal.ApplicationId, "key 应该记录签发时所在的应用")
+
+	// 使用 key 查询账号列表，只能看到签发人数据范围内的账号
+	var names []string
+	var applicationId uint
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		auth.SetApiKey(c, principal)
+	}, middleware.Permission())
+	r.GET("/portal/account/", func(c *gin.Context) {
+		applicationId = auth.GetApplicationId(c)
+		resp, err := accounts.List(c, types2.AccountSearch{Pagination: types.Pagination{PageNumber: 1, PageSize: 10, Sort: "ASC"}})
+		if assert.NoError(t, err) {
+			for _, account := range *resp.Data.(*[]model.Account) {
+				names = append(names, account.Account)
+			}
+		}
+		c.String(http.StatusOK, "ok")
+	})
+	req := httptest.NewRequest(http.MethodGet, "/portal/account/", nil)
+	req.Header.Set(auth.ApplicationHeader, "1")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, "ok", w.Body.String(), "授权范围内的资源应该放行")
+	assert.Equal(t, []string{"zhangsan"}, names, "key 不能访问签发人数据范围以外的账号")
+	assert.Equal(t, uint(2), applicationId, "key 所属的应用不能通过请求头修改")
+
+	// 签发人的数据范围扩大后，轮换的 key 按新的范围访问
+	authorizer.perms.DataScope = auth.DataScope{OrganizationIds: []uint{1, 2}}
+	resp, err = s.RotateKey(c, types2.ApiKeySearchId{Id: serviceAccount.ID, KeyId: resp.ID})
+	assert.NoError(t, err)
+	principal, err = s.ValidateApiKey(context.Background(), resp.Key)
+	assert.NoError(t, err)
+	names = nil
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/portal/account/", nil))
+	assert.Equal(t, []string{"zhangsan", "lisi"}, names, "轮换后的 key 应该按签发人当前的数据范围访问")
+}
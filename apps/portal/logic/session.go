@@ -0,0 +1,150 @@
+package logic
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/model"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/service"
+	types2 "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/auth"
+	"github.com/yanshicheng/ikube-gin-starter/common/errorx"
+	"github.com/yanshicheng/ikube-gin-starter/common/types"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/router"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 登录会话：用户查看和下线自己的会话，管理员下线账号的全部会话。
+// 冻结、禁用、离职的账号由 Account 的更新钩子自动下线。
+
+// 接口检查
+var _ service.SessionService = (*SessionLogic)(nil)
+
+var sessionLogic = &SessionLogic{}
+
+type SessionLogic struct {
+	l  *zap.Logger
+	db *gorm.DB
+}
+
+// Mine 当前账号的全部会话，标记当前请求使用的会话
+func (s *SessionLogic) Mine(c *gin.Context) ([]auth.Session, error) {
+	claims, ok := auth.GetClaims(c)
+	if !ok {
+		return nil, errorx.NewCodeError(errorx.ErrBusinessLogic, "当前登录方式不支持会话管理")
+	}
+	sessions, err := auth.ListSessions(c, claims.AccountId, claims.SessionId)
+	if err != nil {
+		s.l.Error(err.Error())
+		return nil, fmt.Errorf("查询会话失败")
+	}
+	return sessions, nil
+}
+
+// RevokeMine 下线当前账号的一个会话，可以是当前会话
+func (s *SessionLogic) RevokeMine(c *gin.Context, req types2.SessionSearchId) error {
+	claims, ok := auth.GetClaims(c)
+	if !ok {
+		return errorx.NewCodeError(errorx.ErrBusinessLogic, "当前登录方式不支持会话管理")
+	}
+	if err := auth.DeleteSession(c, claims.AccountId, req.SessionId); err != nil {
+		return s.sessionError(err)
+	}
+	s.l.Info(fmt.Sprintf("会话已下线, id: %d, session: %s", claims.AccountId, req.SessionId))
+	return nil
+}
+
+// RevokeMineOthers 下线当前账号除当前会话以外的全部会话
+func (s *SessionLogic) RevokeMineOthers(c *gin.Context) (*types2.SessionRevokeResponse, error) {
+	claims, ok := auth.GetClaims(c)
+	if !ok {
+		return nil, errorx.NewCodeError(errorx.ErrBusinessLogic, "当前登录方式不支持会话管理")
+	}
+	if claims.SessionId == "" {
+		return nil, errorx.NewCodeError(errorx.ErrBusinessLogic, "当前 token 不属于任何会话，请重新登录")
+	}
+	n, err := auth.DeleteSessions(c, claims.AccountId, claims.SessionId)
+	if err != nil {
+		return nil, s.sessionError(err)
+	}
+	s.l.Info(fmt.Sprintf("其他会话已下线, id: %d, count: %d", claims.AccountId, n))
+	return &types2.SessionRevokeResponse{Count: n}, nil
+}
+
+// List 管理员查询账号的全部会话，受数据权限限制
+func (s *SessionLogic) List(c *gin.Context, id types.SearchId) ([]auth.Session, error) {
+	account, err := s.account(c, id)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := auth.ListSessions(c, account.ID, "")
+	if err != nil {
+		s.l.Error(err.Error())
+		return nil, fmt.Errorf("查询会话失败")
+	}
+	return sessions, nil
+}
+
+// RevokeAll 管理员下线账号的全部会话，用于冻结、离职等场景的手动处理
+func (s *SessionLogic) RevokeAll(c *gin.Context, id types.SearchId) (*types2.SessionRevokeResponse, error) {
+	account, err := s.account(c, id)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := auth.ListSessions(c, account.ID, "")
+	if err != nil {
+		s.l.Error(err.Error())
+		return nil, fmt.Errorf("查询会话失败")
+	}
+	// 同时记录强制下线时间，不属于任何会话的 token 一并失效
+	if err := auth.RevokeAccount(c, account.ID); err != nil {
+		return nil, s.sessionError(err)
+	}
+	s.l.Info(fmt.Sprintf("账号全部会话已下线, id: %d, count: %d, operator: %d", account.ID, len(sessions), auth.GetAccountId(c)))
+	return &types2.SessionRevokeResponse{Count: len(sessions)}, nil
+}
+
+// account 按数据权限查询账号
+func (s *SessionLogic) account(c *gin.Context, id types.SearchId) (*model.Account, error) {
+	var account model.Account
+	if err := s.db.WithContext(c).Scopes(auth.DataScopeFilter(c, &model.Account{})).
+		Where("id = ?", id.Id).First(&account).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errorx.NewCodeError(errorx.ErrDataNotFound, "账号不存在")
+		}
+		s.l.Error(fmt.Sprintf("查询账号信息失败, id: %d, error: %s", id.Id, err.Error()))
+		return nil, fmt.Errorf("查询账号信息失败")
+	}
+	return &account, nil
+}
+
+// sessionError 转换会话操作的错误
+func (s *SessionLogic) sessionError(err error) error {
+	switch {
+	case errors.Is(err, auth.ErrSessionNotFound):
+		return errorx.NewCodeError(errorx.ErrDataNotFound, "会话不存在")
+	case errors.Is(err, auth.ErrRedisDisabled):
+		return errorx.NewCodeError(errorx.ErrBusinessLogic, "未启用 redis，不支持会话管理")
+	}
+	s.l.Error(err.Error())
+	return fmt.Errorf("下线会话失败")
+}
+
+// 只需要保证 全局对象Config和全局Logger已经加载完成
+func (s *SessionLogic) Config() {
+	s.l = global.L.Named(portal.AppName).Named(portal.AppSession).Named("logic")
+	s.db = global.DB.GetDb()
+}
+
+func (s *SessionLogic) Name() string {
+	return fmt.Sprintf("%s.%s", portal.AppName, portal.AppSession)
+}
+
+func init() {
+	// 注册
+	router.RegistryService[service.SessionService](sessionLogic)
+}
@@ -0,0 +1,32 @@
+package model
+
+import "github.com/yanshicheng/ikube-gin-starter/common/model"
+
+// 外部身份表，记录单点登录、目录同步的外部用户和节点与本地账号、机构的关联
+func init() {
+	model.Register(&AccountIdentity{}, &OrganizationIdentity{})
+}
+
+// AccountIdentity 外部身份提供方用户与账号的关联，Issuer 和 Subject 唯一确定一个外部用户
+type AccountIdentity struct {
+	model.Model
+	AccountId uint   `json:"accountId" gorm:"type:int;not null;index;comment:账号"`
+	Issuer    string `json:"issuer" gorm:"type:varchar(191);not null;uniqueIndex:idx_account_identity;comment:身份提供方"`
+	Subject   string `json:"subject" gorm:"type:varchar(191);not null;uniqueIndex:idx_account_identity;comment:外部用户标识"`
+}
+
+func (a *AccountIdentity) TableName() string {
+	return "ikubeops_portal_account_identity"
+}
+
+// OrganizationIdentity 外部目录节点与机构的关联，Subject 为规范化后的 DN
+type OrganizationIdentity struct {
+	model.Model
+	OrganizationId uint   `json:"organizationId" gorm:"type:int;not null;index;comment:机构"`
+	Issuer         string `json:"issuer" gorm:"type:varchar(191);not null;uniqueIndex:idx_organization_identity;comment:外部目录"`
+	Subject        string `json:"subject" gorm:"type:varchar(191);not null;uniqueIndex:idx_organization_identity;comment:外部节点标识"`
+}
+
+func (o *OrganizationIdentity) TableName() string {
+	return "ikubeops_portal_organization_identity"
+}
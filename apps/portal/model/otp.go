@@ -0,0 +1,41 @@
+package model
+
+import (
+	"time"
+
+	"github.com/yanshicheng/ikube-gin-starter/common/model"
+)
+
+func init() {
+	model.Register(&AccountOtp{}, &OtpRecoveryCode{})
+}
+
+// AccountOtp 账号的两步验证密钥，EnabledAt 为空表示已生成密钥但尚未完成绑定
+type AccountOtp struct {
+	model.Model
+	AccountId uint       `json:"accountId" gorm:"type:int;not null;uniqueIndex;comment:账号"`
+	Secret    string     `json:"-" gorm:"type:varchar(64);not null;comment:密钥"`
+	EnabledAt *time.Time `json:"enabledAt" gorm:"comment:启用时间"`
+	LastStep  int64      `json:"-" gorm:"not null;default:0;comment:最后使用的时间步"` // 防止同一个密码重复使用
+}
+
+func (o *AccountOtp) TableName() string {
+	return "ikubeops_portal_account_otp"
+}
+
+// Enabled 是否已启用两步验证
+func (o *AccountOtp) Enabled() bool {
+	return o != nil && o.EnabledAt != nil
+}
+
+// OtpRecoveryCode 两步验证恢复码，只保存摘要，每个恢复码只能使用一次
+type OtpRecoveryCode struct {
+	model.Model
+	AccountId uint       `json:"accountId" gorm:"type:int;not null;index;comment:账号"`
+	Hash      string     `json:"-" gorm:"type:char(64);not null;comment:恢复码摘要"`
+	UsedAt    *time.Time `json:"usedAt" gorm:"comment:使用时间"`
+}
+
+func (o *OtpRecoveryCode) TableName() string {
+	return "ikubeops_portal_otp_recovery_code"
+}
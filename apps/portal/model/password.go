@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+
+	"github.com/yanshicheng/ikube-gin-starter/common/model"
+)
+
+func init() {
+	model.Register(&PasswordHistory{})
+}
+
+// PasswordHistory 账号使用过的密码，用于禁止重复使用最近的密码
+type PasswordHistory struct {
+	model.Model
+	AccountId uint   `json:"accountId" gorm:"type:int;not null;index;comment:账号"`
+	Password  string `json:"-" gorm:"type:varchar(256);not null;comment:密码"` // bcrypt 密文
+}
+
+func (p *PasswordHistory) TableName() string {
+	return "ikubeops_portal_password_history"
+}
+
+// PasswordExpired 判断密码是否超过有效期，maxAge 单位为天，0 表示永不过期。
+// 没有密码修改时间的历史账号按创建时间计算。
+func (u *Account) PasswordExpired(maxAge int, now time.Time) bool {
+	if maxAge <= 0 {
+		return false
+	}
+	changedAt := u.CreatedAt
+	if u.PasswordChangedAt != nil {
+		changedAt = *u.PasswordChangedAt
+	}
+	return now.After(changedAt.AddDate(0, 0, maxAge))
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"time"
+
+	"github.com/yanshicheng/ikube-gin-starter/common/auth"
+	"github.com/yanshicheng/ikube-gin-starter/common/model"
+)
+
+// 服务账号表，api key 表
+func init() {
+	model.Register(&ServiceAccount{}, &ApiKey{})
+}
+
+// ServiceAccount 服务账号，供 CI 任务和内部服务等机器客户端通过 api key 调用接口
+type ServiceAccount struct {
+	model.Model
+	Name       string `json:"name" binding:"required,max=32" gorm:"type:varchar(32);not null;uniqueIndex;comment:服务账号" filter:"eq,in,like"`
+	Desc       string `json:"desc" binding:"max=56" gorm:"type:varchar(56);not null;comment:描述"`
+	IsDisabled bool   `json:"isDisabled" binding:"boolean" gorm:"type:tinyint(1);not null;default:false;comment:是否禁用" filter:"eq"`
+}
+
+func (s *ServiceAccount) TableName() string {
+	return "ikubeops_portal_service_account"
+}
+
+// ApiKey 服务账号的 api key，只保存摘要，完整的 key 只在签发时返回一次。
+// 签发和轮换时记录签发人所在的应用和数据权限范围，使用 key 访问时不能超过该范围
+type ApiKey struct {
+	model.Model
+	ServiceAccountId uint              `json:"serviceAccountId" gorm:"type:int;not null;index;comment:服务账号"`
+	Name             string            `json:"name" gorm:"type:varchar(32);not null;comment:名称"`
+	KeyId            string            `json:"keyId" gorm:"type:varchar(32);not null;uniqueIndex;comment:公开标识"`
+	Hash             string            `json:"-" gorm:"type:char(64);not null;comment:摘要"`
+	Scopes           []auth.Permission `json:"scopes" gorm:"type:varchar(2048);serializer:json;comment:授权范围"`
+	ApplicationId    uint              `json:"applicationId" gorm:"type:int;not null;default:0;comment:签发时所在的应用"`
+	DataScope        *auth.DataScope   `json:"dataScope" gorm:"type:varchar(2048);serializer:json;comment:签发人的数据权限范围"`
+	ExpiresAt        *time.Time        `json:"expiresAt" gorm:"type:datetime;comment:过期时间"`
+	LastUsedAt       *time.Time        `json:"lastUsedAt" gorm:"type:datetime;comment:最近使用时间"`
+	RevokedAt        *time.Time        `json:"revokedAt" gorm:"type:datetime;comment:吊销时间"`
+}
+
+func (a *ApiKey) TableName() string {
+	return "ikubeops_portal_api_key"
+}
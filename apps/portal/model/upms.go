@@ -3,7 +3,10 @@ package model
 import (
 	"database/sql/driver"
 	"fmt"
+	"github.com/yanshicheng/ikube-gin-starter/common/auth"
 	"github.com/yanshicheng/ikube-gin-starter/common/model"
+	"gorm.io/gorm"
+	"strconv"
 )
 
 // 角色表，角色菜单关联表，角色账户关联表， 权限表，
@@ -11,16 +14,37 @@ func init() {
 	model.Register(&Role{}, &RoleMenu{}, &RoleAccount{}, &Upms{})
 }
 
+// 角色数据权限范围
+const (
+	DataScopeAll            = "all"              // 全部数据
+	DataScopeOrg            = "org"              // 本机构数据
+	DataScopeOrgAndChildren = "org_and_children" // 本机构及子机构数据
+	DataScopeSelf           = "self"             // 本人数据
+	DataScopeCustom         = "custom"           // 自定义机构数据
+)
+
 type Role struct {
 	model.Model
-	Name          string `json:"name" binding:"required,alphanum,max=32" gorm:"type:varchar(32);not null;unique;comment:角色"`
-	ApplicationId uint   `json:"applicationId" binding:"required,number" gorm:"type:int;not null;comment:应用" `
+	Name                string `json:"name" binding:"required,alphanum,max=32" gorm:"type:varchar(32);not null;uniqueIndex:idx_application_role;comment:角色" filter:"eq,in,like"`
+	ApplicationId       uint   `json:"applicationId" binding:"required,number" gorm:"type:int;not null;uniqueIndex:idx_application_role;comment:应用" filter:"eq,in"`
+	DataScope           string `json:"dataScope" binding:"omitempty,oneof=all org org_and_children self custom" gorm:"type:varchar(16);not null;default:self;comment:数据权限范围" filter:"eq,ne,in"`
+	DataOrganizationIds []uint `json:"dataOrganizationIds" binding:"omitempty,dive,gt=0" gorm:"type:varchar(1024);serializer:json;comment:自定义数据权限机构"`
+	RequireOtp          bool   `json:"requireOtp" binding:"boolean" gorm:"type:tinyint(1);not null;default:false;comment:是否强制两步验证" filter:"eq"`
 }
 
 func (r *Role) TableName() string {
 	return "ikubeops_portal_role"
 }
 
+// 角色名称变更会影响超级管理员判断，更新和删除后清除相关账号的权限缓存
+func (r *Role) AfterUpdate(tx *gorm.DB) error {
+	return clearRolePermissionCache(tx, r.ID)
+}
+
+func (r *Role) AfterDelete(tx *gorm.DB) error {
+	return clearRolePermissionCache(tx, r.ID)
+}
+
 type RoleMenu struct {
 	model.Model
 	RoleId uint `json:"roleId" binding:"required,number" gorm:"type:int;not null;uniqueIndex:idx_role_menu;comment:角色" `
@@ -41,6 +65,22 @@ func (r *RoleAccount) TableName() string {
 	return "ikubeops_portal_role_account"
 }
 
+// 角色分配变更后清除账号的权限缓存，在事务提交后执行
+func (r *RoleAccount) AfterCreate(tx *gorm.DB) error {
+	auth.ClearPermissionCacheAfterCommit(tx, r.AccountId)
+	return nil
+}
+
+func (r *RoleAccount) AfterDelete(tx *gorm.DB) error {
+	if r.AccountId == 0 {
+		// 按条件批量删除时无法确定账号，清除全部缓存
+		auth.ClearAllPermissionCacheAfterCommit(tx)
+		return nil
+	}
+	auth.ClearPermissionCacheAfterCommit(tx, r.AccountId)
+	return nil
+}
+
 type Upms struct {
 	model.Model
 	Name     string     `json:"name" binding:"required,max=32" gorm:"type:varchar(32);not null;unique;comment:权限名称"`
@@ -53,6 +93,30 @@ func (u *Upms) TableName() string {
 	return "ikubeops_portal_upms"
 }
 
+// 权限变更后清除角色下所有账号的权限缓存
+func (u *Upms) AfterSave(tx *gorm.DB) error {
+	return clearRolePermissionCache(tx, u.RoleId)
+}
+
+func (u *Upms) AfterDelete(tx *gorm.DB) error {
+	return clearRolePermissionCache(tx, u.RoleId)
+}
+
+// clearRolePermissionCache 事务提交后清除角色下所有账号的权限缓存，无法确定角色时清除全部缓存
+func clearRolePermissionCache(tx *gorm.DB, roleId uint) error {
+	if roleId == 0 {
+		auth.ClearAllPermissionCacheAfterCommit(tx)
+		return nil
+	}
+	var accountIds []uint
+	if err := tx.Session(&gorm.Session{NewDB: true}).Model(&RoleAccount{}).
+		Where("role_id = ?", roleId).Pluck("account_id", &accountIds).Error; err != nil {
+		return err
+	}
+	auth.ClearPermissionCacheAfterCommit(tx, accountIds...)
+	return nil
+}
+
 // ActionType  定义 ActionType 类型
 type ActionType uint
 
@@ -73,17 +137,26 @@ func (a ActionType) String() string {
 	}
 }
 
-// Scan 实现  接口
+// Scan 实现 Scanner 接口，不同驱动返回的整数类型不一致，需要分别处理
 func (a *ActionType) Scan(value interface{}) error {
-	v, ok := value.(uint)
-	if !ok {
+	switch v := value.(type) {
+	case int64:
+		*a = ActionType(v)
+	case uint:
+		*a = ActionType(v)
+	case []byte:
+		n, err := strconv.ParseUint(string(v), 10, 8)
+		if err != nil {
+			return fmt.Errorf("invalid value for ActionType: %s", v)
+		}
+		*a = ActionType(n)
+	default:
 		return fmt.Errorf("invalid value for ActionType: %v", value)
 	}
-	*a = ActionType(v)
 	return nil
 }
 
-// Value 实现 Valuer 接口
+// Value 实现 Valuer 接口，driver.Value 不支持 uint，需要转换为 int64
 func (a ActionType) Value() (driver.Value, error) {
-	return uint(a), nil
+	return int64(a), nil
 }
@@ -2,7 +2,9 @@ package model
 
 import (
 	"fmt"
+	"github.com/yanshicheng/ikube-gin-starter/common/auth"
 	"github.com/yanshicheng/ikube-gin-starter/common/model"
+	"github.com/yanshicheng/ikube-gin-starter/global"
 	"gorm.io/gorm"
 	"time"
 )
@@ -11,25 +13,29 @@ import (
 
 func init() {
 	model.Register(&Account{}, &Organization{})
+	model.RegisterTree(&Organization{})
 }
 
 const OrganizationLevel = 5
 
 type Account struct {
 	model.Model
-	UserName       string    `json:"userName" binding:"required,max=32" gorm:"type:varchar(32);not null;comment:姓名"`
-	Account        string    `json:"account" binding:"required,max=32" gorm:"type:varchar(32);unique_index;not null;comment:账号"`
-	Password       string    `json:"password" binding:"max=24" gorm:"type:varchar(256);not null;comment:密码"`
-	Icon           string    `json:"icon"  gorm:"type:varchar(256);not null;comment:头像"`
-	Mobile         string    `json:"mobile" binding:"required,max=11" gorm:"type:char(11);unique_index;not null;comment:手机号"`
-	Email          string    `json:"email" binding:"required,max=36,email" gorm:"type:varchar(36);unique_index;not null;comment:邮箱"`
-	WorkNumber     string    `json:"workNumber" binding:"required,max=24" gorm:"type:varchar(24);unique_index;not null;comment:工号"`
-	HireDate       time.Time `json:"hireDate" binding:"required" gorm:"type:date;not null;comment:入职时间"`
-	IsFrozen       bool      `json:"isFrozen" binding:"boolean" gorm:"type:tinyint(1);not null;default:false;comment:是否冻结"`
-	IsDisabled     bool      `json:"isDisabled" binding:"boolean" gorm:"type:tinyint(1);not null;default:false;comment:是否禁用"`
-	IsLeave        bool      `json:"isLeave" binding:"boolean" gorm:"type:tinyint(1);not null;default:false;comment:是否离职"`
-	Position       int       `json:"position" binding:"required,number" gorm:"type:int;not null;comment:职位"` // 对应职位表
-	OrganizationId uint      `json:"organizationId" binding:"required,number" gorm:"type:int;not null;comment:组织"`
+	UserName   string    `json:"userName" binding:"required,max=32" gorm:"type:varchar(32);not null;comment:姓名" filter:"eq,like"`
+	Account    string    `json:"account" binding:"required,max=32" gorm:"type:varchar(32);unique_index;not null;comment:账号" filter:"eq,in,like"`
+	Password   string    `json:"-" gorm:"type:varchar(256);not null;comment:密码"` // bcrypt 密文，不允许输出
+	Icon       string    `json:"icon"  gorm:"type:varchar(256);not null;comment:头像"`
+	Mobile     string    `json:"mobile" binding:"required,max=11" gorm:"type:char(11);unique_index;not null;comment:手机号"`
+	Email      string    `json:"email" binding:"required,max=36,email" gorm:"type:varchar(36);unique_index;not null;comment:邮箱" filter:"eq,like"`
+	WorkNumber string    `json:"workNumber" binding:"required,max=24" gorm:"type:varchar(24);unique_index;not null;comment:工号" filter:"eq,in"`
+	HireDate   time.Time `json:"hireDate" binding:"required" gorm:"type:date;not null;comment:入职时间" filter:"gt,gte,lt,lte,between"`
+	IsFrozen   bool      `json:"isFrozen" binding:"boolean" gorm:"type:tinyint(1);not null;default:false;comment:是否冻结" filter:"eq"`
+	IsDisabled bool      `json:"isDisabled" binding:"boolean" gorm:"type:tinyint(1);not null;default:false;comment:是否禁用" filter:"eq"`
+	IsLeave    bool      `json:"isLeave" binding:"boolean" gorm:"type:tinyint(1);not null;default:false;comment:是否离职" filter:"eq"`
+	// 管理员重置密码后需要用户登录时先修改密码
+	MustChangePassword bool       `json:"mustChangePassword" gorm:"type:tinyint(1);not null;default:false;comment:是否需要修改密码"`
+	PasswordChangedAt  *time.Time `json:"passwordChangedAt" gorm:"comment:密码修改时间" filter:"gt,gte,lt,lte,between,isnull"`
+	Position           int        `json:"position" binding:"required,number" gorm:"type:int;not null;comment:职位" filter:"eq,ne,in"` // 对应职位表
+	OrganizationId     uint       `json:"organizationId" binding:"required,number" gorm:"type:int;not null;comment:组织" filter:"eq,ne,in"`
 }
 
 // 定义表名
@@ -37,11 +43,52 @@ func (u *Account) TableName() string {
 	return "ikubeops_portal_account"
 }
 
+// DataScopeColumns 账号按所属机构过滤，本人数据即账号自身
+func (u *Account) DataScopeColumns() (string, string) {
+	return "organization_id", "id"
+}
+
+const (
+	// SkipSessionRevoke 通过 tx.Set 设置后，账号状态变更不强制下线，用于试运行等会回滚的事务
+	SkipSessionRevoke    = "portal:account:skip_session_revoke"
+	accountStatusChanged = "portal:account:status_changed"
+)
+
+// 冻结、禁用、离职的账号立即强制下线，删除全部会话，无论状态由哪个接口或同步任务修改
+func (u *Account) BeforeUpdate(tx *gorm.DB) error {
+	// 钩子中的 tx 为 NewDB 会话，InstanceSet 会复制 Statement，这里直接写入当前 Statement 的设置
+	if tx.Statement.Changed("IsFrozen", "IsDisabled", "IsLeave") {
+		tx.Statement.Settings.Store(statusChangedKey(tx), true)
+	}
+	return nil
+}
+
+func (u *Account) AfterUpdate(tx *gorm.DB) error {
+	if _, ok := tx.Statement.Settings.LoadAndDelete(statusChangedKey(tx)); !ok || u.ID == 0 {
+		return nil
+	}
+	if !u.IsFrozen && !u.IsDisabled && !u.IsLeave {
+		return nil
+	}
+	if skip, ok := tx.Get(SkipSessionRevoke); ok && skip.(bool) {
+		return nil
+	}
+	if global.RDB == nil || global.J == nil {
+		return nil
+	}
+	return auth.RevokeAccount(tx.Statement.Context, u.ID)
+}
+
+func statusChangedKey(tx *gorm.DB) string {
+	return fmt.Sprintf("%p%s", tx.Statement, accountStatusChanged)
+}
+
 type Organization struct {
 	model.Model
-	Name     string          `json:"name" binding:"required,max=32" gorm:"type:varchar(32);ngit ot null;comment:团队"`
-	ParentId uint            `json:"parentId" binding:"number" gorm:"type:int;not null;comment:父级"`
-	Level    int             `json:"level" gorm:"type:int;not null;comment:层级"`
+	model.TreePath
+	Name     string          `json:"name" binding:"required,max=32" gorm:"type:varchar(32);ngit ot null;comment:团队" filter:"eq,ne,in,like"`
+	ParentId uint            `json:"parentId" binding:"number" gorm:"type:int;not null;comment:父级" filter:"eq,in"`
+	Level    int             `json:"level" gorm:"type:int;not null;comment:层级" filter:"eq,lt,lte,gt,gte"`
 	Desc     string          `json:"Desc" gorm:"type:varchar(56);not null;comment:描述"`
 	Children []*Organization `gorm:"-"` // 使用指针类型存储子组织
 }
@@ -50,6 +97,22 @@ func (u *Organization) TableName() string {
 	return "ikubeops_portal_organization"
 }
 
+// DataScopeColumns 机构按自身ID过滤
+func (u *Organization) DataScopeColumns() (string, string) {
+	return "id", ""
+}
+
+// 机构变更会影响数据权限中子机构的范围，创建和删除的事务提交后清除全部权限缓存
+func (o *Organization) AfterCreate(tx *gorm.DB) error {
+	auth.ClearAllPermissionCacheAfterCommit(tx)
+	return nil
+}
+
+func (o *Organization) AfterDelete(tx *gorm.DB) error {
+	auth.ClearAllPermissionCacheAfterCommit(tx)
+	return nil
+}
+
 // 机构表 创建钩子函数
 func (o *Organization) BeforeCreate(tx *gorm.DB) error {
 	// 检查是否有父节点，如果没有父节点，则为根节点
@@ -65,8 +128,9 @@ func (o *Organization) BeforeCreate(tx *gorm.DB) error {
 			return err // 返回错误，中断创建操作
 		}
 
-		// 如果父节点查询成功，设置当前节点的层级为父节点层级 + 1
+		// 如果父节点查询成功，设置当前节点的层级为父节点层级 + 1，祖先路径为父节点路径加父节点ID
 		o.Level = parent.Level + 1
+		o.SetParent(&parent.TreePath, parent.ID)
 
 		// 检查层级是否超过5
 		if o.Level > OrganizationLevel {
@@ -76,39 +140,37 @@ func (o *Organization) BeforeCreate(tx *gorm.DB) error {
 	} else {
 		// 如果 ParentID 为0，说明此节点没有父节点，即它是一个根节点
 		o.Level = 1 // 设置根节点的层级为1
+		o.SetParent(nil, 0)
 	}
 	// 如果所有检查都通过，没有错误，则返回 nil，允许创建操作继续进行
 	return nil
 }
 
+// GetAllDescendants 查询所有子孙节点并构建为树，org 为根节点时返回整棵机构树。
+// 子孙节点通过祖先路径一次查询得到。
 func (org *Organization) GetAllDescendants(db *gorm.DB) ([]*Organization, error) {
+	query := db
+	if org.ID != 0 {
+		query = query.Scopes(model.Descendants(org.TreePath, org.ID))
+	}
 	var allOrgs []*Organization
-	err := db.Find(&allOrgs).Error
-	if err != nil {
+	if err := query.Order("level ASC").Order("id ASC").Find(&allOrgs).Error; err != nil {
 		return nil, err
 	}
 
-	orgMap := make(map[uint]*Organization)
-	var rootOrgs []*Organization
-
-	// 将所有组织存入 map，便于快速查找
+	orgMap := make(map[uint]*Organization, len(allOrgs))
 	for _, o := range allOrgs {
 		orgMap[o.ID] = o
 	}
 
-	// 构建树形结构
+	// 构建树形结构，按层级排序保证父节点先于子节点出现，父节点不在结果中（如被数据权限过滤）的节点作为根节点
+	var rootOrgs []*Organization
 	for _, o := range allOrgs {
-		if o.ParentId == 0 {
-			rootOrgs = append(rootOrgs, o)
-		} else {
-			parent := orgMap[uint(o.ParentId)]
+		if parent, ok := orgMap[o.ParentId]; ok {
 			parent.Children = append(parent.Children, o)
+		} else {
+			rootOrgs = append(rootOrgs, o)
 		}
 	}
-
-	if org.ID == 0 {
-		return rootOrgs, nil
-	} else {
-		return orgMap[uint(org.ID)].Children, nil
-	}
+	return rootOrgs, nil
 }
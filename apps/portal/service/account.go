@@ -0,0 +1,20 @@
+package service
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/model"
+	otypes "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/types"
+)
+
+type AccountService interface {
+	Get(*gin.Context, types.SearchId) (*model.Account, error)
+	List(*gin.Context, otypes.AccountSearch) (*types.QueryResponse, error)
+	Create(*gin.Context, otypes.AccountCreateRequest) (*model.Account, error)
+	Put(*gin.Context, types.SearchId, otypes.AccountUpdateRequest) (*model.Account, error)
+	Delete(*gin.Context, types.SearchId) error
+	ResetPassword(*gin.Context, types.SearchId, otypes.AccountPasswordResetRequest) (*model.Account, error)
+	Freeze(*gin.Context, types.SearchId, bool) (*model.Account, error)
+	Disable(*gin.Context, types.SearchId, bool) (*model.Account, error)
+	Leave(*gin.Context, types.SearchId, bool) (*model.Account, error)
+}
@@ -0,0 +1,20 @@
+package service
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/model"
+	otypes "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/types"
+)
+
+type ApplicationService interface {
+	Get(*gin.Context, types.SearchId) (*model.Application, error)
+	List(*gin.Context, otypes.ApplicationSearch) (*types.QueryResponse, error)
+	Mine(*gin.Context) ([]model.Application, error)
+	Create(*gin.Context, *model.Application) error
+	Put(*gin.Context, types.SearchId, *model.Application) (*model.Application, error)
+	Delete(*gin.Context, types.SearchId) error
+	Accounts(*gin.Context, types.SearchId, otypes.ApplicationAccountSearch) (*types.QueryResponse, error)
+	Grant(*gin.Context, types.SearchId, otypes.ApplicationAccountRequest) error
+	Revoke(*gin.Context, types.SearchId, otypes.ApplicationAccountRequest) error
+}
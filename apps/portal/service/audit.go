@@ -0,0 +1,13 @@
+package service
+
+import (
+	"github.com/gin-gonic/gin"
+	atypes "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/audit"
+	"github.com/yanshicheng/ikube-gin-starter/common/types"
+)
+
+type AuditService interface {
+	List(*gin.Context, atypes.AuditSearch) (*types.QueryResponse, error)
+	Get(*gin.Context, types.SearchId) (*audit.Log, error)
+}
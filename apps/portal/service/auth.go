@@ -0,0 +1,18 @@
+package service
+
+import (
+	"github.com/gin-gonic/gin"
+	otypes "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/types"
+)
+
+type AuthService interface {
+	Login(*gin.Context, otypes.LoginRequest) (*otypes.TokenResponse, error)
+	Refresh(*gin.Context, otypes.RefreshRequest) (*otypes.TokenResponse, error)
+	Logout(*gin.Context, otypes.LogoutRequest) error
+	Revoke(*gin.Context, types.SearchId) error
+	ChangePassword(*gin.Context, otypes.PasswordChangeRequest) error
+	ExpiredPassword(*gin.Context, otypes.PasswordExpiredRequest) error
+	OidcLogin(*gin.Context) (*otypes.OidcLoginResponse, error)
+	OidcCallback(*gin.Context, otypes.OidcCallbackRequest) (*otypes.TokenResponse, error)
+}
@@ -0,0 +1,11 @@
+package service
+
+import (
+	"context"
+
+	otypes "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+)
+
+type LdapSyncService interface {
+	Sync(ctx context.Context, dryRun bool) (*otypes.LdapSyncReport, error)
+}
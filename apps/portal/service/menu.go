@@ -0,0 +1,17 @@
+package service
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/model"
+	otypes "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/types"
+)
+
+type MenuService interface {
+	Get(*gin.Context, types.SearchId) (*model.Menu, error)
+	List(*gin.Context, otypes.MenuSearch) ([]*model.Menu, error)
+	Mine(*gin.Context, otypes.MenuSearch) ([]*otypes.MenuRoute, error)
+	Create(*gin.Context, *model.Menu) error
+	Put(*gin.Context, types.SearchId, *model.Menu) (*model.Menu, error)
+	Delete(*gin.Context, types.SearchId) error
+}
@@ -9,8 +9,9 @@ import (
 
 type OrganizationService interface {
 	Get(*gin.Context, otypes.OrganizationSearch) ([]*model.Organization, error)
-	List(*gin.Context, otypes.OrganizationSearch) ([]*model.Organization, error)
+	List(*gin.Context, types.Filters) ([]*model.Organization, error)
 	Create(*gin.Context, *model.Organization) error
 	Put(*gin.Context, types.SearchId, *model.Organization) (*model.Organization, error)
+	Move(*gin.Context, types.SearchId, otypes.OrganizationMoveRequest) (*model.Organization, error)
 	Delete(*gin.Context, types.SearchId) error
 }
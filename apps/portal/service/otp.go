@@ -0,0 +1,18 @@
+package service
+
+import (
+	"github.com/gin-gonic/gin"
+	otypes "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/types"
+)
+
+type OtpService interface {
+	Status(*gin.Context) (*otypes.OtpStatusResponse, error)
+	Enroll(*gin.Context) (*otypes.OtpEnrollResponse, error)
+	Enable(*gin.Context, otypes.OtpCodeRequest) (*otypes.OtpRecoveryCodesResponse, error)
+	Disable(*gin.Context, otypes.OtpCodeRequest) error
+	RecoveryCodes(*gin.Context, otypes.OtpCodeRequest) (*otypes.OtpRecoveryCodesResponse, error)
+	Reset(*gin.Context, types.SearchId) error
+	LoginEnroll(*gin.Context, otypes.OtpChallengeRequest) (*otypes.OtpEnrollResponse, error)
+	Login(*gin.Context, otypes.OtpLoginRequest) (*otypes.TokenResponse, error)
+}
@@ -0,0 +1,21 @@
+package service
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/model"
+	otypes "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/types"
+)
+
+type RoleService interface {
+	Get(*gin.Context, types.SearchId) (*model.Role, error)
+	List(*gin.Context, otypes.RoleSearch) (*types.QueryResponse, error)
+	Create(*gin.Context, *model.Role) error
+	Put(*gin.Context, types.SearchId, *model.Role) (*model.Role, error)
+	Delete(*gin.Context, types.SearchId) error
+	Menus(*gin.Context, types.SearchId) ([]uint, error)
+	PutMenus(*gin.Context, types.SearchId, otypes.RoleMenuRequest) ([]uint, error)
+	Accounts(*gin.Context, types.SearchId) ([]uint, error)
+	PutAccounts(*gin.Context, types.SearchId, otypes.RoleAccountRequest) ([]uint, error)
+	AccountRoles(*gin.Context, types.SearchId, otypes.AccountRoleSearch) ([]model.Role, error)
+}
@@ -0,0 +1,20 @@
+package service
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/model"
+	otypes "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/types"
+)
+
+type ServiceAccountService interface {
+	Get(*gin.Context, types.SearchId) (*model.ServiceAccount, error)
+	List(*gin.Context, otypes.ServiceAccountSearch) (*types.QueryResponse, error)
+	Create(*gin.Context, *model.ServiceAccount) error
+	Put(*gin.Context, types.SearchId, *model.ServiceAccount) (*model.ServiceAccount, error)
+	Delete(*gin.Context, types.SearchId) error
+	Keys(*gin.Context, types.SearchId) ([]model.ApiKey, error)
+	IssueKey(*gin.Context, types.SearchId, otypes.ApiKeyCreateRequest) (*otypes.ApiKeyIssueResponse, error)
+	RotateKey(*gin.Context, otypes.ApiKeySearchId) (*otypes.ApiKeyIssueResponse, error)
+	RevokeKey(*gin.Context, otypes.ApiKeySearchId) error
+}
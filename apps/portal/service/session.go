@@ -0,0 +1,16 @@
+package service
+
+import (
+	"github.com/gin-gonic/gin"
+	stypes "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/auth"
+	"github.com/yanshicheng/ikube-gin-starter/common/types"
+)
+
+type SessionService interface {
+	Mine(*gin.Context) ([]auth.Session, error)
+	RevokeMine(*gin.Context, stypes.SessionSearchId) error
+	RevokeMineOthers(*gin.Context) (*stypes.SessionRevokeResponse, error)
+	List(*gin.Context, types.SearchId) ([]auth.Session, error)
+	RevokeAll(*gin.Context, types.SearchId) (*stypes.SessionRevokeResponse, error)
+}
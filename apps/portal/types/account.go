@@ -0,0 +1,53 @@
+package types
+
+import (
+	"time"
+
+	"github.com/yanshicheng/ikube-gin-starter/common/types"
+)
+
+type AccountCreateRequest struct {
+	UserName       string    `json:"userName" binding:"required,max=32"`
+	Account        string    `json:"account" binding:"required,max=32"`
+	Password       string    `json:"password" binding:"required,password_length,password_class,password_account=Account"`
+	Icon           string    `json:"icon" binding:"max=256"`
+	Mobile         string    `json:"mobile" binding:"required,max=11"`
+	Email          string    `json:"email" binding:"required,max=36,email"`
+	WorkNumber     string    `json:"workNumber" binding:"required,max=24"`
+	HireDate       time.Time `json:"hireDate" binding:"required"`
+	Position       int       `json:"position" binding:"required,number"`
+	OrganizationId uint      `json:"organizationId" binding:"required,number"`
+}
+
+type AccountUpdateRequest struct {
+	UserName       string    `json:"userName" binding:"required,max=32"`
+	Icon           string    `json:"icon" binding:"max=256"`
+	Mobile         string    `json:"mobile" binding:"required,max=11"`
+	Email          string    `json:"email" binding:"required,max=36,email"`
+	WorkNumber     string    `json:"workNumber" binding:"required,max=24"`
+	HireDate       time.Time `json:"hireDate" binding:"required"`
+	Position       int       `json:"position" binding:"required,number"`
+	OrganizationId uint      `json:"organizationId" binding:"required,number"`
+}
+
+type AccountSearch struct {
+	Account        string `json:"account" form:"account"`
+	UserName       string `json:"userName" form:"userName"`
+	OrganizationId uint   `json:"organizationId" form:"organizationId"`
+	IsFrozen       *bool  `json:"isFrozen" form:"isFrozen"`
+	IsDisabled     *bool  `json:"isDisabled" form:"isDisabled"`
+	IsLeave        *bool  `json:"isLeave" form:"isLeave"`
+	types.Pagination
+	types.Filters
+}
+
+// AccountPasswordResetRequest 管理员重置密码，Account 由被重置的账号填充，用于校验密码不能包含账号
+type AccountPasswordResetRequest struct {
+	Account  string `json:"-"`
+	Password string `json:"password" binding:"required,password_length,password_class,password_account=Account"`
+}
+
+// AccountStatusRequest 冻结、禁用、离职操作的请求体
+type AccountStatusRequest struct {
+	Value *bool `json:"value" binding:"required"`
+}
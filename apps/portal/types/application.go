@@ -0,0 +1,20 @@
+package types
+
+import "github.com/yanshicheng/ikube-gin-starter/common/types"
+
+type ApplicationSearch struct {
+	Name string `json:"name" form:"name"`
+	types.Pagination
+	types.Filters
+}
+
+// ApplicationAccountSearch 查询应用已授权的账号
+type ApplicationAccountSearch struct {
+	Account string `json:"account" form:"account"`
+	types.Pagination
+}
+
+// ApplicationAccountRequest 授权或撤销账号访问应用的请求体
+type ApplicationAccountRequest struct {
+	AccountIds []uint `json:"accountIds" binding:"required,min=1,dive,gt=0"`
+}
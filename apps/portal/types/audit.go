@@ -0,0 +1,22 @@
+package types
+
+import (
+	"time"
+
+	"github.com/yanshicheng/ikube-gin-starter/common/types"
+)
+
+// AuditSearch 审计日志查询条件，时间格式为 RFC3339
+type AuditSearch struct {
+	AccountId  uint       `json:"accountId" form:"accountId"`
+	Account    string     `json:"account" form:"account"`
+	Action     string     `json:"action" form:"action" binding:"omitempty,oneof=create update delete"`
+	EntityType string     `json:"entityType" form:"entityType"`
+	EntityId   string     `json:"entityId" form:"entityId"`
+	Route      string     `json:"route" form:"route"`
+	Ip         string     `json:"ip" form:"ip"`
+	Start      *time.Time `json:"start" form:"start"`
+	End        *time.Time `json:"end" form:"end"`
+	types.Pagination
+	types.Filters
+}
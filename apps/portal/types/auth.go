@@ -0,0 +1,52 @@
+package types
+
+type LoginRequest struct {
+	Account  string `json:"account" binding:"required,max=32"`
+	Password string `json:"password" binding:"required,max=72"`
+}
+
+// PasswordChangeRequest 修改当前登录账号的密码，Account 由登录信息填充，用于校验新密码不能包含账号
+type PasswordChangeRequest struct {
+	Account     string `json:"-"`
+	OldPassword string `json:"oldPassword" binding:"required,max=72"`
+	NewPassword string `json:"newPassword" binding:"required,password_length,password_class,password_account=Account"`
+}
+
+// PasswordExpiredRequest 密码过期或被管理员重置后，登录前修改密码。
+// 账号需要两步验证时第一次请求返回验证凭据，再次请求时携带凭据和一次性密码
+type PasswordExpiredRequest struct {
+	Account     string `json:"account" binding:"required,max=32"`
+	OldPassword string `json:"oldPassword" binding:"required,max=72"`
+	NewPassword string `json:"newPassword" binding:"required,password_length,password_class,password_account=Account"`
+	Challenge   string `json:"challenge"`
+	Code        string `json:"code" binding:"required_with=Challenge,max=16"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+type TokenResponse struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	TokenType    string `json:"tokenType"`
+	ExpiresIn    int64  `json:"expiresIn"` // access token 有效期，单位 s
+	// 登录时完成两步验证绑定才会返回，只返回一次
+	RecoveryCodes []string `json:"recoveryCodes,omitempty"`
+}
+
+// OidcLoginResponse 单点登录授权地址，前端跳转到 AuthUrl 完成登录
+type OidcLoginResponse struct {
+	AuthUrl string `json:"authUrl"`
+	State   string `json:"state"`
+}
+
+// OidcCallbackRequest 身份提供方回调携带的授权码和 state
+type OidcCallbackRequest struct {
+	Code  string `json:"code" form:"code" binding:"required"`
+	State string `json:"state" form:"state" binding:"required"`
+}
@@ -0,0 +1,20 @@
+package types
+
+type LdapSyncRequest struct {
+	DryRun bool `json:"dryRun" form:"dryRun"`
+}
+
+// LdapSyncItem 一条同步变更，Action 为 create、update、link、missing 或 skip
+type LdapSyncItem struct {
+	Action  string   `json:"action"`
+	Dn      string   `json:"dn"`
+	Name    string   `json:"name"`
+	Changes []string `json:"changes,omitempty"`
+}
+
+// LdapSyncReport 目录同步报告，DryRun 为 true 时只计算差异，不写入数据库
+type LdapSyncReport struct {
+	DryRun        bool           `json:"dryRun"`
+	Organizations []LdapSyncItem `json:"organizations"`
+	Accounts      []LdapSyncItem `json:"accounts"`
+}
@@ -0,0 +1,28 @@
+package types
+
+type MenuSearch struct {
+	ApplicationId uint `json:"applicationId" form:"applicationId" binding:"required,number"`
+}
+
+// MenuRoute 前端路由结构，与 vue-router 的 RouteRecordRaw 保持一致
+type MenuRoute struct {
+	Path      string       `json:"path"`
+	Name      string       `json:"name"`
+	Component string       `json:"component"`
+	Redirect  string       `json:"redirect,omitempty"`
+	Meta      MenuMeta     `json:"meta"`
+	Children  []*MenuRoute `json:"children,omitempty"`
+}
+
+type MenuMeta struct {
+	Title            string `json:"title"`
+	Icon             string `json:"icon,omitempty"`
+	Expanded         bool   `json:"expanded"`
+	OrderNo          int    `json:"orderNo"`
+	Hidden           bool   `json:"hidden"`
+	HiddenBreadcrumb bool   `json:"hiddenBreadcrumb"`
+	Single           bool   `json:"single"`
+	FrameSrc         string `json:"frameSrc,omitempty"`
+	FrameBlank       bool   `json:"frameBlank"`
+	KeepAlive        bool   `json:"keepAlive"`
+}
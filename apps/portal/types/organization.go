@@ -3,3 +3,8 @@ package types
 type OrganizationSearch struct {
 	Name string `json:"name" form:"name" uri:"name" `
 }
+
+// OrganizationMoveRequest 移动机构的请求体，ParentId 为 0 表示移动为根节点
+type OrganizationMoveRequest struct {
+	ParentId *uint `json:"parentId" binding:"required"`
+}
@@ -0,0 +1,45 @@
+package types
+
+import "time"
+
+// OtpStatusResponse 当前账号的两步验证状态
+type OtpStatusResponse struct {
+	Enabled       bool       `json:"enabled"`
+	Enforced      bool       `json:"enforced"` // 所属角色要求启用两步验证
+	EnabledAt     *time.Time `json:"enabledAt"`
+	RecoveryCodes int64      `json:"recoveryCodes"` // 剩余可用的恢复码数量
+}
+
+// OtpEnrollResponse 绑定验证器应用使用的密钥和 otpauth 地址
+type OtpEnrollResponse struct {
+	Secret string `json:"secret"`
+	Uri    string `json:"uri"`
+}
+
+// OtpCodeRequest 验证器应用生成的一次性密码，已启用时也可以使用恢复码
+type OtpCodeRequest struct {
+	Code string `json:"code" binding:"required,max=16"`
+}
+
+// OtpRecoveryCodesResponse 新生成的恢复码，只返回一次
+type OtpRecoveryCodesResponse struct {
+	Codes []string `json:"codes"`
+}
+
+// OtpChallengeResponse 密码校验通过后返回的两步验证凭据，Enrolled 为 false 表示角色要求启用但尚未绑定
+type OtpChallengeResponse struct {
+	Challenge string `json:"challenge"`
+	Enrolled  bool   `json:"enrolled"`
+	ExpiresIn int64  `json:"expiresIn"` // 单位 s
+}
+
+// OtpChallengeRequest 使用两步验证凭据绑定验证器应用
+type OtpChallengeRequest struct {
+	Challenge string `json:"challenge" binding:"required"`
+}
+
+// OtpLoginRequest 两步登录的第二步
+type OtpLoginRequest struct {
+	Challenge string `json:"challenge" binding:"required"`
+	Code      string `json:"code" binding:"required,max=16"`
+}
@@ -0,0 +1,25 @@
+package types
+
+import "github.com/yanshicheng/ikube-gin-starter/common/types"
+
+type RoleSearch struct {
+	Name          string `json:"name" form:"name"`
+	ApplicationId uint   `json:"applicationId" form:"applicationId"`
+	types.Pagination
+	types.Filters
+}
+
+// RoleMenuRequest 替换角色菜单的请求体，传空数组表示清空
+type RoleMenuRequest struct {
+	MenuIds []uint `json:"menuIds" binding:"required,dive,gt=0"`
+}
+
+// RoleAccountRequest 替换角色账号的请求体，传空数组表示清空
+type RoleAccountRequest struct {
+	AccountIds []uint `json:"accountIds" binding:"required,dive,gt=0"`
+}
+
+// AccountRoleSearch 查询账号生效的角色，ApplicationId 为空时返回全部应用下的角色
+type AccountRoleSearch struct {
+	ApplicationId uint `json:"applicationId" form:"applicationId"`
+}
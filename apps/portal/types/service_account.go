@@ -0,0 +1,37 @@
+package types
+
+import (
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/model"
+	"github.com/yanshicheng/ikube-gin-starter/common/types"
+)
+
+type ServiceAccountSearch struct {
+	Name string `json:"name" form:"name"`
+	types.Pagination
+	types.Filters
+}
+
+// ApiKeySearchId api key 路径参数
+type ApiKeySearchId struct {
+	Id    uint `json:"id" uri:"id" binding:"required,number"`
+	KeyId uint `json:"keyId" uri:"keyId" binding:"required,number"`
+}
+
+// ApiKeyScope api key 授权范围，Resource 为路由模式，支持以 * 结尾的前缀匹配
+type ApiKeyScope struct {
+	Resource string `json:"resource" binding:"required,max=255"`
+	Action   string `json:"action" binding:"required,oneof=read write"`
+}
+
+// ApiKeyCreateRequest 签发 api key 的请求体，ExpiresIn 为有效期，单位 s，0 表示永不过期
+type ApiKeyCreateRequest struct {
+	Name      string        `json:"name" binding:"required,max=32"`
+	Scopes    []ApiKeyScope `json:"scopes" binding:"required,min=1,dive"`
+	ExpiresIn int           `json:"expiresIn" binding:"min=0"`
+}
+
+// ApiKeyIssueResponse 签发或轮换 api key 的响应，Key 只返回这一次
+type ApiKeyIssueResponse struct {
+	model.ApiKey
+	Key string `json:"key"`
+}
@@ -0,0 +1,11 @@
+package types
+
+// SessionSearchId 会话ID
+type SessionSearchId struct {
+	SessionId string `uri:"sessionId" binding:"required,max=64"`
+}
+
+// SessionRevokeResponse 批量下线的会话数量
+type SessionRevokeResponse struct {
+	Count int `json:"count"`
+}
@@ -4,10 +4,13 @@ import (
 	"fmt"
 	"github.com/spf13/cobra"
 	_ "github.com/yanshicheng/ikube-gin-starter/apps/all"
+	portalmodel "github.com/yanshicheng/ikube-gin-starter/apps/portal/model"
+	"github.com/yanshicheng/ikube-gin-starter/common/model"
 	"github.com/yanshicheng/ikube-gin-starter/global"
 	"github.com/yanshicheng/ikube-gin-starter/pkg/config"
 	"github.com/yanshicheng/ikube-gin-starter/pkg/logger"
 	"github.com/yanshicheng/ikube-gin-starter/pkg/mysql"
+	"gorm.io/gorm"
 	"log"
 )
 
@@ -85,10 +88,23 @@ var dbCommand = &cobra.Command{
 		}(global.DB)
 		if global.DB != nil && len(global.M) > 0 {
 			db := global.DB.GetDb()
+			// 迁移前记录角色表是否缺少数据权限范围列，新增该列时已有角色不能使用新的默认值
+			role := &portalmodel.Role{}
+			legacyRoles := db.Migrator().HasTable(role) && !db.Migrator().HasColumn(role, "DataScope")
 			if err := db.AutoMigrate(global.M...); err != nil {
 				global.LSys.Error(fmt.Sprintf("数据库迁移失败: %s\n", err.Error()))
 			} else {
 				global.LSys.Info("数据库迁移成功")
+				if err := migrateRoleDataScope(db, legacyRoles); err != nil {
+					global.LSys.Error(fmt.Sprintf("迁移角色数据权限范围失败: %s", err.Error()))
+					return err
+				}
+				// 根据 parent_id 补齐已有数据的祖先路径
+				if err := model.RebuildTreePaths(db); err != nil {
+					global.LSys.Error(fmt.Sprintf("重建树形路径失败: %s", err.Error()))
+					return err
+				}
+				global.LSys.Info("重建树形路径成功")
 				return nil
 			}
 		} else {
@@ -98,6 +114,22 @@ var dbCommand = &cobra.Command{
 	},
 }
 
+// migrateRoleDataScope 角色数据权限范围默认为本人数据，升级前已有的角色不限制数据范围，
+// 迁移时显式设置为全部数据以保持原有权限；legacy 为 true 时表示该列由本次迁移新增，否则只迁移空值
+func migrateRoleDataScope(db *gorm.DB, legacy bool) error {
+	role := &portalmodel.Role{}
+	if !db.Migrator().HasTable(role) {
+		return nil
+	}
+	query := db.Unscoped().Model(role)
+	if legacy {
+		query = query.Where("1 = 1")
+	} else {
+		query = query.Where("data_scope = ?", "")
+	}
+	return query.Update("data_scope", portalmodel.DataScopeAll).Error
+}
+
 func init() {
 	rootCommand.AddCommand(dbCommand)
 	dbCommand.Flags().StringVarP(&db, "database", "d", "default", "database")
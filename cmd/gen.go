@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/gen"
+)
+
+var (
+	genDir    string
+	genFields []string
+)
+
+var genCommand = &cobra.Command{
+	Use:   "gen",
+	Short: "代码生成",
+	Long:  "代码生成",
+}
+
+var genAppCommand = &cobra.Command{
+	Use:   "app <name>",
+	Short: "生成应用脚手架",
+	Long: `按 book 应用的分层结构生成 app.go、model、service、logic、handler 和测试骨架，并在 apps/all 中引入新应用。
+增删改查使用通用实现，列表接口按字段类型允许 filter 参数过滤。
+字段格式为 name:type[:required]，类型可选 string, text, int, uint, float, bool, time, json，如:
+  gen app article --fields title:string:required,content:text,views:int`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		fields, err := gen.ParseFields(genFields)
+		if err != nil {
+			return err
+		}
+		module, err := gen.ModulePath(genDir)
+		if err != nil {
+			return err
+		}
+		app := gen.App{Module: module, Name: args[0], Fields: fields}
+		files, err := gen.Generate(genDir, app)
+		for _, f := range files {
+			fmt.Printf("生成文件: %s\n", f)
+		}
+		if err != nil {
+			return err
+		}
+		registered, err := gen.Register(genDir, app)
+		if err != nil {
+			return err
+		}
+		if registered {
+			fmt.Println("已在 apps/all 中引入应用")
+		}
+		fmt.Println("生成完成，执行 db 命令迁移数据表，执行 swag init 更新接口文档")
+		return nil
+	},
+}
+
+func init() {
+	rootCommand.AddCommand(genCommand)
+	genCommand.AddCommand(genAppCommand)
+	genAppCommand.Flags().StringVar(&genDir, "dir", ".", "项目根目录，需要包含 go.mod")
+	genAppCommand.Flags().StringSliceVar(&genFields, "fields", nil, "模型字段，格式为 name:type[:required]，多个字段用逗号分隔")
+	_ = genAppCommand.MarkFlagRequired("fields")
+}
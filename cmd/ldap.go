@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/yanshicheng/ikube-gin-starter/apps/portal/logic"
+	types2 "github.com/yanshicheng/ikube-gin-starter/apps/portal/types"
+	"github.com/yanshicheng/ikube-gin-starter/common/audit"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/config"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/jwt"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/logger"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/mysql"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/redis"
+)
+
+var (
+	ldapDryRun bool
+	ldapOutput string
+)
+
+var ldapCommand = &cobra.Command{
+	Use:   "ldap",
+	Short: "ldap 目录同步",
+	Long:  "ldap 目录同步",
+}
+
+var ldapSyncCommand = &cobra.Command{
+	Use:   "sync",
+	Short: "从 ldap 目录同步机构和账号",
+	Long:  "从 ldap 目录同步机构和账号，--dry-run 只输出差异报告，不写入数据库",
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		cmd.SilenceUsage = true
+		if ldapOutput != "table" && ldapOutput != "json" {
+			return fmt.Errorf("不支持的输出格式: %s", ldapOutput)
+		}
+		// 初始化全局变量
+		err = config.InitIkubeConfig(confFile, confType, global.C)
+		if err != nil {
+			log.Printf("初始化配置文件失败: %s", err)
+			return err
+		}
+
+		// 初始化日志
+		global.L, err = logger.InitIkubeLogger(
+			global.C.Logger.Output,
+			global.C.Logger.Output,
+			global.C.Logger.Level,
+			global.C.Logger.MaxFile,
+			global.C.Logger.Dev,
+			global.C.Logger.FilePath,
+			global.C.Logger.MaxSize,
+			global.C.Logger.MaxAge,
+			global.C.Logger.MaxBackups)
+		if err != nil {
+			log.Printf("初始化日志失败: %s", err)
+			return err
+		}
+		global.LSys = global.L.Named("system")
+
+		// 初始化数据库
+		if !global.C.Mysql.Enable {
+			global.LSys.Error("ldap 同步失败，未启用mysql配置")
+			return fmt.Errorf("未启用mysql配置")
+		}
+		global.DB, err = mysql.InitIkubeGorm(
+			fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?%s", global.C.Mysql.User, global.C.Mysql.Password, global.C.Mysql.Host, global.C.Mysql.Port, global.C.Mysql.DbName, global.C.Mysql.Opts),
+			global.C.Mysql.MaxIdleConns,
+			global.C.Mysql.MaxOpenConns,
+			global.C.Mysql.LogToFile,
+			global.C.Mysql.Level,
+		)
+		if err != nil {
+			global.LSys.Error(fmt.Sprintf("初始化数据库失败: %s", err))
+			return err
+		}
+		defer global.DB.Close()
+		if global.C.Audit.Enable {
+			if err = audit.RegisterCallbacks(global.DB.GetDb(), global.C.Audit.Exclude); err != nil {
+				global.LSys.Error(fmt.Sprintf("注册审计回调失败: %s", err))
+				return err
+			}
+		}
+
+		// redis 和 jwt 用于清除权限缓存和强制离职账号下线，未启用时跳过
+		if global.C.Redis.Enable {
+			global.RDB, err = redis.InitIkubeRedis(
+				fmt.Sprintf("%s:%d", global.C.Redis.Host, global.C.Redis.Port),
+				global.C.Redis.Password,
+				global.C.Redis.Db,
+				global.C.Redis.PoolSize,
+			)
+			if err != nil {
+				global.LSys.Error(fmt.Sprintf("Reids 初始化数据库失败: %s", err))
+				return err
+			}
+			global.J, err = jwt.InitIkubeJwt(
+				global.C.Jwt.SigningKey,
+				global.C.Jwt.SigningMethod,
+				global.C.Jwt.Issuer,
+				global.C.Jwt.AccessExpire,
+				global.C.Jwt.RefreshExpire,
+			)
+			if err != nil {
+				global.LSys.Error(fmt.Sprintf("Jwt 初始化失败: %s", err))
+				return err
+			}
+		}
+
+		ctx := audit.WithActor(context.Background(), &audit.Actor{Account: "ldap"})
+		report, err := logic.NewLdapSyncLogic(global.DB.GetDb(), global.L.Named("ldap")).Sync(ctx, ldapDryRun)
+		if err != nil {
+			return err
+		}
+		return printLdapReport(report, ldapOutput)
+	},
+}
+
+// printLdapReport 输出同步报告
+func printLdapReport(report *types2.LdapSyncReport, output string) error {
+	if output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TYPE\tACTION\tNAME\tDN\tCHANGES")
+	for _, item := range report.Organizations {
+		fmt.Fprintf(w, "organization\t%s\t%s\t%s\t%s\n", item.Action, item.Name, item.Dn, strings.Join(item.Changes, "; "))
+	}
+	for _, item := range report.Accounts {
+		fmt.Fprintf(w, "account\t%s\t%s\t%s\t%s\n", item.Action, item.Name, item.Dn, strings.Join(item.Changes, "; "))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if report.DryRun {
+		fmt.Println("dry run: 以上变更未写入数据库")
+	}
+	return nil
+}
+
+func init() {
+	rootCommand.AddCommand(ldapCommand)
+	ldapCommand.AddCommand(ldapSyncCommand)
+	ldapSyncCommand.Flags().BoolVar(&ldapDryRun, "dry-run", false, "只输出差异报告，不写入数据库")
+	ldapSyncCommand.Flags().StringVarP(&ldapOutput, "output", "o", "table", "输出格式 [table/json]")
+}
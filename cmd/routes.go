@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+	_ "github.com/yanshicheng/ikube-gin-starter/apps/all"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/config"
+	"github.com/yanshicheng/ikube-gin-starter/router"
+)
+
+var routesOutput string
+
+var routesCommand = &cobra.Command{
+	Use:   "routes",
+	Short: "输出全部路由",
+	Long:  "输出已启用应用注册的全部路由，包括方法、路径、权限资源、所在路由组、所属应用和处理函数，不启动服务",
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		cmd.SilenceUsage = true
+		if routesOutput != "table" && routesOutput != "json" && routesOutput != "csv" {
+			return fmt.Errorf("不支持的输出格式: %s", routesOutput)
+		}
+		// 初始化全局变量，应用开关、路由前缀和 API 前缀影响输出的路由
+		err = config.InitIkubeConfig(confFile, confType, global.C)
+		if err != nil {
+			log.Printf("初始化配置文件失败: %s", err)
+			return err
+		}
+		return router.WriteRoutes(os.Stdout, router.Routes(), routesOutput)
+	},
+}
+
+func init() {
+	rootCommand.AddCommand(routesCommand)
+	routesCommand.Flags().StringVarP(&routesOutput, "output", "o", "table", "输出格式 [table/json/csv]")
+}
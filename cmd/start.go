@@ -1,16 +1,20 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	ut "github.com/go-playground/universal-translator"
 	"github.com/spf13/cobra"
 	_ "github.com/yanshicheng/ikube-gin-starter/apps/all"
+	"github.com/yanshicheng/ikube-gin-starter/common/audit"
 	"github.com/yanshicheng/ikube-gin-starter/common/validator"
 	"github.com/yanshicheng/ikube-gin-starter/global"
 	"github.com/yanshicheng/ikube-gin-starter/pkg/config"
 	"github.com/yanshicheng/ikube-gin-starter/pkg/http"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/jwt"
 	"github.com/yanshicheng/ikube-gin-starter/pkg/logger"
 	"github.com/yanshicheng/ikube-gin-starter/pkg/mysql"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/oidc"
 	"github.com/yanshicheng/ikube-gin-starter/pkg/redis"
 	"github.com/yanshicheng/ikube-gin-starter/pkg/version"
 	"github.com/yanshicheng/ikube-gin-starter/router"
@@ -74,6 +78,13 @@ var serviceCmd = &cobra.Command{
 				}
 				global.LSys.Info("Mysql 数据库初始化成功!")
 			}
+			// 注册审计回调
+			if global.C.Audit.Enable {
+				if err = audit.RegisterCallbacks(global.DB.GetDb(), global.C.Audit.Exclude); err != nil {
+					global.LSys.Error(fmt.Sprintf("注册审计回调失败: %s", err))
+					return err
+				}
+			}
 		}
 
 		// 初始化 redis
@@ -95,6 +106,36 @@ var serviceCmd = &cobra.Command{
 			}
 		}
 
+		// 初始化 jwt
+		global.J, err = jwt.InitIkubeJwt(
+			global.C.Jwt.SigningKey,
+			global.C.Jwt.SigningMethod,
+			global.C.Jwt.Issuer,
+			global.C.Jwt.AccessExpire,
+			global.C.Jwt.RefreshExpire,
+		)
+		if err != nil {
+			global.LSys.Error(fmt.Sprintf("Jwt 初始化失败: %s", err))
+			return err
+		}
+		global.LSys.Info("Jwt 初始化成功!")
+
+		// 初始化 oidc
+		if global.C.Oidc.Enable {
+			global.O, err = oidc.InitIkubeOidc(
+				global.C.Oidc.Issuer,
+				global.C.Oidc.ClientId,
+				global.C.Oidc.ClientSecret,
+				global.C.Oidc.RedirectUrl,
+				global.C.Oidc.Scopes,
+			)
+			if err != nil {
+				global.LSys.Error(fmt.Sprintf("Oidc 初始化失败: %s", err))
+				return err
+			}
+			global.LSys.Info("Oidc 初始化成功!")
+		}
+
 		// 初始化Gin框架翻译器
 		var uni *ut.UniversalTranslator
 		if global.IkubeopsTrans, uni, err = validator.InitTrans(global.C.App.Language); err != nil {
@@ -113,7 +154,16 @@ var serviceCmd = &cobra.Command{
 		global.LSys.Info("验证器加载成功!")
 		// 启动服务
 		// 获取gin app 实例
-		businessRouter := router.InitGin()
+		businessRouter, err := router.InitGin()
+		if err != nil {
+			global.LSys.Error(fmt.Sprintf("初始化服务失败: %s", err))
+			return err
+		}
+		// 按依赖顺序启动服务的后台任务
+		if err = router.Start(context.Background()); err != nil {
+			global.LSys.Error(err.Error())
+			return err
+		}
 		// 初始化路由
 		healthRouter := router.HealthRouter()
 
@@ -129,6 +179,8 @@ var serviceCmd = &cobra.Command{
 			global.C.App.KeyFile)
 		serverManager.AddServer(fmt.Sprintf("%s:%d", global.C.App.HttpAddr, global.C.App.HttpPort), "business", businessRouter)
 		serverManager.AddServer(fmt.Sprintf("%s:%d", global.C.App.HttpAddr, global.C.App.HealthPort), "healthy", healthRouter)
+		// 服务器关闭后按启动的相反顺序停止服务
+		serverManager.AddShutdownHook(router.Stop)
 		serverManager.Run()
 		return nil
 
@@ -0,0 +1,321 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// 审计回调：修改和删除前按相同条件查询变更前的数据，操作完成后重新查询变更后的数据并对比，
+// 审计日志与业务数据在同一个事务中写入，写入失败时业务操作一并回滚。
+// 数据通过 json 序列化记录，json:"-" 的字段（如密码）不会出现在审计日志中。
+
+const snapshotKey = "audit:snapshot"
+
+// snapshot 修改和删除前的数据
+type snapshot struct {
+	ids  []interface{}                     // 主键值，用于重新查询变更后的数据
+	keys []string                          // 数据ID，保持查询顺序
+	rows map[string]map[string]interface{} // 按数据ID保存变更前的数据
+}
+
+type auditor struct {
+	exclude map[string]bool
+}
+
+// RegisterCallbacks 注册审计回调，exclude 中的表不记录审计日志
+func RegisterCallbacks(db *gorm.DB, exclude []string) error {
+	a := &auditor{exclude: map[string]bool{(&Log{}).TableName(): true}}
+	for _, table := range exclude {
+		a.exclude[table] = true
+	}
+	cb := db.Callback()
+	if err := cb.Create().After("gorm:after_create").Before("gorm:commit_or_rollback_transaction").
+		Register("audit:after_create", a.afterCreate); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:before_update").Before("gorm:update").
+		Register("audit:before_update", a.before); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:after_update").Before("gorm:commit_or_rollback_transaction").
+		Register("audit:after_update", a.afterUpdate); err != nil {
+		return err
+	}
+	if err := cb.Delete().After("gorm:before_delete").Before("gorm:delete").
+		Register("audit:before_delete", a.before); err != nil {
+		return err
+	}
+	if err := cb.Delete().After("gorm:after_delete").Before("gorm:commit_or_rollback_transaction").
+		Register("audit:after_delete", a.afterDelete); err != nil {
+		return err
+	}
+	return nil
+}
+
+// auditable 判断本次操作是否需要记录，只记录通过模型操作且未排除的表
+func (a *auditor) auditable(tx *gorm.DB) bool {
+	stmt := tx.Statement
+	return tx.Error == nil && !stmt.DryRun && stmt.Schema != nil &&
+		!a.exclude[stmt.Schema.Table] && !a.exclude[stmt.Table]
+}
+
+func (a *auditor) afterCreate(tx *gorm.DB) {
+	if !a.auditable(tx) {
+		return
+	}
+	stmt := tx.Statement
+	logs := make([]Log, 0)
+	for _, rv := range elements(stmt.ReflectValue) {
+		after, err := toMap(rv.Addr().Interface())
+		if err != nil {
+			tx.AddError(err)
+			return
+		}
+		logs = append(logs, newLog(tx, ActionCreate, entityId(stmt, rv), nil, after))
+	}
+	save(tx, logs)
+}
+
+// before 修改和删除前查询受影响的数据，没有任何条件的全表操作不记录
+func (a *auditor) before(tx *gorm.DB) {
+	if !a.auditable(tx) || tx.Statement.Schema.PrioritizedPrimaryField == nil {
+		return
+	}
+	stmt := tx.Statement
+	q := tx.Session(&gorm.Session{NewDB: true, SkipHooks: true}).Table(stmt.Table)
+	if stmt.Unscoped {
+		q = q.Unscoped()
+	}
+	conditions := false
+	if c, ok := stmt.Clauses["WHERE"]; ok {
+		if where, ok := c.Expression.(clause.Where); ok && len(where.Exprs) > 0 {
+			q = q.Clauses(where)
+			conditions = true
+		}
+	}
+	if ids := primaryKeys(stmt); len(ids) > 0 {
+		q = q.Where(clause.IN{Column: clause.PrimaryColumn, Values: ids})
+		conditions = true
+	}
+	if !conditions {
+		return
+	}
+	rows, err := find(q, stmt.Schema)
+	if err != nil {
+		tx.AddError(fmt.Errorf("查询审计数据失败: %w", err))
+		return
+	}
+	snap := &snapshot{rows: make(map[string]map[string]interface{}, len(rows))}
+	for _, rv := range rows {
+		before, err := toMap(rv.Addr().Interface())
+		if err != nil {
+			tx.AddError(err)
+			return
+		}
+		id := entityId(stmt, rv)
+		v, _ := stmt.Schema.PrioritizedPrimaryField.ValueOf(stmt.Context, rv)
+		snap.ids = append(snap.ids, v)
+		snap.keys = append(snap.keys, id)
+		snap.rows[id] = before
+	}
+	tx.InstanceSet(snapshotKey, snap)
+}
+
+func (a *auditor) afterUpdate(tx *gorm.DB) {
+	snap, ok := getSnapshot(tx)
+	if !ok {
+		return
+	}
+	stmt := tx.Statement
+	q := tx.Session(&gorm.Session{NewDB: true, SkipHooks: true}).Table(stmt.Table).Unscoped().
+		Where(clause.IN{Column: clause.PrimaryColumn, Values: snap.ids})
+	rows, err := find(q, stmt.Schema)
+	if err != nil {
+		tx.AddError(fmt.Errorf("查询审计数据失败: %w", err))
+		return
+	}
+	ignore := autoUpdateKeys(stmt.Schema)
+	logs := make([]Log, 0)
+	for _, rv := range rows {
+		id := entityId(stmt, rv)
+		before, ok := snap.rows[id]
+		if !ok {
+			continue
+		}
+		after, err := toMap(rv.Addr().Interface())
+		if err != nil {
+			tx.AddError(err)
+			return
+		}
+		b, f := diff(before, after, ignore)
+		if len(b) == 0 && len(f) == 0 {
+			continue
+		}
+		logs = append(logs, newLog(tx, ActionUpdate, id, b, f))
+	}
+	save(tx, logs)
+}
+
+func (a *auditor) afterDelete(tx *gorm.DB) {
+	snap, ok := getSnapshot(tx)
+	if !ok {
+		return
+	}
+	logs := make([]Log, 0, len(snap.keys))
+	for _, id := range snap.keys {
+		logs = append(logs, newLog(tx, ActionDelete, id, snap.rows[id], nil))
+	}
+	save(tx, logs)
+}
+
+// getSnapshot 获取操作前的数据，操作失败或没有影响任何数据时不记录
+func getSnapshot(tx *gorm.DB) (*snapshot, bool) {
+	v, ok := tx.InstanceGet(snapshotKey)
+	if !ok || tx.Error != nil || tx.Statement.RowsAffected == 0 {
+		return nil, false
+	}
+	snap := v.(*snapshot)
+	return snap, len(snap.keys) > 0
+}
+
+func newLog(tx *gorm.DB, action, entityId string, before, after map[string]interface{}) Log {
+	log := Log{
+		Action:     action,
+		EntityType: tx.Statement.Table,
+		EntityId:   entityId,
+	}
+	if before != nil {
+		log.Before, _ = json.Marshal(before)
+	}
+	if after != nil {
+		log.After, _ = json.Marshal(after)
+	}
+	if actor := GetActor(tx.Statement.Context); actor != nil {
+		log.AccountId = actor.AccountId
+		log.Account = actor.Account
+		log.ServiceAccountId = actor.ServiceAccountId
+		log.Ip = actor.Ip
+		log.Method = actor.Method
+		log.Route = actor.Route
+	}
+	return log
+}
+
+// save 在当前事务中写入审计日志
+func save(tx *gorm.DB, logs []Log) {
+	if len(logs) == 0 {
+		return
+	}
+	db := tx.Session(&gorm.Session{NewDB: true, SkipHooks: true, SkipDefaultTransaction: true})
+	if err := db.Create(&logs).Error; err != nil {
+		tx.AddError(fmt.Errorf("写入审计日志失败: %w", err))
+	}
+}
+
+// find 按条件查询模型数据
+func find(q *gorm.DB, s *schema.Schema) ([]reflect.Value, error) {
+	rows := reflect.New(reflect.SliceOf(s.ModelType))
+	if err := q.Find(rows.Interface()).Error; err != nil {
+		return nil, err
+	}
+	return elements(rows.Elem()), nil
+}
+
+// elements 返回单条或多条数据中每条数据的结构体值
+func elements(rv reflect.Value) []reflect.Value {
+	rv = reflect.Indirect(rv)
+	values := make([]reflect.Value, 0)
+	switch rv.Kind() {
+	case reflect.Struct:
+		if rv.CanAddr() {
+			values = append(values, rv)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if v := reflect.Indirect(rv.Index(i)); v.Kind() == reflect.Struct && v.CanAddr() {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
+
+// primaryKeys 操作的模型中已设置的主键值
+func primaryKeys(stmt *gorm.Statement) []interface{} {
+	field := stmt.Schema.PrioritizedPrimaryField
+	ids := make([]interface{}, 0)
+	for _, rv := range elements(stmt.ReflectValue) {
+		if rv.Type() != stmt.Schema.ModelType {
+			continue
+		}
+		if v, zero := field.ValueOf(stmt.Context, rv); !zero {
+			ids = append(ids, v)
+		}
+	}
+	return ids
+}
+
+func entityId(stmt *gorm.Statement, rv reflect.Value) string {
+	field := stmt.Schema.PrioritizedPrimaryField
+	if field == nil || rv.Type() != stmt.Schema.ModelType {
+		return ""
+	}
+	v, _ := field.ValueOf(stmt.Context, rv)
+	return fmt.Sprint(v)
+}
+
+// autoUpdateKeys 自动更新时间字段的 json 名称，修改时不作为变更记录
+func autoUpdateKeys(s *schema.Schema) map[string]bool {
+	keys := make(map[string]bool)
+	for _, field := range s.Fields {
+		if field.AutoUpdateTime == 0 {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" {
+			name = field.Name
+		}
+		keys[name] = true
+	}
+	return keys
+}
+
+// diff 返回发生变化的字段在变更前后的值
+func diff(before, after map[string]interface{}, ignore map[string]bool) (map[string]interface{}, map[string]interface{}) {
+	b := make(map[string]interface{})
+	f := make(map[string]interface{})
+	for k, v := range after {
+		if ignore[k] {
+			continue
+		}
+		if old, ok := before[k]; !ok || !reflect.DeepEqual(old, v) {
+			b[k] = before[k]
+			f[k] = v
+		}
+	}
+	for k, v := range before {
+		if _, ok := after[k]; !ok && !ignore[k] {
+			b[k] = v
+			f[k] = nil
+		}
+	}
+	return b, f
+}
+
+func toMap(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("序列化审计数据失败: %w", err)
+	}
+	m := make(map[string]interface{})
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("序列化审计数据失败: %w", err)
+	}
+	return m, nil
+}
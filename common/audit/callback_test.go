@@ -0,0 +1,142 @@
+package audit_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yanshicheng/ikube-gin-starter/common/audit"
+	"github.com/yanshicheng/ikube-gin-starter/common/model"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/testenv"
+	"gorm.io/gorm"
+)
+
+// auditRecord 记录审计日志的模型，Secret 不序列化，不应该出现在审计日志中
+type auditRecord struct {
+	model.Model
+	Name   string `json:"name" gorm:"type:varchar(32)"`
+	Count  int    `json:"count"`
+	Secret string `json:"-" gorm:"type:varchar(32)"`
+}
+
+// skippedRecord 排除的表，不记录审计日志
+type skippedRecord struct {
+	ID   uint   `json:"id" gorm:"primaryKey"`
+	Name string `json:"name"`
+}
+
+func newAuditDB(t *testing.T) *gorm.DB {
+	testenv.Setup(t)
+	db := testenv.DB(t, &audit.Log{}, &auditRecord{}, &skippedRecord{})
+	assert.NoError(t, audit.RegisterCallbacks(db, []string{"skipped_records"}), "注册审计回调应该成功")
+	return db
+}
+
+// auditLogs 按写入顺序查询审计日志
+func auditLogs(t *testing.T, db *gorm.DB) []audit.Log {
+	var logs []audit.Log
+	assert.NoError(t, db.Order("id").Find(&logs).Error)
+	return logs
+}
+
+func decode(t *testing.T, data json.RawMessage) map[string]interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	m := make(map[string]interface{})
+	assert.NoError(t, json.Unmarshal(data, &m))
+	return m
+}
+
+func TestAuditCreate(t *testing.T) {
+	db := newAuditDB(t)
+	ctx := audit.WithActor(context.Background(), &audit.Actor{AccountId: 1, Account: "admin", Ip: "10.0.0.1"})
+	record := &auditRecord{Name: "book", Count: 1, Secret: "secret"}
+	assert.NoError(t, db.WithContext(ctx).Create(record).Error)
+
+	logs := auditLogs(t, db)
+	if assert.Len(t, logs, 1, "新增应该记录一条审计日志") {
+		log := logs[0]
+		assert.Equal(t, audit.ActionCreate, log.Action)
+		assert.Equal(t, "audit_records", log.EntityType, "数据类型应该为表名")
+		assert.Equal(t, "1", log.EntityId, "数据ID应该为主键")
+		assert.Equal(t, "admin", log.Account, "应该记录操作人")
+		assert.Equal(t, "10.0.0.1", log.Ip, "应该记录来源IP")
+		assert.Nil(t, decode(t, log.Before), "新增不应该记录变更前的数据")
+		after := decode(t, log.After)
+		assert.Equal(t, "book", after["name"], "应该记录新增的数据")
+		assert.NotContains(t, after, "Secret", "json:\"-\" 的字段不应该被记录")
+	}
+}
+
+func TestAuditUpdate(t *testing.T) {
+	db := newAuditDB(t)
+	records := []*auditRecord{{Name: "a", Secret: "s1"}, {Name: "b", Secret: "s2"}}
+	assert.NoError(t, db.Create(records).Error)
+	assert.NoError(t, db.Where("1 = 1").Delete(&audit.Log{}).Error)
+
+	// map 更新只记录发生变化的字段，json:"-" 和自动更新时间的字段不记录
+	assert.NoError(t, db.Model(records[0]).Updates(map[string]interface{}{"name": "c", "secret": "s3"}).Error)
+	logs := auditLogs(t, db)
+	if assert.Len(t, logs, 1, "修改应该记录一条审计日志") {
+		assert.Equal(t, audit.ActionUpdate, logs[0].Action)
+		assert.Equal(t, map[string]interface{}{"name": "a"}, decode(t, logs[0].Before), "变更前只应该包含修改的字段")
+		assert.Equal(t, map[string]interface{}{"name": "c"}, decode(t, logs[0].After), "变更后只应该包含修改的字段")
+	}
+
+	// 没有变化的修改不记录
+	assert.NoError(t, db.Model(records[0]).Updates(map[string]interface{}{"secret": "s4"}).Error)
+	assert.Len(t, auditLogs(t, db), 1, "只修改不记录的字段时不应该记录审计日志")
+
+	// Save 按主键修改
+	records[1].Count = 2
+	assert.NoError(t, db.Save(records[1]).Error)
+	logs = auditLogs(t, db)
+	if assert.Len(t, logs, 2, "Save 应该记录一条审计日志") {
+		assert.Equal(t, fmt.Sprint(records[1].ID), logs[1].EntityId, "数据ID应该为修改的数据")
+		assert.Equal(t, map[string]interface{}{"count": float64(0)}, decode(t, logs[1].Before))
+		assert.Equal(t, map[string]interface{}{"count": float64(2)}, decode(t, logs[1].After))
+	}
+
+	// 按条件修改多条数据，每条数据记录一条审计日志
+	assert.NoError(t, db.Model(&auditRecord{}).Where("name IN ?", []string{"b", "c"}).Update("count", 5).Error)
+	assert.Len(t, auditLogs(t, db), 4, "按条件修改应该为每条数据记录审计日志")
+}
+
+func TestAuditDelete(t *testing.T) {
+	db := newAuditDB(t)
+	records := []*auditRecord{{Name: "a"}, {Name: "b"}}
+	assert.NoError(t, db.Create(records).Error)
+	assert.NoError(t, db.Where("1 = 1").Delete(&audit.Log{}).Error)
+
+	// 软删除和物理删除都记录删除前的数据
+	assert.NoError(t, db.Delete(records[0]).Error)
+	assert.NoError(t, db.Unscoped().Delete(records[1]).Error)
+	logs := auditLogs(t, db)
+	if assert.Len(t, logs, 2, "删除应该为每条数据记录审计日志") {
+		for i, log := range logs {
+			assert.Equal(t, audit.ActionDelete, log.Action)
+			assert.Equal(t, records[i].Name, decode(t, log.Before)["name"], "应该记录删除前的数据")
+			assert.Nil(t, decode(t, log.After), "删除不应该记录变更后的数据")
+		}
+	}
+
+	// 物理删除已软删除的数据同样记录
+	assert.NoError(t, db.Unscoped().Delete(records[0]).Error)
+	assert.Len(t, auditLogs(t, db), 3, "物理删除已软删除的数据应该记录审计日志")
+}
+
+func TestAuditExclude(t *testing.T) {
+	db := newAuditDB(t)
+	record := &skippedRecord{Name: "a"}
+	assert.NoError(t, db.Create(record).Error)
+	assert.NoError(t, db.Model(record).Update("name", "b").Error)
+	assert.NoError(t, db.Delete(record).Error)
+	assert.Empty(t, auditLogs(t, db), "排除的表不应该记录审计日志")
+
+	// 试运行不记录
+	assert.NoError(t, db.Session(&gorm.Session{DryRun: true}).Create(&auditRecord{Name: "a"}).Error)
+	assert.Empty(t, auditLogs(t, db), "试运行不应该记录审计日志")
+}
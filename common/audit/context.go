@@ -0,0 +1,48 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ActorKey 当前请求的操作人在 gin 上下文中的键
+const ActorKey = "ikubeops.audit.actor"
+
+type actorKey struct{}
+
+// Actor 数据变更的操作人和来源请求，由审计中间件写入上下文
+type Actor struct {
+	AccountId        uint
+	Account          string
+	ServiceAccountId uint
+	Ip               string
+	Method           string
+	Route            string
+}
+
+// SetActor 将操作人写入 gin 上下文
+func SetActor(c *gin.Context, actor *Actor) {
+	c.Set(ActorKey, actor)
+}
+
+// WithActor 为非请求触发的变更（如定时任务、命令行）指定操作人
+func WithActor(ctx context.Context, actor *Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// GetActor 从上下文中获取操作人，未设置返回 nil
+func GetActor(ctx context.Context) *Actor {
+	if ctx == nil {
+		return nil
+	}
+	if c, ok := ctx.(*gin.Context); ok {
+		if v, ok := c.Get(ActorKey); ok {
+			actor, _ := v.(*Actor)
+			return actor
+		}
+		return nil
+	}
+	actor, _ := ctx.Value(actorKey{}).(*Actor)
+	return actor
+}
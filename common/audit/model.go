@@ -0,0 +1,39 @@
+package audit
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/yanshicheng/ikube-gin-starter/common/model"
+)
+
+const (
+	ActionCreate = "create" // 新增
+	ActionUpdate = "update" // 修改
+	ActionDelete = "delete" // 删除
+)
+
+func init() {
+	model.Register(&Log{})
+}
+
+// Log 审计日志，记录一条数据的一次变更。新增只有 After，删除只有 Before，修改只记录发生变化的字段
+type Log struct {
+	ID               uint            `json:"id" gorm:"primaryKey;autoIncrement;comment:自增主键"`
+	CreatedAt        time.Time       `json:"createdAt" gorm:"type:datetime;autoCreateTime;index;comment:创建时间" filter:"gt,gte,lt,lte,between"`
+	AccountId        uint            `json:"accountId" gorm:"type:int;not null;default:0;index;comment:操作账号ID" filter:"eq,in"`
+	Account          string          `json:"account" gorm:"type:varchar(64);not null;default:'';comment:操作账号" filter:"eq,in,like"`
+	ServiceAccountId uint            `json:"serviceAccountId" gorm:"type:int;not null;default:0;comment:操作服务账号ID" filter:"eq,in"`
+	Ip               string          `json:"ip" gorm:"type:varchar(64);not null;default:'';comment:来源IP" filter:"eq,like"`
+	Method           string          `json:"method" gorm:"type:varchar(16);not null;default:'';comment:请求方法" filter:"eq,in"`
+	Route            string          `json:"route" gorm:"type:varchar(256);not null;default:'';comment:请求路由" filter:"eq,like"`
+	Action           string          `json:"action" gorm:"type:varchar(16);not null;comment:操作类型" filter:"eq,in"`
+	EntityType       string          `json:"entityType" gorm:"type:varchar(64);not null;index:idx_audit_entity;comment:数据类型" filter:"eq,in"`
+	EntityId         string          `json:"entityId" gorm:"type:varchar(64);not null;default:'';index:idx_audit_entity;comment:数据ID" filter:"eq,in"`
+	Before           json.RawMessage `json:"before" gorm:"type:json;serializer:json;comment:变更前" swaggertype:"object"`
+	After            json.RawMessage `json:"after" gorm:"type:json;serializer:json;comment:变更后" swaggertype:"object"`
+}
+
+func (l *Log) TableName() string {
+	return "ikubeops_audit_log"
+}
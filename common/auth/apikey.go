@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ApiKeyKey 当前服务账号信息在 gin 上下文中的键
+const ApiKeyKey = "ikubeops.apikey"
+
+var (
+	ErrApiKeyInvalid = errors.New("api key 无效")
+	ErrApiKeyExpired = errors.New("api key 已过期")
+)
+
+// ApiKeyPrincipal 通过 api key 认证的服务账号，Scopes 限定了 key 可以访问的资源，
+// ApplicationId 和 DataScope 为签发人签发时所在的应用和数据权限范围
+type ApiKeyPrincipal struct {
+	KeyId            uint         `json:"keyId"`
+	ServiceAccountId uint         `json:"serviceAccountId"`
+	Name             string       `json:"name"`
+	Scopes           []Permission `json:"scopes"`
+	ApplicationId    uint         `json:"applicationId"`
+	DataScope        *DataScope   `json:"dataScope"`
+}
+
+// ApiKeyValidator api key 校验接口，由业务应用实现并通过 RegistryApiKeyValidator 注册。
+// key 无效返回 ErrApiKeyInvalid，过期返回 ErrApiKeyExpired。
+type ApiKeyValidator interface {
+	ValidateApiKey(ctx context.Context, key string) (*ApiKeyPrincipal, error)
+}
+
+var apiKeyValidator ApiKeyValidator
+
+// RegistryApiKeyValidator 注册 api key 校验实现
+func RegistryApiKeyValidator(v ApiKeyValidator) {
+	apiKeyValidator = v
+}
+
+// GetApiKeyValidator 获取已注册的 api key 校验实现，未注册返回 nil
+func GetApiKeyValidator() ApiKeyValidator {
+	return apiKeyValidator
+}
+
+// SetApiKey 将通过认证的服务账号写入上下文
+func SetApiKey(c *gin.Context, principal *ApiKeyPrincipal) {
+	c.Set(ApiKeyKey, principal)
+}
+
+// GetApiKey 从上下文中获取通过 api key 认证的服务账号
+func GetApiKey(c *gin.Context) (*ApiKeyPrincipal, bool) {
+	v, ok := c.Get(ApiKeyKey)
+	if !ok {
+		return nil, false
+	}
+	principal, ok := v.(*ApiKeyPrincipal)
+	return principal, ok
+}
@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/jwt"
+)
+
+const (
+	blacklistKeyPrefix = "ikubeops:token:blacklist:" // token 黑名单，按 token ID 存储
+	revisionKeyPrefix  = "ikubeops:token:revision:"  // 账号 token 版本号，强制下线时递增，版本号小于该值的 token 全部失效
+)
+
+var ErrRedisDisabled = errors.New("未启用 redis 配置")
+
+// AddBlacklist 将 token 加入黑名单，保留到 token 自然过期为止
+func AddBlacklist(ctx context.Context, claims *jwt.Claims) error {
+	if global.RDB == nil {
+		return ErrRedisDisabled
+	}
+	ttl := time.Until(claims.ExpiresAt.Time)
+	if ttl <= 0 {
+		// 已过期的 token 无需加入黑名单
+		return nil
+	}
+	if err := global.RDB.GetClient().Set(ctx, blacklistKeyPrefix+claims.ID, claims.AccountId, ttl).Err(); err != nil {
+		return fmt.Errorf("token 加入黑名单失败: %s", err)
+	}
+	return nil
+}
+
+// ClaimToken 原子地将 token 加入黑名单，token 已在黑名单中时返回 false，用于保证 refresh token 只能使用一次
+func ClaimToken(ctx context.Context, claims *jwt.Claims) (bool, error) {
+	if global.RDB == nil {
+		return false, ErrRedisDisabled
+	}
+	ttl := time.Until(claims.ExpiresAt.Time)
+	if ttl <= 0 {
+		return false, nil
+	}
+	ok, err := global.RDB.GetClient().SetNX(ctx, blacklistKeyPrefix+claims.ID, claims.AccountId, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("token 加入黑名单失败: %s", err)
+	}
+	return ok, nil
+}
+
+// TokenRevision 返回账号当前的 token 版本号，签发 token 时写入载荷
+func TokenRevision(ctx context.Context, accountId uint) (int64, error) {
+	if global.RDB == nil {
+		return 0, nil
+	}
+	rev, err := global.RDB.GetClient().Get(ctx, revisionKeyPrefix+strconv.Itoa(int(accountId))).Int64()
+	if err != nil && !errors.Is(err, goredis.Nil) {
+		return 0, fmt.Errorf("查询账号 token 版本号失败: %s", err)
+	}
+	return rev, nil
+}
+
+// IsBlacklisted 检查 token 是否已被加入黑名单或账号已被强制下线
+func IsBlacklisted(ctx context.Context, claims *jwt.Claims) (bool, error) {
+	if global.RDB == nil {
+		return false, nil
+	}
+	client := global.RDB.GetClient()
+	n, err := client.Exists(ctx, blacklistKeyPrefix+claims.ID).Result()
+	if err != nil {
+		return false, fmt.Errorf("查询 token 黑名单失败: %s", err)
+	}
+	if n > 0 {
+		return true, nil
+	}
+	// 会话已被删除的 token 同样失效
+	if claims.SessionId != "" {
+		n, err := client.Exists(ctx, sessionKeyPrefix+claims.SessionId).Result()
+		if err != nil {
+			return false, fmt.Errorf("查询会话失败: %s", err)
+		}
+		if n == 0 {
+			return true, nil
+		}
+	}
+	rev, err := TokenRevision(ctx, claims.AccountId)
+	if err != nil {
+		return false, err
+	}
+	return claims.Revision < rev, nil
+}
+
+// RevokeAccount 强制账号下线，递增账号的 token 版本号使此前签发的所有 token 立即失效，并删除账号的全部会话
+func RevokeAccount(ctx context.Context, accountId uint) error {
+	if global.RDB == nil {
+		return ErrRedisDisabled
+	}
+	// 版本号不设置过期时间，过期后归零会使已失效的 token 重新生效
+	key := revisionKeyPrefix + strconv.Itoa(int(accountId))
+	if err := global.RDB.GetClient().Incr(ctx, key).Err(); err != nil {
+		return fmt.Errorf("账号强制下线失败: %s", err)
+	}
+	if _, err := DeleteSessions(ctx, accountId, ""); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package auth_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yanshicheng/ikube-gin-starter/common/auth"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/jwt"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/testenv"
+)
+
+var jwtClaims = jwt.Claims{AccountId: 1, Account: "admin", UserName: "管理员"}
+
+func TestClaimToken(t *testing.T) {
+	testenv.Setup(t)
+	testenv.Redis(t)
+	ctx := context.Background()
+	token, err := global.J.GenerateRefreshToken(&jwtClaims)
+	assert.NoError(t, err)
+	claims, err := global.J.ParseToken(token)
+	assert.NoError(t, err)
+
+	// 并发使用同一个 refresh token，只有一个请求可以占用成功
+	var claimed int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := auth.ClaimToken(ctx, claims)
+			assert.NoError(t, err)
+			if ok {
+				atomic.AddInt32(&claimed, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), claimed, "refresh token 只能被占用一次")
+	blacklisted, err := auth.IsBlacklisted(ctx, claims)
+	assert.NoError(t, err)
+	assert.True(t, blacklisted, "占用后的 token 应该在黑名单中")
+}
+
+func TestRevokeAccount(t *testing.T) {
+	testenv.Setup(t)
+	testenv.Redis(t)
+	ctx := context.Background()
+	old, err := global.J.GenerateToken(&jwtClaims)
+	assert.NoError(t, err)
+	oldClaims, _ := global.J.ParseToken(old)
+
+	assert.NoError(t, auth.RevokeAccount(ctx, jwtClaims.AccountId))
+	blacklisted, err := auth.IsBlacklisted(ctx, oldClaims)
+	assert.NoError(t, err)
+	assert.True(t, blacklisted, "强制下线前签发的 token 应该失效")
+
+	// 强制下线后立即签发的 token 不受影响，与签发时间的精度无关
+	rev, err := auth.TokenRevision(ctx, jwtClaims.AccountId)
+	assert.NoError(t, err)
+	claims := jwtClaims
+	claims.Revision = rev
+	fresh, err := global.J.GenerateToken(&claims)
+	assert.NoError(t, err)
+	freshClaims, _ := global.J.ParseToken(fresh)
+	blacklisted, err = auth.IsBlacklisted(ctx, freshClaims)
+	assert.NoError(t, err)
+	assert.False(t, blacklisted, "强制下线后签发的 token 应该有效")
+}
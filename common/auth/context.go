@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/jwt"
+)
+
+const (
+	// ClaimsKey 当前登录账号信息在 gin 上下文中的键
+	ClaimsKey = "ikubeops.claims"
+	// ApplicationHeader 前端通过该请求头声明当前所在的应用，用于限定权限和菜单范围
+	ApplicationHeader = "X-Application-Id"
+)
+
+// SetClaims 将解析后的 token 载荷写入上下文
+func SetClaims(c *gin.Context, claims *jwt.Claims) {
+	c.Set(ClaimsKey, claims)
+}
+
+// GetClaims 从上下文中获取当前登录账号信息
+func GetClaims(c *gin.Context) (*jwt.Claims, bool) {
+	v, ok := c.Get(ClaimsKey)
+	if !ok {
+		return nil, false
+	}
+	claims, ok := v.(*jwt.Claims)
+	return claims, ok
+}
+
+// GetAccountId 获取当前登录账号ID，未登录返回 0
+func GetAccountId(c *gin.Context) uint {
+	if claims, ok := GetClaims(c); ok {
+		return claims.AccountId
+	}
+	return 0
+}
+
+// GetApplicationId 获取当前请求所属的应用ID，api key 请求为签发时所在的应用，
+// 其他请求为请求头中声明的应用，未声明或格式错误返回 0
+func GetApplicationId(c *gin.Context) uint {
+	if principal, ok := GetApiKey(c); ok {
+		return principal.ApplicationId
+	}
+	id, err := strconv.ParseUint(c.GetHeader(ApplicationHeader), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return uint(id)
+}
@@ -0,0 +1,57 @@
+package auth_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yanshicheng/ikube-gin-starter/common/auth"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/testenv"
+	"gorm.io/gorm"
+)
+
+type scopedRecord struct {
+	ID             uint
+	OrganizationId uint
+	AccountId      uint
+}
+
+type plainRecord struct {
+	ID   uint
+	Name string
+}
+
+func TestDataScopeFilter(t *testing.T) {
+	testenv.Setup(t)
+	gin.SetMode(gin.TestMode)
+	db := testenv.DB(t).Session(&gorm.Session{DryRun: true})
+	sqlOf := func(scope *auth.DataScope, model interface{}) string {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		if scope != nil {
+			auth.SetDataScope(c, scope)
+		}
+		return db.Model(model).Scopes(auth.DataScopeFilter(c, model)).Find(model).Statement.SQL.String()
+	}
+
+	tests := []struct {
+		name  string
+		scope *auth.DataScope
+		model interface{}
+		want  string
+	}{
+		{"未设置数据权限时不返回数据", nil, &scopedRecord{}, "SELECT * FROM `scoped_records` WHERE 1 = 0"},
+		{"全部数据不过滤", &auth.DataScope{All: true}, &scopedRecord{}, "SELECT * FROM `scoped_records`"},
+		{"按机构过滤", &auth.DataScope{OrganizationIds: []uint{1, 2}}, &scopedRecord{},
+			"SELECT * FROM `scoped_records` WHERE (scoped_records.organization_id IN (?,?))"},
+		{"机构或本人", &auth.DataScope{Self: true, AccountId: 3, OrganizationIds: []uint{1}}, &scopedRecord{},
+			"SELECT * FROM `scoped_records` WHERE (scoped_records.organization_id IN (?) OR scoped_records.account_id = ?)"},
+		{"没有任何范围时不返回数据", &auth.DataScope{}, &scopedRecord{}, "SELECT * FROM `scoped_records` WHERE 1 = 0"},
+		{"模型不包含机构和账号列时不过滤", &auth.DataScope{Self: true, AccountId: 3}, &plainRecord{}, "SELECT * FROM `plain_records`"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, sqlOf(tt.scope, tt.model), tt.name)
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/mysql"
+	"gorm.io/gorm"
+)
+
+const (
+	ActionRead  = "read"  // 读操作
+	ActionWrite = "write" // 写操作
+
+	permissionKeyPrefix = "ikubeops:rbac:permission:" // 账号权限缓存，hash 结构，field 为应用ID
+)
+
+// Permission 权限项，Resource 为路由模式，支持以 * 结尾的前缀匹配
+type Permission struct {
+	Resource string `json:"resource"`
+	Action   string `json:"action"`
+}
+
+// PermissionSet 账号的权限集合
+type PermissionSet struct {
+	Super       bool         `json:"super"` // 超级管理员拥有所有权限
+	Permissions []Permission `json:"permissions"`
+	DataScope   DataScope    `json:"dataScope"` // 数据权限范围
+}
+
+// Allow 判断是否拥有资源的操作权限，写权限包含读权限
+func (p *PermissionSet) Allow(resource, action string) bool {
+	if p.Super {
+		return true
+	}
+	for _, perm := range p.Permissions {
+		if perm.Action != action && perm.Action != ActionWrite {
+			continue
+		}
+		if MatchResource(perm.Resource, resource) {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorizer 权限加载接口，由业务应用实现并通过 RegistryAuthorizer 注册。
+// 账号必须被授权访问 applicationId 对应的应用，applicationId 为 0 时只有超级管理员拥有权限。
+type Authorizer interface {
+	LoadPermissions(ctx context.Context, accountId, applicationId uint) (*PermissionSet, error)
+}
+
+var authorizer Authorizer
+
+// RegistryAuthorizer 注册权限加载实现
+func RegistryAuthorizer(a Authorizer) {
+	authorizer = a
+}
+
+// GetAuthorizer 获取已注册的权限加载实现，未注册返回 nil
+func GetAuthorizer() Authorizer {
+	return authorizer
+}
+
+// ActionOf 根据 HTTP 方法映射读写操作
+func ActionOf(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return ActionRead
+	default:
+		return ActionWrite
+	}
+}
+
+// MatchResource 判断资源是否匹配，pattern 以 * 结尾时按前缀匹配，忽略末尾的 /
+func MatchResource(pattern, resource string) bool {
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(resource, strings.TrimSuffix(pattern, "*"))
+	}
+	return strings.TrimSuffix(pattern, "/") == strings.TrimSuffix(resource, "/")
+}
+
+// ResourceOf 路由模式对应的权限资源，去掉全局 API 前缀，修改前缀不影响已配置的权限
+func ResourceOf(path string) string {
+	if prefix := global.C.Api.PathPrefix(); prefix != "" && strings.HasPrefix(path, prefix+"/") {
+		return strings.TrimPrefix(path, prefix)
+	}
+	return path
+}
+
+// GetPermissions 获取账号在应用下的权限，优先读取 redis 缓存
+func GetPermissions(ctx context.Context, accountId, applicationId uint) (*PermissionSet, error) {
+	if authorizer == nil {
+		return nil, errors.New("未注册权限加载实现")
+	}
+	if global.RDB == nil {
+		return authorizer.LoadPermissions(ctx, accountId, applicationId)
+	}
+	client := global.RDB.GetClient()
+	key := permissionKeyPrefix + strconv.Itoa(int(accountId))
+	field := strconv.Itoa(int(applicationId))
+	data, err := client.HGet(ctx, key, field).Bytes()
+	if err == nil {
+		var perms PermissionSet
+		if err := json.Unmarshal(data, &perms); err == nil {
+			return &perms, nil
+		}
+	} else if !errors.Is(err, goredis.Nil) {
+		return nil, fmt.Errorf("查询权限缓存失败: %s", err)
+	}
+	perms, err := authorizer.LoadPermissions(ctx, accountId, applicationId)
+	if err != nil {
+		return nil, err
+	}
+	if data, err = json.Marshal(perms); err == nil {
+		expire := time.Duration(global.C.Rbac.CacheExpire) * time.Second
+		pipe := client.TxPipeline()
+		pipe.HSet(ctx, key, field, data)
+		pipe.Expire(ctx, key, expire)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, fmt.Errorf("写入权限缓存失败: %s", err)
+		}
+	}
+	return perms, nil
+}
+
+// ClearPermissionCache 清除账号的权限缓存，角色分配或权限变更时调用
+func ClearPermissionCache(ctx context.Context, accountIds ...uint) error {
+	if global.RDB == nil || len(accountIds) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(accountIds))
+	for _, id := range accountIds {
+		keys = append(keys, permissionKeyPrefix+strconv.Itoa(int(id)))
+	}
+	if err := global.RDB.GetClient().Del(ctx, keys...).Err(); err != nil {
+		return fmt.Errorf("清除权限缓存失败: %s", err)
+	}
+	return nil
+}
+
+// ClearAllPermissionCache 清除所有账号的权限缓存
+func ClearAllPermissionCache(ctx context.Context) error {
+	if global.RDB == nil {
+		return nil
+	}
+	client := global.RDB.GetClient()
+	iter := client.Scan(ctx, 0, permissionKeyPrefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		if err := client.Del(ctx, iter.Val()).Err(); err != nil {
+			return fmt.Errorf("清除权限缓存失败: %s", err)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("清除权限缓存失败: %s", err)
+	}
+	return nil
+}
+
+// ClearPermissionCacheAfterCommit 在当前事务提交后清除账号的权限缓存。
+// 事务提交前清除时，并发请求可能重新缓存旧的权限；清除失败只记录日志，不影响已提交的数据
+func ClearPermissionCacheAfterCommit(tx *gorm.DB, accountIds ...uint) {
+	if len(accountIds) == 0 {
+		return
+	}
+	ctx := tx.Statement.Context
+	mysql.AfterCommit(tx, func() {
+		if err := ClearPermissionCache(ctx, accountIds...); err != nil {
+			global.LSys.Error(fmt.Sprintf("清除权限缓存失败, ids: %v, error: %s", accountIds, err))
+		}
+	})
+}
+
+// ClearAllPermissionCacheAfterCommit 在当前事务提交后清除所有账号的权限缓存
+func ClearAllPermissionCacheAfterCommit(tx *gorm.DB) {
+	ctx := tx.Statement.Context
+	mysql.AfterCommit(tx, func() {
+		if err := ClearAllPermissionCache(ctx); err != nil {
+			global.LSys.Error(fmt.Sprintf("清除权限缓存失败, error: %s", err))
+		}
+	})
+}
@@ -0,0 +1,283 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/jwt"
+)
+
+// 登录会话：同一次登录签发的 access token 和 refresh token 携带相同的会话ID，
+// 会话信息保存在 redis 中，删除会话后该会话的 token 立即失效。未启用 redis 时不记录会话。
+
+const (
+	sessionKeyPrefix        = "ikubeops:session:"  // 会话信息，按会话ID存储
+	accountSessionKeyPrefix = "ikubeops:sessions:" // 账号的会话ID集合
+	sessionTouchInterval    = time.Minute          // 最后活跃时间的更新间隔
+)
+
+var ErrSessionNotFound = errors.New("会话不存在")
+
+// Session 登录会话
+type Session struct {
+	Id         string    `json:"id"`
+	AccountId  uint      `json:"accountId"`
+	Device     string    `json:"device"`
+	Ip         string    `json:"ip"`
+	UserAgent  string    `json:"userAgent"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	Current    bool      `json:"current"` // 是否为当前请求使用的会话
+}
+
+// CreateSession 创建登录会话，未启用 redis 时返回空的会话ID
+func CreateSession(ctx context.Context, accountId uint, ip, userAgent string) (string, error) {
+	if global.RDB == nil {
+		return "", nil
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("生成会话ID失败: %s", err)
+	}
+	id := hex.EncodeToString(b)
+	now := time.Now().Unix()
+	ttl := global.J.RefreshExpire()
+	key := sessionKeyPrefix + id
+	setKey := accountSessionKey(accountId)
+	pipe := global.RDB.GetClient().TxPipeline()
+	pipe.HSet(ctx, key,
+		"account", accountId,
+		"device", DeviceName(userAgent),
+		"ip", ip,
+		"user_agent", userAgent,
+		"created_at", now,
+		"last_seen", now,
+	)
+	pipe.Expire(ctx, key, ttl)
+	pipe.SAdd(ctx, setKey, id)
+	pipe.Expire(ctx, setKey, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", fmt.Errorf("保存会话失败: %s", err)
+	}
+	return id, nil
+}
+
+// 会话可能在更新的同时被删除，更新只在会话仍然存在时执行，避免重新创建已删除的会话
+var (
+	// refreshSessionScript KEYS: 会话, 账号的会话集合; ARGV: ip, 当前时间, 有效期(s)。会话不存在时返回 0
+	refreshSessionScript = goredis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return 0
+end
+redis.call("HSET", KEYS[1], "ip", ARGV[1], "last_seen", ARGV[2])
+redis.call("EXPIRE", KEYS[1], ARGV[3])
+redis.call("EXPIRE", KEYS[2], ARGV[3])
+return 1
+`)
+	// touchSessionScript KEYS: 会话; ARGV: ip, 当前时间, 更新间隔(s)。距上次更新不足间隔时跳过
+	touchSessionScript = goredis.NewScript(`
+local last = redis.call("HGET", KEYS[1], "last_seen")
+if not last then
+	return 0
+end
+if tonumber(ARGV[2]) - tonumber(last) < tonumber(ARGV[3]) then
+	return 1
+end
+redis.call("HSET", KEYS[1], "ip", ARGV[1], "last_seen", ARGV[2])
+return 1
+`)
+)
+
+// RefreshSession 刷新 token 时更新会话活跃时间，并将会话有效期延长到新的 refresh token 过期为止，
+// 会话已删除时返回 ErrSessionNotFound
+func RefreshSession(ctx context.Context, claims *jwt.Claims, ip string) error {
+	if global.RDB == nil || claims.SessionId == "" {
+		return nil
+	}
+	ttl := global.J.RefreshExpire()
+	keys := []string{sessionKeyPrefix + claims.SessionId, accountSessionKey(claims.AccountId)}
+	ok, err := refreshSessionScript.Run(ctx, global.RDB.GetClient(), keys, ip, time.Now().Unix(), int64(ttl.Seconds())).Int()
+	if err != nil {
+		return fmt.Errorf("刷新会话失败: %s", err)
+	}
+	if ok == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// TouchSession 更新会话最后活跃时间和 ip，距上次更新不足一分钟或会话已删除时跳过
+func TouchSession(ctx context.Context, claims *jwt.Claims, ip string) error {
+	if global.RDB == nil || claims.SessionId == "" {
+		return nil
+	}
+	keys := []string{sessionKeyPrefix + claims.SessionId}
+	interval := int64(sessionTouchInterval.Seconds())
+	if err := touchSessionScript.Run(ctx, global.RDB.GetClient(), keys, ip, time.Now().Unix(), interval).Err(); err != nil {
+		return fmt.Errorf("更新会话失败: %s", err)
+	}
+	return nil
+}
+
+// ListSessions 查询账号的全部会话，按最后活跃时间倒序，current 为当前请求的会话ID
+func ListSessions(ctx context.Context, accountId uint, current string) ([]Session, error) {
+	sessions := make([]Session, 0)
+	if global.RDB == nil {
+		return sessions, nil
+	}
+	client := global.RDB.GetClient()
+	setKey := accountSessionKey(accountId)
+	ids, err := client.SMembers(ctx, setKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("查询会话失败: %s", err)
+	}
+	if len(ids) == 0 {
+		return sessions, nil
+	}
+	pipe := client.Pipeline()
+	cmds := make([]*goredis.StringStringMapCmd, len(ids))
+	for i, id := range ids {
+		cmds[i] = pipe.HGetAll(ctx, sessionKeyPrefix+id)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("查询会话失败: %s", err)
+	}
+	expired := make([]interface{}, 0)
+	for i, cmd := range cmds {
+		values := cmd.Val()
+		if len(values) == 0 {
+			// 会话已过期，从集合中清理
+			expired = append(expired, ids[i])
+			continue
+		}
+		s := parseSession(ids[i], values)
+		s.Current = ids[i] == current
+		sessions = append(sessions, s)
+	}
+	if len(expired) > 0 {
+		client.SRem(ctx, setKey, expired...)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+	return sessions, nil
+}
+
+// DeleteSession 删除账号的一个会话，会话不属于该账号时返回 ErrSessionNotFound
+func DeleteSession(ctx context.Context, accountId uint, sessionId string) error {
+	if global.RDB == nil {
+		return ErrRedisDisabled
+	}
+	client := global.RDB.GetClient()
+	setKey := accountSessionKey(accountId)
+	ok, err := client.SIsMember(ctx, setKey, sessionId).Result()
+	if err != nil {
+		return fmt.Errorf("查询会话失败: %s", err)
+	}
+	if !ok {
+		return ErrSessionNotFound
+	}
+	pipe := client.TxPipeline()
+	pipe.Del(ctx, sessionKeyPrefix+sessionId)
+	pipe.SRem(ctx, setKey, sessionId)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("删除会话失败: %s", err)
+	}
+	return nil
+}
+
+// DeleteSessions 删除账号除 except 以外的全部会话，返回删除的数量
+func DeleteSessions(ctx context.Context, accountId uint, except string) (int, error) {
+	if global.RDB == nil {
+		return 0, ErrRedisDisabled
+	}
+	client := global.RDB.GetClient()
+	setKey := accountSessionKey(accountId)
+	ids, err := client.SMembers(ctx, setKey).Result()
+	if err != nil {
+		return 0, fmt.Errorf("查询会话失败: %s", err)
+	}
+	pipe := client.TxPipeline()
+	n := 0
+	for _, id := range ids {
+		if id == except {
+			continue
+		}
+		pipe.Del(ctx, sessionKeyPrefix+id)
+		pipe.SRem(ctx, setKey, id)
+		n++
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("删除会话失败: %s", err)
+	}
+	return n, nil
+}
+
+// DeviceName 根据 User-Agent 识别设备的操作系统和浏览器
+func DeviceName(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	var os, browser string
+	switch {
+	case strings.Contains(ua, "iphone") || strings.Contains(ua, "ipad"):
+		os = "iOS"
+	case strings.Contains(ua, "android"):
+		os = "Android"
+	case strings.Contains(ua, "windows"):
+		os = "Windows"
+	case strings.Contains(ua, "mac os"):
+		os = "macOS"
+	case strings.Contains(ua, "linux"):
+		os = "Linux"
+	}
+	switch {
+	case strings.Contains(ua, "edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "chrome/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "safari/"):
+		browser = "Safari"
+	case strings.Contains(ua, "curl/"):
+		browser = "curl"
+	}
+	switch {
+	case os != "" && browser != "":
+		return os + " / " + browser
+	case os != "" || browser != "":
+		return os + browser
+	default:
+		return "unknown"
+	}
+}
+
+func accountSessionKey(accountId uint) string {
+	return accountSessionKeyPrefix + strconv.Itoa(int(accountId))
+}
+
+func parseSession(id string, values map[string]string) Session {
+	account, _ := strconv.ParseUint(values["account"], 10, 64)
+	createdAt, _ := strconv.ParseInt(values["created_at"], 10, 64)
+	lastSeen, _ := strconv.ParseInt(values["last_seen"], 10, 64)
+	return Session{
+		Id:         id,
+		AccountId:  uint(account),
+		Device:     values["device"],
+		Ip:         values["ip"],
+		UserAgent:  values["user_agent"],
+		CreatedAt:  time.Unix(createdAt, 0),
+		LastSeenAt: time.Unix(lastSeen, 0),
+	}
+}
@@ -0,0 +1,43 @@
+package auth_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yanshicheng/ikube-gin-starter/common/auth"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/jwt"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/testenv"
+)
+
+func TestSessionUpdate(t *testing.T) {
+	testenv.Setup(t)
+	mr := testenv.Redis(t)
+	ctx := context.Background()
+	id, err := auth.CreateSession(ctx, 1, "10.0.0.1", "curl/8.0")
+	assert.NoError(t, err, "创建会话应该成功")
+	key := "ikubeops:session:" + id
+	claims := &jwt.Claims{AccountId: 1, SessionId: id}
+
+	// 距上次更新不足间隔时跳过
+	assert.NoError(t, auth.TouchSession(ctx, claims, "10.0.0.2"))
+	assert.Equal(t, "10.0.0.1", mr.HGet(key, "ip"), "间隔内不应该更新会话")
+
+	// 超过间隔后更新活跃时间和 ip
+	mr.HSet(key, "last_seen", strconv.FormatInt(time.Now().Add(-2*time.Minute).Unix(), 10))
+	assert.NoError(t, auth.TouchSession(ctx, claims, "10.0.0.2"))
+	assert.Equal(t, "10.0.0.2", mr.HGet(key, "ip"), "超过间隔后应该更新会话")
+
+	assert.NoError(t, auth.RefreshSession(ctx, claims, "10.0.0.3"), "刷新会话应该成功")
+	assert.Equal(t, "10.0.0.3", mr.HGet(key, "ip"), "刷新时应该更新会话")
+	assert.True(t, mr.TTL(key) > 0, "刷新后会话应该有过期时间")
+
+	// 删除后的会话不会被更新重新创建
+	assert.NoError(t, auth.DeleteSession(ctx, 1, id), "删除会话应该成功")
+	assert.NoError(t, auth.TouchSession(ctx, claims, "10.0.0.4"))
+	assert.False(t, mr.Exists(key), "更新活跃时间不应该重新创建已删除的会话")
+	assert.ErrorIs(t, auth.RefreshSession(ctx, claims, "10.0.0.4"), auth.ErrSessionNotFound, "刷新已删除的会话应该失败")
+	assert.False(t, mr.Exists(key), "刷新不应该重新创建已删除的会话")
+}
@@ -0,0 +1,212 @@
+package crud
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yanshicheng/ikube-gin-starter/common/auth"
+	"github.com/yanshicheng/ikube-gin-starter/common/filter"
+	"github.com/yanshicheng/ikube-gin-starter/common/model"
+	"github.com/yanshicheng/ikube-gin-starter/common/response"
+	"github.com/yanshicheng/ikube-gin-starter/common/types"
+	"go.uber.org/zap"
+)
+
+// 通用处理函数执行的操作，传给 Authorize 和 Validate 钩子
+const (
+	ActionCreate = "create"
+	ActionList   = "list"
+	ActionGet    = "get"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// Hooks 通用处理函数的扩展点，未设置的钩子不执行
+type Hooks[T model.Entity] struct {
+	// Authorize 处理请求前调用，返回错误时拒绝请求，用于在权限中间件之外补充授权规则，
+	// 如返回 errorx.NewCodeError(errorx.ErrPermissionDenied, "权限不足")
+	Authorize func(c *gin.Context, action string) error
+	// Validate 创建和修改前调用，用于绑定校验之外的业务校验，返回 validator.ValidationErrors 时按参数错误翻译
+	Validate func(c *gin.Context, action string, entity *T) error
+	// Scope 列表、详情、修改和删除时追加的数据范围条件，在数据权限过滤之外使用
+	Scope func(c *gin.Context) Scope
+	// Filter 列表查询条件，在查询参数 filter 之外追加，返回错误时按参数错误返回
+	Filter func(c *gin.Context) (Scope, error)
+}
+
+// Handler 通用增删改查处理函数，按账号的数据权限范围过滤数据
+type Handler[T model.Entity] struct {
+	l     *zap.Logger
+	repo  Store[T]
+	hooks Hooks[T]
+}
+
+// NewHandler 创建通用处理函数，repo 一般为 Repository 或嵌入 Store 的 service
+func NewHandler[T model.Entity](repo Store[T], l *zap.Logger, hooks Hooks[T]) *Handler[T] {
+	return &Handler[T]{l: l, repo: repo, hooks: hooks}
+}
+
+// Register 在分组 r 下注册 POST、GET、GET /:id、PUT /:id 和 DELETE /:id 五个路由
+func (h *Handler[T]) Register(r gin.IRouter) {
+	r.POST("", h.Create)
+	r.GET("", h.List)
+	r.GET("/:id", h.Get)
+	r.PUT("/:id", h.Update)
+	r.DELETE("/:id", h.Delete)
+}
+
+func (h *Handler[T]) Create(c *gin.Context) {
+	if !h.authorize(c, ActionCreate) {
+		return
+	}
+	entity := new(T)
+	if err := c.ShouldBindJSON(entity); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if !h.validate(c, ActionCreate, entity) {
+		return
+	}
+	if err := h.repo.Create(c, entity); err != nil {
+		response.FailedError(c, err)
+		return
+	}
+	response.SuccessMap(c, entity)
+}
+
+func (h *Handler[T]) List(c *gin.Context) {
+	if !h.authorize(c, ActionList) {
+		return
+	}
+	var query struct {
+		types.Pagination
+		types.Filters
+	}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	// 查询参数 filter 按模型的 filter 标签过滤
+	scope, err := filter.Scope(new(T), query.Filter)
+	if err != nil {
+		response.FailedParam(c, err)
+		return
+	}
+	scopes := append(h.scopes(c), scope)
+	if h.hooks.Filter != nil {
+		extra, err := h.hooks.Filter(c)
+		if err != nil {
+			h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+			response.FailedParam(c, err)
+			return
+		}
+		if extra != nil {
+			scopes = append(scopes, extra)
+		}
+	}
+	resp, err := h.repo.List(c, query.Pagination, scopes...)
+	if err != nil {
+		response.FailedError(c, err)
+		return
+	}
+	response.SuccessSlice(c, resp)
+}
+
+func (h *Handler[T]) Get(c *gin.Context) {
+	if !h.authorize(c, ActionGet) {
+		return
+	}
+	var id types.SearchId
+	if err := c.ShouldBindUri(&id); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	entity, err := h.repo.Get(c, id.Id, h.scopes(c)...)
+	if err != nil {
+		response.FailedError(c, err)
+		return
+	}
+	response.SuccessMap(c, entity)
+}
+
+func (h *Handler[T]) Update(c *gin.Context) {
+	if !h.authorize(c, ActionUpdate) {
+		return
+	}
+	var id types.SearchId
+	if err := c.ShouldBindUri(&id); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	entity := new(T)
+	if err := c.ShouldBindJSON(entity); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if !h.validate(c, ActionUpdate, entity) {
+		return
+	}
+	updated, err := h.repo.Update(c, id.Id, entity, h.scopes(c)...)
+	if err != nil {
+		response.FailedError(c, err)
+		return
+	}
+	response.SuccessMap(c, updated)
+}
+
+func (h *Handler[T]) Delete(c *gin.Context) {
+	if !h.authorize(c, ActionDelete) {
+		return
+	}
+	var id types.SearchId
+	if err := c.ShouldBindUri(&id); err != nil {
+		h.l.Error(fmt.Sprintf("数据绑定失败: %s", err))
+		response.FailedParam(c, err)
+		return
+	}
+	if err := h.repo.Delete(c, id.Id, h.scopes(c)...); err != nil {
+		response.FailedError(c, err)
+		return
+	}
+	response.SuccessMap(c, "")
+}
+
+// authorize 执行 Authorize 钩子，拒绝时返回错误响应
+func (h *Handler[T]) authorize(c *gin.Context, action string) bool {
+	if h.hooks.Authorize == nil {
+		return true
+	}
+	if err := h.hooks.Authorize(c, action); err != nil {
+		response.FailedError(c, err)
+		return false
+	}
+	return true
+}
+
+// validate 执行 Validate 钩子，校验失败时返回错误响应
+func (h *Handler[T]) validate(c *gin.Context, action string, entity *T) bool {
+	if h.hooks.Validate == nil {
+		return true
+	}
+	if err := h.hooks.Validate(c, action, entity); err != nil {
+		response.FailedError(c, err)
+		return false
+	}
+	return true
+}
+
+// scopes 数据权限过滤和 Scope 钩子的条件
+func (h *Handler[T]) scopes(c *gin.Context) []Scope {
+	scopes := []Scope{auth.DataScopeFilter(c, new(T))}
+	if h.hooks.Scope != nil {
+		if scope := h.hooks.Scope(c); scope != nil {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
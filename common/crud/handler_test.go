@@ -0,0 +1,186 @@
+package crud_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yanshicheng/ikube-gin-starter/common/auth"
+	"github.com/yanshicheng/ikube-gin-starter/common/crud"
+	"github.com/yanshicheng/ikube-gin-starter/common/errorx"
+	"github.com/yanshicheng/ikube-gin-starter/common/model"
+	"github.com/yanshicheng/ikube-gin-starter/common/validator"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/testenv"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// crudRecord 按机构过滤数据权限的模型
+type crudRecord struct {
+	model.Model
+	Name           string `json:"name" binding:"required,max=32" gorm:"type:varchar(32)" filter:"eq,like"`
+	Status         string `json:"status" gorm:"type:varchar(16)"`
+	OrganizationId uint   `json:"organizationId"`
+}
+
+// body 响应体，Data 按需要的类型解析
+type body struct {
+	Code errorx.ErrorCode
+	Data json.RawMessage
+}
+
+// newEngine 注册 crudRecord 的五个路由，scope 为 nil 时不设置数据权限范围
+func newEngine(t *testing.T, scope *auth.DataScope, hooks crud.Hooks[crudRecord]) (*gin.Engine, *gorm.DB) {
+	testenv.Setup(t)
+	gin.SetMode(gin.TestMode)
+	db := testenv.DB(t, &crudRecord{})
+	h := crud.NewHandler[crudRecord](crud.NewRepository[crudRecord](db, zap.NewNop()), zap.NewNop(), hooks)
+	r := gin.New()
+	h.Register(r.Group("/records", func(c *gin.Context) {
+		if scope != nil {
+			auth.SetDataScope(c, scope)
+		}
+	}))
+	return r, db
+}
+
+func serve(t *testing.T, r *gin.Engine, method, path string, data interface{}) body {
+	var buf bytes.Buffer
+	if data != nil {
+		assert.NoError(t, json.NewEncoder(&buf).Encode(data))
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var resp body
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp), "响应应该是 json: %s", w.Body.String())
+	return resp
+}
+
+// listNames 查询列表，返回数据的名称
+func listNames(t *testing.T, r *gin.Engine, query string) []string {
+	resp := serve(t, r, http.MethodGet, "/records"+query, nil)
+	assert.Equal(t, errorx.ErrNormal, resp.Code, "查询列表应该成功")
+	var page struct{ Data []crudRecord }
+	assert.NoError(t, json.Unmarshal(resp.Data, &page))
+	names := make([]string, 0, len(page.Data))
+	for _, record := range page.Data {
+		names = append(names, record.Name)
+	}
+	return names
+}
+
+func TestHandler(t *testing.T) {
+	r, db := newEngine(t, &auth.DataScope{All: true}, crud.Hooks[crudRecord]{})
+
+	// 创建时忽略请求中的主键
+	resp := serve(t, r, http.MethodPost, "/records", map[string]interface{}{"id": 100, "name": "a", "status": "on"})
+	assert.Equal(t, errorx.ErrNormal, resp.Code, "创建应该成功")
+	var created crudRecord
+	assert.NoError(t, json.Unmarshal(resp.Data, &created))
+	assert.NotEqual(t, uint(100), created.ID, "创建时不应该使用请求中的主键")
+	assert.Equal(t, errorx.ErrParamParse, serve(t, r, http.MethodPost, "/records", map[string]string{}).Code, "缺少必填字段时应该返回参数错误")
+	assert.NoError(t, db.Create(&crudRecord{Name: "b"}).Error)
+
+	// 详情
+	path := fmt.Sprintf("/records/%d", created.ID)
+	resp = serve(t, r, http.MethodGet, path, nil)
+	assert.Equal(t, errorx.ErrNormal, resp.Code, "查询详情应该成功")
+	assert.Equal(t, errorx.ErrDataNotFound, serve(t, r, http.MethodGet, "/records/999", nil).Code, "数据不存在时应该返回数据未找到")
+
+	// 列表和 filter 参数
+	assert.Equal(t, []string{"b", "a"}, listNames(t, r, ""), "默认按主键倒序")
+	assert.Equal(t, []string{"a", "b"}, listNames(t, r, "?Sort=ASC"), "应该按主键正序")
+	assert.Equal(t, []string{"a"}, listNames(t, r, "?filter=name:eq:a"), "应该按 filter 参数过滤")
+	assert.Equal(t, errorx.ErrParamParse, serve(t, r, http.MethodGet, "/records?filter=status:eq:on", nil).Code, "未声明的过滤字段应该返回参数错误")
+
+	// 修改全部字段，包括零值，主键不变
+	resp = serve(t, r, http.MethodPut, path, map[string]interface{}{"id": 100, "name": "c"})
+	assert.Equal(t, errorx.ErrNormal, resp.Code, "修改应该成功")
+	var updated crudRecord
+	assert.NoError(t, json.Unmarshal(resp.Data, &updated))
+	assert.Equal(t, created.ID, updated.ID, "修改不应该改变主键")
+	assert.Equal(t, "c", updated.Name)
+	assert.Empty(t, updated.Status, "零值字段应该被修改")
+	assert.Equal(t, created.CreatedAt.Unix(), updated.CreatedAt.Unix(), "修改不应该改变创建时间")
+
+	// 删除
+	assert.Equal(t, errorx.ErrNormal, serve(t, r, http.MethodDelete, path, nil).Code, "删除应该成功")
+	assert.Equal(t, errorx.ErrDataNotFound, serve(t, r, http.MethodGet, path, nil).Code, "删除后应该查询不到")
+	assert.Equal(t, errorx.ErrDataNotFound, serve(t, r, http.MethodDelete, path, nil).Code, "重复删除应该返回数据未找到")
+}
+
+func TestHandlerScope(t *testing.T) {
+	r, db := newEngine(t, &auth.DataScope{OrganizationIds: []uint{1}}, crud.Hooks[crudRecord]{
+		// 只能访问启用的数据
+		Scope: func(c *gin.Context) crud.Scope {
+			return func(db *gorm.DB) *gorm.DB { return db.Where("status = ?", "on") }
+		},
+	})
+	records := []*crudRecord{
+		{Name: "a", Status: "on", OrganizationId: 1},
+		{Name: "b", Status: "on", OrganizationId: 2},
+		{Name: "c", Status: "off", OrganizationId: 1},
+	}
+	assert.NoError(t, db.Create(records).Error)
+
+	assert.Equal(t, []string{"a"}, listNames(t, r, ""), "列表应该按数据权限和 Scope 钩子过滤")
+	for _, record := range records[1:] {
+		path := fmt.Sprintf("/records/%d", record.ID)
+		assert.Equal(t, errorx.ErrDataNotFound, serve(t, r, http.MethodGet, path, nil).Code, "范围外的数据应该查询不到")
+		assert.Equal(t, errorx.ErrDataNotFound, serve(t, r, http.MethodPut, path, map[string]string{"name": "d"}).Code, "范围外的数据不能修改")
+		assert.Equal(t, errorx.ErrDataNotFound, serve(t, r, http.MethodDelete, path, nil).Code, "范围外的数据不能删除")
+	}
+	var count int64
+	assert.NoError(t, db.Model(&crudRecord{}).Where("name = ?", "d").Count(&count).Error)
+	assert.Equal(t, int64(0), count, "范围外的数据不应该被修改")
+
+	// 上下文中没有数据权限范围时不返回任何数据
+	r, db = newEngine(t, nil, crud.Hooks[crudRecord]{})
+	assert.NoError(t, db.Create(&crudRecord{Name: "a"}).Error)
+	assert.Empty(t, listNames(t, r, ""), "没有数据权限范围时不应该返回数据")
+}
+
+func TestHandlerHooks(t *testing.T) {
+	var actions []string
+	r, db := newEngine(t, &auth.DataScope{All: true}, crud.Hooks[crudRecord]{
+		// 拒绝删除
+		Authorize: func(c *gin.Context, action string) error {
+			actions = append(actions, action)
+			if action == crud.ActionDelete {
+				return errorx.NewCodeError(errorx.ErrPermissionDenied, "权限不足")
+			}
+			return nil
+		},
+		// 名称不能为 admin
+		Validate: func(c *gin.Context, action string, entity *crudRecord) error {
+			if entity.Name == "admin" {
+				return validator.FieldErrors{"name": "名称不能为 admin"}
+			}
+			return nil
+		},
+	})
+	record := &crudRecord{Name: "a"}
+	assert.NoError(t, db.Create(record).Error)
+	path := fmt.Sprintf("/records/%d", record.ID)
+
+	assert.Equal(t, errorx.ErrParamParse, serve(t, r, http.MethodPost, "/records", map[string]string{"name": "admin"}).Code, "创建时应该执行 Validate 钩子")
+	assert.Equal(t, errorx.ErrParamParse, serve(t, r, http.MethodPut, path, map[string]string{"name": "admin"}).Code, "修改时应该执行 Validate 钩子")
+	var count int64
+	assert.NoError(t, db.Model(&crudRecord{}).Where("name = ?", "admin").Count(&count).Error)
+	assert.Equal(t, int64(0), count, "校验失败时不应该写入数据")
+
+	assert.Equal(t, errorx.ErrPermissionDenied, serve(t, r, http.MethodDelete, path, nil).Code, "Authorize 钩子拒绝时应该返回其错误")
+	assert.NoError(t, db.First(&crudRecord{}, record.ID).Error, "拒绝删除时数据应该保留")
+
+	serve(t, r, http.MethodGet, "/records", nil)
+	serve(t, r, http.MethodGet, path, nil)
+	assert.Equal(t, []string{crud.ActionCreate, crud.ActionUpdate, crud.ActionDelete, crud.ActionList, crud.ActionGet}, actions,
+		"每个操作都应该执行 Authorize 钩子")
+}
@@ -0,0 +1,119 @@
+package crud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/yanshicheng/ikube-gin-starter/common/errorx"
+	"github.com/yanshicheng/ikube-gin-starter/common/model"
+	"github.com/yanshicheng/ikube-gin-starter/common/sql"
+	"github.com/yanshicheng/ikube-gin-starter/common/types"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 通用增删改查：Repository 封装基于 model.Model 的模型的五个基础操作，Handler 在此基础上注册五个路由。
+// 模型需要嵌入 model.Model，业务逻辑较多的模型仍然按 handler、logic、sql 分层实现。
+
+// Scope GORM 查询条件，用于限制可以访问的数据
+type Scope = func(*gorm.DB) *gorm.DB
+
+// Store 通用增删改查的数据操作，Repository 实现该接口，应用的 service 可以嵌入该接口
+type Store[T model.Entity] interface {
+	Create(ctx context.Context, entity *T) error
+	Get(ctx context.Context, id uint, scopes ...Scope) (*T, error)
+	List(ctx context.Context, page types.Pagination, scopes ...Scope) (*types.QueryResponse, error)
+	Update(ctx context.Context, id uint, entity *T, scopes ...Scope) (*T, error)
+	Delete(ctx context.Context, id uint, scopes ...Scope) error
+}
+
+var _ Store[model.Model] = (*Repository[model.Model])(nil)
+
+type Repository[T model.Entity] struct {
+	l  *zap.Logger
+	db *gorm.DB
+}
+
+func NewRepository[T model.Entity](db *gorm.DB, l *zap.Logger) *Repository[T] {
+	return &Repository[T]{l: l, db: db}
+}
+
+// DB 返回模型 T 的查询，用于通用操作之外的查询
+func (r *Repository[T]) DB(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Model(new(T))
+}
+
+func (r *Repository[T]) Create(ctx context.Context, entity *T) error {
+	setId(entity, 0)
+	if err := r.db.WithContext(ctx).Create(entity).Error; err != nil {
+		r.l.Error(fmt.Sprintf("创建数据失败, error: %s", err))
+		return errorx.NewCodeError(errorx.ErrDataCreation, "创建数据失败")
+	}
+	return nil
+}
+
+// Get 按主键查询，数据不存在或不在 scopes 范围内时返回 ErrDataNotFound
+func (r *Repository[T]) Get(ctx context.Context, id uint, scopes ...Scope) (*T, error) {
+	entity := new(T)
+	err := r.db.WithContext(ctx).Scopes(scopes...).
+		Where(clause.Eq{Column: clause.PrimaryColumn, Value: id}).
+		First(entity).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errorx.NewCodeError(errorx.ErrDataNotFound, "数据不存在")
+		}
+		r.l.Error(fmt.Sprintf("查询数据失败, id: %d, error: %s", id, err))
+		return nil, fmt.Errorf("查询数据失败")
+	}
+	return entity, nil
+}
+
+// List 分页查询，按主键和 page.Sort 排序
+func (r *Repository[T]) List(ctx context.Context, page types.Pagination, scopes ...Scope) (*types.QueryResponse, error) {
+	db := r.DB(ctx).Scopes(scopes...).
+		Order(clause.OrderByColumn{Column: clause.PrimaryColumn, Desc: page.Sort != "ASC"})
+	items := make([]T, 0)
+	resp, err := sql.GetPageResponse(db, page, &items)
+	if err != nil {
+		r.l.Error(fmt.Sprintf("查询数据列表失败, error: %s", err))
+		return nil, fmt.Errorf("查询数据列表失败")
+	}
+	return resp, nil
+}
+
+// Update 修改全部字段，包括零值，主键、创建时间、删除时间和关联不修改，返回修改后的数据
+func (r *Repository[T]) Update(ctx context.Context, id uint, entity *T, scopes ...Scope) (*T, error) {
+	old, err := r.Get(ctx, id, scopes...)
+	if err != nil {
+		return nil, err
+	}
+	setId(entity, id)
+	err = r.db.WithContext(ctx).Model(old).
+		Select("*").Omit("ID", "CreatedAt", "DeletedAt", clause.Associations).
+		Updates(entity).Error
+	if err != nil {
+		r.l.Error(fmt.Sprintf("修改数据失败, id: %d, error: %s", id, err))
+		return nil, fmt.Errorf("修改数据失败")
+	}
+	return r.Get(ctx, id)
+}
+
+// Delete 按主键删除，数据不存在或不在 scopes 范围内时返回 ErrDataNotFound
+func (r *Repository[T]) Delete(ctx context.Context, id uint, scopes ...Scope) error {
+	entity, err := r.Get(ctx, id, scopes...)
+	if err != nil {
+		return err
+	}
+	if err := r.db.WithContext(ctx).Delete(entity).Error; err != nil {
+		r.l.Error(fmt.Sprintf("删除数据失败, id: %d, error: %s", id, err))
+		return errorx.NewCodeError(errorx.ErrDataDeletion, "删除数据失败")
+	}
+	return nil
+}
+
+// setId 设置模型的主键，T 嵌入了 model.Model，*T 一定包含 SetId 方法
+func setId[T model.Entity](entity *T, id uint) {
+	any(entity).(interface{ SetId(uint) }).SetId(id)
+}
@@ -14,6 +14,8 @@ const (
 	ErrTokenBlacklisted ErrorCode = 11004 // Token 被列入黑名单
 	ErrLoginExpired     ErrorCode = 10110 // 登录过期
 	ErrLoginInvalid     ErrorCode = 10111 // 登录信息无效
+	ErrPasswordExpired  ErrorCode = 10112 // 密码过期或被重置，需要修改密码后登录
+	ErrOtpRequired      ErrorCode = 10113 // 需要两步验证，Data 中携带验证凭据
 	// 权限相关
 
 	ErrPermissionDenied ErrorCode = 10130 // 权限不足
@@ -34,3 +36,24 @@ const (
 	// 服务器相关错误
 	ErrServerErr ErrorCode = 10500
 )
+
+// CodeError 携带错误码的错误，用于业务层向接口层传递具体的错误码
+type CodeError struct {
+	Code ErrorCode
+	Msg  string
+	Data interface{} // 需要返回给前端的附加数据，可以为空
+}
+
+func (e *CodeError) Error() string {
+	return e.Msg
+}
+
+// NewCodeError 创建携带错误码的错误
+func NewCodeError(code ErrorCode, msg string) error {
+	return &CodeError{Code: code, Msg: msg}
+}
+
+// NewCodeErrorData 创建携带错误码和附加数据的错误
+func NewCodeErrorData(code ErrorCode, msg string, data interface{}) error {
+	return &CodeError{Code: code, Msg: msg, Data: data}
+}
@@ -0,0 +1,330 @@
+package filter
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yanshicheng/ikube-gin-starter/common/validator"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// 列表过滤条件：查询参数 filter=字段:操作:值，可以传多个，多个条件之间为 AND。
+// 模型通过 filter 标签声明允许过滤的字段和操作，如 filter:"eq,in,like"，字段使用 json 名称，
+// 未声明的字段和操作按参数错误返回。in 和 between 的多个值用逗号分隔，isnull 的值为 true 或 false，省略时为 true。
+// 值按字段类型转换后作为 SQL 参数，不会拼接到 SQL 语句中。
+
+// 支持的操作
+const (
+	OpEq      = "eq"      // 等于
+	OpNe      = "ne"      // 不等于
+	OpIn      = "in"      // 在多个值中
+	OpLike    = "like"    // 前缀匹配，只用于字符串，值中的 % 和 _ 按普通字符匹配
+	OpGt      = "gt"      // 大于
+	OpGte     = "gte"     // 大于等于
+	OpLt      = "lt"      // 小于
+	OpLte     = "lte"     // 小于等于
+	OpBetween = "between" // 在两个值之间，包含边界
+	OpIsNull  = "isnull"  // 为空或不为空
+)
+
+const (
+	maxFilters = 20  // 单次查询的过滤条件数量上限
+	maxValues  = 100 // in 操作的值数量上限
+)
+
+// timeLayouts 时间字段支持的格式
+var timeLayouts = []string{time.RFC3339, time.DateTime, time.DateOnly}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// likeEscaper 转义 like 值中的通配符和转义字符
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+var (
+	fieldsCache sync.Map // 模型类型 -> 允许过滤的字段
+	schemaCache sync.Map // GORM 模型解析缓存
+)
+
+// field 允许过滤的字段
+type field struct {
+	name string // 模型字段名，用于查找数据库列
+	typ  reflect.Type
+	ops  map[string]bool
+}
+
+// Condition 解析后的过滤条件
+type Condition struct {
+	Field  string        // 查询参数中的字段名
+	Op     string        // 操作
+	Values []interface{} // 按字段类型转换后的值
+	name   string
+}
+
+// Scope 解析过滤条件，返回模型 model 的查询条件，字段或值不合法时返回 validator.FieldErrors
+func Scope(model interface{}, filters []string) (func(*gorm.DB) *gorm.DB, error) {
+	conditions, err := Parse(model, filters)
+	if err != nil {
+		return nil, err
+	}
+	return func(db *gorm.DB) *gorm.DB {
+		if len(conditions) == 0 {
+			return db
+		}
+		s, err := schema.Parse(model, &schemaCache, db.NamingStrategy)
+		if err != nil {
+			_ = db.AddError(fmt.Errorf("解析过滤字段失败: %s", err))
+			return db
+		}
+		for _, c := range conditions {
+			f := s.LookUpField(c.name)
+			if f == nil || f.DBName == "" {
+				_ = db.AddError(fmt.Errorf("过滤字段 %s 没有对应的数据库列", c.Field))
+				return db
+			}
+			db = db.Where(c.expression(clause.Column{Table: clause.CurrentTable, Name: f.DBName}))
+		}
+		return db
+	}, nil
+}
+
+// Prefix 按前缀模糊匹配列 column，值中的通配符按普通字符匹配，用于列表接口中保留的简单查询参数
+func Prefix(column, value string) clause.Expression {
+	return Condition{Op: OpLike, Values: []interface{}{value}}.expression(clause.Column{Name: column})
+}
+
+// Parse 解析过滤条件，所有不合法的条件按字段汇总到 validator.FieldErrors 中返回
+func Parse(model interface{}, filters []string) ([]Condition, error) {
+	fields, err := modelFields(reflect.TypeOf(model))
+	if err != nil {
+		return nil, err
+	}
+	if len(filters) > maxFilters {
+		return nil, validator.FieldErrors{"filter": validator.Translate(tagTooMany, strconv.Itoa(maxFilters))}
+	}
+	errs := validator.FieldErrors{}
+	fail := func(name, msg string) {
+		// 同一字段只返回第一个错误
+		if _, ok := errs[name]; !ok {
+			errs[name] = msg
+		}
+	}
+	conditions := make([]Condition, 0, len(filters))
+	for _, raw := range filters {
+		parts := strings.SplitN(raw, ":", 3)
+		if len(parts) < 2 || parts[0] == "" {
+			fail("filter", validator.Translate(tagSyntax, raw))
+			continue
+		}
+		name, op := parts[0], parts[1]
+		f, ok := fields[name]
+		if !ok {
+			fail(name, validator.Translate(tagUnknown, name))
+			continue
+		}
+		if !f.ops[op] {
+			fail(name, validator.Translate(tagOperator, name, op))
+			continue
+		}
+		value := ""
+		if len(parts) == 3 {
+			value = parts[2]
+		}
+		values, msg := f.values(name, op, value)
+		if msg != "" {
+			fail(name, msg)
+			continue
+		}
+		conditions = append(conditions, Condition{Field: name, Op: op, Values: values, name: f.name})
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return conditions, nil
+}
+
+// values 按操作拆分并转换值，不合法时返回翻译后的错误信息
+func (f *field) values(name, op, value string) ([]interface{}, string) {
+	if op == OpIsNull {
+		if value == "" {
+			return []interface{}{true}, ""
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, validator.Translate(tagValue, name, value)
+		}
+		return []interface{}{b}, ""
+	}
+	raws := []string{value}
+	switch op {
+	case OpIn:
+		raws = strings.Split(value, ",")
+		if value == "" || len(raws) > maxValues {
+			return nil, validator.Translate(tagCount, name)
+		}
+	case OpBetween:
+		raws = strings.Split(value, ",")
+		if len(raws) != 2 {
+			return nil, validator.Translate(tagCount, name)
+		}
+	}
+	values := make([]interface{}, 0, len(raws))
+	for _, raw := range raws {
+		v, err := convert(f.typ, raw)
+		if err != nil {
+			return nil, validator.Translate(tagValue, name, raw)
+		}
+		values = append(values, v)
+	}
+	return values, ""
+}
+
+// expression 生成参数化的查询条件
+func (c Condition) expression(column clause.Column) clause.Expression {
+	switch c.Op {
+	case OpNe:
+		return clause.Neq{Column: column, Value: c.Values[0]}
+	case OpIn:
+		return clause.IN{Column: column, Values: c.Values}
+	case OpLike:
+		// 值中的通配符按普通字符匹配，转义字符使用 ! 避免与 MySQL 字符串中的反斜杠转义冲突
+		return clause.Expr{SQL: "? LIKE ? ESCAPE '!'", Vars: []interface{}{column, likeEscaper.Replace(c.Values[0].(string)) + "%"}}
+	case OpGt:
+		return clause.Gt{Column: column, Value: c.Values[0]}
+	case OpGte:
+		return clause.Gte{Column: column, Value: c.Values[0]}
+	case OpLt:
+		return clause.Lt{Column: column, Value: c.Values[0]}
+	case OpLte:
+		return clause.Lte{Column: column, Value: c.Values[0]}
+	case OpBetween:
+		return clause.Expr{SQL: "? BETWEEN ? AND ?", Vars: []interface{}{column, c.Values[0], c.Values[1]}}
+	case OpIsNull:
+		if c.Values[0].(bool) {
+			return clause.Eq{Column: column, Value: nil}
+		}
+		return clause.Neq{Column: column, Value: nil}
+	default:
+		return clause.Eq{Column: column, Value: c.Values[0]}
+	}
+}
+
+// modelFields 解析模型中声明了 filter 标签的字段，按 json 名称索引，结果按类型缓存
+func modelFields(typ reflect.Type) (map[string]*field, error) {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if v, ok := fieldsCache.Load(typ); ok {
+		return v.(map[string]*field), nil
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("过滤模型 %s 不是结构体", typ)
+	}
+	fields := make(map[string]*field)
+	if err := collectFields(typ, typ, fields); err != nil {
+		return nil, err
+	}
+	fieldsCache.Store(typ, fields)
+	return fields, nil
+}
+
+func collectFields(model, typ reflect.Type, fields map[string]*field) error {
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct && sf.Tag.Get("filter") == "" {
+			if err := collectFields(model, sf.Type, fields); err != nil {
+				return err
+			}
+			continue
+		}
+		tag := sf.Tag.Get("filter")
+		if tag == "" || tag == "-" || !sf.IsExported() {
+			continue
+		}
+		name := strings.SplitN(sf.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		f := &field{name: sf.Name, typ: sf.Type, ops: make(map[string]bool)}
+		for _, op := range strings.Split(tag, ",") {
+			op = strings.TrimSpace(op)
+			if !supported(sf.Type, op) {
+				return fmt.Errorf("模型 %s 字段 %s 不支持 %s 过滤", model, sf.Name, op)
+			}
+			f.ops[op] = true
+		}
+		fields[name] = f
+	}
+	return nil
+}
+
+// supported 判断字段类型是否支持该操作，isnull 支持所有类型
+func supported(typ reflect.Type, op string) bool {
+	if op == OpIsNull {
+		return true
+	}
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	kind := typ.Kind()
+	if typ != timeType && !scalar(kind) {
+		return false
+	}
+	switch op {
+	case OpEq, OpNe, OpIn:
+		return true
+	case OpLike:
+		return kind == reflect.String
+	case OpGt, OpGte, OpLt, OpLte, OpBetween:
+		return kind != reflect.Bool
+	default:
+		return false
+	}
+}
+
+func scalar(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// convert 按字段类型转换查询参数中的值
+func convert(typ reflect.Type, value string) (interface{}, error) {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == timeType {
+		for _, layout := range timeLayouts {
+			if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+				return t, nil
+			}
+		}
+		return nil, fmt.Errorf("时间格式错误: %s", value)
+	}
+	switch typ.Kind() {
+	case reflect.String:
+		return value, nil
+	case reflect.Bool:
+		return strconv.ParseBool(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.ParseInt(value, 10, typ.Bits())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.ParseUint(value, 10, typ.Bits())
+	case reflect.Float32, reflect.Float64:
+		return strconv.ParseFloat(value, typ.Bits())
+	}
+	return nil, fmt.Errorf("不支持的字段类型: %s", typ)
+}
@@ -0,0 +1,159 @@
+package filter_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yanshicheng/ikube-gin-starter/common/filter"
+	"github.com/yanshicheng/ikube-gin-starter/common/model"
+	"github.com/yanshicheng/ikube-gin-starter/common/validator"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/testenv"
+	"gorm.io/gorm"
+)
+
+// filterRecord 声明了各类型过滤字段的模型，Secret 未声明 filter 标签
+type filterRecord struct {
+	model.Model
+	Name     string     `json:"name" gorm:"type:varchar(32)" filter:"eq,ne,in,like"`
+	Count    int        `json:"count" filter:"eq,in,gt,gte,lt,lte,between"`
+	Enabled  bool       `json:"enabled" filter:"eq"`
+	ExpireAt *time.Time `json:"expireAt" filter:"lt,isnull"`
+	Secret   string     `json:"secret"`
+}
+
+func TestParse(t *testing.T) {
+	testenv.Setup(t)
+	tests := []struct {
+		name    string
+		filters []string
+		values  []interface{} // 解析成功时第一个条件的值
+		errs    validator.FieldErrors
+	}{
+		{"等于", []string{"name:eq:go"}, []interface{}{"go"}, nil},
+		{"值中包含冒号", []string{"name:eq:a:b"}, []interface{}{"a:b"}, nil},
+		{"按字段类型转换", []string{"count:gt:10"}, []interface{}{int64(10)}, nil},
+		{"布尔值", []string{"enabled:eq:true"}, []interface{}{true}, nil},
+		{"公共字段", []string{"id:in:1,2"}, []interface{}{uint64(1), uint64(2)}, nil},
+		{"between", []string{"count:between:1,5"}, []interface{}{int64(1), int64(5)}, nil},
+		{"isnull 省略值", []string{"expireAt:isnull"}, []interface{}{true}, nil},
+		{"isnull", []string{"expireAt:isnull:false"}, []interface{}{false}, nil},
+		{"格式错误", []string{"name"}, nil, validator.FieldErrors{"filter": "过滤条件name格式错误，应为 字段:操作:值"}},
+		{"未声明的字段", []string{"secret:eq:a"}, nil, validator.FieldErrors{"secret": "secret不支持过滤"}},
+		{"不存在的字段", []string{"password:eq:a"}, nil, validator.FieldErrors{"password": "password不支持过滤"}},
+		{"未声明的操作", []string{"name:gt:a"}, nil, validator.FieldErrors{"name": "name不支持gt操作"}},
+		{"未知的操作", []string{"count:regex:1"}, nil, validator.FieldErrors{"count": "count不支持regex操作"}},
+		{"值类型错误", []string{"count:eq:abc"}, nil, validator.FieldErrors{"count": "count的值abc格式错误"}},
+		{"in 中的值类型错误", []string{"count:in:1,x"}, nil, validator.FieldErrors{"count": "count的值x格式错误"}},
+		{"in 没有值", []string{"count:in:"}, nil, validator.FieldErrors{"count": "count的值数量错误"}},
+		{"between 值数量错误", []string{"count:between:1"}, nil, validator.FieldErrors{"count": "count的值数量错误"}},
+		{"between 值过多", []string{"count:between:1,2,3"}, nil, validator.FieldErrors{"count": "count的值数量错误"}},
+		{"isnull 值错误", []string{"expireAt:isnull:maybe"}, nil, validator.FieldErrors{"expireAt": "expireAt的值maybe格式错误"}},
+		{"按字段汇总错误", []string{"secret:eq:a", "count:eq:abc", "count:in:"}, nil,
+			validator.FieldErrors{"secret": "secret不支持过滤", "count": "count的值abc格式错误"}},
+		{"条件过多", make([]string, 21), nil, validator.FieldErrors{"filter": "过滤条件不能超过20个"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conditions, err := filter.Parse(&filterRecord{}, tt.filters)
+			if tt.errs != nil {
+				assert.Equal(t, tt.errs, err, "错误信息与预期不符")
+				return
+			}
+			if assert.NoError(t, err) && assert.Len(t, conditions, 1) {
+				assert.Equal(t, tt.values, conditions[0].Values, "解析的值与预期不符")
+			}
+		})
+	}
+
+	// in 最多 100 个值
+	_, err := filter.Parse(&filterRecord{}, []string{"count:in:" + strings.Repeat("1,", 100) + "1"})
+	assert.Equal(t, validator.FieldErrors{"count": "count的值数量错误"}, err, "in 超过 100 个值时应该返回错误")
+
+	// 模型声明了字段类型不支持的操作
+	_, err = filter.Parse(&struct {
+		Enabled bool `json:"enabled" filter:"like"`
+	}{}, nil)
+	if assert.Error(t, err, "声明了不支持的操作时应该返回错误") {
+		assert.Contains(t, err.Error(), "字段 Enabled 不支持 like 过滤")
+	}
+}
+
+func TestScope(t *testing.T) {
+	testenv.Setup(t)
+	db := testenv.DB(t, &filterRecord{})
+	tests := []struct {
+		name    string
+		filters []string
+		sql     string
+		vars    []interface{}
+	}{
+		{"没有条件", nil, "SELECT * FROM `filter_records` WHERE `filter_records`.`deleted_at` IS NULL", nil},
+		{"多个条件", []string{"name:ne:a", "count:gte:1"},
+			"SELECT * FROM `filter_records` WHERE `filter_records`.`name` <> ? AND `filter_records`.`count` >= ? AND `filter_records`.`deleted_at` IS NULL",
+			[]interface{}{"a", int64(1)}},
+		{"in", []string{"id:in:1,2"},
+			"SELECT * FROM `filter_records` WHERE `filter_records`.`id` IN (?,?) AND `filter_records`.`deleted_at` IS NULL",
+			[]interface{}{uint64(1), uint64(2)}},
+		{"like 转义通配符", []string{"name:like:50%_off!"},
+			"SELECT * FROM `filter_records` WHERE `filter_records`.`name` LIKE ? ESCAPE '!' AND `filter_records`.`deleted_at` IS NULL",
+			[]interface{}{"50!%!_off!!%"}},
+		{"between", []string{"count:between:1,5"},
+			"SELECT * FROM `filter_records` WHERE (`filter_records`.`count` BETWEEN ? AND ?) AND `filter_records`.`deleted_at` IS NULL",
+			[]interface{}{int64(1), int64(5)}},
+		{"isnull", []string{"expireAt:isnull"},
+			"SELECT * FROM `filter_records` WHERE `filter_records`.`expire_at` IS NULL AND `filter_records`.`deleted_at` IS NULL", nil},
+		{"is not null", []string{"expireAt:isnull:false"},
+			"SELECT * FROM `filter_records` WHERE `filter_records`.`expire_at` IS NOT NULL AND `filter_records`.`deleted_at` IS NULL", nil},
+		{"注入的值作为参数", []string{"name:eq:a' OR '1'='1"},
+			"SELECT * FROM `filter_records` WHERE `filter_records`.`name` = ? AND `filter_records`.`deleted_at` IS NULL",
+			[]interface{}{"a' OR '1'='1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scope, err := filter.Scope(&filterRecord{}, tt.filters)
+			assert.NoError(t, err)
+			stmt := db.Session(&gorm.Session{DryRun: true}).Scopes(scope).Find(&[]filterRecord{}).Statement
+			assert.Equal(t, tt.sql, stmt.SQL.String(), "生成的 SQL 与预期不符")
+			assert.Equal(t, tt.vars, stmt.Vars, "SQL 参数与预期不符")
+		})
+	}
+
+	_, err := filter.Scope(&filterRecord{}, []string{"secret:eq:a"})
+	assert.IsType(t, validator.FieldErrors{}, err, "条件不合法时应该返回参数错误")
+}
+
+func TestScopeLike(t *testing.T) {
+	testenv.Setup(t)
+	db := testenv.DB(t, &filterRecord{})
+	assert.NoError(t, db.Create([]*filterRecord{{Name: "50%_off"}, {Name: "50 off"}, {Name: "50a_off"}, {Name: "a!b"}}).Error)
+
+	names := func(filters ...string) []string {
+		scope, err := filter.Scope(&filterRecord{}, filters)
+		assert.NoError(t, err)
+		var records []filterRecord
+		assert.NoError(t, db.Scopes(scope).Order("id").Find(&records).Error)
+		names := make([]string, 0, len(records))
+		for _, record := range records {
+			names = append(names, record.Name)
+		}
+		return names
+	}
+	assert.Equal(t, []string{"50%_off"}, names("name:like:50%"), "% 应该按普通字符匹配")
+	assert.Equal(t, []string{"50%_off", "50 off", "50a_off"}, names("name:like:50"), "应该按前缀匹配")
+	assert.Empty(t, names("name:like:50_"), "_ 应该按普通字符匹配")
+	assert.Equal(t, []string{"a!b"}, names("name:like:a!"), "转义字符应该按普通字符匹配")
+
+	// Prefix 与 like 操作使用相同的转义规则
+	prefix := func(value string) []string {
+		var names []string
+		assert.NoError(t, db.Model(&filterRecord{}).Where(filter.Prefix("name", value)).Order("id").Pluck("name", &names).Error)
+		return names
+	}
+	assert.Equal(t, []string{"50%_off"}, prefix("50%"), "% 应该按普通字符匹配")
+	assert.Equal(t, []string{"50%_off", "50 off", "50a_off"}, prefix("50"), "应该按前缀匹配")
+	assert.Empty(t, prefix("50_"), "_ 应该按普通字符匹配")
+	stmt := db.Session(&gorm.Session{DryRun: true}).Where(filter.Prefix("t.name", "a")).Find(&[]filterRecord{}).Statement
+	assert.Contains(t, stmt.SQL.String(), "`t`.`name` LIKE ? ESCAPE '!'", "应该支持带表名的列")
+}
@@ -0,0 +1,47 @@
+package filter
+
+import (
+	"github.com/yanshicheng/ikube-gin-starter/common/validator"
+)
+
+// 过滤条件错误信息的翻译标签，通过参数校验的翻译器按配置的 app.language 翻译
+const (
+	tagSyntax   = "filterSyntax"
+	tagUnknown  = "filterUnknown"
+	tagOperator = "filterOperator"
+	tagValue    = "filterValue"
+	tagCount    = "filterCount"
+	tagTooMany  = "filterTooMany"
+)
+
+// 注册
+func init() {
+	for _, vt := range []*validator.ValidatorTranslation{
+		{Tag: tagSyntax, Translations: []validator.TranslationDetail{
+			{Locale: validator.LocaleEN, TranslationMsg: "filter {0} must be in the form field:op:value"},
+			{Locale: validator.LocaleZH, TranslationMsg: "过滤条件{0}格式错误，应为 字段:操作:值"},
+		}},
+		{Tag: tagUnknown, Translations: []validator.TranslationDetail{
+			{Locale: validator.LocaleEN, TranslationMsg: "{0} is not filterable"},
+			{Locale: validator.LocaleZH, TranslationMsg: "{0}不支持过滤"},
+		}},
+		{Tag: tagOperator, Translations: []validator.TranslationDetail{
+			{Locale: validator.LocaleEN, TranslationMsg: "{0} does not support operator {1}"},
+			{Locale: validator.LocaleZH, TranslationMsg: "{0}不支持{1}操作"},
+		}},
+		{Tag: tagValue, Translations: []validator.TranslationDetail{
+			{Locale: validator.LocaleEN, TranslationMsg: "{0} has an invalid value {1}"},
+			{Locale: validator.LocaleZH, TranslationMsg: "{0}的值{1}格式错误"},
+		}},
+		{Tag: tagCount, Translations: []validator.TranslationDetail{
+			{Locale: validator.LocaleEN, TranslationMsg: "{0} has a wrong number of values"},
+			{Locale: validator.LocaleZH, TranslationMsg: "{0}的值数量错误"},
+		}},
+		{Tag: tagTooMany, Translations: []validator.TranslationDetail{
+			{Locale: validator.LocaleEN, TranslationMsg: "no more than {0} filters are allowed"},
+			{Locale: validator.LocaleZH, TranslationMsg: "过滤条件不能超过{0}个"},
+		}},
+	} {
+		validator.RegistryValidator(vt)
+	}
+}
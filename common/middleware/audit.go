@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/yanshicheng/ikube-gin-starter/common/audit"
+	"github.com/yanshicheng/ikube-gin-starter/common/auth"
+)
+
+// Audit 审计中间件，将操作人和请求信息写入上下文，由审计回调记录到数据变更日志中。
+// 鉴权路由需要放在鉴权中间件之后，公开路由的操作人为空。
+func Audit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actor := &audit.Actor{
+			Ip:     c.ClientIP(),
+			Method: c.Request.Method,
+			Route:  c.FullPath(),
+		}
+		if claims, ok := auth.GetClaims(c); ok {
+			actor.AccountId = claims.AccountId
+			actor.Account = claims.Account
+		} else if principal, ok := auth.GetApiKey(c); ok {
+			actor.ServiceAccountId = principal.ServiceAccountId
+			actor.Account = principal.Name
+		}
+		audit.SetActor(c, actor)
+		c.Next()
+	}
+}
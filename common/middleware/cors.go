@@ -29,10 +29,11 @@ func Cors() gin.HandlerFunc {
 
 		// AllowHeaders 定义了允许在请求中发送的自定义头信息。
 		AllowHeaders: []string{
-			"Origin",         // 允许 Origin 头
-			"Content-Length", // 允许 Content-Length 头
-			"Content-Type",   // 允许 Content-Type 头
-			"Authorization",  // 允许 Authorization 头，通常用于认证信息
+			"Origin",           // 允许 Origin 头
+			"Content-Length",   // 允许 Content-Length 头
+			"Content-Type",     // 允许 Content-Type 头
+			"Authorization",    // 允许 Authorization 头，通常用于认证信息
+			"X-Application-Id", // 允许 X-Application-Id 头，用于声明当前所在的应用
 		},
 
 		// AllowCredentials 设置为 false，表示服务器不会将响应的 Cookies 包含在响应中。
@@ -42,9 +43,11 @@ func Cors() gin.HandlerFunc {
 		// MaxAge 定义了预请求（OPTIONS 请求）的有效时间，这里设置为 12 小时。
 		MaxAge: 12 * time.Hour,
 
-		// ExposeHeaders 定义了客户端可以访问的响应头，这里只允许 Content-Length。
-		//ExposeHeaders: []string{
-		//	"Content-Length", // 允许客户端访问响应的 Content-Length 头
-		//},
+		// ExposeHeaders 定义了客户端可以访问的响应头，这里允许访问 API 版本弃用相关的头。
+		ExposeHeaders: []string{
+			"Deprecation", // 版本已弃用
+			"Sunset",      // 版本下线时间
+			"Link",        // 迁移说明地址
+		},
 	})
 }
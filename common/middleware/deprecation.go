@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deprecation 弃用版本中间件，在响应中携带 Deprecation、Sunset 和 Link 头，提示客户端迁移到新版本。
+// deprecation 为零值时 Deprecation 头为 true，sunset 为零值或 link 为空时不携带对应的头
+func Deprecation(deprecation, sunset time.Time, link string) gin.HandlerFunc {
+	value := "true"
+	if !deprecation.IsZero() {
+		value = fmt.Sprintf("@%d", deprecation.Unix())
+	}
+	return func(c *gin.Context) {
+		header := c.Writer.Header()
+		header.Set("Deprecation", value)
+		if !sunset.IsZero() {
+			header.Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+		}
+		if link != "" {
+			header.Add("Link", fmt.Sprintf("<%s>; rel=\"deprecation\"", link))
+		}
+		c.Next()
+	}
+}
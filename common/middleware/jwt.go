@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yanshicheng/ikube-gin-starter/common/auth"
+	"github.com/yanshicheng/ikube-gin-starter/common/errorx"
+	"github.com/yanshicheng/ikube-gin-starter/common/response"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/jwt"
+)
+
+// JwtAuth 鉴权中间件，校验请求头中的 access token，并将账号信息写入上下文。
+// 启用 api key 时，携带 api key 请求头的服务账号请求改为校验 api key。
+func JwtAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if global.C.ApiKey.Enable {
+			if key := c.GetHeader(global.C.ApiKey.Header); key != "" {
+				apiKeyAuth(c, key)
+				return
+			}
+		}
+		header := c.GetHeader("Authorization")
+		if header == "" {
+			response.FailedCode(c, errorx.ErrTokenMissing, "请求未携带 token")
+			c.Abort()
+			return
+		}
+		// 格式为 Bearer <token>
+		parts := strings.SplitN(header, " ", 2)
+		if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") || parts[1] == "" {
+			response.FailedCode(c, errorx.ErrTokenInvalid, "token 格式错误")
+			c.Abort()
+			return
+		}
+		claims, err := global.J.ParseToken(parts[1])
+		if err != nil {
+			if errors.Is(err, jwt.ErrTokenExpired) {
+				response.FailedCode(c, errorx.ErrTokenExpired, "token 已过期")
+			} else {
+				global.LSys.Debug(err.Error())
+				response.FailedCode(c, errorx.ErrTokenInvalid, "token 无效")
+			}
+			c.Abort()
+			return
+		}
+		// refresh token 不允许访问业务接口
+		if claims.TokenType != jwt.TokenTypeAccess {
+			response.FailedCode(c, errorx.ErrTokenInvalid, "token 类型错误")
+			c.Abort()
+			return
+		}
+		// 黑名单检查，注销或强制下线的 token 立即失效
+		blacklisted, err := auth.IsBlacklisted(c, claims)
+		if err != nil {
+			global.LSys.Error(err.Error())
+			response.FailedCode(c, errorx.ErrServerErr, "token 校验失败")
+			c.Abort()
+			return
+		}
+		if blacklisted {
+			response.FailedCode(c, errorx.ErrTokenBlacklisted, "token 已失效，请重新登录")
+			c.Abort()
+			return
+		}
+		// 记录会话最后活跃时间，失败不影响请求
+		if err := auth.TouchSession(c, claims, c.ClientIP()); err != nil {
+			global.LSys.Warn(err.Error())
+		}
+		auth.SetClaims(c, claims)
+		c.Next()
+	}
+}
+
+// apiKeyAuth 校验 api key，并将服务账号信息写入上下文
+func apiKeyAuth(c *gin.Context, key string) {
+	validator := auth.GetApiKeyValidator()
+	if validator == nil {
+		response.FailedCode(c, errorx.ErrTokenInvalid, "api key 无效")
+		c.Abort()
+		return
+	}
+	principal, err := validator.ValidateApiKey(c, key)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrApiKeyExpired):
+			response.FailedCode(c, errorx.ErrTokenExpired, "api key 已过期")
+		case errors.Is(err, auth.ErrApiKeyInvalid):
+			response.FailedCode(c, errorx.ErrTokenInvalid, "api key 无效")
+		default:
+			global.LSys.Error(err.Error())
+			response.FailedCode(c, errorx.ErrServerErr, "api key 校验失败")
+		}
+		c.Abort()
+		return
+	}
+	auth.SetApiKey(c, principal)
+	c.Next()
+}
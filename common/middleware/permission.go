@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yanshicheng/ikube-gin-starter/common/auth"
+	"github.com/yanshicheng/ikube-gin-starter/common/errorx"
+	"github.com/yanshicheng/ikube-gin-starter/common/response"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+)
+
+// Permission 权限中间件，需在 JwtAuth 之后使用。
+// 以路由模式作为资源，根据 HTTP 方法映射读写操作，校验当前账号是否拥有对应权限。
+// 服务账号只能访问 api key 授权范围内的资源，不受 RBAC 开关影响。
+func Permission() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if principal, ok := auth.GetApiKey(c); ok {
+			apiKeyPermission(c, principal)
+			return
+		}
+		if !global.C.Rbac.Enable {
+			// 未启用 rbac 时不限制数据范围
+			auth.SetDataScope(c, &auth.DataScope{All: true})
+			c.Next()
+			return
+		}
+		// 启用 rbac 但未注册权限加载实现时拒绝所有请求，启动时已经检查，这里作为兜底
+		if auth.GetAuthorizer() == nil {
+			global.LSys.Error("已启用 rbac 但未注册权限加载实现")
+			response.FailedCode(c, errorx.ErrPermissionDenied, "权限不足")
+			c.Abort()
+			return
+		}
+		claims, ok := auth.GetClaims(c)
+		if !ok {
+			response.FailedCode(c, errorx.ErrTokenMissing, "请求未携带 token")
+			c.Abort()
+			return
+		}
+		resource := resourceOf(c)
+		// 未匹配到路由或在跳过列表中的资源不做校验，只能访问本人的数据
+		if resource == "" || skipPermission(resource) {
+			auth.SetDataScope(c, &auth.DataScope{Self: true, AccountId: claims.AccountId})
+			c.Next()
+			return
+		}
+		// 角色和权限都属于应用，未声明应用时无法确定权限范围，直接拒绝
+		applicationId := auth.GetApplicationId(c)
+		if applicationId == 0 {
+			response.FailedCode(c, errorx.ErrPermissionDenied, fmt.Sprintf("请求未声明所属应用, 请求头 %s 不能为空", auth.ApplicationHeader))
+			c.Abort()
+			return
+		}
+		perms, err := auth.GetPermissions(c, claims.AccountId, applicationId)
+		if err != nil {
+			global.LSys.Error(fmt.Sprintf("加载账号权限失败, id: %d, error: %s", claims.AccountId, err))
+			response.FailedCode(c, errorx.ErrServerErr, "权限校验失败")
+			c.Abort()
+			return
+		}
+		action := auth.ActionOf(c.Request.Method)
+		if !perms.Allow(resource, action) {
+			global.LSys.Info(fmt.Sprintf("权限不足, id: %d, resource: %s, action: %s", claims.AccountId, resource, action))
+			response.FailedCode(c, errorx.ErrPermissionDenied, "权限不足")
+			c.Abort()
+			return
+		}
+		// 超级管理员不限制数据范围
+		if perms.Super {
+			auth.SetDataScope(c, &auth.DataScope{All: true})
+		} else {
+			auth.SetDataScope(c, &perms.DataScope)
+		}
+		c.Next()
+	}
+}
+
+// apiKeyPermission 校验服务账号的 api key 授权范围
+func apiKeyPermission(c *gin.Context, principal *auth.ApiKeyPrincipal) {
+	resource := resourceOf(c)
+	action := auth.ActionOf(c.Request.Method)
+	perms := auth.PermissionSet{Permissions: principal.Scopes}
+	if resource == "" || !perms.Allow(resource, action) {
+		global.LSys.Info(fmt.Sprintf("api key 权限不足, key: %d, resource: %s, action: %s", principal.KeyId, resource, action))
+		response.FailedCode(c, errorx.ErrPermissionDenied, "权限不足")
+		c.Abort()
+		return
+	}
+	// 数据范围为签发人签发时的范围，没有记录范围的 key 不设置，数据权限过滤时不返回任何数据
+	if principal.DataScope != nil {
+		auth.SetDataScope(c, principal.DataScope)
+	}
+	c.Next()
+}
+
+// resourceOf 权限校验的资源，为去掉全局 API 前缀的路由模式
+func resourceOf(c *gin.Context) string {
+	return auth.ResourceOf(c.FullPath())
+}
+
+// skipPermission 判断资源是否在跳过权限校验的列表中
+func skipPermission(resource string) bool {
+	for _, pattern := range global.C.Rbac.SkipResources {
+		if auth.MatchResource(pattern, resource) {
+			return true
+		}
+	}
+	return false
+}
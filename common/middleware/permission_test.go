@@ -0,0 +1,129 @@
+package middleware_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yanshicheng/ikube-gin-starter/common/auth"
+	"github.com/yanshicheng/ikube-gin-starter/common/errorx"
+	"github.com/yanshicheng/ikube-gin-starter/common/middleware"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/jwt"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/testenv"
+)
+
+func TestPermissionWithoutAuthorizer(t *testing.T) {
+	testenv.Setup(t)
+	gin.SetMode(gin.TestMode)
+	enable := global.C.Rbac.Enable
+	t.Cleanup(func() { global.C.Rbac.Enable = enable })
+
+	r := gin.New()
+	r.Use(middleware.Permission())
+	r.GET("/portal/account/", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+	// 启用 rbac 但没有注册权限加载实现时拒绝请求
+	global.C.Rbac.Enable = true
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/portal/account/", nil))
+	var body struct{ Code errorx.ErrorCode }
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body), "响应应该是 json")
+	assert.Equal(t, errorx.ErrPermissionDenied, body.Code, "未注册权限加载实现时应该拒绝请求")
+
+	// 关闭 rbac 时不做校验
+	global.C.Rbac.Enable = false
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/portal/account/", nil))
+	assert.Equal(t, "ok", w.Body.String(), "关闭 rbac 时应该放行")
+}
+
+// recordAuthorizer 记录加载权限时的应用ID，只授予 /portal/account/ 的读权限
+type recordAuthorizer struct {
+	applicationIds []uint
+}
+
+func (r *recordAuthorizer) LoadPermissions(_ context.Context, _, applicationId uint) (*auth.PermissionSet, error) {
+	r.applicationIds = append(r.applicationIds, applicationId)
+	return &auth.PermissionSet{Permissions: []auth.Permission{{Resource: "/portal/account/", Action: auth.ActionRead}}}, nil
+}
+
+func TestPermissionRequireApplication(t *testing.T) {
+	testenv.Setup(t)
+	gin.SetMode(gin.TestMode)
+	enable := global.C.Rbac.Enable
+	previous := auth.GetAuthorizer()
+	t.Cleanup(func() {
+		global.C.Rbac.Enable = enable
+		auth.RegistryAuthorizer(previous)
+	})
+	global.C.Rbac.Enable = true
+	authorizer := &recordAuthorizer{}
+	auth.RegistryAuthorizer(authorizer)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		auth.SetClaims(c, &jwt.Claims{AccountId: 1})
+	}, middleware.Permission())
+	r.GET("/portal/account/", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+	// 未声明应用时拒绝请求，不加载权限
+	for _, header := range []string{"", "0", "abc"} {
+		req := httptest.NewRequest(http.MethodGet, "/portal/account/", nil)
+		req.Header.Set(auth.ApplicationHeader, header)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var body struct{ Code errorx.ErrorCode }
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body), "响应应该是 json")
+		assert.Equal(t, errorx.ErrPermissionDenied, body.Code, "应用请求头为 %q 时应该拒绝请求", header)
+	}
+	assert.Empty(t, authorizer.applicationIds, "未声明应用时不应该加载权限")
+
+	// 声明应用后按应用加载权限
+	req := httptest.NewRequest(http.MethodGet, "/portal/account/", nil)
+	req.Header.Set(auth.ApplicationHeader, "2")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, "ok", w.Body.String(), "拥有权限时应该放行")
+	assert.Equal(t, []uint{2}, authorizer.applicationIds, "应该按请求声明的应用加载权限")
+}
+
+func TestPermissionDataScope(t *testing.T) {
+	testenv.Setup(t)
+	gin.SetMode(gin.TestMode)
+	enable, skip := global.C.Rbac.Enable, global.C.Rbac.SkipResources
+	previous := auth.GetAuthorizer()
+	t.Cleanup(func() {
+		global.C.Rbac.Enable, global.C.Rbac.SkipResources = enable, skip
+		auth.RegistryAuthorizer(previous)
+	})
+	auth.RegistryAuthorizer(&recordAuthorizer{})
+	global.C.Rbac.SkipResources = []string{"/portal/session/mine"}
+
+	var scope *auth.DataScope
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		auth.SetClaims(c, &jwt.Claims{AccountId: 1})
+	}, middleware.Permission())
+	handler := func(c *gin.Context) {
+		scope, _ = auth.GetDataScope(c)
+		c.String(http.StatusOK, "ok")
+	}
+	r.GET("/portal/account/", handler)
+	r.GET("/portal/session/mine", handler)
+
+	// 关闭 rbac 时显式设置为全部数据
+	global.C.Rbac.Enable = false
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/portal/account/", nil))
+	assert.Equal(t, &auth.DataScope{All: true}, scope, "关闭 rbac 时应该不限制数据范围")
+
+	// 跳过权限校验的资源只能访问本人的数据
+	global.C.Rbac.Enable = true
+	scope = nil
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/portal/session/mine", nil))
+	assert.Equal(t, &auth.DataScope{Self: true, AccountId: 1}, scope, "跳过权限校验的资源应该只能访问本人的数据")
+}
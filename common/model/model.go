@@ -5,9 +5,28 @@ import (
 	"time"
 )
 
+// Model 通用字段，主键和创建、更新时间可以在列表接口中过滤
 type Model struct {
-	ID        uint           `json:"id" gorm:"primaryKey;autoIncrement;comment:自增主键"`            // 自增主键
-	CreatedAt time.Time      `json:"createdAt" gorm:"type:datetime;autoCreateTime;comment:创建时间"` // 创建时间
-	UpdatedAt time.Time      `json:"updatedAt" gorm:"type:datetime;autoUpdateTime;comment:更新时间"` // 更新时间
-	DeletedAt gorm.DeletedAt `json:"deletedAt" gorm:"type:datetime;index;comment:删除时间"`          // 删除时间
+	ID        uint           `json:"id" gorm:"primaryKey;autoIncrement;comment:自增主键" filter:"eq,ne,in"`                         // 自增主键
+	CreatedAt time.Time      `json:"createdAt" gorm:"type:datetime;autoCreateTime;comment:创建时间" filter:"gt,gte,lt,lte,between"` // 创建时间
+	UpdatedAt time.Time      `json:"updatedAt" gorm:"type:datetime;autoUpdateTime;comment:更新时间" filter:"gt,gte,lt,lte,between"` // 更新时间
+	DeletedAt gorm.DeletedAt `json:"deletedAt" gorm:"type:datetime;index;comment:删除时间"`                                         // 删除时间
+}
+
+// Entity 嵌入 Model 的模型，未导出的方法保证只有嵌入 Model 的类型满足该约束，通用增删改查只接受这类模型
+type Entity interface {
+	GetId() uint
+	entity()
+}
+
+// GetId 返回自增主键
+func (m Model) GetId() uint {
+	return m.ID
+}
+
+func (Model) entity() {}
+
+// SetId 设置自增主键
+func (m *Model) SetId(id uint) {
+	m.ID = id
 }
@@ -0,0 +1,157 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// TreeRoot 根节点的祖先路径
+const TreeRoot = "/"
+
+// TreePath 物化路径，按顺序记录节点所有祖先的ID，如 "/1/5/"，根节点为 "/"。
+// 路径列带索引，祖先、子孙和子树数量都可以通过一次查询得到。
+type TreePath struct {
+	TreePath string `json:"treePath" gorm:"type:varchar(255);not null;default:'/';index;comment:祖先路径"`
+}
+
+// treeModels 使用物化路径的模型，迁移后用于重建路径
+var treeModels []interface{}
+
+// RegisterTree 注册使用物化路径的模型，模型需要包含 id、parent_id 和 tree_path 列
+func RegisterTree(model ...interface{}) {
+	treeModels = append(treeModels, model...)
+}
+
+// SetParent 根据父节点设置祖先路径，parent 为 nil 表示根节点
+func (t *TreePath) SetParent(parent *TreePath, parentId uint) {
+	if parent == nil || parentId == 0 {
+		t.TreePath = TreeRoot
+		return
+	}
+	t.TreePath = parent.ChildrenPath(parentId)
+}
+
+// ChildrenPath 节点的子节点的祖先路径，也是所有子孙节点路径的公共前缀
+func (t TreePath) ChildrenPath(id uint) string {
+	path := t.TreePath
+	if path == "" {
+		path = TreeRoot
+	}
+	return path + strconv.FormatUint(uint64(id), 10) + "/"
+}
+
+// AncestorIds 从根节点开始返回所有祖先ID
+func (t TreePath) AncestorIds() []uint {
+	ids := make([]uint, 0)
+	for _, s := range strings.Split(strings.Trim(t.TreePath, "/"), "/") {
+		if s == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids
+}
+
+// IsDescendantOf 判断是否为 id 节点的子孙节点
+func (t TreePath) IsDescendantOf(node TreePath, id uint) bool {
+	return strings.HasPrefix(t.TreePath, node.ChildrenPath(id))
+}
+
+// Descendants 查询节点所有子孙节点的 scope
+func Descendants(node TreePath, id uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("tree_path LIKE ?", node.ChildrenPath(id)+"%")
+	}
+}
+
+// Ancestors 查询节点所有祖先节点的 scope
+func Ancestors(node TreePath) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		ids := node.AncestorIds()
+		if len(ids) == 0 {
+			return db.Where("1 = 0")
+		}
+		return db.Where("id IN ?", ids)
+	}
+}
+
+// MoveTreePath 子树移动后替换所有子孙节点的路径前缀，tx 需要指定 Model。
+// 同一条路径中祖先ID不会重复出现，前缀只会被匹配一次，因此可以直接使用 REPLACE。
+// 软删除的子孙节点同样更新，保证恢复后路径与 parent_id 一致。
+func MoveTreePath(tx *gorm.DB, oldPrefix, newPrefix string) error {
+	if oldPrefix == newPrefix {
+		return nil
+	}
+	return tx.Unscoped().Where("tree_path LIKE ?", oldPrefix+"%").
+		Update("tree_path", gorm.Expr("REPLACE(tree_path, ?, ?)", oldPrefix, newPrefix)).Error
+}
+
+// RebuildTreePaths 根据 parent_id 重建所有注册模型的物化路径，用于迁移已有数据。
+// 路径只由 parent_id 决定，包含软删除的节点：软删除节点的路径同样重建，恢复后可以直接使用；
+// 父节点被软删除的节点，路径仍然经过该父节点，与 parent_id 保持一致。
+func RebuildTreePaths(db *gorm.DB) error {
+	for _, m := range treeModels {
+		if err := rebuildTreePath(db, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func rebuildTreePath(db *gorm.DB, m interface{}) error {
+	var nodes []struct {
+		ID       uint
+		ParentId uint
+		TreePath string
+	}
+	if err := db.Unscoped().Model(m).Select("id", "parent_id", "tree_path").Find(&nodes).Error; err != nil {
+		return err
+	}
+	parents := make(map[uint]uint, len(nodes))
+	for _, n := range nodes {
+		parents[n.ID] = n.ParentId
+	}
+	paths := make(map[uint]string, len(nodes))
+	var pathOf func(id uint, depth int) (string, error)
+	pathOf = func(id uint, depth int) (string, error) {
+		if p, ok := paths[id]; ok {
+			return p, nil
+		}
+		if depth > len(nodes) {
+			return "", fmt.Errorf("节点 %d 存在循环引用", id)
+		}
+		parentId, ok := parents[id]
+		if !ok || parentId == 0 {
+			paths[id] = TreeRoot
+			return TreeRoot, nil
+		}
+		parentPath, err := pathOf(parentId, depth+1)
+		if err != nil {
+			return "", err
+		}
+		paths[id] = TreePath{TreePath: parentPath}.ChildrenPath(parentId)
+		return paths[id], nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, n := range nodes {
+			path, err := pathOf(n.ID, 0)
+			if err != nil {
+				return err
+			}
+			if path == n.TreePath {
+				continue
+			}
+			if err := tx.Unscoped().Model(m).Where("id = ?", n.ID).Update("tree_path", path).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,46 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yanshicheng/ikube-gin-starter/common/model"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/testenv"
+)
+
+type treeNode struct {
+	model.Model
+	model.TreePath
+	ParentId uint
+}
+
+func TestRebuildTreePaths(t *testing.T) {
+	testenv.Setup(t)
+	db := testenv.DB(t, &treeNode{})
+	model.RegisterTree(&treeNode{})
+
+	// 1 -> 2 -> 3，2 已被软删除，路径都是错误的
+	nodes := []*treeNode{{ParentId: 0}, {ParentId: 1}, {ParentId: 2}}
+	for _, n := range nodes {
+		assert.NoError(t, db.Create(n).Error)
+	}
+	assert.NoError(t, db.Delete(nodes[1]).Error)
+	assert.NoError(t, db.Unscoped().Model(&treeNode{}).Where("1 = 1").Update("tree_path", model.TreeRoot).Error)
+
+	assert.NoError(t, model.RebuildTreePaths(db))
+	paths := make(map[uint]string)
+	var rows []treeNode
+	assert.NoError(t, db.Unscoped().Order("id").Find(&rows).Error)
+	for _, row := range rows {
+		paths[row.ID] = row.TreePath.TreePath
+	}
+	assert.Equal(t, "/", paths[1], "根节点路径应为 /")
+	assert.Equal(t, "/1/", paths[2], "软删除的节点路径同样重建")
+	assert.Equal(t, "/1/2/", paths[3], "父节点被软删除时路径仍然经过父节点")
+
+	// 子树移动同样更新软删除的子孙节点
+	assert.NoError(t, model.MoveTreePath(db.Model(&treeNode{}), "/1/", "/9/1/"))
+	assert.NoError(t, db.Unscoped().Order("id").Find(&rows).Error)
+	assert.Equal(t, "/9/1/", rows[1].TreePath.TreePath, "软删除的子孙节点路径应该更新")
+	assert.Equal(t, "/9/1/2/", rows[2].TreePath.TreePath, "子孙节点路径应该更新")
+}
@@ -104,6 +104,11 @@ func FailedParam(c *gin.Context, err error) {
 	if errors.As(err, &validationErrs) {
 		FailedCode[map[string]string](c, errorx.ErrParamParse, v.RemoveTopStruct(validationErrs))
 		return
+	}
+	var fieldErrs v.FieldErrors
+	if errors.As(err, &fieldErrs) {
+		FailedCode[map[string]string](c, errorx.ErrParamParse, fieldErrs)
+		return
 	} else {
 		if err == io.EOF {
 			FailedMap(c, "请求体为空或格式不正确")
@@ -114,3 +119,29 @@ func FailedParam(c *gin.Context, err error) {
 
 	}
 }
+
+// FailedError 根据错误类型返回，携带错误码的错误使用其错误码，其余按通用错误返回
+func FailedError(c *gin.Context, err error) {
+	var codeErr *errorx.CodeError
+	if errors.As(err, &codeErr) {
+		if codeErr.Data != nil {
+			c.JSON(http.StatusOK, &types.Data[string]{
+				Code:     codeErr.Code,
+				Data:     codeErr.Data,
+				Message:  codeErr.Msg,
+				DataType: types.DataTypeJson,
+			})
+			return
+		}
+		FailedCode(c, codeErr.Code, codeErr.Msg)
+		return
+	}
+	// 业务层补充校验返回的参数错误，按参数错误翻译后返回
+	var validationErrs validator.ValidationErrors
+	var fieldErrs v.FieldErrors
+	if errors.As(err, &validationErrs) || errors.As(err, &fieldErrs) {
+		FailedParam(c, err)
+		return
+	}
+	FailedStr(c, err.Error())
+}
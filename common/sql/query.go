@@ -2,37 +2,33 @@ package sql
 
 import (
 	"fmt"
-	"github.com/yanshicheng/ikube-gin-starter/apps/book/model"
 	"github.com/yanshicheng/ikube-gin-starter/common/pagination"
 	"github.com/yanshicheng/ikube-gin-starter/common/types"
 	"gorm.io/gorm"
 	"math"
 )
 
-func GetQueryResponse(db *gorm.DB, query *model.GetQuery, modelSlice interface{}) (*types.QueryResponse, error) {
+// GetPageResponse 分页查询，modelSlice 必须为切片指针
+func GetPageResponse(db *gorm.DB, page types.Pagination, modelSlice interface{}) (*types.QueryResponse, error) {
 	// 获取总记录数
 	var totalRecords int64
-	if err := db.Count(&totalRecords).Error; err != nil {
+	if err := db.Session(&gorm.Session{}).Count(&totalRecords).Error; err != nil {
 		return nil, fmt.Errorf("数据统计查询失败: %s", err.Error())
 	}
 
 	// 计算总页数
-	totalPages := int(math.Ceil(float64(totalRecords) / float64(query.Pagination.PageSize)))
+	totalPages := int(math.Ceil(float64(totalRecords) / float64(page.PageSize)))
 
 	// 应用分页查询条件
-	if err := db.Scopes(
-		pagination.PaginateQuery(query.Pagination),
-	).Find(&modelSlice).Error; err != nil {
+	if err := db.Scopes(pagination.PaginateQuery(page)).Find(modelSlice).Error; err != nil {
 		return nil, fmt.Errorf("数据查询失败: %s", err)
 	}
 
-	resp := &types.QueryResponse{
-		Page:       query.Pagination.PageSize,
-		PageNumber: query.Pagination.PageNumber,
+	return &types.QueryResponse{
+		Page:       page.PageSize,
+		PageNumber: page.PageNumber,
 		TotalPage:  totalPages,
 		Total:      int(totalRecords),
 		Data:       modelSlice,
-	}
-
-	return resp, nil
+	}, nil
 }
@@ -0,0 +1,7 @@
+package types
+
+// Filters 列表接口的过滤条件，格式为 字段:操作:值，可以传多个，如 filter=name:like:go&filter=id:in:1,2，
+// 通过 common/filter 按模型声明的 filter 标签解析
+type Filters struct {
+	Filter []string `json:"filter" form:"filter"`
+}
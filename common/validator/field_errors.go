@@ -0,0 +1,39 @@
+package validator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/yanshicheng/ikube-gin-starter/global"
+)
+
+// FieldErrors 按字段返回的已翻译参数错误，用于 binding 校验之外的参数检查，如列表过滤条件，
+// 接口层与 validator.ValidationErrors 一样按参数错误返回
+type FieldErrors map[string]string
+
+func (e FieldErrors) Error() string {
+	fields := make([]string, 0, len(e))
+	for field := range e {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	msgs := make([]string, 0, len(fields))
+	for _, field := range fields {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", field, e[field]))
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Translate 使用参数校验的翻译器翻译 tag 对应的信息，tag 通过 RegistryValidator 注册翻译，
+// 翻译器未初始化或没有对应的翻译时返回 tag
+func Translate(tag string, params ...string) string {
+	if global.IkubeopsTrans == nil {
+		return tag
+	}
+	msg, err := global.IkubeopsTrans.T(tag, params...)
+	if err != nil {
+		return tag
+	}
+	return msg
+}
@@ -22,7 +22,7 @@ const (
 // ValidatorTranslation 自定义验证器和翻译的结构定义
 type ValidatorTranslation struct {
 	Tag            string              // 验证器标签
-	ValidationFunc validator.Func      // 验证函数
+	ValidationFunc validator.Func      // 验证函数，为空时只注册翻译，用于 binding 之外的参数检查，如列表过滤条件
 	Translations   []TranslationDetail // 翻译详情列表
 }
 
@@ -38,8 +38,10 @@ func RegisterValidatorsAndTranslations(validators []*ValidatorTranslation, uni *
 	transZh, _ := uni.GetTranslator("zh")
 	for _, vt := range validators {
 		if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
-			if err := v.RegisterValidation(vt.Tag, vt.ValidationFunc); err != nil {
-				return fmt.Errorf("failed to register validation for %s: %v", vt.Tag, err)
+			if vt.ValidationFunc != nil {
+				if err := v.RegisterValidation(vt.Tag, vt.ValidationFunc); err != nil {
+					return fmt.Errorf("failed to register validation for %s: %v", vt.Tag, err)
+				}
 			}
 			for _, td := range vt.Translations {
 				var trans ut.Translator
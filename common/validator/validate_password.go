@@ -0,0 +1,117 @@
+package validator
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/password"
+)
+
+// 密码策略校验，规则来自配置文件 password 部分：
+//   - password_length 长度范围
+//   - password_class 必需的字符类型
+//   - password_account=Account 不允许包含同一结构体中 Account 字段的值
+
+// PasswordPolicy 根据当前配置生成密码策略
+func PasswordPolicy() password.Policy {
+	cfg := global.C.Password
+	return password.Policy{
+		MinLength:     cfg.MinLength,
+		MaxLength:     cfg.MaxLength,
+		Upper:         cfg.RequireUpper,
+		Lower:         cfg.RequireLower,
+		Digit:         cfg.RequireDigit,
+		Special:       cfg.RequireSpecial,
+		RejectAccount: cfg.RejectAccount,
+	}
+}
+
+func validatePasswordLength(fl validator.FieldLevel) bool {
+	return PasswordPolicy().ValidLength(fl.Field().String())
+}
+
+func validatePasswordClass(fl validator.FieldLevel) bool {
+	return len(PasswordPolicy().MissingClasses(fl.Field().String())) == 0
+}
+
+func validatePasswordAccount(fl validator.FieldLevel) bool {
+	parent := reflect.Indirect(fl.Parent())
+	if parent.Kind() != reflect.Struct {
+		return true
+	}
+	account := parent.FieldByName(fl.Param())
+	if !account.IsValid() || account.Kind() != reflect.String {
+		return true
+	}
+	return !PasswordPolicy().ContainsAccount(fl.Field().String(), account.String())
+}
+
+var passwordClassNames = map[string]map[password.Class]string{
+	"zh": {
+		password.ClassUpper:   "大写字母",
+		password.ClassLower:   "小写字母",
+		password.ClassDigit:   "数字",
+		password.ClassSpecial: "特殊字符",
+	},
+	"en": {
+		password.ClassUpper:   "uppercase letters",
+		password.ClassLower:   "lowercase letters",
+		password.ClassDigit:   "digits",
+		password.ClassSpecial: "special characters",
+	},
+}
+
+func passwordLengthTranslation(ut ut.Translator, fe validator.FieldError) string {
+	policy := PasswordPolicy()
+	t, _ := ut.T(fe.Tag(), fe.Field(), strconv.Itoa(policy.MinLength), strconv.Itoa(policy.MaxLength))
+	return t
+}
+
+func passwordClassTranslation(ut ut.Translator, fe validator.FieldError) string {
+	names, ok := passwordClassNames[ut.Locale()]
+	if !ok {
+		names = passwordClassNames["en"]
+	}
+	classes := PasswordPolicy().RequiredClasses()
+	required := make([]string, 0, len(classes))
+	for _, c := range classes {
+		required = append(required, names[c])
+	}
+	sep := ", "
+	if ut.Locale() == "zh" {
+		sep = "、"
+	}
+	t, _ := ut.T(fe.Tag(), fe.Field(), strings.Join(required, sep))
+	return t
+}
+
+func init() {
+	RegistryValidator(&ValidatorTranslation{
+		Tag:            "password_length",
+		ValidationFunc: validatePasswordLength,
+		Translations: []TranslationDetail{
+			{Locale: LocaleEN, TranslationMsg: "{0} must be between {1} and {2} characters", TranslationFunc: passwordLengthTranslation},
+			{Locale: LocaleZH, TranslationMsg: "{0}长度必须在{1}到{2}个字符之间", TranslationFunc: passwordLengthTranslation},
+		},
+	})
+	RegistryValidator(&ValidatorTranslation{
+		Tag:            "password_class",
+		ValidationFunc: validatePasswordClass,
+		Translations: []TranslationDetail{
+			{Locale: LocaleEN, TranslationMsg: "{0} must contain {1}", TranslationFunc: passwordClassTranslation},
+			{Locale: LocaleZH, TranslationMsg: "{0}必须包含{1}", TranslationFunc: passwordClassTranslation},
+		},
+	})
+	RegistryValidator(&ValidatorTranslation{
+		Tag:            "password_account",
+		ValidationFunc: validatePasswordAccount,
+		Translations: []TranslationDetail{
+			{Locale: LocaleEN, TranslationMsg: "{0} must not contain the account name", TranslationFunc: nil},
+			{Locale: LocaleZH, TranslationMsg: "{0}不能包含账号", TranslationFunc: nil},
+		},
+	})
+}
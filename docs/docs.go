@@ -20,34 +20,193 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/user/info": {
+        "/v1/book-shelf/book": {
             "get": {
                 "security": [
                     {
-                        "types.SearchId": []
+                        "ApiKeyAuth": []
                     }
                 ],
-                "description": "获取当前用户的详细信息",
+                "description": "分页查询书籍列表，filter 参数格式为 字段:操作:值，可重复",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "书籍管理"
+                ],
+                "summary": "查询书籍列表",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "页码",
+                        "name": "PageNumber",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "每页数量",
+                        "name": "PageSize",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "ASC",
+                            "DESC"
+                        ],
+                        "type": "string",
+                        "description": "排序",
+                        "name": "Sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "过滤条件，如 Title:like:go",
+                        "name": "filter",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/types.Data-string"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "Data": {
+                                            "allOf": [
+                                                {
+                                                    "$ref": "#/definitions/types.QueryResponse"
+                                                },
+                                                {
+                                                    "type": "object",
+                                                    "properties": {
+                                                        "Data": {
+                                                            "type": "array",
+                                                            "items": {
+                                                                "$ref": "#/definitions/model.Book"
+                                                            }
+                                                        }
+                                                    }
+                                                }
+                                            ]
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "创建书籍",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "获取用户信息",
+                "tags": [
+                    "书籍管理"
+                ],
+                "summary": "创建书籍",
+                "parameters": [
+                    {
+                        "description": "书籍信息",
+                        "name": "req",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.Book"
+                        }
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.Book"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/types.Data-string"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "Data": {
+                                            "$ref": "#/definitions/model.Book"
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     }
                 }
             }
         },
-        "/book-shelf/book": {
-            "post": {
-                "description": "创建书籍接口",
+        "/v1/book-shelf/book/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "查询书籍详情",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "书籍管理"
+                ],
+                "summary": "查询书籍详情",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "书籍ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/types.Data-string"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "Data": {
+                                            "$ref": "#/definitions/model.Book"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "修改书籍的全部字段",
                 "consumes": [
                     "application/json"
                 ],
@@ -57,10 +216,17 @@ const docTemplate = `{
                 "tags": [
                     "书籍管理"
                 ],
-                "summary": "创建书籍接口",
+                "summary": "修改书籍",
                 "parameters": [
                     {
-                        "description": "书籍名称",
+                        "type": "integer",
+                        "description": "书籍ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "书籍信息",
                         "name": "req",
                         "in": "body",
                         "required": true,
@@ -71,16 +237,16 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "desc",
+                        "description": "OK",
                         "schema": {
                             "allOf": [
                                 {
-                                    "$ref": "#/definitions/types.Data"
+                                    "$ref": "#/definitions/types.Data-string"
                                 },
                                 {
                                     "type": "object",
                                     "properties": {
-                                        "data": {
+                                        "Data": {
                                             "$ref": "#/definitions/model.Book"
                                         }
                                     }
@@ -89,6 +255,38 @@ const docTemplate = `{
                         }
                     }
                 }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "删除书籍",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "书籍管理"
+                ],
+                "summary": "删除书籍",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "书籍ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/types.Data-string"
+                        }
+                    }
+                }
             }
         }
     },
@@ -105,6 +303,8 @@ const docTemplate = `{
                 11004,
                 10110,
                 10111,
+                10112,
+                10113,
                 10130,
                 10131,
                 18000,
@@ -127,7 +327,9 @@ const docTemplate = `{
                 "ErrGeneric": "正常",
                 "ErrLoginExpired": "登录过期",
                 "ErrLoginInvalid": "登录信息无效",
+                "ErrOtpRequired": "需要两步验证，Data 中携带验证凭据",
                 "ErrParamParse": "参数解析失败",
+                "ErrPasswordExpired": "密码过期或被重置，需要修改密码后登录",
                 "ErrPermissionDenied": "权限不足",
                 "ErrRoleNotFound": "角色未找到",
                 "ErrToOperation": "\"to\" 操作相关错误",
@@ -147,6 +349,8 @@ const docTemplate = `{
                 "ErrTokenBlacklisted",
                 "ErrLoginExpired",
                 "ErrLoginInvalid",
+                "ErrPasswordExpired",
+                "ErrOtpRequired",
                 "ErrPermissionDenied",
                 "ErrRoleNotFound",
                 "ErrDatabase",
@@ -195,20 +399,28 @@ const docTemplate = `{
                     "example": "My Book"
                 },
                 "createdAt": {
+                    "description": "创建时间",
                     "type": "string"
                 },
                 "deletedAt": {
-                    "$ref": "#/definitions/gorm.DeletedAt"
+                    "description": "删除时间",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/gorm.DeletedAt"
+                        }
+                    ]
                 },
                 "id": {
+                    "description": "自增主键",
                     "type": "integer"
                 },
                 "updatedAt": {
+                    "description": "更新时间",
                     "type": "string"
                 }
             }
         },
-        "types.Data": {
+        "types.Data-string": {
             "type": "object",
             "properties": {
                 "Code": {
@@ -235,6 +447,24 @@ const docTemplate = `{
                 "DataTypeJson",
                 "DataTypeSlice"
             ]
+        },
+        "types.QueryResponse": {
+            "type": "object",
+            "properties": {
+                "Data": {},
+                "Page": {
+                    "type": "integer"
+                },
+                "PageNumber": {
+                    "type": "integer"
+                },
+                "Total": {
+                    "type": "integer"
+                },
+                "TotalPage": {
+                    "type": "integer"
+                }
+            }
         }
     },
     "securityDefinitions": {
@@ -2,7 +2,9 @@ package global
 
 import (
 	ut "github.com/go-playground/universal-translator"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/jwt"
 	"github.com/yanshicheng/ikube-gin-starter/pkg/mysql"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/oidc"
 	"github.com/yanshicheng/ikube-gin-starter/pkg/redis"
 	"github.com/yanshicheng/ikube-gin-starter/pkg/types"
 	"go.uber.org/zap"
@@ -15,5 +17,7 @@ var (
 	LSys          *zap.Logger
 	DB            *mysql.IkubeGorm
 	RDB           *redis.IkubeRedis
+	J             *jwt.IkubeJwt
+	O             *oidc.IkubeOidc
 	M             []interface{}
 )
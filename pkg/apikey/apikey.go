@@ -0,0 +1,62 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// api key 格式为 <prefix>_<id>_<secret>，<prefix>_<id> 作为公开标识保存在数据库中用于查找，
+// 完整的 key 只在签发时返回一次，数据库中只保存 sha256 摘要。
+
+var ErrKeyFormat = errors.New("api key 格式错误")
+
+const (
+	idBytes     = 4
+	secretBytes = 32
+)
+
+// Generate 生成 api key，返回完整的 key 和公开标识
+func Generate(prefix string) (key string, id string, err error) {
+	if prefix == "" || strings.Contains(prefix, "_") {
+		return "", "", errors.New("api key 前缀不能为空且不能包含下划线")
+	}
+	idBuf := make([]byte, idBytes)
+	if _, err := rand.Read(idBuf); err != nil {
+		return "", "", err
+	}
+	secretBuf := make([]byte, secretBytes)
+	if _, err := rand.Read(secretBuf); err != nil {
+		return "", "", err
+	}
+	id = prefix + "_" + hex.EncodeToString(idBuf)
+	key = id + "_" + base64.RawURLEncoding.EncodeToString(secretBuf)
+	return key, id, nil
+}
+
+// Parse 校验 api key 格式并返回公开标识
+func Parse(prefix, key string) (string, error) {
+	parts := strings.SplitN(key, "_", 3)
+	if len(parts) != 3 || parts[0] != prefix || len(parts[1]) != idBytes*2 || parts[2] == "" {
+		return "", ErrKeyFormat
+	}
+	if _, err := hex.DecodeString(parts[1]); err != nil {
+		return "", ErrKeyFormat
+	}
+	return parts[0] + "_" + parts[1], nil
+}
+
+// Hash 计算 api key 的 sha256 摘要
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify 使用常量时间比较 api key 与摘要是否匹配
+func Verify(key, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(Hash(key)), []byte(hash)) == 1
+}
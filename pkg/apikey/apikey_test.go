@@ -0,0 +1,36 @@
+package apikey_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/apikey"
+)
+
+func TestApiKey_GenerateAndParse(t *testing.T) {
+	key, id, err := apikey.Generate("ikube")
+	assert.NoError(t, err, "生成 api key 应该成功")
+	assert.True(t, strings.HasPrefix(key, id+"_"), "api key 应该以公开标识开头")
+
+	parsed, err := apikey.Parse("ikube", key)
+	assert.NoError(t, err, "解析 api key 应该成功")
+	assert.Equal(t, id, parsed, "公开标识与预期不符")
+
+	hash := apikey.Hash(key)
+	assert.True(t, apikey.Verify(key, hash), "api key 应该与摘要匹配")
+	assert.False(t, apikey.Verify(key+"x", hash), "篡改的 api key 不应该与摘要匹配")
+
+	other, _, err := apikey.Generate("ikube")
+	assert.NoError(t, err, "生成 api key 应该成功")
+	assert.NotEqual(t, key, other, "两次生成的 api key 不应该相同")
+}
+
+func TestApiKey_ParseInvalid(t *testing.T) {
+	for _, key := range []string{"", "ikube", "ikube_zzzzzzzz_secret", "other_0a0b0c0d_secret", "ikube_0a0b0c0d_"} {
+		_, err := apikey.Parse("ikube", key)
+		assert.ErrorIs(t, err, apikey.ErrKeyFormat, "非法的 api key 应该解析失败: %s", key)
+	}
+	_, _, err := apikey.Generate("ik_ube")
+	assert.Error(t, err, "包含下划线的前缀应该生成失败")
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/yanshicheng/ikube-gin-starter/pkg/types"
 	"os"
 	"testing"
+	"time"
 )
 
 func setupEnvironment(t *testing.T) {
@@ -45,3 +46,55 @@ func TestLoadFileConfig(t *testing.T) {
 	assert.Equal(t, "172.16.1.100", destConfig.App.HttpAddr, "Loaded HttpAddr should match the environment setting of 172.16.1.100")
 	assert.Equal(t, true, destConfig.App.Tls, "Loaded Tls should match the file setting of true")
 }
+
+func TestLoadAppsConfig(t *testing.T) {
+	configFile := "./config_apps_test.yaml"
+	testConfigContent := []byte("apps:\n  portal:\n    prefix: \"/admin\"\n  book:\n    enable: true\n    config:\n      page_size: \"20\"\n      timeout: 3s\n  demo:\n    enable: false\n")
+	err := os.WriteFile(configFile, testConfigContent, 0644)
+	assert.NoError(t, err, "Writing to config file should not produce an error")
+	t.Cleanup(func() { os.Remove(configFile) }) // 清理文件
+
+	destConfig := types.NewDefaultConfig()
+	err = config.InitIkubeConfig(configFile, "IKUBEOPS_", destConfig)
+	assert.NoError(t, err, "Loading file config should not produce an error")
+
+	assert.True(t, destConfig.Apps.Enabled("portal"), "Apps without enable should be enabled")
+	assert.True(t, destConfig.Apps.Enabled("book"), "Book should be enabled by the file setting")
+	assert.False(t, destConfig.Apps.Enabled("demo"), "Demo should be disabled by the file setting")
+	assert.True(t, destConfig.Apps.Enabled("unknown"), "Unconfigured apps should be enabled")
+	assert.Equal(t, "/admin", destConfig.Apps.Prefix("portal"))
+
+	// 应用自定义配置解码到应用自己的结构体，未配置的字段保持默认值
+	bookConfig := struct {
+		PageSize int           `mapstructure:"page_size"`
+		Timeout  time.Duration `mapstructure:"timeout"`
+		Title    string        `mapstructure:"title"`
+	}{Title: "default"}
+	assert.NoError(t, destConfig.Apps.Decode("book", &bookConfig))
+	assert.Equal(t, 20, bookConfig.PageSize)
+	assert.Equal(t, 3*time.Second, bookConfig.Timeout)
+	assert.Equal(t, "default", bookConfig.Title)
+
+	var invalid struct {
+		PageSize []int `mapstructure:"timeout"`
+	}
+	assert.Error(t, destConfig.Apps.Decode("book", &invalid))
+}
+
+func TestLoadApiConfig(t *testing.T) {
+	configFile := "./config_api_test.yaml"
+	testConfigContent := []byte("api:\n  prefix: \"api/\"\n  versions:\n    v1:\n      deprecated: true\n      sunset: \"2030-01-01T00:00:00Z\"\n    v2:\n      deprecated: false\n")
+	err := os.WriteFile(configFile, testConfigContent, 0644)
+	assert.NoError(t, err, "Writing to config file should not produce an error")
+	t.Cleanup(func() { os.Remove(configFile) }) // 清理文件
+
+	destConfig := types.NewDefaultConfig()
+	assert.Equal(t, "", destConfig.Api.PathPrefix(), "Default api prefix should be empty")
+	err = config.InitIkubeConfig(configFile, "IKUBEOPS_", destConfig)
+	assert.NoError(t, err, "Loading file config should not produce an error")
+
+	assert.Equal(t, "/api", destConfig.Api.PathPrefix(), "Api prefix should start with / and not end with /")
+	assert.True(t, destConfig.Api.Versions["v1"].Deprecated)
+	assert.Equal(t, "2030-01-01T00:00:00Z", destConfig.Api.Versions["v1"].Sunset)
+	assert.False(t, destConfig.Api.Versions["v2"].Deprecated)
+}
@@ -0,0 +1,315 @@
+package gen
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// 应用脚手架：按 book 应用的分层结构生成 app.go、model、service、logic、handler 和测试骨架，
+// 增删改查使用 common/crud 的通用实现，并在 apps/all 中引入新应用。任一目标文件已存在时不写入任何文件。
+
+//go:embed templates/*.tmpl
+var templates embed.FS
+
+var (
+	appNamePattern   = regexp.MustCompile(`^[a-z][a-z0-9]*$`)
+	fieldNamePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*$`)
+	// 公共模型中已有的字段
+	reservedFields = map[string]bool{"id": true, "createdat": true, "updatedat": true, "deletedat": true, "model": true}
+	// 与生成代码中引入的包同名的应用名称
+	reservedApps = map[string]bool{
+		"all": true, "model": true, "service": true, "sql": true, "logic": true, "handler": true, "types": true,
+		"crud": true, "router": true, "global": true, "errorx": true, "response": true, "gin": true, "zap": true, "gorm": true,
+		"fmt": true, "errors": true, "context": true, "time": true, "json": true, "testing": true, "assert": true,
+	}
+)
+
+// fieldTypes 字段类型对应的 Go 类型、gorm 标签、校验规则和列表接口允许的过滤操作
+var fieldTypes = map[string]struct {
+	GoType  string
+	Gorm    string
+	Binding string
+	Filter  string
+}{
+	"string": {GoType: "string", Gorm: "type:varchar(255);not null", Binding: "max=255", Filter: "eq,ne,in,like"},
+	"text":   {GoType: "string", Gorm: "type:text"},
+	"int":    {GoType: "int", Gorm: "not null;default:0", Filter: "eq,ne,in,gt,gte,lt,lte,between"},
+	"uint":   {GoType: "uint", Gorm: "not null;default:0", Filter: "eq,ne,in,gt,gte,lt,lte,between"},
+	"float":  {GoType: "float64", Gorm: "not null;default:0", Filter: "gt,gte,lt,lte,between"},
+	"bool":   {GoType: "bool", Gorm: "not null;default:false", Filter: "eq"},
+	"time":   {GoType: "time.Time", Gorm: "type:datetime", Filter: "gt,gte,lt,lte,between"},
+	"json":   {GoType: "json.RawMessage", Gorm: "type:json;serializer:json"},
+}
+
+// Field 模型字段
+type Field struct {
+	Name     string // Go 字段名，如 PageNumber
+	Json     string // json 和查询参数名，如 pageNumber
+	Column   string // 数据库列名，如 page_number
+	Type     string // 字段类型，如 string、int
+	GoType   string
+	Gorm     string
+	Binding  string
+	Filter   string // filter 标签，列表接口允许的过滤操作，为空时不允许过滤
+	Required bool
+}
+
+// App 生成应用的参数
+type App struct {
+	Module string  // go module 路径
+	Name   string  // 应用名称，如 book
+	Fields []Field // 模型字段
+}
+
+// Title 应用名称首字母大写，用于类型名称，如 Book
+func (a App) Title() string {
+	return strings.ToUpper(a.Name[:1]) + a.Name[1:]
+}
+
+// Imports 模型需要引入的标准库
+func (a App) Imports() []string {
+	imports := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, f := range a.Fields {
+		var pkg string
+		switch f.Type {
+		case "time":
+			pkg = "time"
+		case "json":
+			pkg = "encoding/json"
+		}
+		if pkg != "" && !seen[pkg] {
+			seen[pkg] = true
+			imports = append(imports, pkg)
+		}
+	}
+	return imports
+}
+
+// ParseFields 解析字段定义，格式为 name:type[:required]，如 title:string:required
+func ParseFields(specs []string) ([]Field, error) {
+	fields := make([]Field, 0, len(specs))
+	seen := make(map[string]bool)
+	for _, spec := range specs {
+		parts := strings.Split(strings.TrimSpace(spec), ":")
+		if len(parts) < 2 || len(parts) > 3 {
+			return nil, fmt.Errorf("字段定义格式错误: %s，格式为 name:type[:required]", spec)
+		}
+		name, typ := parts[0], strings.ToLower(parts[1])
+		if !fieldNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("字段名称不合法: %s", name)
+		}
+		t, ok := fieldTypes[typ]
+		if !ok {
+			return nil, fmt.Errorf("字段 %s 的类型 %s 不支持，可选类型: string, text, int, uint, float, bool, time, json", name, typ)
+		}
+		required := false
+		if len(parts) == 3 {
+			if parts[2] != "required" {
+				return nil, fmt.Errorf("字段 %s 的选项 %s 不支持，可选选项: required", name, parts[2])
+			}
+			if typ == "bool" {
+				return nil, fmt.Errorf("字段 %s 为 bool 类型，不能设置为 required", name)
+			}
+			required = true
+		}
+		words := splitWords(name)
+		key := strings.ToLower(strings.Join(words, ""))
+		if reservedFields[key] {
+			return nil, fmt.Errorf("字段 %s 与公共模型字段冲突", name)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("字段 %s 重复", name)
+		}
+		seen[key] = true
+		binding := t.Binding
+		if required {
+			binding = strings.Trim("required,"+binding, ",")
+		} else if binding != "" {
+			binding = "omitempty," + binding
+		}
+		fields = append(fields, Field{
+			Name:     pascal(words),
+			Json:     camel(words),
+			Column:   strings.ToLower(strings.Join(words, "_")),
+			Type:     typ,
+			GoType:   t.GoType,
+			Gorm:     t.Gorm,
+			Binding:  binding,
+			Filter:   t.Filter,
+			Required: required,
+		})
+	}
+	if len(fields) == 0 {
+		return nil, errors.New("至少需要定义一个字段")
+	}
+	return fields, nil
+}
+
+// file 生成的文件和使用的模板
+type file struct {
+	path     string
+	template string
+}
+
+func (a App) files() []file {
+	dir := filepath.Join("apps", a.Name)
+	return []file{
+		{filepath.Join(dir, "app.go"), "app.go.tmpl"},
+		{filepath.Join(dir, "model", a.Name+".go"), "model.go.tmpl"},
+		{filepath.Join(dir, "service", a.Name+".go"), "service.go.tmpl"},
+		{filepath.Join(dir, "logic", a.Name+".go"), "logic.go.tmpl"},
+		{filepath.Join(dir, "handler", a.Name+".go"), "handler.go.tmpl"},
+		{filepath.Join(dir, "handler", a.Name+"_test.go"), "handler_test.go.tmpl"},
+	}
+}
+
+// Generate 在 root 目录下生成应用，返回生成的文件。任一文件已存在时返回错误，不写入任何文件
+func Generate(root string, app App) ([]string, error) {
+	if !appNamePattern.MatchString(app.Name) {
+		return nil, fmt.Errorf("应用名称不合法: %s，只能包含小写字母和数字，并以字母开头", app.Name)
+	}
+	if reservedApps[app.Name] {
+		return nil, fmt.Errorf("应用名称 %s 与生成代码中引入的包同名", app.Name)
+	}
+	tmpl, err := template.ParseFS(templates, "templates/*.tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("解析模板失败: %w", err)
+	}
+	files := app.files()
+	// 先检查全部文件，避免生成一半
+	existing := make([]string, 0)
+	for _, f := range files {
+		if _, err := os.Stat(filepath.Join(root, f.path)); err == nil {
+			existing = append(existing, f.path)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("检查文件失败: %w", err)
+		}
+	}
+	if len(existing) > 0 {
+		return nil, fmt.Errorf("文件已存在，拒绝覆盖: %s", strings.Join(existing, ", "))
+	}
+	contents := make([][]byte, len(files))
+	for i, f := range files {
+		var buf bytes.Buffer
+		if err := tmpl.ExecuteTemplate(&buf, f.template, app); err != nil {
+			return nil, fmt.Errorf("生成 %s 失败: %w", f.path, err)
+		}
+		src, err := format.Source(buf.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("格式化 %s 失败: %w", f.path, err)
+		}
+		contents[i] = src
+	}
+	generated := make([]string, 0, len(files))
+	for i, f := range files {
+		path := filepath.Join(root, f.path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return generated, fmt.Errorf("创建目录失败: %w", err)
+		}
+		if err := os.WriteFile(path, contents[i], 0644); err != nil {
+			return generated, fmt.Errorf("写入 %s 失败: %w", f.path, err)
+		}
+		generated = append(generated, f.path)
+	}
+	return generated, nil
+}
+
+// Register 在 apps/all 中引入应用的 handler、logic 和 model 包，已引入时不修改，返回是否修改了文件
+func Register(root string, app App) (bool, error) {
+	path := filepath.Join(root, "apps", "all", "all.go")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, fmt.Errorf("读取 %s 失败: %w", path, err)
+	}
+	src := string(data)
+	imports := make([]string, 0, 3)
+	for _, pkg := range []string{"handler", "logic", "model"} {
+		line := fmt.Sprintf("\t_ \"%s/apps/%s/%s\"\n", app.Module, app.Name, pkg)
+		if !strings.Contains(src, line) {
+			imports = append(imports, line)
+		}
+	}
+	if len(imports) == 0 {
+		return false, nil
+	}
+	// 插入到最后一个应用包之后，由 gofmt 排序
+	appPrefix := fmt.Sprintf("\t_ \"%s/apps/", app.Module)
+	i := strings.LastIndex(src, appPrefix)
+	if i < 0 {
+		i = strings.Index(src, "import (\n")
+		if i < 0 {
+			return false, fmt.Errorf("%s 中没有找到 import 块", path)
+		}
+		i += len("import (")
+	} else {
+		i += strings.Index(src[i:], "\n")
+	}
+	src = src[:i+1] + strings.Join(imports, "") + src[i+1:]
+	out, err := format.Source([]byte(src))
+	if err != nil {
+		return false, fmt.Errorf("格式化 %s 失败: %w", path, err)
+	}
+	if err := os.WriteFile(path, out, 0644); err != nil {
+		return false, fmt.Errorf("写入 %s 失败: %w", path, err)
+	}
+	return true, nil
+}
+
+// ModulePath 读取 root 目录下 go.mod 中的 module 路径
+func ModulePath(root string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return "", fmt.Errorf("读取 go.mod 失败: %w", err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "module ") {
+			return strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "module ")), `"`), nil
+		}
+	}
+	return "", errors.New("go.mod 中没有 module 声明")
+}
+
+// splitWords 按下划线、中划线和大小写边界拆分名称，如 pageNumber、page_number 都拆分为 page、number
+func splitWords(name string) []string {
+	words := make([]string, 0)
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '-' }) {
+		start := 0
+		for i := 1; i < len(part); i++ {
+			if isUpper(part[i]) && !isUpper(part[i-1]) {
+				words = append(words, strings.ToLower(part[start:i]))
+				start = i
+			}
+		}
+		words = append(words, strings.ToLower(part[start:]))
+	}
+	return words
+}
+
+func isUpper(b byte) bool {
+	return b >= 'A' && b <= 'Z'
+}
+
+// pascal 拼接为大驼峰，如 PageNumber
+func pascal(words []string) string {
+	var b strings.Builder
+	for _, w := range words {
+		b.WriteString(strings.ToUpper(w[:1]) + w[1:])
+	}
+	return b.String()
+}
+
+// camel 拼接为小驼峰，如 pageNumber
+func camel(words []string) string {
+	s := pascal(words)
+	return strings.ToLower(s[:1]) + s[1:]
+}
@@ -0,0 +1,105 @@
+package gen_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/gen"
+)
+
+func TestParseFields(t *testing.T) {
+	fields, err := gen.ParseFields([]string{"title:string:required", "page_number:int", "publishedAt:time"})
+	assert.NoError(t, err)
+	assert.Len(t, fields, 3)
+	assert.Equal(t, "Title", fields[0].Name)
+	assert.Equal(t, "required,max=255", fields[0].Binding)
+	assert.Equal(t, "PageNumber", fields[1].Name)
+	assert.Equal(t, "pageNumber", fields[1].Json)
+	assert.Equal(t, "page_number", fields[1].Column)
+	assert.Equal(t, "published_at", fields[2].Column)
+	assert.Equal(t, "time.Time", fields[2].GoType)
+
+	for _, specs := range [][]string{
+		nil,
+		{"title"},
+		{"title:unknown"},
+		{"title:string:unique"},
+		{"enabled:bool:required"},
+		{"id:int"},
+		{"created_at:time"},
+		{"title:string", "Title:text"},
+		{"1title:string"},
+	} {
+		_, err := gen.ParseFields(specs)
+		assert.Error(t, err, specs)
+	}
+}
+
+func setupProject(t *testing.T) string {
+	t.Helper() // 标记为辅助函数
+	root := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/demo\n\ngo 1.22\n"), 0644))
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "apps", "all"), 0755))
+	all := "package all\n\nimport (\n\t_ \"example.com/demo/apps/book/handler\"\n\t// 引入自定义验证器\n\t_ \"example.com/demo/common/validator\"\n)\n"
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "apps", "all", "all.go"), []byte(all), 0644))
+	return root
+}
+
+func TestGenerate(t *testing.T) {
+	root := setupProject(t)
+	module, err := gen.ModulePath(root)
+	assert.NoError(t, err)
+	assert.Equal(t, "example.com/demo", module)
+
+	fields, err := gen.ParseFields([]string{"title:string:required", "meta:json"})
+	assert.NoError(t, err)
+	app := gen.App{Module: module, Name: "article", Fields: fields}
+	files, err := gen.Generate(root, app)
+	assert.NoError(t, err)
+	assert.Len(t, files, 6)
+	for _, f := range files {
+		assert.FileExists(t, filepath.Join(root, f))
+	}
+	data, err := os.ReadFile(filepath.Join(root, "apps", "article", "model", "article.go"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `json:"title" binding:"required,max=255" gorm:"column:title;type:varchar(255);not null" filter:"eq,ne,in,like"`)
+	assert.Contains(t, string(data), `"encoding/json"`)
+
+	// 已存在的文件不会被覆盖
+	handler := filepath.Join(root, "apps", "article", "handler", "article.go")
+	assert.NoError(t, os.WriteFile(handler, []byte("package handler\n"), 0644))
+	_, err = gen.Generate(root, app)
+	assert.Error(t, err)
+	data, err = os.ReadFile(handler)
+	assert.NoError(t, err)
+	assert.Equal(t, "package handler\n", string(data))
+
+	// 与引入的包同名或不合法的应用名称
+	for _, name := range []string{"sql", "Article", "my-app", ""} {
+		_, err = gen.Generate(root, gen.App{Module: module, Name: name, Fields: fields})
+		assert.Error(t, err, name)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	root := setupProject(t)
+	app := gen.App{Module: "example.com/demo", Name: "article"}
+	registered, err := gen.Register(root, app)
+	assert.NoError(t, err)
+	assert.True(t, registered)
+	data, err := os.ReadFile(filepath.Join(root, "apps", "all", "all.go"))
+	assert.NoError(t, err)
+	src := string(data)
+	for _, pkg := range []string{"handler", "logic", "model"} {
+		assert.Contains(t, src, `_ "example.com/demo/apps/article/`+pkg+`"`)
+	}
+	assert.Less(t, strings.Index(src, "apps/article/handler"), strings.Index(src, "apps/book/handler"), "imports should be sorted")
+
+	// 已引入时不修改文件
+	registered, err = gen.Register(root, app)
+	assert.NoError(t, err)
+	assert.False(t, registered)
+}
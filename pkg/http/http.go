@@ -21,8 +21,9 @@ type NamedServer struct {
 
 // IkubeopsServerManager 结构体管理多个HTTP服务器
 type IkubeopsServerManager struct {
-	servers []*NamedServer // 存储HTTP服务器的数组
-	g       errgroup.Group // 等待所有服务器关闭的等待组
+	servers []*NamedServer                    // 存储HTTP服务器的数组
+	g       errgroup.Group                    // 等待所有服务器关闭的等待组
+	hooks   []func(ctx context.Context) error // 服务器关闭后、数据库关闭前执行的退出钩子
 
 	// 通用参数
 	MaxHeaderSize     int    `mapstructure:"max_header_size" json:"max_header_size" yaml:"max_header_size" env:"APP_MAX_HEADER_SIZE"`
@@ -64,6 +65,11 @@ func (ism *IkubeopsServerManager) AddServer(addr, name string, router *gin.Engin
 	})
 }
 
+// AddShutdownHook 添加退出钩子，所有HTTP服务器关闭后按添加顺序执行，与关闭服务器共用超时时间
+func (ism *IkubeopsServerManager) AddShutdownHook(hook func(ctx context.Context) error) {
+	ism.hooks = append(ism.hooks, hook)
+}
+
 // Run 启动所有添加的HTTP服务器
 func (ism *IkubeopsServerManager) Run() {
 	for _, nameServer := range ism.servers {
@@ -114,6 +120,12 @@ func (ism *IkubeopsServerManager) Run() {
 			global.LSys.Error(fmt.Sprintf("关闭服务器异常: %s", err))
 		}
 	}
+	// 执行退出钩子
+	for _, hook := range ism.hooks {
+		if err := hook(ctx); err != nil {
+			global.LSys.Error(fmt.Sprintf("执行退出钩子异常: %s", err))
+		}
+	}
 	// 关闭数据库链接
 	err := global.DB.Close()
 	if err != nil {
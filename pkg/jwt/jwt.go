@@ -0,0 +1,131 @@
+package jwt
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrTokenExpired = errors.New("token 已过期")
+	ErrTokenInvalid = errors.New("token 无效")
+)
+
+const (
+	TokenTypeAccess  = "access"  // 访问 token
+	TokenTypeRefresh = "refresh" // 刷新 token
+)
+
+// Claims 自定义的 token 载荷
+type Claims struct {
+	AccountId uint   `json:"accountId"`     // 账号ID
+	Account   string `json:"account"`       // 账号
+	UserName  string `json:"userName"`      // 姓名
+	TokenType string `json:"tokenType"`     // token 类型
+	SessionId string `json:"sid,omitempty"` // 登录会话ID，同一次登录签发的 token 相同
+	Revision  int64  `json:"rev,omitempty"` // 账号 token 版本号，账号强制下线后此前版本的 token 全部失效
+	jwt.RegisteredClaims
+}
+
+// IkubeJwt 结构体用于签发和解析 JWT
+type IkubeJwt struct {
+	signingKey    []byte            // 签名密钥
+	signingMethod jwt.SigningMethod // 签名算法
+	issuer        string            // 签发者
+	accessExpire  time.Duration     // access token 有效期
+	refreshExpire time.Duration     // refresh token 有效期
+}
+
+// InitIkubeJwt 初始化一个新的 IkubeJwt 实例
+func InitIkubeJwt(signingKey, signingMethod, issuer string, accessExpire, refreshExpire int) (*IkubeJwt, error) {
+	if signingKey == "" {
+		return nil, errors.New("jwt 签名密钥不能为空")
+	}
+	if accessExpire <= 0 {
+		return nil, fmt.Errorf("jwt access token 有效期必须大于 0: %d", accessExpire)
+	}
+	if refreshExpire <= accessExpire {
+		return nil, fmt.Errorf("jwt refresh token 有效期必须大于 access token 有效期: %d", refreshExpire)
+	}
+	// 签名密钥为字符串，只支持 HMAC 系列算法
+	method, ok := jwt.GetSigningMethod(strings.ToUpper(signingMethod)).(*jwt.SigningMethodHMAC)
+	if !ok {
+		return nil, fmt.Errorf("不支持的 jwt 签名算法: %s", signingMethod)
+	}
+	return &IkubeJwt{
+		signingKey:    []byte(signingKey),
+		signingMethod: method,
+		issuer:        issuer,
+		accessExpire:  time.Duration(accessExpire) * time.Second,
+		refreshExpire: time.Duration(refreshExpire) * time.Second,
+	}, nil
+}
+
+// GenerateToken 签发 access token
+func (ij *IkubeJwt) GenerateToken(claims *Claims) (string, error) {
+	return ij.generate(claims, TokenTypeAccess, ij.accessExpire)
+}
+
+// GenerateRefreshToken 签发 refresh token
+func (ij *IkubeJwt) GenerateRefreshToken(claims *Claims) (string, error) {
+	return ij.generate(claims, TokenTypeRefresh, ij.refreshExpire)
+}
+
+// generate 签发 token，类型、签发时间、过期时间、签发者以及 ID 由此处统一填充
+func (ij *IkubeJwt) generate(claims *Claims, tokenType string, expire time.Duration) (string, error) {
+	id, err := newTokenId()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	claims.TokenType = tokenType
+	claims.ID = id
+	claims.Issuer = ij.issuer
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.NotBefore = jwt.NewNumericDate(now)
+	claims.ExpiresAt = jwt.NewNumericDate(now.Add(expire))
+	return jwt.NewWithClaims(ij.signingMethod, claims).SignedString(ij.signingKey)
+}
+
+// ParseToken 解析并校验 token，过期返回 ErrTokenExpired，其余失败均返回 ErrTokenInvalid
+func (ij *IkubeJwt) ParseToken(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return ij.signingKey, nil
+	},
+		jwt.WithValidMethods([]string{ij.signingMethod.Alg()}),
+		jwt.WithIssuer(ij.issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, ErrTokenExpired
+		}
+		return nil, fmt.Errorf("%w: %s", ErrTokenInvalid, err)
+	}
+	return claims, nil
+}
+
+// AccessExpire 返回 access token 有效期
+func (ij *IkubeJwt) AccessExpire() time.Duration {
+	return ij.accessExpire
+}
+
+// RefreshExpire 返回 refresh token 有效期
+func (ij *IkubeJwt) RefreshExpire() time.Duration {
+	return ij.refreshExpire
+}
+
+// newTokenId 生成 token 唯一ID
+func newTokenId() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("生成 token ID 失败: %s", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,71 @@
+package jwt_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/jwt"
+)
+
+func TestIkubeJwt_GenerateAndParse(t *testing.T) {
+	ij, err := jwt.InitIkubeJwt("test-signing-key", "HS256", "ikubeops", 60, 120)
+	assert.NoError(t, err, "初始化 IkubeJwt 应该成功")
+
+	token, err := ij.GenerateToken(&jwt.Claims{AccountId: 1, Account: "admin", UserName: "管理员"})
+	assert.NoError(t, err, "签发 token 应该成功")
+
+	claims, err := ij.ParseToken(token)
+	assert.NoError(t, err, "解析 token 应该成功")
+	assert.Equal(t, uint(1), claims.AccountId, "AccountId 与预期不符")
+	assert.Equal(t, "admin", claims.Account, "Account 与预期不符")
+	assert.Equal(t, "ikubeops", claims.Issuer, "Issuer 与预期不符")
+	assert.Equal(t, jwt.TokenTypeAccess, claims.TokenType, "TokenType 与预期不符")
+	assert.NotEmpty(t, claims.ID, "token ID 不应为空")
+
+	refresh, err := ij.GenerateRefreshToken(&jwt.Claims{AccountId: 1, Account: "admin"})
+	assert.NoError(t, err, "签发 refresh token 应该成功")
+	refreshClaims, err := ij.ParseToken(refresh)
+	assert.NoError(t, err, "解析 refresh token 应该成功")
+	assert.Equal(t, jwt.TokenTypeRefresh, refreshClaims.TokenType, "TokenType 与预期不符")
+	assert.NotEqual(t, claims.ID, refreshClaims.ID, "token ID 不应重复")
+}
+
+func TestIkubeJwt_ParseInvalid(t *testing.T) {
+	ij, err := jwt.InitIkubeJwt("test-signing-key", "HS256", "ikubeops", 60, 120)
+	assert.NoError(t, err, "初始化 IkubeJwt 应该成功")
+	other, err := jwt.InitIkubeJwt("other-signing-key", "HS256", "ikubeops", 60, 120)
+	assert.NoError(t, err, "初始化 IkubeJwt 应该成功")
+
+	token, err := other.GenerateToken(&jwt.Claims{AccountId: 1})
+	assert.NoError(t, err, "签发 token 应该成功")
+
+	_, err = ij.ParseToken(token)
+	assert.ErrorIs(t, err, jwt.ErrTokenInvalid, "签名密钥不一致应该解析失败")
+
+	_, err = ij.ParseToken("not-a-token")
+	assert.ErrorIs(t, err, jwt.ErrTokenInvalid, "非法 token 应该解析失败")
+}
+
+func TestIkubeJwt_ParseExpired(t *testing.T) {
+	ij, err := jwt.InitIkubeJwt("test-signing-key", "HS256", "ikubeops", 1, 120)
+	assert.NoError(t, err, "初始化 IkubeJwt 应该成功")
+
+	token, err := ij.GenerateToken(&jwt.Claims{AccountId: 1})
+	assert.NoError(t, err, "签发 token 应该成功")
+
+	time.Sleep(2 * time.Second)
+	_, err = ij.ParseToken(token)
+	assert.ErrorIs(t, err, jwt.ErrTokenExpired, "过期 token 应该返回 ErrTokenExpired")
+}
+
+func TestInitIkubeJwt_Invalid(t *testing.T) {
+	_, err := jwt.InitIkubeJwt("", "HS256", "ikubeops", 60, 120)
+	assert.Error(t, err, "签名密钥为空应该初始化失败")
+
+	_, err = jwt.InitIkubeJwt("test-signing-key", "RS256", "ikubeops", 60, 120)
+	assert.Error(t, err, "非 HMAC 算法应该初始化失败")
+
+	_, err = jwt.InitIkubeJwt("test-signing-key", "HS256", "ikubeops", 60, 60)
+	assert.Error(t, err, "refresh token 有效期不大于 access token 应该初始化失败")
+}
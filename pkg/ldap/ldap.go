@@ -0,0 +1,143 @@
+package ldap
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	goldap "github.com/go-ldap/ldap/v3"
+)
+
+// IkubeLdap LDAP 客户端，每次查询建立新的连接，适用于低频的目录同步任务
+type IkubeLdap struct {
+	url                string
+	bindDn             string
+	bindPassword       string
+	startTls           bool
+	insecureSkipVerify bool
+	pageSize           uint32
+	timeout            time.Duration
+}
+
+// Entry 目录条目，属性名统一转换为小写
+type Entry struct {
+	DN         string
+	Attributes map[string][]string
+}
+
+// Attr 返回属性的第一个值，属性名不区分大小写
+func (e *Entry) Attr(name string) string {
+	values := e.Attributes[strings.ToLower(name)]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// InitIkubeLdap 初始化一个新的 IkubeLdap 实例，timeout 单位 s
+func InitIkubeLdap(url, bindDn, bindPassword string, startTls, insecureSkipVerify bool, pageSize, timeout int) (*IkubeLdap, error) {
+	if url == "" {
+		return nil, errors.New("ldap 地址不能为空")
+	}
+	if pageSize <= 0 {
+		pageSize = 500
+	}
+	if timeout <= 0 {
+		timeout = 10
+	}
+	return &IkubeLdap{
+		url:                url,
+		bindDn:             bindDn,
+		bindPassword:       bindPassword,
+		startTls:           startTls,
+		insecureSkipVerify: insecureSkipVerify,
+		pageSize:           uint32(pageSize),
+		timeout:            time.Duration(timeout) * time.Second,
+	}, nil
+}
+
+// Search 在 baseDn 子树中分页查询符合 filter 的条目
+func (l *IkubeLdap) Search(baseDn, filter string, attributes []string) ([]*Entry, error) {
+	conn, err := l.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	req := goldap.NewSearchRequest(baseDn, goldap.ScopeWholeSubtree, goldap.NeverDerefAliases,
+		0, 0, false, filter, attributes, nil)
+	result, err := conn.SearchWithPaging(req, l.pageSize)
+	if err != nil {
+		return nil, fmt.Errorf("ldap 查询失败, base: %s, filter: %s, error: %w", baseDn, filter, err)
+	}
+	entries := make([]*Entry, 0, len(result.Entries))
+	for _, e := range result.Entries {
+		entry := &Entry{DN: e.DN, Attributes: make(map[string][]string, len(e.Attributes))}
+		for _, attr := range e.Attributes {
+			entry.Attributes[strings.ToLower(attr.Name)] = attr.Values
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// connect 建立连接并绑定管理账号
+func (l *IkubeLdap) connect() (*goldap.Conn, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: l.insecureSkipVerify}
+	conn, err := goldap.DialURL(l.url, goldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, fmt.Errorf("ldap 连接失败: %w", err)
+	}
+	conn.SetTimeout(l.timeout)
+	if l.startTls {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("ldap StartTLS 失败: %w", err)
+		}
+	}
+	if l.bindDn != "" {
+		if err := conn.Bind(l.bindDn, l.bindPassword); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("ldap 绑定失败: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+// NormalizeDN 规范化 DN，属性名和值统一转换为小写并去除多余空格，用于比较
+func NormalizeDN(dn string) string {
+	parsed, err := goldap.ParseDN(dn)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(dn))
+	}
+	rdns := make([]string, 0, len(parsed.RDNs))
+	for _, rdn := range parsed.RDNs {
+		attrs := make([]string, 0, len(rdn.Attributes))
+		for _, attr := range rdn.Attributes {
+			attrs = append(attrs, strings.ToLower(attr.Type)+"="+strings.ToLower(attr.Value))
+		}
+		sort.Strings(attrs)
+		rdns = append(rdns, strings.Join(attrs, "+"))
+	}
+	return strings.Join(rdns, ",")
+}
+
+// ParentDN 返回规范化后的父级 DN，顶级条目返回空字符串
+func ParentDN(dn string) string {
+	normalized := NormalizeDN(dn)
+	if i := strings.Index(normalized, ","); i >= 0 {
+		return normalized[i+1:]
+	}
+	return ""
+}
+
+// Depth 返回 DN 的层级数，用于按从上到下的顺序处理条目
+func Depth(dn string) int {
+	parsed, err := goldap.ParseDN(dn)
+	if err != nil {
+		return strings.Count(dn, ",") + 1
+	}
+	return len(parsed.RDNs)
+}
@@ -0,0 +1,44 @@
+package ldap_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/ldap"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/ldap/ldaptest"
+)
+
+func TestIkubeLdap_Search(t *testing.T) {
+	srv, err := ldaptest.NewServer("cn=admin,dc=ikubeops,dc=local", "secret")
+	assert.NoError(t, err, "启动目录桩应该成功")
+	defer srv.Close()
+	srv.SetEntries(
+		ldaptest.NewEntry("ou=ops,dc=ikubeops,dc=local", "objectClass", "organizationalUnit", "ou", "ops"),
+		ldaptest.NewEntry("uid=zhangsan,ou=ops,dc=ikubeops,dc=local", "objectClass", "inetOrgPerson", "uid", "zhangsan", "mail", "zs@ikubeops.local"),
+		ldaptest.NewEntry("uid=lisi,ou=ops,dc=ikubeops,dc=local", "objectClass", "inetOrgPerson", "uid", "lisi"),
+	)
+
+	l, err := ldap.InitIkubeLdap(srv.URL, srv.BindDn, srv.BindPassword, false, false, 10, 5)
+	assert.NoError(t, err, "初始化 IkubeLdap 应该成功")
+	users, err := l.Search("dc=ikubeops,dc=local", "(objectClass=inetOrgPerson)", []string{"uid", "mail"})
+	assert.NoError(t, err, "查询应该成功")
+	assert.Len(t, users, 2, "用户数量与预期不符")
+	assert.Equal(t, "zhangsan", users[0].Attr("UID"), "属性名应该不区分大小写")
+	assert.Equal(t, "zs@ikubeops.local", users[0].Attr("mail"), "mail 与预期不符")
+
+	users, err = l.Search("dc=ikubeops,dc=local", "(&(objectClass=inetOrgPerson)(mail=*))", []string{"uid"})
+	assert.NoError(t, err, "查询应该成功")
+	assert.Len(t, users, 1, "组合条件查询结果与预期不符")
+
+	bad, err := ldap.InitIkubeLdap(srv.URL, srv.BindDn, "wrong", false, false, 10, 5)
+	assert.NoError(t, err, "初始化 IkubeLdap 应该成功")
+	_, err = bad.Search("dc=ikubeops,dc=local", "(objectClass=*)", nil)
+	assert.Error(t, err, "密码错误应该绑定失败")
+}
+
+func TestDN(t *testing.T) {
+	assert.Equal(t, "ou=ops,dc=ikubeops,dc=local", ldap.NormalizeDN("OU=Ops, DC=ikubeops,DC=local"), "规范化 DN 与预期不符")
+	assert.Equal(t, "dc=ikubeops,dc=local", ldap.ParentDN("ou=ops,dc=ikubeops,dc=local"), "父级 DN 与预期不符")
+	assert.Equal(t, "", ldap.ParentDN("dc=local"), "顶级条目的父级 DN 应该为空")
+	assert.Equal(t, 3, ldap.Depth("ou=ops,dc=ikubeops,dc=local"), "DN 层级与预期不符")
+}
@@ -0,0 +1,226 @@
+package ldaptest
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	goldap "github.com/go-ldap/ldap/v3"
+	"github.com/jimlambrt/gldap"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/ldap"
+)
+
+// Server 进程内的 LDAP 目录桩，支持简单绑定和按 base、scope、filter 查询，
+// filter 支持 and、or、not、等值、存在和子串匹配，用于测试目录同步。
+type Server struct {
+	URL          string
+	BindDn       string
+	BindPassword string
+
+	srv     *gldap.Server
+	mu      sync.Mutex
+	entries []*ldap.Entry
+}
+
+// NewServer 在本地随机端口启动目录桩，使用完毕后需要调用 Close
+func NewServer(bindDn, bindPassword string) (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	s := &Server{URL: "ldap://" + addr, BindDn: bindDn, BindPassword: bindPassword}
+	s.srv, err = gldap.NewServer()
+	if err != nil {
+		return nil, err
+	}
+	mux, err := gldap.NewMux()
+	if err != nil {
+		return nil, err
+	}
+	if err := mux.Bind(s.bind); err != nil {
+		return nil, err
+	}
+	if err := mux.Search(s.search); err != nil {
+		return nil, err
+	}
+	if err := s.srv.Router(mux); err != nil {
+		return nil, err
+	}
+	go func() { _ = s.srv.Run(addr) }()
+	for i := 0; !s.srv.Ready(); i++ {
+		if i > 1000 {
+			return nil, fmt.Errorf("ldap 目录桩启动超时")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return s, nil
+}
+
+// Close 停止目录桩
+func (s *Server) Close() {
+	_ = s.srv.Stop()
+}
+
+// SetEntries 替换目录中的全部条目
+func (s *Server) SetEntries(entries ...*ldap.Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = entries
+}
+
+// NewEntry 创建目录条目，attrs 按属性名和值交替给出
+func NewEntry(dn string, attrs ...string) *ldap.Entry {
+	entry := &ldap.Entry{DN: dn, Attributes: map[string][]string{}}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		name := strings.ToLower(attrs[i])
+		entry.Attributes[name] = append(entry.Attributes[name], attrs[i+1])
+	}
+	return entry
+}
+
+func (s *Server) bind(w *gldap.ResponseWriter, r *gldap.Request) {
+	resp := r.NewBindResponse(gldap.WithResponseCode(gldap.ResultInvalidCredentials))
+	defer func() { _ = w.Write(resp) }()
+	m, err := r.GetSimpleBindMessage()
+	if err != nil {
+		return
+	}
+	if m.UserName == s.BindDn && string(m.Password) == s.BindPassword {
+		resp.SetResultCode(gldap.ResultSuccess)
+	}
+}
+
+func (s *Server) search(w *gldap.ResponseWriter, r *gldap.Request) {
+	done := r.NewSearchDoneResponse(gldap.WithResponseCode(gldap.ResultOperationsError))
+	defer func() { _ = w.Write(done) }()
+	m, err := r.GetSearchMessage()
+	if err != nil {
+		return
+	}
+	filter, err := goldap.CompileFilter(m.Filter)
+	if err != nil {
+		done.SetResultCode(gldap.ResultProtocolError)
+		return
+	}
+	base := ldap.NormalizeDN(m.BaseDN)
+	s.mu.Lock()
+	entries := s.entries
+	s.mu.Unlock()
+	for _, e := range entries {
+		if !inScope(ldap.NormalizeDN(e.DN), base, m.Scope) || !match(filter, e) {
+			continue
+		}
+		resp := r.NewSearchResponseEntry(e.DN)
+		for name, values := range e.Attributes {
+			if wanted(name, m.Attributes) {
+				resp.AddAttribute(name, values)
+			}
+		}
+		if err := w.Write(resp); err != nil {
+			return
+		}
+	}
+	done.SetResultCode(gldap.ResultSuccess)
+}
+
+func inScope(dn, base string, scope gldap.Scope) bool {
+	switch scope {
+	case gldap.BaseObject:
+		return dn == base
+	case gldap.SingleLevel:
+		return ldap.ParentDN(dn) == base
+	default:
+		return dn == base || base == "" || strings.HasSuffix(dn, ","+base)
+	}
+}
+
+func wanted(name string, attributes []string) bool {
+	if len(attributes) == 0 {
+		return true
+	}
+	for _, attr := range attributes {
+		if attr == "*" || strings.EqualFold(attr, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// match 计算编译后的 filter，不支持的匹配规则视为不匹配
+func match(f *ber.Packet, e *ldap.Entry) bool {
+	switch f.Tag {
+	case goldap.FilterAnd:
+		for _, child := range f.Children {
+			if !match(child, e) {
+				return false
+			}
+		}
+		return true
+	case goldap.FilterOr:
+		for _, child := range f.Children {
+			if match(child, e) {
+				return true
+			}
+		}
+		return false
+	case goldap.FilterNot:
+		return len(f.Children) == 1 && !match(f.Children[0], e)
+	case goldap.FilterPresent:
+		return len(e.Attributes[strings.ToLower(f.Data.String())]) > 0
+	case goldap.FilterEqualityMatch:
+		name, value := packetString(f.Children[0]), packetString(f.Children[1])
+		for _, v := range e.Attributes[strings.ToLower(name)] {
+			if strings.EqualFold(v, value) {
+				return true
+			}
+		}
+		return false
+	case goldap.FilterSubstrings:
+		name := packetString(f.Children[0])
+		for _, v := range e.Attributes[strings.ToLower(name)] {
+			if matchSubstrings(strings.ToLower(v), f.Children[1].Children) {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+func matchSubstrings(value string, parts []*ber.Packet) bool {
+	for _, part := range parts {
+		s := strings.ToLower(packetString(part))
+		switch part.Tag {
+		case goldap.FilterSubstringsInitial:
+			if !strings.HasPrefix(value, s) {
+				return false
+			}
+			value = value[len(s):]
+		case goldap.FilterSubstringsAny:
+			i := strings.Index(value, s)
+			if i < 0 {
+				return false
+			}
+			value = value[i+len(s):]
+		case goldap.FilterSubstringsFinal:
+			if !strings.HasSuffix(value, s) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func packetString(p *ber.Packet) string {
+	if s, ok := p.Value.(string); ok {
+		return s
+	}
+	return p.Data.String()
+}
@@ -0,0 +1,99 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// 事务提交回调：连接池开启的事务在提交成功后执行通过 AfterCommit 注册的函数，回滚时丢弃。
+// GORM 的默认事务、Transaction 以及嵌套事务（保存点）都由同一个连接池开启，
+// 因此模型钩子中注册的函数总是在最外层事务提交后执行。
+
+// commitPool 包装 GORM 的连接池，开启的事务支持提交后回调
+type commitPool struct {
+	gorm.ConnPool
+}
+
+func (p *commitPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	var (
+		tx  *sql.Tx
+		err error
+	)
+	switch beginner := p.ConnPool.(type) {
+	case gorm.TxBeginner:
+		tx, err = beginner.BeginTx(ctx, opts)
+	default:
+		return nil, gorm.ErrInvalidTransaction
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &commitTx{Tx: tx, pool: p}, nil
+}
+
+func (p *commitPool) GetDBConn() (*sql.DB, error) {
+	if db, ok := p.ConnPool.(*sql.DB); ok {
+		return db, nil
+	}
+	if connector, ok := p.ConnPool.(gorm.GetDBConnector); ok {
+		return connector.GetDBConn()
+	}
+	return nil, gorm.ErrInvalidDB
+}
+
+// commitTx 记录事务中注册的提交后回调
+type commitTx struct {
+	*sql.Tx
+	pool  *commitPool
+	mu    sync.Mutex
+	hooks []func()
+}
+
+func (t *commitTx) Commit() error {
+	if err := t.Tx.Commit(); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	hooks := t.hooks
+	t.hooks = nil
+	t.mu.Unlock()
+	for _, fn := range hooks {
+		fn()
+	}
+	return nil
+}
+
+func (t *commitTx) Rollback() error {
+	t.mu.Lock()
+	t.hooks = nil
+	t.mu.Unlock()
+	return t.Tx.Rollback()
+}
+
+func (t *commitTx) GetDBConn() (*sql.DB, error) {
+	return t.pool.GetDBConn()
+}
+
+// AfterCommit 在当前事务提交成功后执行 fn，不在事务中时立即执行。
+// 用于清除缓存等不能回滚的操作，避免事务提交前其他请求读到旧数据后重新写入缓存。
+func AfterCommit(db *gorm.DB, fn func()) {
+	if tx, ok := db.Statement.ConnPool.(*commitTx); ok {
+		tx.mu.Lock()
+		tx.hooks = append(tx.hooks, fn)
+		tx.mu.Unlock()
+		return
+	}
+	fn()
+}
+
+// wrapConnPool 替换连接池，使开启的事务支持提交后回调
+func wrapConnPool(db *gorm.DB) {
+	if _, ok := db.ConnPool.(*commitPool); ok {
+		return
+	}
+	db.ConnPool = &commitPool{ConnPool: db.ConnPool}
+	db.Statement.ConnPool = db.ConnPool
+}
@@ -0,0 +1,81 @@
+package mysql_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type commitItem struct {
+	ID   uint
+	Name string
+}
+
+// AfterCreate 在钩子中注册提交后回调，记录回调的执行顺序
+func (i *commitItem) AfterCreate(tx *gorm.DB) error {
+	mysql.AfterCommit(tx, func() {
+		committed = append(committed, i.Name)
+	})
+	return nil
+}
+
+var committed []string
+
+func openCommitDb(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	assert.NoError(t, err, "打开测试数据库应该成功")
+	assert.NoError(t, db.AutoMigrate(&commitItem{}), "迁移测试表应该成功")
+	committed = nil
+	return mysql.NewIkubeGormFromDb(db).GetDb()
+}
+
+func TestAfterCommit(t *testing.T) {
+	db := openCommitDb(t)
+
+	// 默认事务提交后执行
+	assert.NoError(t, db.Create(&commitItem{Name: "default"}).Error)
+	assert.Equal(t, []string{"default"}, committed, "默认事务提交后应该执行回调")
+
+	// 显式事务中注册的回调在最外层事务提交后执行，嵌套事务的回调同样延迟
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&commitItem{Name: "outer"}).Error; err != nil {
+			return err
+		}
+		if err := tx.Transaction(func(tx *gorm.DB) error {
+			return tx.Create(&commitItem{Name: "nested"}).Error
+		}); err != nil {
+			return err
+		}
+		assert.Equal(t, []string{"default"}, committed, "事务提交前不应该执行回调")
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"default", "outer", "nested"}, committed, "事务提交后应该按注册顺序执行回调")
+
+	// 回滚时丢弃回调
+	committed = nil
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&commitItem{Name: "rollback"}).Error; err != nil {
+			return err
+		}
+		return errors.New("rollback")
+	})
+	assert.Error(t, err)
+	assert.Empty(t, committed, "事务回滚时不应该执行回调")
+
+	// 不在事务中时立即执行
+	called := false
+	mysql.AfterCommit(db, func() { called = true })
+	assert.True(t, called, "不在事务中时应该立即执行回调")
+
+	sqlDB, err := db.DB()
+	assert.NoError(t, err, "包装后的连接池应该可以获取 *sql.DB")
+	assert.NoError(t, sqlDB.Ping())
+}
@@ -39,6 +39,12 @@ func InitIkubeGorm(dsn string, maxIdleConns, maxOpenConns int, logToFile bool, l
 	return ikube, nil
 }
 
+// NewIkubeGormFromDb 使用已经打开的连接创建实例，用于测试或其他数据库驱动
+func NewIkubeGormFromDb(db *gorm.DB) *IkubeGorm {
+	wrapConnPool(db)
+	return &IkubeGorm{db: db}
+}
+
 // load 初始化 MySQL 连接并设置数据库
 func (ikube *IkubeGorm) load() error {
 	// 配置 MySQL 连接参数
@@ -62,6 +68,7 @@ func (ikube *IkubeGorm) load() error {
 	sqlDB, _ := db.DB()
 	sqlDB.SetMaxIdleConns(ikube.maxIdleConns)
 	sqlDB.SetMaxOpenConns(ikube.maxOpenConns)
+	wrapConnPool(db)
 
 	ikube.db = db
 	return nil
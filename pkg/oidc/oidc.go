@@ -0,0 +1,210 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	gooidc "github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+var (
+	ErrNonceMismatch  = errors.New("id token nonce 不匹配")
+	ErrIdTokenMissing = errors.New("token 响应中缺少 id token")
+)
+
+// IkubeOidc OIDC 客户端，使用授权码 + PKCE 流程登录。
+// 服务发现在第一次使用时进行，身份提供方暂时不可用不影响服务启动。
+type IkubeOidc struct {
+	issuer       string
+	clientId     string
+	clientSecret string
+	redirectUrl  string
+	scopes       []string
+
+	mu       sync.Mutex
+	provider *gooidc.Provider
+	verifier *gooidc.IDTokenVerifier
+	oauth    *oauth2.Config
+}
+
+// InitIkubeOidc 初始化一个新的 IkubeOidc 实例
+func InitIkubeOidc(issuer, clientId, clientSecret, redirectUrl string, scopes []string) (*IkubeOidc, error) {
+	if issuer == "" || clientId == "" || redirectUrl == "" {
+		return nil, errors.New("oidc issuer、client id 和回调地址不能为空")
+	}
+	if len(scopes) == 0 {
+		scopes = []string{gooidc.ScopeOpenID, "profile", "email"}
+	}
+	// openid 是 OIDC 必须的 scope
+	hasOpenId := false
+	for _, scope := range scopes {
+		if scope == gooidc.ScopeOpenID {
+			hasOpenId = true
+		}
+	}
+	if !hasOpenId {
+		scopes = append([]string{gooidc.ScopeOpenID}, scopes...)
+	}
+	return &IkubeOidc{
+		issuer:       issuer,
+		clientId:     clientId,
+		clientSecret: clientSecret,
+		redirectUrl:  redirectUrl,
+		scopes:       scopes,
+	}, nil
+}
+
+// discover 获取身份提供方的元数据，成功后缓存
+func (o *IkubeOidc) discover(ctx context.Context) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.provider != nil {
+		return nil
+	}
+	provider, err := gooidc.NewProvider(ctx, o.issuer)
+	if err != nil {
+		return fmt.Errorf("oidc 服务发现失败: %w", err)
+	}
+	o.provider = provider
+	o.verifier = provider.Verifier(&gooidc.Config{ClientID: o.clientId})
+	o.oauth = &oauth2.Config{
+		ClientID:     o.clientId,
+		ClientSecret: o.clientSecret,
+		RedirectURL:  o.redirectUrl,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       o.scopes,
+	}
+	return nil
+}
+
+// AuthCodeURL 生成跳转到身份提供方的授权地址，verifier 为 PKCE 校验码
+func (o *IkubeOidc) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	if err := o.discover(ctx); err != nil {
+		return "", err
+	}
+	return o.oauth.AuthCodeURL(state, gooidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// Exchange 使用授权码换取 token，校验 id token 和 nonce 后返回用户声明。
+// 身份提供方提供 userinfo 接口时，id token 中缺少的声明从 userinfo 中补充。
+func (o *IkubeOidc) Exchange(ctx context.Context, code, nonce, verifier string) (Claims, error) {
+	if err := o.discover(ctx); err != nil {
+		return nil, err
+	}
+	token, err := o.oauth.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("oidc 授权码换取 token 失败: %w", err)
+	}
+	rawIdToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIdToken == "" {
+		return nil, ErrIdTokenMissing
+	}
+	idToken, err := o.verifier.Verify(ctx, rawIdToken)
+	if err != nil {
+		return nil, fmt.Errorf("oidc id token 校验失败: %w", err)
+	}
+	if idToken.Nonce != nonce {
+		return nil, ErrNonceMismatch
+	}
+	claims := Claims{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("oidc id token 解析失败: %w", err)
+	}
+	if o.provider.UserInfoEndpoint() == "" {
+		return claims, nil
+	}
+	userInfo, err := o.provider.UserInfo(ctx, oauth2.StaticTokenSource(token))
+	if err != nil {
+		return nil, fmt.Errorf("oidc 获取用户信息失败: %w", err)
+	}
+	if userInfo.Subject != idToken.Subject {
+		return nil, errors.New("oidc 用户信息与 id token 不属于同一用户")
+	}
+	extra := Claims{}
+	if err := userInfo.Claims(&extra); err != nil {
+		return nil, fmt.Errorf("oidc 用户信息解析失败: %w", err)
+	}
+	for k, v := range extra {
+		if _, ok := claims[k]; !ok {
+			claims[k] = v
+		}
+	}
+	return claims, nil
+}
+
+// Issuer 身份提供方地址，与 subject 一起唯一标识一个外部用户
+func (o *IkubeOidc) Issuer() string {
+	return o.issuer
+}
+
+// Claims OIDC 用户声明
+type Claims map[string]interface{}
+
+// Subject 外部用户的唯一标识
+func (c Claims) Subject() string {
+	return c.String("sub")
+}
+
+// String 按名称读取声明并转换为字符串，名称支持以 . 分隔的嵌套声明，数组取第一个元素
+func (c Claims) String(name string) string {
+	if name == "" {
+		return ""
+	}
+	var value interface{} = map[string]interface{}(c)
+	for _, key := range strings.Split(name, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		value = m[key]
+	}
+	if list, ok := value.([]interface{}); ok {
+		if len(list) == 0 {
+			return ""
+		}
+		value = list[0]
+	}
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return ""
+	}
+}
+
+// Bool 按名称读取布尔声明，声明不存在时返回 def
+func (c Claims) Bool(name string, def bool) bool {
+	switch c.String(name) {
+	case "true":
+		return true
+	case "false":
+		return false
+	default:
+		return def
+	}
+}
+
+// RandomString 生成 URL 安全的随机字符串，用于 state 和 nonce
+func RandomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// GenerateVerifier 生成 PKCE 校验码
+func GenerateVerifier() string {
+	return oauth2.GenerateVerifier()
+}
@@ -0,0 +1,79 @@
+package oidc_test
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/oidc"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/oidc/oidctest"
+)
+
+func TestIkubeOidc_AuthCodeFlow(t *testing.T) {
+	idp, err := oidctest.NewServer("ikube", "secret")
+	assert.NoError(t, err, "启动身份提供方桩应该成功")
+	defer idp.Close()
+	idp.SetClaims(map[string]interface{}{
+		"sub":   "u-1001",
+		"email": "zhangsan@ikubeops.local",
+		"org":   map[string]interface{}{"name": "运维部"},
+	})
+
+	o, err := oidc.InitIkubeOidc(idp.URL, "ikube", "secret", "http://localhost/callback", nil)
+	assert.NoError(t, err, "初始化 IkubeOidc 应该成功")
+	ctx := context.Background()
+	verifier := oidc.GenerateVerifier()
+	authUrl, err := o.AuthCodeURL(ctx, "state-1", "nonce-1", verifier)
+	assert.NoError(t, err, "生成授权地址应该成功")
+	u, _ := url.Parse(authUrl)
+	assert.Equal(t, "S256", u.Query().Get("code_challenge_method"), "授权地址应该携带 PKCE 参数")
+
+	code, state, err := idp.Authorize(authUrl)
+	assert.NoError(t, err, "授权应该成功")
+	assert.Equal(t, "state-1", state, "state 与预期不符")
+
+	claims, err := o.Exchange(ctx, code, "nonce-1", verifier)
+	assert.NoError(t, err, "换取 token 应该成功")
+	assert.Equal(t, "u-1001", claims.Subject(), "sub 与预期不符")
+	assert.Equal(t, "zhangsan@ikubeops.local", claims.String("email"), "email 与预期不符")
+	assert.Equal(t, "运维部", claims.String("org.name"), "嵌套声明与预期不符")
+}
+
+func TestIkubeOidc_ExchangeInvalid(t *testing.T) {
+	idp, err := oidctest.NewServer("ikube", "secret")
+	assert.NoError(t, err, "启动身份提供方桩应该成功")
+	defer idp.Close()
+	idp.SetClaims(map[string]interface{}{"sub": "u-1001"})
+
+	o, err := oidc.InitIkubeOidc(idp.URL, "ikube", "secret", "http://localhost/callback", nil)
+	assert.NoError(t, err, "初始化 IkubeOidc 应该成功")
+	ctx := context.Background()
+	verifier := oidc.GenerateVerifier()
+	authUrl, err := o.AuthCodeURL(ctx, "state-1", "nonce-1", verifier)
+	assert.NoError(t, err, "生成授权地址应该成功")
+
+	code, _, err := idp.Authorize(authUrl)
+	assert.NoError(t, err, "授权应该成功")
+	_, err = o.Exchange(ctx, code, "nonce-1", oidc.GenerateVerifier())
+	assert.Error(t, err, "PKCE 校验码不一致应该失败")
+
+	code, _, err = idp.Authorize(authUrl)
+	assert.NoError(t, err, "授权应该成功")
+	_, err = o.Exchange(ctx, code, "nonce-2", verifier)
+	assert.ErrorIs(t, err, oidc.ErrNonceMismatch, "nonce 不一致应该失败")
+}
+
+func TestClaims_String(t *testing.T) {
+	claims := oidc.Claims{
+		"sub":    "u-1",
+		"number": float64(10086),
+		"groups": []interface{}{"ops", "dev"},
+		"flag":   true,
+	}
+	assert.Equal(t, "10086", claims.String("number"), "数字声明应该转换为字符串")
+	assert.Equal(t, "ops", claims.String("groups"), "数组声明应该取第一个元素")
+	assert.Equal(t, "", claims.String("missing.key"), "不存在的声明应该返回空字符串")
+	assert.True(t, claims.Bool("flag", false), "布尔声明与预期不符")
+	assert.False(t, claims.Bool("missing", false), "不存在的布尔声明应该返回默认值")
+}
@@ -0,0 +1,110 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 密码策略校验，只负责判断明文密码是否满足规则，密码的加密和历史记录由业务层处理。
+
+var (
+	ErrLength  = errors.New("密码长度不符合要求")
+	ErrClass   = errors.New("密码缺少必需的字符类型")
+	ErrAccount = errors.New("密码不能包含账号")
+)
+
+// Class 字符类型
+type Class string
+
+const (
+	ClassUpper   Class = "upper"
+	ClassLower   Class = "lower"
+	ClassDigit   Class = "digit"
+	ClassSpecial Class = "special"
+)
+
+// Policy 密码策略
+type Policy struct {
+	MinLength     int
+	MaxLength     int
+	Upper         bool // 必须包含大写字母
+	Lower         bool // 必须包含小写字母
+	Digit         bool // 必须包含数字
+	Special       bool // 必须包含特殊字符
+	RejectAccount bool // 不允许包含账号，忽略大小写
+}
+
+// ValidLength 判断密码长度是否在范围内，按字符计算，MaxLength 为 0 表示不限制
+func (p Policy) ValidLength(password string) bool {
+	n := utf8.RuneCountInString(password)
+	if n < p.MinLength {
+		return false
+	}
+	return p.MaxLength <= 0 || n <= p.MaxLength
+}
+
+// RequiredClasses 策略要求的字符类型
+func (p Policy) RequiredClasses() []Class {
+	classes := make([]Class, 0, 4)
+	if p.Upper {
+		classes = append(classes, ClassUpper)
+	}
+	if p.Lower {
+		classes = append(classes, ClassLower)
+	}
+	if p.Digit {
+		classes = append(classes, ClassDigit)
+	}
+	if p.Special {
+		classes = append(classes, ClassSpecial)
+	}
+	return classes
+}
+
+// MissingClasses 密码缺少的字符类型
+func (p Policy) MissingClasses(password string) []Class {
+	has := make(map[Class]bool, 4)
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			has[ClassUpper] = true
+		case unicode.IsLower(r):
+			has[ClassLower] = true
+		case unicode.IsDigit(r):
+			has[ClassDigit] = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || r == ' ':
+			has[ClassSpecial] = true
+		}
+	}
+	missing := make([]Class, 0)
+	for _, c := range p.RequiredClasses() {
+		if !has[c] {
+			missing = append(missing, c)
+		}
+	}
+	return missing
+}
+
+// ContainsAccount 判断密码是否包含账号，策略未启用或账号为空时返回 false
+func (p Policy) ContainsAccount(password, account string) bool {
+	if !p.RejectAccount || account == "" {
+		return false
+	}
+	return strings.Contains(strings.ToLower(password), strings.ToLower(account))
+}
+
+// Check 按长度、字符类型、账号的顺序校验密码，返回第一个不满足的规则
+func (p Policy) Check(password, account string) error {
+	if !p.ValidLength(password) {
+		return ErrLength
+	}
+	if len(p.MissingClasses(password)) > 0 {
+		return ErrClass
+	}
+	if p.ContainsAccount(password, account) {
+		return ErrAccount
+	}
+	return nil
+}
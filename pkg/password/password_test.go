@@ -0,0 +1,41 @@
+package password_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/password"
+)
+
+var policy = password.Policy{
+	MinLength:     8,
+	MaxLength:     24,
+	Upper:         true,
+	Lower:         true,
+	Digit:         true,
+	RejectAccount: true,
+}
+
+func TestPolicy_Check(t *testing.T) {
+	assert.NoError(t, policy.Check("Ikube2024", "admin"), "符合策略的密码应该校验通过")
+	assert.ErrorIs(t, policy.Check("Ik2", "admin"), password.ErrLength, "过短的密码应该校验失败")
+	assert.ErrorIs(t, policy.Check("Ikube2024Ikube2024Ikube2024", "admin"), password.ErrLength, "过长的密码应该校验失败")
+	assert.ErrorIs(t, policy.Check("ikube2024", "admin"), password.ErrClass, "缺少大写字母的密码应该校验失败")
+	assert.ErrorIs(t, policy.Check("xAdmin2024", "admin"), password.ErrAccount, "包含账号的密码应该校验失败")
+	assert.NoError(t, policy.Check("xAdmin2024", ""), "账号为空时不校验账号规则")
+}
+
+func TestPolicy_MissingClasses(t *testing.T) {
+	p := password.Policy{Upper: true, Lower: true, Digit: true, Special: true}
+	assert.Equal(t, []password.Class{password.ClassUpper, password.ClassSpecial}, p.MissingClasses("ikube2024"),
+		"缺少的字符类型与预期不符")
+	assert.Empty(t, p.MissingClasses("Ikube@2024"), "包含全部字符类型时不应该缺少")
+	assert.Empty(t, password.Policy{}.MissingClasses(""), "未要求字符类型时不应该缺少")
+}
+
+func TestPolicy_ValidLength(t *testing.T) {
+	p := password.Policy{MinLength: 4}
+	assert.True(t, p.ValidLength("密码密码"), "长度应该按字符计算")
+	assert.False(t, p.ValidLength("密码"), "过短的密码应该校验失败")
+	assert.True(t, p.ValidLength("0123456789012345678901234567890123456789"), "最大长度为 0 时不限制")
+}
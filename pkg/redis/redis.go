@@ -33,6 +33,11 @@ func InitIkubeRedis(addr, password string, db, poolSize int) (*IkubeRedis, error
 	return ikube, nil
 }
 
+// NewIkubeRedisFromClient 使用已经创建的客户端创建实例，用于测试
+func NewIkubeRedisFromClient(client *redis.Client) *IkubeRedis {
+	return &IkubeRedis{client: client, addr: client.Options().Addr}
+}
+
 // load 初始化 Redis 连接并设置客户端
 func (ikube *IkubeRedis) load() error {
 	// 创建 Redis 客户端
@@ -0,0 +1,81 @@
+package testenv
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/glebarez/sqlite"
+	ut "github.com/go-playground/universal-translator"
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/yanshicheng/ikube-gin-starter/common/validator"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/jwt"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/mysql"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/redis"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// 测试环境：使用内存 sqlite 和 miniredis 代替 mysql 和 redis，初始化测试所需的全局变量，只在测试中使用。
+
+var transOnce sync.Once
+
+// Setup 初始化日志、jwt 和参数校验翻译
+func Setup(t testing.TB) {
+	t.Helper()
+	global.L = zap.NewNop()
+	global.LSys = global.L
+	j, err := jwt.InitIkubeJwt("testenv-signing-key", "HS256", "ikubeops", 60, 120)
+	if err != nil {
+		t.Fatalf("初始化 jwt 失败: %s", err)
+	}
+	global.J = j
+	transOnce.Do(func() {
+		var uni *ut.UniversalTranslator
+		if global.IkubeopsTrans, uni, err = validator.InitTrans(global.C.App.Language); err != nil {
+			return
+		}
+		err = validator.RegisterValidatorsAndTranslations(validator.ValidatorSlice, uni)
+	})
+	if err != nil {
+		t.Fatalf("初始化翻译器失败: %s", err)
+	}
+}
+
+// DB 创建当前测试独占的内存数据库并迁移 models，设置为全局数据库
+func DB(t testing.TB, models ...interface{}) *gorm.DB {
+	t.Helper()
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", name)), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %s", err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("迁移测试数据库失败: %s", err)
+	}
+	global.DB = mysql.NewIkubeGormFromDb(db)
+	global.C.Mysql.Enable = true
+	return db
+}
+
+// Redis 启动 miniredis 并设置为全局 redis，测试结束后关闭
+func Redis(t testing.TB) *miniredis.Miniredis {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		_ = client.Close()
+		global.RDB = nil
+	})
+	global.RDB = redis.NewIkubeRedisFromClient(client)
+	global.C.Redis.Enable = true
+	return mr
+}
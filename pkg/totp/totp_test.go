@@ -0,0 +1,63 @@
+package totp_test
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/totp"
+)
+
+// RFC 6238 附录 B 的 SHA1 测试密钥
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestTotp_Code(t *testing.T) {
+	// RFC 6238 中的 8 位密码取后 6 位
+	cases := map[int64]string{59: "287082", 1111111109: "081804", 1234567890: "005924", 2000000000: "279037"}
+	for unix, want := range cases {
+		code, err := totp.Code(rfcSecret, totp.Step(time.Unix(unix, 0)))
+		assert.NoError(t, err, "计算一次性密码应该成功")
+		assert.Equal(t, want, code, "一次性密码与 RFC 测试向量不符: %d", unix)
+	}
+	_, err := totp.Code("not base32!", 1)
+	assert.ErrorIs(t, err, totp.ErrSecretFormat, "非法密钥应该返回错误")
+}
+
+func TestTotp_Validate(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	assert.NoError(t, err, "生成密钥应该成功")
+	now := time.Now()
+	code, _ := totp.Code(secret, totp.Step(now)-1)
+
+	step, ok := totp.Validate(secret, code, now, 1)
+	assert.True(t, ok, "时钟偏差内的密码应该校验通过")
+	assert.Equal(t, totp.Step(now)-1, step, "返回的步数与预期不符")
+
+	_, ok = totp.Validate(secret, code, now, 0)
+	assert.False(t, ok, "不允许偏差时上一步的密码应该校验失败")
+	_, ok = totp.Validate(secret, "12345", now, 1)
+	assert.False(t, ok, "位数错误的密码应该校验失败")
+}
+
+func TestTotp_URI(t *testing.T) {
+	uri := totp.URI("ikubeops", "admin", "ABC")
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/ikubeops:admin?"), "otpauth 地址格式与预期不符: %s", uri)
+	assert.Contains(t, uri, "secret=ABC", "otpauth 地址应该包含密钥")
+	assert.Contains(t, uri, "issuer=ikubeops", "otpauth 地址应该包含签发方")
+}
+
+func TestTotp_RecoveryCodes(t *testing.T) {
+	codes, err := totp.GenerateRecoveryCodes(10)
+	assert.NoError(t, err, "生成恢复码应该成功")
+	assert.Len(t, codes, 10, "恢复码数量与预期不符")
+	seen := map[string]bool{}
+	for _, code := range codes {
+		assert.Len(t, code, 11, "恢复码格式与预期不符: %s", code)
+		seen[code] = true
+	}
+	assert.Len(t, seen, 10, "恢复码不应该重复")
+	assert.Equal(t, totp.HashRecoveryCode(codes[0]), totp.HashRecoveryCode(" "+strings.ToUpper(strings.ReplaceAll(codes[0], "-", ""))),
+		"恢复码摘要应该忽略大小写和分隔符")
+}
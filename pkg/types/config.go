@@ -1,6 +1,12 @@
 package types
 
-import "github.com/yanshicheng/ikube-gin-starter/pkg/logger"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/logger"
+)
 
 type AppConfig struct {
 	HttpAddr          string `mapstructure:"http_addr" json:"http_addr" yaml:"http_addr" env:"APP_HTTP_ADDR"`
@@ -40,11 +46,188 @@ type RedisConfig struct {
 	Enable   bool   `mapstructure:"enable" json:"enable" yaml:"enable" env:"REDIS_ENABLE"`
 }
 
+type JwtConfig struct {
+	SigningKey    string `mapstructure:"signing_key" json:"signing_key" yaml:"signing_key" env:"JWT_SIGNING_KEY"`
+	SigningMethod string `mapstructure:"signing_method" json:"signing_method" yaml:"signing_method" env:"JWT_SIGNING_METHOD"`
+	Issuer        string `mapstructure:"issuer" json:"issuer" yaml:"issuer" env:"JWT_ISSUER"`
+	AccessExpire  int    `mapstructure:"access_expire" json:"access_expire" yaml:"access_expire" env:"JWT_ACCESS_EXPIRE"`
+	RefreshExpire int    `mapstructure:"refresh_expire" json:"refresh_expire" yaml:"refresh_expire" env:"JWT_REFRESH_EXPIRE"`
+}
+
+type RbacConfig struct {
+	Enable        bool     `mapstructure:"enable" json:"enable" yaml:"enable" env:"RBAC_ENABLE"`
+	SuperRole     string   `mapstructure:"super_role" json:"super_role" yaml:"super_role" env:"RBAC_SUPER_ROLE"`
+	CacheExpire   int      `mapstructure:"cache_expire" json:"cache_expire" yaml:"cache_expire" env:"RBAC_CACHE_EXPIRE"`
+	SkipResources []string `mapstructure:"skip_resources" json:"skip_resources" yaml:"skip_resources" env:"RBAC_SKIP_RESOURCES"`
+}
+
+type ApiKeyConfig struct {
+	Enable           bool   `mapstructure:"enable" json:"enable" yaml:"enable" env:"API_KEY_ENABLE"`
+	Header           string `mapstructure:"header" json:"header" yaml:"header" env:"API_KEY_HEADER"`
+	Prefix           string `mapstructure:"prefix" json:"prefix" yaml:"prefix" env:"API_KEY_PREFIX"`
+	LastUsedInterval int    `mapstructure:"last_used_interval" json:"last_used_interval" yaml:"last_used_interval" env:"API_KEY_LAST_USED_INTERVAL"`
+}
+
+type OidcConfig struct {
+	Enable                bool            `mapstructure:"enable" json:"enable" yaml:"enable" env:"OIDC_ENABLE"`
+	Issuer                string          `mapstructure:"issuer" json:"issuer" yaml:"issuer" env:"OIDC_ISSUER"`
+	ClientId              string          `mapstructure:"client_id" json:"client_id" yaml:"client_id" env:"OIDC_CLIENT_ID"`
+	ClientSecret          string          `mapstructure:"client_secret" json:"client_secret" yaml:"client_secret" env:"OIDC_CLIENT_SECRET"`
+	RedirectUrl           string          `mapstructure:"redirect_url" json:"redirect_url" yaml:"redirect_url" env:"OIDC_REDIRECT_URL"`
+	Scopes                []string        `mapstructure:"scopes" json:"scopes" yaml:"scopes" env:"OIDC_SCOPES"`
+	StateExpire           int             `mapstructure:"state_expire" json:"state_expire" yaml:"state_expire" env:"OIDC_STATE_EXPIRE"`
+	AutoCreate            bool            `mapstructure:"auto_create" json:"auto_create" yaml:"auto_create" env:"OIDC_AUTO_CREATE"`
+	LinkBy                []string        `mapstructure:"link_by" json:"link_by" yaml:"link_by" env:"OIDC_LINK_BY"`
+	DefaultOrganizationId uint            `mapstructure:"default_organization_id" json:"default_organization_id" yaml:"default_organization_id" env:"OIDC_DEFAULT_ORGANIZATION_ID"`
+	Claims                OidcClaimConfig `mapstructure:"claims" json:"claims" yaml:"claims"`
+}
+
+// OidcClaimConfig 账号字段与 OIDC 声明的映射，声明名称支持以 . 分隔的嵌套声明
+type OidcClaimConfig struct {
+	Account      string `mapstructure:"account" json:"account" yaml:"account" env:"OIDC_CLAIM_ACCOUNT"`
+	UserName     string `mapstructure:"user_name" json:"user_name" yaml:"user_name" env:"OIDC_CLAIM_USER_NAME"`
+	Email        string `mapstructure:"email" json:"email" yaml:"email" env:"OIDC_CLAIM_EMAIL"`
+	WorkNumber   string `mapstructure:"work_number" json:"work_number" yaml:"work_number" env:"OIDC_CLAIM_WORK_NUMBER"`
+	Mobile       string `mapstructure:"mobile" json:"mobile" yaml:"mobile" env:"OIDC_CLAIM_MOBILE"`
+	Organization string `mapstructure:"organization" json:"organization" yaml:"organization" env:"OIDC_CLAIM_ORGANIZATION"`
+}
+
+type LdapConfig struct {
+	Enable             bool                `mapstructure:"enable" json:"enable" yaml:"enable" env:"LDAP_ENABLE"`
+	Url                string              `mapstructure:"url" json:"url" yaml:"url" env:"LDAP_URL"`
+	BindDn             string              `mapstructure:"bind_dn" json:"bind_dn" yaml:"bind_dn" env:"LDAP_BIND_DN"`
+	BindPassword       string              `mapstructure:"bind_password" json:"bind_password" yaml:"bind_password" env:"LDAP_BIND_PASSWORD"`
+	StartTls           bool                `mapstructure:"start_tls" json:"start_tls" yaml:"start_tls" env:"LDAP_START_TLS"`
+	InsecureSkipVerify bool                `mapstructure:"insecure_skip_verify" json:"insecure_skip_verify" yaml:"insecure_skip_verify" env:"LDAP_INSECURE_SKIP_VERIFY"`
+	BaseDn             string              `mapstructure:"base_dn" json:"base_dn" yaml:"base_dn" env:"LDAP_BASE_DN"`
+	UserFilter         string              `mapstructure:"user_filter" json:"user_filter" yaml:"user_filter" env:"LDAP_USER_FILTER"`
+	OrganizationFilter string              `mapstructure:"organization_filter" json:"organization_filter" yaml:"organization_filter" env:"LDAP_ORGANIZATION_FILTER"`
+	PageSize           int                 `mapstructure:"page_size" json:"page_size" yaml:"page_size" env:"LDAP_PAGE_SIZE"`
+	Timeout            int                 `mapstructure:"timeout" json:"timeout" yaml:"timeout" env:"LDAP_TIMEOUT"`
+	Interval           int                 `mapstructure:"interval" json:"interval" yaml:"interval" env:"LDAP_INTERVAL"`
+	RootOrganizationId uint                `mapstructure:"root_organization_id" json:"root_organization_id" yaml:"root_organization_id" env:"LDAP_ROOT_ORGANIZATION_ID"`
+	MissingLeave       bool                `mapstructure:"missing_leave" json:"missing_leave" yaml:"missing_leave" env:"LDAP_MISSING_LEAVE"`
+	MissingDisable     bool                `mapstructure:"missing_disable" json:"missing_disable" yaml:"missing_disable" env:"LDAP_MISSING_DISABLE"`
+	MissingThreshold   int                 `mapstructure:"missing_threshold" json:"missing_threshold" yaml:"missing_threshold" env:"LDAP_MISSING_THRESHOLD"`
+	LinkAccounts       []string            `mapstructure:"link_accounts" json:"link_accounts" yaml:"link_accounts" env:"LDAP_LINK_ACCOUNTS"`
+	Attributes         LdapAttributeConfig `mapstructure:"attributes" json:"attributes" yaml:"attributes"`
+}
+
+// LdapAttributeConfig 账号和机构字段对应的目录属性
+type LdapAttributeConfig struct {
+	Uid          string `mapstructure:"uid" json:"uid" yaml:"uid" env:"LDAP_ATTR_UID"`
+	Account      string `mapstructure:"account" json:"account" yaml:"account" env:"LDAP_ATTR_ACCOUNT"`
+	UserName     string `mapstructure:"user_name" json:"user_name" yaml:"user_name" env:"LDAP_ATTR_USER_NAME"`
+	Email        string `mapstructure:"email" json:"email" yaml:"email" env:"LDAP_ATTR_EMAIL"`
+	WorkNumber   string `mapstructure:"work_number" json:"work_number" yaml:"work_number" env:"LDAP_ATTR_WORK_NUMBER"`
+	Mobile       string `mapstructure:"mobile" json:"mobile" yaml:"mobile" env:"LDAP_ATTR_MOBILE"`
+	Organization string `mapstructure:"organization" json:"organization" yaml:"organization" env:"LDAP_ATTR_ORGANIZATION"`
+	Desc         string `mapstructure:"desc" json:"desc" yaml:"desc" env:"LDAP_ATTR_DESC"`
+}
+
+type PasswordConfig struct {
+	MinLength      int  `mapstructure:"min_length" json:"min_length" yaml:"min_length" env:"PASSWORD_MIN_LENGTH"`
+	MaxLength      int  `mapstructure:"max_length" json:"max_length" yaml:"max_length" env:"PASSWORD_MAX_LENGTH"`
+	RequireUpper   bool `mapstructure:"require_upper" json:"require_upper" yaml:"require_upper" env:"PASSWORD_REQUIRE_UPPER"`
+	RequireLower   bool `mapstructure:"require_lower" json:"require_lower" yaml:"require_lower" env:"PASSWORD_REQUIRE_LOWER"`
+	RequireDigit   bool `mapstructure:"require_digit" json:"require_digit" yaml:"require_digit" env:"PASSWORD_REQUIRE_DIGIT"`
+	RequireSpecial bool `mapstructure:"require_special" json:"require_special" yaml:"require_special" env:"PASSWORD_REQUIRE_SPECIAL"`
+	RejectAccount  bool `mapstructure:"reject_account" json:"reject_account" yaml:"reject_account" env:"PASSWORD_REJECT_ACCOUNT"`
+	History        int  `mapstructure:"history" json:"history" yaml:"history" env:"PASSWORD_HISTORY"`
+	MaxAge         int  `mapstructure:"max_age" json:"max_age" yaml:"max_age" env:"PASSWORD_MAX_AGE"`
+}
+
+type OtpConfig struct {
+	Issuer          string `mapstructure:"issuer" json:"issuer" yaml:"issuer" env:"OTP_ISSUER"`
+	Skew            int    `mapstructure:"skew" json:"skew" yaml:"skew" env:"OTP_SKEW"`
+	RecoveryCodes   int    `mapstructure:"recovery_codes" json:"recovery_codes" yaml:"recovery_codes" env:"OTP_RECOVERY_CODES"`
+	ChallengeExpire int    `mapstructure:"challenge_expire" json:"challenge_expire" yaml:"challenge_expire" env:"OTP_CHALLENGE_EXPIRE"`
+	MaxAttempts     int    `mapstructure:"max_attempts" json:"max_attempts" yaml:"max_attempts" env:"OTP_MAX_ATTEMPTS"`
+}
+
+type AuditConfig struct {
+	Enable    bool     `mapstructure:"enable" json:"enable" yaml:"enable" env:"AUDIT_ENABLE"`
+	Retention int      `mapstructure:"retention" json:"retention" yaml:"retention" env:"AUDIT_RETENTION"`
+	Interval  int      `mapstructure:"interval" json:"interval" yaml:"interval" env:"AUDIT_INTERVAL"`
+	Exclude   []string `mapstructure:"exclude" json:"exclude" yaml:"exclude" env:"AUDIT_EXCLUDE"`
+}
+
+type ApiConfig struct {
+	Prefix   string                      `mapstructure:"prefix" json:"prefix" yaml:"prefix" env:"API_PREFIX"`
+	Versions map[string]ApiVersionConfig `mapstructure:"versions" json:"versions" yaml:"versions"`
+}
+
+// PathPrefix 返回规范化的全局 API 前缀，以 / 开头且不以 / 结尾，未配置时为空
+func (a ApiConfig) PathPrefix() string {
+	prefix := strings.TrimSuffix(a.Prefix, "/")
+	if prefix != "" && !strings.HasPrefix(prefix, "/") {
+		prefix = "/" + prefix
+	}
+	return prefix
+}
+
+// ApiVersionConfig API 版本配置，key 为版本名称，如 v1、v2
+type ApiVersionConfig struct {
+	Deprecated  bool   `mapstructure:"deprecated" json:"deprecated" yaml:"deprecated"`
+	Deprecation string `mapstructure:"deprecation" json:"deprecation" yaml:"deprecation"` // 弃用时间，RFC3339 格式
+	Sunset      string `mapstructure:"sunset" json:"sunset" yaml:"sunset"`                // 下线时间，RFC3339 格式
+	Link        string `mapstructure:"link" json:"link" yaml:"link"`                      // 迁移说明地址
+}
+
+// AppsConfig 按应用名称配置应用，应用名称为服务名称中 . 之前的部分，如 portal、book
+type AppsConfig map[string]AppsItemConfig
+
+type AppsItemConfig struct {
+	Enable *bool                  `mapstructure:"enable" json:"enable" yaml:"enable"` // 未配置时启用
+	Prefix string                 `mapstructure:"prefix" json:"prefix" yaml:"prefix"`
+	Config map[string]interface{} `mapstructure:"config" json:"config" yaml:"config"` // 应用自定义配置，由应用通过 Decode 解码
+}
+
+// Enabled 应用是否启用，未配置的应用默认启用
+func (a AppsConfig) Enabled(name string) bool {
+	item, ok := a[name]
+	return !ok || item.Enable == nil || *item.Enable
+}
+
+// Prefix 应用的路由前缀，未配置时为空
+func (a AppsConfig) Prefix(name string) string {
+	return a[name].Prefix
+}
+
+// Decode 将应用的自定义配置解码到 dst，dst 使用 mapstructure 标签，未配置时保持 dst 原有的默认值
+func (a AppsConfig) Decode(name string, dst interface{}) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToSliceHookFunc(","),
+		),
+		WeaklyTypedInput: true,
+		Result:           dst,
+	})
+	if err != nil {
+		return fmt.Errorf("解析应用 %s 的配置失败: %w", name, err)
+	}
+	if err := decoder.Decode(a[name].Config); err != nil {
+		return fmt.Errorf("解析应用 %s 的配置失败: %w", name, err)
+	}
+	return nil
+}
+
 type Config struct {
-	App    AppConfig          `mapstructure:"app" json:"app" yaml:"app" env:"IKUBEOPS"`
-	Logger logger.IkubeLogger `mapstructure:"logger" json:"logger" yaml:"logger" env:"IKUBEOPS"`
-	Mysql  MysqlConfig        `mapstructure:"mysql" json:"mysql" yaml:"mysql" env:"IKUBEOPS"`
-	Redis  RedisConfig        `mapstructure:"redis" json:"redis" yaml:"redis" env:"IKUBEOPS"`
+	App      AppConfig          `mapstructure:"app" json:"app" yaml:"app" env:"IKUBEOPS"`
+	Logger   logger.IkubeLogger `mapstructure:"logger" json:"logger" yaml:"logger" env:"IKUBEOPS"`
+	Mysql    MysqlConfig        `mapstructure:"mysql" json:"mysql" yaml:"mysql" env:"IKUBEOPS"`
+	Redis    RedisConfig        `mapstructure:"redis" json:"redis" yaml:"redis" env:"IKUBEOPS"`
+	Jwt      JwtConfig          `mapstructure:"jwt" json:"jwt" yaml:"jwt" env:"IKUBEOPS"`
+	Rbac     RbacConfig         `mapstructure:"rbac" json:"rbac" yaml:"rbac" env:"IKUBEOPS"`
+	ApiKey   ApiKeyConfig       `mapstructure:"api_key" json:"api_key" yaml:"api_key" env:"IKUBEOPS"`
+	Oidc     OidcConfig         `mapstructure:"oidc" json:"oidc" yaml:"oidc" env:"IKUBEOPS"`
+	Ldap     LdapConfig         `mapstructure:"ldap" json:"ldap" yaml:"ldap" env:"IKUBEOPS"`
+	Password PasswordConfig     `mapstructure:"password" json:"password" yaml:"password" env:"IKUBEOPS"`
+	Otp      OtpConfig          `mapstructure:"otp" json:"otp" yaml:"otp" env:"IKUBEOPS"`
+	Audit    AuditConfig        `mapstructure:"audit" json:"audit" yaml:"audit" env:"IKUBEOPS"`
+	Api      ApiConfig          `mapstructure:"api" json:"api" yaml:"api" env:"IKUBEOPS"`
+	Apps     AppsConfig         `mapstructure:"apps" json:"apps" yaml:"apps"`
 }
 
 func NewAppConfig() AppConfig {
@@ -104,11 +287,138 @@ func NewRedisConfig() RedisConfig {
 	}
 }
 
+func NewJwtConfig() JwtConfig {
+	return JwtConfig{
+		SigningKey:    "",
+		SigningMethod: "HS256",
+		Issuer:        "ikubeops",
+		AccessExpire:  7200,
+		RefreshExpire: 604800,
+	}
+}
+
+func NewRbacConfig() RbacConfig {
+	return RbacConfig{
+		Enable:        false,
+		SuperRole:     "admin",
+		CacheExpire:   1800,
+		SkipResources: []string{"/portal/auth/logout", "/portal/auth/password", "/portal/otp/mine*", "/portal/session/mine*", "/portal/menu/mine", "/portal/application/mine"},
+	}
+}
+
+func NewApiKeyConfig() ApiKeyConfig {
+	return ApiKeyConfig{
+		Enable:           true,
+		Header:           "X-Api-Key",
+		Prefix:           "ikube",
+		LastUsedInterval: 60,
+	}
+}
+
+func NewOidcConfig() OidcConfig {
+	return OidcConfig{
+		Enable:      false,
+		Scopes:      []string{"openid", "profile", "email"},
+		StateExpire: 600,
+		AutoCreate:  true,
+		LinkBy:      []string{"email"},
+		Claims: OidcClaimConfig{
+			Account:      "preferred_username",
+			UserName:     "name",
+			Email:        "email",
+			WorkNumber:   "employee_number",
+			Mobile:       "phone_number",
+			Organization: "department",
+		},
+	}
+}
+
+func NewLdapConfig() LdapConfig {
+	return LdapConfig{
+		Enable:             false,
+		UserFilter:         "(objectClass=inetOrgPerson)",
+		OrganizationFilter: "(objectClass=organizationalUnit)",
+		PageSize:           500,
+		Timeout:            10,
+		Interval:           0,
+		MissingLeave:       true,
+		MissingDisable:     true,
+		MissingThreshold:   20,
+		Attributes: LdapAttributeConfig{
+			Uid:          "entryUUID",
+			Account:      "uid",
+			UserName:     "cn",
+			Email:        "mail",
+			WorkNumber:   "employeeNumber",
+			Mobile:       "mobile",
+			Organization: "ou",
+			Desc:         "description",
+		},
+	}
+}
+
+func NewPasswordConfig() PasswordConfig {
+	return PasswordConfig{
+		MinLength:      8,
+		MaxLength:      24,
+		RequireUpper:   true,
+		RequireLower:   true,
+		RequireDigit:   true,
+		RequireSpecial: false,
+		RejectAccount:  true,
+		History:        5,
+		MaxAge:         90,
+	}
+}
+
+func NewOtpConfig() OtpConfig {
+	return OtpConfig{
+		Issuer:          "ikubeops",
+		Skew:            1,
+		RecoveryCodes:   10,
+		ChallengeExpire: 300,
+		MaxAttempts:     5,
+	}
+}
+
+func NewAuditConfig() AuditConfig {
+	return AuditConfig{
+		Enable:    true,
+		Retention: 180,
+		Interval:  3600,
+		Exclude:   []string{"ikubeops_portal_password_history", "ikubeops_portal_otp_recovery_code"},
+	}
+}
+
+func NewApiConfig() ApiConfig {
+	return ApiConfig{
+		Prefix:   "",
+		Versions: map[string]ApiVersionConfig{},
+	}
+}
+
+func NewAppsConfig() AppsConfig {
+	disable := false
+	return AppsConfig{
+		"book": {Enable: &disable},
+	}
+}
+
 func NewDefaultConfig() *Config {
 	return &Config{
-		App:    NewAppConfig(),
-		Logger: NewLoggerConfig(),
-		Mysql:  NewMysqlConfig(),
-		Redis:  NewRedisConfig(),
+		App:      NewAppConfig(),
+		Logger:   NewLoggerConfig(),
+		Mysql:    NewMysqlConfig(),
+		Redis:    NewRedisConfig(),
+		Jwt:      NewJwtConfig(),
+		Rbac:     NewRbacConfig(),
+		ApiKey:   NewApiKeyConfig(),
+		Oidc:     NewOidcConfig(),
+		Ldap:     NewLdapConfig(),
+		Password: NewPasswordConfig(),
+		Otp:      NewOtpConfig(),
+		Audit:    NewAuditConfig(),
+		Api:      NewApiConfig(),
+		Apps:     NewAppsConfig(),
 	}
 }
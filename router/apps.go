@@ -0,0 +1,65 @@
+package router
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/yanshicheng/ikube-gin-starter/global"
+)
+
+// 应用开关：服务名称中 . 之前的部分为应用名称，未启用的应用不执行 Config 和 Start，也不注册路由，
+// 其他应用依赖或注入未启用应用的服务时在启动时返回错误。
+
+// AppName 返回服务所属的应用名称
+func AppName(name string) string {
+	return strings.SplitN(name, ".", 2)[0]
+}
+
+// appEnabled 服务所属的应用是否启用
+func appEnabled(name string) bool {
+	return global.C.Apps.Enabled(AppName(name))
+}
+
+// checkDependency 检查依赖的 logic 是否已注册，以及所属的应用是否启用
+func checkDependency(kind, name, dep string) error {
+	if _, ok := logicApps[dep]; !ok {
+		return fmt.Errorf("%s %s 依赖的 logic %s 未注册", kind, name, dep)
+	}
+	if !appEnabled(dep) {
+		return fmt.Errorf("%s %s 依赖的 logic %s 所属应用 %s 未启用", kind, name, dep, AppName(dep))
+	}
+	return nil
+}
+
+// enabledGinApps 返回已启用应用的 gin 服务
+func enabledGinApps() map[string]GinService {
+	apps := make(map[string]GinService, len(ginApps))
+	for name, svc := range ginApps {
+		if appEnabled(name) {
+			apps[name] = svc
+		}
+	}
+	return apps
+}
+
+// DisabledApps 返回已注册但未启用的应用名称
+func DisabledApps() []string {
+	seen := make(map[string]bool)
+	apps := make([]string, 0)
+	add := func(name string) {
+		app := AppName(name)
+		if !seen[app] && !appEnabled(name) {
+			seen[app] = true
+			apps = append(apps, app)
+		}
+	}
+	for name := range logicApps {
+		add(name)
+	}
+	for name := range ginApps {
+		add(name)
+	}
+	sort.Strings(apps)
+	return apps
+}
@@ -3,7 +3,9 @@ package router
 import (
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/yanshicheng/ikube-gin-starter/common/auth"
 	"github.com/yanshicheng/ikube-gin-starter/global"
+	"strings"
 )
 
 var (
@@ -34,41 +36,62 @@ func RegistryLogic(svc LogicService) {
 
 // LoadedGinApp 查询加载成功的服务
 func LoadedGinApp() (apps []string) {
-	for k := range ginApps {
+	for k := range enabledGinApps() {
 		apps = append(apps, k)
 	}
 	return
 }
 
-// 返回一个对象, 任何类型都可以, 使用时, 由使用方进行断言
+// 返回一个对象, 任何类型都可以, 使用时, 由使用方进行断言。
+// 按名称查找，未注册返回 nil，推荐使用按服务接口查找的 Lookup 或 Inject
 func GetLogic(name string) interface{} {
-
-	for k, v := range logicApps {
-		if k == name {
-			return v
-		}
+	if svc, ok := logicApps[name]; ok {
+		return svc
 	}
-
 	return nil
 }
 
-// 用于初始化注册到 IOC容器中的所有服务
-func InitImpl() {
-	for _, v := range logicApps {
+// 用于初始化注册到 IOC容器中的所有服务，按依赖顺序执行 Config
+func InitImpl() error {
+	if err := registryError(); err != nil {
+		return err
+	}
+	logics, err := sortLogics()
+	if err != nil {
+		return err
+	}
+	for _, v := range logics {
 		v.Config()
 	}
+	return registryError()
 }
 
-// 用于初始化注册到 IOC容器中的所有服务
-func InitGin() *gin.Engine {
-	// 初始化 logic
-	for _, v := range logicApps {
-		v.Config()
+// 用于初始化注册到 IOC容器中的所有服务，按依赖顺序执行 Config，依赖缺失或存在循环时返回错误，
+// 服务接口重复注册、未实现以及注入失败同样在这里返回
+func InitGin() (*gin.Engine, error) {
+	if err := registryError(); err != nil {
+		return nil, err
 	}
-	for _, v := range ginApps {
+	services, err := ordered()
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range services {
 		v.Config()
-		global.LSys.Info(fmt.Sprintf("服务注册成功: %s", v.Name()))
+		if _, ok := v.(GinService); ok {
+			global.LSys.Info(fmt.Sprintf("服务注册成功: %s", v.Name()))
+		}
+	}
+	if err := registryError(); err != nil {
+		return nil, err
+	}
+	// 权限加载实现由应用在 Config 中注册，应用未启用时所有请求都会被拒绝
+	if global.C.Rbac.Enable && auth.GetAuthorizer() == nil {
+		return nil, fmt.Errorf("已启用 rbac，但没有应用注册权限加载实现，需要启用 portal 应用或关闭 rbac")
+	}
+	if apps := DisabledApps(); len(apps) > 0 {
+		global.LSys.Info(fmt.Sprintf("应用未启用: %s", strings.Join(apps, ", ")))
 	}
 	// 自动注册路由
-	return BusinessRouter(ginApps)
+	return BusinessRouter(enabledGinApps())
 }
@@ -0,0 +1,155 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/yanshicheng/ikube-gin-starter/global"
+)
+
+// service logic 和 gin 服务的公共部分
+type service interface {
+	Config()
+	Name() string
+}
+
+// started 已启动的服务，按启动顺序保存，退出时倒序停止
+var started []service
+
+// dependencies 返回服务声明的依赖，未实现 Dependent 返回 nil
+func dependencies(svc service) []string {
+	if d, ok := svc.(Dependent); ok {
+		return d.Dependencies()
+	}
+	return nil
+}
+
+// sortLogics 按依赖对已启用应用的 logic 拓扑排序，没有依赖关系的按名称排序，依赖缺失或存在循环时返回错误
+func sortLogics() ([]service, error) {
+	names := make([]string, 0, len(logicApps))
+	for name := range logicApps {
+		if appEnabled(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int, len(names))
+	ordered := make([]service, 0, len(names))
+	var path []string
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			// 从第一次出现的位置截取循环路径
+			for i, n := range path {
+				if n == name {
+					return fmt.Errorf("logic 依赖存在循环: %s", strings.Join(append(path[i:], name), " -> "))
+				}
+			}
+		}
+		state[name] = visiting
+		path = append(path, name)
+		svc := logicApps[name]
+		for _, dep := range dependencies(svc) {
+			if err := checkDependency("logic", name, dep); err != nil {
+				return err
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		ordered = append(ordered, svc)
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// sortGins 按名称排序已启用应用的 gin 服务，并检查依赖的 logic 是否已注册和启用
+func sortGins() ([]service, error) {
+	apps := enabledGinApps()
+	names := make([]string, 0, len(apps))
+	for name := range apps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	ordered := make([]service, 0, len(names))
+	for _, name := range names {
+		svc := apps[name]
+		for _, dep := range dependencies(svc) {
+			if err := checkDependency("gin 服务", name, dep); err != nil {
+				return nil, err
+			}
+		}
+		ordered = append(ordered, svc)
+	}
+	return ordered, nil
+}
+
+// ordered 返回全部服务的初始化顺序，logic 在前，gin 服务在后
+func ordered() ([]service, error) {
+	logics, err := sortLogics()
+	if err != nil {
+		return nil, err
+	}
+	gins, err := sortGins()
+	if err != nil {
+		return nil, err
+	}
+	return append(logics, gins...), nil
+}
+
+// Start 按依赖顺序启动实现了 Lifecycle 的服务，任一服务启动失败时停止已启动的服务并返回错误
+func Start(ctx context.Context) error {
+	services, err := ordered()
+	if err != nil {
+		return err
+	}
+	for _, svc := range services {
+		l, ok := svc.(Lifecycle)
+		if !ok {
+			continue
+		}
+		if err := l.Start(ctx); err != nil {
+			err = fmt.Errorf("服务 %s 启动失败: %w", svc.Name(), err)
+			if stopErr := Stop(ctx); stopErr != nil {
+				err = errors.Join(err, stopErr)
+			}
+			return err
+		}
+		started = append(started, svc)
+		global.LSys.Info(fmt.Sprintf("服务启动成功: %s", svc.Name()))
+	}
+	return nil
+}
+
+// Stop 按启动的相反顺序停止服务，单个服务停止失败不影响其他服务，返回全部错误
+func Stop(ctx context.Context) error {
+	var errs []error
+	for i := len(started) - 1; i >= 0; i-- {
+		svc := started[i]
+		if err := svc.(Lifecycle).Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("服务 %s 停止失败: %w", svc.Name(), err))
+			continue
+		}
+		global.LSys.Info(fmt.Sprintf("服务停止成功: %s", svc.Name()))
+	}
+	started = nil
+	return errors.Join(errs...)
+}
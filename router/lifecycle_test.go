@@ -0,0 +1,132 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/testenv"
+	pkgtypes "github.com/yanshicheng/ikube-gin-starter/pkg/types"
+)
+
+// 依赖错误会让其他测试的 InitImpl 失败，这里使用内部测试，每个测试替换为独立的注册表
+
+var errStart = errors.New("端口被占用")
+
+// events 记录 Config、Start 和 Stop 的调用顺序
+var events []string
+
+type testLogic struct {
+	name     string
+	deps     []string
+	startErr error
+}
+
+func (l *testLogic) Config()                { events = append(events, "config "+l.name) }
+func (l *testLogic) Name() string           { return l.name }
+func (l *testLogic) Dependencies() []string { return l.deps }
+
+// lifecycleLogic 实现 Lifecycle 的 logic
+type lifecycleLogic struct {
+	testLogic
+}
+
+func (l *lifecycleLogic) Start(context.Context) error {
+	events = append(events, "start "+l.name)
+	return l.startErr
+}
+
+func (l *lifecycleLogic) Stop(context.Context) error {
+	events = append(events, "stop "+l.name)
+	return nil
+}
+
+// resetLogics 替换为只包含 logics 的注册表并启用全部应用，测试结束后恢复
+func resetLogics(t *testing.T, logics ...LogicService) {
+	testenv.Setup(t)
+	previousLogics, previousStarted, previousApps := logicApps, started, global.C.Apps
+	t.Cleanup(func() {
+		logicApps, started, global.C.Apps = previousLogics, previousStarted, previousApps
+	})
+	logicApps = map[string]LogicService{}
+	started = nil
+	global.C.Apps = pkgtypes.AppsConfig{}
+	for _, l := range logics {
+		RegistryLogic(l)
+	}
+	events = nil
+}
+
+func TestSortLogics(t *testing.T) {
+	// 依赖先于自身执行 Config，没有依赖关系的按名称排序
+	resetLogics(t,
+		&testLogic{name: "order.c", deps: []string{"order.a", "order.b"}},
+		&testLogic{name: "order.a", deps: []string{"order.b"}},
+		&testLogic{name: "order.b"},
+		&testLogic{name: "order.d"},
+	)
+	assert.NoError(t, InitImpl())
+	assert.Equal(t, []string{"config order.b", "config order.a", "config order.c", "config order.d"}, events, "Config 顺序与预期不符")
+
+	disable := false
+	tests := []struct {
+		name   string
+		logics []LogicService
+		apps   pkgtypes.AppsConfig
+		err    string
+	}{
+		{"循环依赖", []LogicService{
+			&testLogic{name: "cycle.a", deps: []string{"cycle.b"}},
+			&testLogic{name: "cycle.b", deps: []string{"cycle.c"}},
+			&testLogic{name: "cycle.c", deps: []string{"cycle.b"}},
+		}, nil, "logic 依赖存在循环: cycle.b -> cycle.c -> cycle.b"},
+		{"依赖未注册", []LogicService{
+			&testLogic{name: "missing.a", deps: []string{"missing.none"}},
+		}, nil, "logic missing.a 依赖的 logic missing.none 未注册"},
+		{"依赖的应用未启用", []LogicService{
+			&testLogic{name: "disabled.a"},
+			&testLogic{name: "depends.a", deps: []string{"disabled.a"}},
+		}, pkgtypes.AppsConfig{"disabled": pkgtypes.AppsItemConfig{Enable: &disable}},
+			"logic depends.a 依赖的 logic disabled.a 所属应用 disabled 未启用"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetLogics(t, tt.logics...)
+			if tt.apps != nil {
+				global.C.Apps = tt.apps
+			}
+			err := InitImpl()
+			if assert.Error(t, err, "应该返回依赖错误") {
+				assert.Equal(t, tt.err, err.Error(), "错误信息与预期不符")
+			}
+			assert.Empty(t, events, "依赖错误时不应该执行 Config")
+		})
+	}
+}
+
+func TestStartFailure(t *testing.T) {
+	resetLogics(t,
+		&lifecycleLogic{testLogic{name: "lifecycle.a"}},
+		&testLogic{name: "lifecycle.b", deps: []string{"lifecycle.a"}},
+		&lifecycleLogic{testLogic{name: "lifecycle.c", deps: []string{"lifecycle.b"}}},
+		&lifecycleLogic{testLogic{name: "lifecycle.d", deps: []string{"lifecycle.c"}, startErr: errStart}},
+	)
+	assert.NoError(t, InitImpl())
+	events = nil
+
+	// 启动失败时按相反顺序停止已启动的服务，未实现 Lifecycle 的服务跳过
+	err := Start(context.Background())
+	assert.ErrorIs(t, err, errStart, "应该返回启动失败的原因")
+	assert.EqualError(t, err, "服务 lifecycle.d 启动失败: 端口被占用")
+	assert.Equal(t, []string{
+		"start lifecycle.a", "start lifecycle.c", "start lifecycle.d",
+		"stop lifecycle.c", "stop lifecycle.a",
+	}, events, "启动和停止顺序与预期不符")
+
+	// 已启动的服务停止后不会再次停止
+	events = nil
+	assert.NoError(t, Stop(context.Background()))
+	assert.Empty(t, events, "停止后不应该再次停止")
+}
@@ -0,0 +1,76 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// 按服务接口注册和查找 logic：logic 在 init 中通过 RegistryService 声明实现的服务接口，
+// gin 服务在 Config 中通过 Inject 按接口注入。注册和注入的错误不会 panic，而是在 InitGin 中统一返回。
+
+var (
+	// 服务接口到 logic 的映射
+	services = map[reflect.Type]LogicService{}
+	// 注册和注入过程中的错误
+	registryErrors []error
+)
+
+// serviceType 返回服务接口的类型
+func serviceType[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// RegistryService 注册 logic 并声明其实现的服务接口 T，同一个 logic 可以注册多个接口。
+// T 不是接口、logic 未实现 T 或 T 已由其他 logic 注册时，启动时返回错误。
+func RegistryService[T any](svc LogicService) {
+	if _, ok := logicApps[svc.Name()]; !ok {
+		RegistryLogic(svc)
+	}
+	t := serviceType[T]()
+	switch {
+	case t.Kind() != reflect.Interface:
+		registryErrors = append(registryErrors, fmt.Errorf("logic %s 注册的服务类型 %s 不是接口", svc.Name(), t))
+	case !reflect.TypeOf(svc).Implements(t):
+		registryErrors = append(registryErrors, fmt.Errorf("logic %s 未实现服务接口 %s", svc.Name(), t))
+	default:
+		if exist, ok := services[t]; ok {
+			registryErrors = append(registryErrors, fmt.Errorf("服务接口 %s 重复注册: %s, %s", t, exist.Name(), svc.Name()))
+			return
+		}
+		services[t] = svc
+	}
+}
+
+// Lookup 按服务接口查找 logic，如 router.Lookup[service.BookService]()
+func Lookup[T any]() (T, error) {
+	var zero T
+	t := serviceType[T]()
+	svc, ok := services[t]
+	if !ok {
+		return zero, fmt.Errorf("服务接口 %s 未注册，需要在 logic 的 init 中调用 router.RegistryService", t)
+	}
+	if !appEnabled(svc.Name()) {
+		return zero, fmt.Errorf("服务接口 %s 的实现 %s 所属应用 %s 未启用", t, svc.Name(), AppName(svc.Name()))
+	}
+	v, ok := svc.(T)
+	if !ok {
+		return zero, fmt.Errorf("logic %s 未实现服务接口 %s", svc.Name(), t)
+	}
+	return v, nil
+}
+
+// Inject 在 Config 中按服务接口注入 logic，查找失败时记录错误，由 InitGin 返回
+func Inject[T any](target *T) {
+	v, err := Lookup[T]()
+	if err != nil {
+		registryErrors = append(registryErrors, err)
+		return
+	}
+	*target = v
+}
+
+// registryError 返回注册和注入过程中的全部错误
+func registryError() error {
+	return errors.Join(registryErrors...)
+}
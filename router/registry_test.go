@@ -0,0 +1,124 @@
+package router
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/testenv"
+	pkgtypes "github.com/yanshicheng/ikube-gin-starter/pkg/types"
+)
+
+// 注册错误会让其他测试的 InitImpl 失败，这里使用内部测试，每个测试替换为独立的注册表
+
+type greeter interface {
+	Greet() string
+}
+
+type registryLogic struct {
+	name string
+}
+
+func (l *registryLogic) Config()                {}
+func (l *registryLogic) Name() string           { return l.name }
+func (l *registryLogic) Dependencies() []string { return nil }
+
+// greeterLogic 实现 greeter 的 logic
+type greeterLogic struct {
+	registryLogic
+}
+
+func (l *greeterLogic) Greet() string { return "hello " + l.name }
+
+// resetRegistry 替换为空的注册表，测试结束后恢复
+func resetRegistry(t *testing.T) {
+	testenv.Setup(t)
+	previousLogics, previousServices, previousErrors := logicApps, services, registryErrors
+	previousApps := global.C.Apps
+	t.Cleanup(func() {
+		logicApps, services, registryErrors = previousLogics, previousServices, previousErrors
+		global.C.Apps = previousApps
+	})
+	logicApps = map[string]LogicService{}
+	services = map[reflect.Type]LogicService{}
+	registryErrors = nil
+	global.C.Apps = pkgtypes.AppsConfig{}
+}
+
+func TestLookup(t *testing.T) {
+	resetRegistry(t)
+	_, err := Lookup[greeter]()
+	assert.EqualError(t, err, "服务接口 router.greeter 未注册，需要在 logic 的 init 中调用 router.RegistryService")
+
+	svc := &greeterLogic{registryLogic{name: "registry.a"}}
+	RegistryService[greeter](svc)
+	assert.NoError(t, InitImpl(), "注册正确时不应该返回错误")
+	assert.Same(t, svc, GetLogic("registry.a"), "应该同时按名称注册 logic")
+	g, err := Lookup[greeter]()
+	if assert.NoError(t, err, "应该可以按服务接口查找") {
+		assert.Equal(t, "hello registry.a", g.Greet())
+	}
+
+	// 按服务接口注入，注入失败时由 InitImpl 返回
+	var target greeter
+	Inject(&target)
+	assert.Same(t, svc, target, "应该注入已注册的实现")
+	var missing interface{ Missing() }
+	Inject(&missing)
+	assert.Nil(t, missing)
+	assert.EqualError(t, InitImpl(), "服务接口 interface { Missing() } 未注册，需要在 logic 的 init 中调用 router.RegistryService")
+}
+
+func TestLookupDisabledApp(t *testing.T) {
+	resetRegistry(t)
+	RegistryService[greeter](&greeterLogic{registryLogic{name: "registry.a"}})
+	disable := false
+	global.C.Apps = pkgtypes.AppsConfig{"registry": pkgtypes.AppsItemConfig{Enable: &disable}}
+
+	_, err := Lookup[greeter]()
+	assert.EqualError(t, err, "服务接口 router.greeter 的实现 registry.a 所属应用 registry 未启用")
+}
+
+func TestRegistryServiceError(t *testing.T) {
+	tests := []struct {
+		name     string
+		registry func()
+		err      string
+	}{
+		{
+			"重复注册",
+			func() {
+				RegistryService[greeter](&greeterLogic{registryLogic{name: "registry.a"}})
+				RegistryService[greeter](&greeterLogic{registryLogic{name: "registry.b"}})
+			},
+			"服务接口 router.greeter 重复注册: registry.a, registry.b",
+		},
+		{
+			"不是接口",
+			func() { RegistryService[greeterLogic](&greeterLogic{registryLogic{name: "registry.a"}}) },
+			"logic registry.a 注册的服务类型 router.greeterLogic 不是接口",
+		},
+		{
+			"未实现接口",
+			func() { RegistryService[greeter](&registryLogic{name: "registry.a"}) },
+			"logic registry.a 未实现服务接口 router.greeter",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetRegistry(t)
+			tt.registry()
+			assert.EqualError(t, InitImpl(), tt.err, "启动时应该返回注册错误")
+			_, err := InitGin()
+			assert.EqualError(t, err, tt.err, "启动时应该返回注册错误")
+		})
+	}
+
+	// 同一个 logic 可以注册多个接口
+	resetRegistry(t)
+	svc := &greeterLogic{registryLogic{name: "registry.a"}}
+	RegistryService[greeter](svc)
+	RegistryService[LogicService](svc)
+	assert.NoError(t, InitImpl(), "同一个 logic 注册多个接口不应该返回错误")
+}
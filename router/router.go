@@ -11,8 +11,12 @@ import (
 	"net/http"
 )
 
-// 业务路由
-func BusinessRouter(ginApps map[string]GinService) *gin.Engine {
+// 业务路由，全部业务路由注册在全局 API 前缀下
+func BusinessRouter(ginApps map[string]GinService) (*gin.Engine, error) {
+	middlewares, err := deprecations()
+	if err != nil {
+		return nil, err
+	}
 	gin.SetMode(gin.ReleaseMode)
 	router := gin.New()
 
@@ -26,22 +30,35 @@ func BusinessRouter(ginApps map[string]GinService) *gin.Engine {
 		})
 	})
 	// 开放的路由组配置
-	PublicRouterGroup := router.Group("")
+	PublicRouterGroup := router.Group(global.C.Api.PathPrefix())
+	PublicRouterGroup.Use(middleware.Audit())
 	{
 		registerSwagger(PublicRouterGroup)
 	}
 
 	// 鉴权路由
-	AuthRouterGroup := router.Group("")
+	AuthRouterGroup := router.Group(global.C.Api.PathPrefix())
 	// 鉴权中间件配置
-	//AuthRouterGroup.Use()
-	{
-	}
+	AuthRouterGroup.Use(middleware.JwtAuth(), middleware.Permission(), middleware.Audit())
 	for _, ginApp := range ginApps {
-		ginApp.PublicRegistry(PublicRouterGroup)
-		ginApp.AuthRegistry(AuthRouterGroup)
+		registerApp(ginApp, PublicRouterGroup, AuthRouterGroup, middlewares)
+	}
+	return router, nil
+}
+
+// registerApp 在公开和鉴权路由组中注册服务的路由，应用配置了路由前缀时，在前缀分组下注册
+func registerApp(ginApp GinService, public, auth gin.IRouter, middlewares map[string]gin.HandlerFunc) {
+	prefix := global.C.Apps.Prefix(AppName(ginApp.Name()))
+	ginApp.PublicRegistry(public.Group(prefix))
+	ginApp.AuthRegistry(auth.Group(prefix))
+	if v, ok := ginApp.(VersionedService); ok {
+		v.VersionRegistry(&Versions{
+			public:      public,
+			auth:        auth,
+			prefix:      prefix,
+			middlewares: middlewares,
+		})
 	}
-	return router
 }
 
 // 健康检查路由
@@ -96,7 +113,11 @@ func registerSwagger(r gin.IRouter) {
 	// API文档访问地址: http://host/swagger/index.html
 	// 注解定义可参考 https://github.com/swaggo/swag#declarative-comments-format
 	// 样例 https://github.com/swaggo/swag/blob/master/example/basic/api/api.go
-	docs.SwaggerInfo.BasePath = "/api/v1"
+	// 接口注解中的路径包含版本，如 /v1/book-shelf/book，BasePath 为全局 API 前缀
+	docs.SwaggerInfo.BasePath = global.C.Api.PathPrefix()
+	if docs.SwaggerInfo.BasePath == "" {
+		docs.SwaggerInfo.BasePath = "/"
+	}
 	docs.SwaggerInfo.Title = "管理后台接口"
 	docs.SwaggerInfo.Description = "实现一个管理系统的后端API服务"
 	docs.SwaggerInfo.Version = "1.0"
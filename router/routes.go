@@ -0,0 +1,106 @@
+package router
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yanshicheng/ikube-gin-starter/common/auth"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+)
+
+const (
+	RouteGroupPublic = "public" // 公开路由组
+	RouteGroupAuth   = "auth"   // 鉴权路由组
+)
+
+// RouteInfo 路由信息
+type RouteInfo struct {
+	Method   string `json:"method"`
+	Path     string `json:"path"`
+	Resource string `json:"resource"` // 权限校验的资源，去掉全局 API 前缀，公开路由不校验权限，为空
+	Group    string `json:"group"`
+	App      string `json:"app"` // 所属应用，swagger 等框架路由为空
+	Handler  string `json:"handler"`
+}
+
+// Routes 返回已启用应用注册的全部路由，按路径和方法排序。
+// 只调用服务的路由注册函数，不执行 Config，不需要初始化数据库等依赖。
+func Routes() []RouteInfo {
+	gin.SetMode(gin.ReleaseMode)
+	routes := make([]RouteInfo, 0)
+	// 在独立的引擎中注册，公开和鉴权路由分别挂在 /public 和 /auth 下，用于区分路由所在的组
+	collect := func(app string, register func(public, auth gin.IRouter)) {
+		engine := gin.New()
+		prefix := global.C.Api.PathPrefix()
+		register(engine.Group("/"+RouteGroupPublic+prefix), engine.Group("/"+RouteGroupAuth+prefix))
+		for _, r := range engine.Routes() {
+			group, path, _ := strings.Cut(strings.TrimPrefix(r.Path, "/"), "/")
+			info := RouteInfo{
+				Method:  r.Method,
+				Path:    "/" + path,
+				Group:   group,
+				App:     app,
+				Handler: r.Handler,
+			}
+			if group == RouteGroupAuth {
+				info.Resource = auth.ResourceOf(info.Path)
+			}
+			routes = append(routes, info)
+		}
+	}
+	collect("", func(public, auth gin.IRouter) {
+		registerSwagger(public)
+	})
+	for name, ginApp := range enabledGinApps() {
+		collect(AppName(name), func(public, auth gin.IRouter) {
+			registerApp(ginApp, public, auth, nil)
+		})
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
+// WriteRoutes 按格式输出路由列表，支持 table、json 和 csv
+func WriteRoutes(w io.Writer, routes []RouteInfo, output string) error {
+	switch output {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(routes)
+	case "csv":
+		cw := csv.NewWriter(w)
+		_ = cw.Write([]string{"method", "path", "resource", "group", "app", "handler"})
+		for _, r := range routes {
+			_ = cw.Write([]string{r.Method, r.Path, r.Resource, r.Group, r.App, r.Handler})
+		}
+		cw.Flush()
+		return cw.Error()
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "METHOD\tPATH\tRESOURCE\tGROUP\tAPP\tHANDLER")
+		for _, r := range routes {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Method, r.Path, orDash(r.Resource), r.Group, orDash(r.App), r.Handler)
+		}
+		return tw.Flush()
+	}
+	return fmt.Errorf("不支持的输出格式: %s", output)
+}
+
+// orDash 表格中的空值输出为 -
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
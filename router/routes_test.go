@@ -0,0 +1,90 @@
+package router_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/testenv"
+	"github.com/yanshicheng/ikube-gin-starter/router"
+)
+
+type routesHandler struct{}
+
+func (h *routesHandler) Config()      {}
+func (h *routesHandler) Name() string { return "routes" }
+
+func (h *routesHandler) PublicRegistry(r gin.IRouter) {
+	r.GET("/routes/ping", func(*gin.Context) {})
+}
+
+func (h *routesHandler) AuthRegistry(r gin.IRouter) {
+	r.GET("/routes/books/:id", func(*gin.Context) {})
+	r.POST("/routes/books", func(*gin.Context) {})
+}
+
+func init() {
+	router.RegistryGinRouter(&routesHandler{})
+}
+
+// appRoutes 返回测试应用注册的路由
+func appRoutes(t *testing.T) []router.RouteInfo {
+	testenv.Setup(t)
+	prefix := global.C.Api.Prefix
+	t.Cleanup(func() { global.C.Api.Prefix = prefix })
+	global.C.Api.Prefix = "/api"
+	routes := make([]router.RouteInfo, 0)
+	for _, r := range router.Routes() {
+		if r.App == "routes" {
+			r.Handler = ""
+			routes = append(routes, r)
+		}
+	}
+	return routes
+}
+
+func TestRoutes(t *testing.T) {
+	// 路径包含全局 API 前缀，权限资源与权限校验一致，去掉全局 API 前缀，公开路由没有权限资源
+	assert.Equal(t, []router.RouteInfo{
+		{Method: "POST", Path: "/api/routes/books", Resource: "/routes/books", Group: router.RouteGroupAuth, App: "routes"},
+		{Method: "GET", Path: "/api/routes/books/:id", Resource: "/routes/books/:id", Group: router.RouteGroupAuth, App: "routes"},
+		{Method: "GET", Path: "/api/routes/ping", Group: router.RouteGroupPublic, App: "routes"},
+	}, appRoutes(t), "路由信息与预期不符")
+}
+
+func TestWriteRoutes(t *testing.T) {
+	routes := appRoutes(t)
+	var buf bytes.Buffer
+
+	assert.NoError(t, router.WriteRoutes(&buf, routes, "json"))
+	var decoded []router.RouteInfo
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &decoded), "json 输出应该可以解析")
+	assert.Equal(t, routes, decoded, "json 输出与路由信息不一致")
+
+	buf.Reset()
+	assert.NoError(t, router.WriteRoutes(&buf, routes, "csv"))
+	records, err := csv.NewReader(&buf).ReadAll()
+	assert.NoError(t, err, "csv 输出应该可以解析")
+	assert.Equal(t, [][]string{
+		{"method", "path", "resource", "group", "app", "handler"},
+		{"POST", "/api/routes/books", "/routes/books", "auth", "routes", ""},
+		{"GET", "/api/routes/books/:id", "/routes/books/:id", "auth", "routes", ""},
+		{"GET", "/api/routes/ping", "", "public", "routes", ""},
+	}, records, "csv 输出与预期不符")
+
+	buf.Reset()
+	assert.NoError(t, router.WriteRoutes(&buf, routes, "table"))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if assert.Len(t, lines, 4, "表格应该包含表头和每条路由") {
+		assert.Equal(t, []string{"METHOD", "PATH", "RESOURCE", "GROUP", "APP", "HANDLER"}, strings.Fields(lines[0]))
+		assert.Equal(t, []string{"POST", "/api/routes/books", "/routes/books", "auth", "routes"}, strings.Fields(lines[1]))
+		assert.Equal(t, []string{"GET", "/api/routes/ping", "-", "public", "routes"}, strings.Fields(lines[3]), "公开路由的权限资源应该输出为 -")
+	}
+
+	assert.EqualError(t, router.WriteRoutes(&buf, routes, "yaml"), "不支持的输出格式: yaml")
+}
@@ -1,6 +1,8 @@
 package router
 
 import (
+	"context"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -15,3 +17,21 @@ type LogicService interface {
 	Config()
 	Name() string
 }
+
+// Dependent 可选接口，LogicService 和 GinService 通过它声明依赖的 logic 名称，
+// 依赖的 logic 先于自身执行 Config 和 Start，后于自身执行 Stop
+type Dependent interface {
+	Dependencies() []string
+}
+
+// Lifecycle 可选接口，全部服务 Config 完成后按依赖顺序调用 Start，服务退出时按相反顺序调用 Stop。
+// 后台任务应在 Start 中启动、在 Stop 中停止，而不是在 Config 中启动
+type Lifecycle interface {
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+// VersionedService 可选接口，GinService 通过它按 API 版本注册路由，同一个服务可以同时注册 v1 和 v2 的处理函数
+type VersionedService interface {
+	VersionRegistry(v *Versions)
+}
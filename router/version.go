@@ -0,0 +1,73 @@
+package router
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yanshicheng/ikube-gin-starter/common/middleware"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+)
+
+// API 版本：全部业务路由注册在全局前缀 api.prefix 下，版本分组的路径为 {api.prefix}/{version}/{应用路由前缀}，
+// 在 api.versions 中配置为弃用的版本自动携带 Deprecation 和 Sunset 响应头。
+
+// Versions 按 API 版本创建服务的路由分组
+type Versions struct {
+	public      gin.IRouter
+	auth        gin.IRouter
+	prefix      string // 应用路由前缀
+	middlewares map[string]gin.HandlerFunc
+}
+
+// Public 版本的公开路由分组
+func (v *Versions) Public(version string) gin.IRouter {
+	return v.group(v.public, version)
+}
+
+// Auth 版本的认证路由分组
+func (v *Versions) Auth(version string) gin.IRouter {
+	return v.group(v.auth, version)
+}
+
+func (v *Versions) group(r gin.IRouter, version string) gin.IRouter {
+	g := r.Group(version)
+	if m, ok := v.middlewares[version]; ok {
+		g.Use(m)
+	}
+	return g.Group(v.prefix)
+}
+
+// deprecations 根据版本配置生成弃用版本的中间件，时间格式错误时返回错误
+func deprecations() (map[string]gin.HandlerFunc, error) {
+	versions := make([]string, 0, len(global.C.Api.Versions))
+	for version := range global.C.Api.Versions {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	middlewares := make(map[string]gin.HandlerFunc)
+	for _, version := range versions {
+		c := global.C.Api.Versions[version]
+		if !c.Deprecated {
+			continue
+		}
+		deprecation, err := parseTime(c.Deprecation)
+		if err != nil {
+			return nil, fmt.Errorf("API 版本 %s 的弃用时间格式错误: %w", version, err)
+		}
+		sunset, err := parseTime(c.Sunset)
+		if err != nil {
+			return nil, fmt.Errorf("API 版本 %s 的下线时间格式错误: %w", version, err)
+		}
+		middlewares[version] = middleware.Deprecation(deprecation, sunset, c.Link)
+	}
+	return middlewares, nil
+}
+
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,80 @@
+package router_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/yanshicheng/ikube-gin-starter/global"
+	"github.com/yanshicheng/ikube-gin-starter/pkg/testenv"
+	pkgtypes "github.com/yanshicheng/ikube-gin-starter/pkg/types"
+	"github.com/yanshicheng/ikube-gin-starter/router"
+)
+
+// versionedHandler 在 v1 和 v2 下注册相同的公开路由
+type versionedHandler struct{}
+
+func (h *versionedHandler) Config()                    {}
+func (h *versionedHandler) Name() string               { return "versioned" }
+func (h *versionedHandler) PublicRegistry(gin.IRouter) {}
+func (h *versionedHandler) AuthRegistry(gin.IRouter)   {}
+
+func (h *versionedHandler) VersionRegistry(v *router.Versions) {
+	for _, version := range []string{"v1", "v2"} {
+		v.Public(version).GET("/ping", func(c *gin.Context) { c.String(http.StatusOK, "pong") })
+	}
+}
+
+// setVersions 设置 API 前缀和版本配置，测试结束后恢复
+func setVersions(t *testing.T, versions map[string]pkgtypes.ApiVersionConfig) {
+	testenv.Setup(t)
+	api := global.C.Api
+	t.Cleanup(func() { global.C.Api = api })
+	global.C.Api = pkgtypes.ApiConfig{Prefix: "/api", Versions: versions}
+}
+
+func TestDeprecatedVersion(t *testing.T) {
+	setVersions(t, map[string]pkgtypes.ApiVersionConfig{
+		"v1": {Deprecated: true, Deprecation: "2026-01-01T00:00:00Z", Sunset: "2026-12-31T00:00:00Z", Link: "https://example.com/migrate-v2"},
+		"v2": {},
+	})
+	r, err := router.BusinessRouter(map[string]router.GinService{"versioned": &versionedHandler{}})
+	assert.NoError(t, err, "创建业务路由应该成功")
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusOK, w.Code, "%s 应该可以访问", path)
+		return w
+	}
+	header := serve("/api/v1/ping").Header()
+	assert.Equal(t, "@1767225600", header.Get("Deprecation"), "弃用版本应该携带 Deprecation 头")
+	assert.Equal(t, "Thu, 31 Dec 2026 00:00:00 GMT", header.Get("Sunset"), "弃用版本应该携带 Sunset 头")
+	assert.Equal(t, `<https://example.com/migrate-v2>; rel="deprecation"`, header.Get("Link"), "弃用版本应该携带 Link 头")
+
+	header = serve("/api/v2/ping").Header()
+	for _, name := range []string{"Deprecation", "Sunset", "Link"} {
+		assert.Empty(t, header.Get(name), "未弃用的版本不应该携带 %s 头", name)
+	}
+}
+
+func TestDeprecatedVersionWithoutTime(t *testing.T) {
+	// 未配置时间和迁移地址时只携带 Deprecation 头
+	setVersions(t, map[string]pkgtypes.ApiVersionConfig{"v1": {Deprecated: true}})
+	r, err := router.BusinessRouter(map[string]router.GinService{"versioned": &versionedHandler{}})
+	assert.NoError(t, err, "创建业务路由应该成功")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/ping", nil))
+	assert.Equal(t, "true", w.Header().Get("Deprecation"), "未配置弃用时间时 Deprecation 头应该为 true")
+	assert.Empty(t, w.Header().Get("Sunset"), "未配置下线时间时不应该携带 Sunset 头")
+	assert.Empty(t, w.Header().Get("Link"), "未配置迁移地址时不应该携带 Link 头")
+
+	// 时间格式错误时无法创建路由
+	setVersions(t, map[string]pkgtypes.ApiVersionConfig{"v1": {Deprecated: true, Sunset: "2026-12-31"}})
+	_, err = router.BusinessRouter(map[string]router.GinService{"versioned": &versionedHandler{}})
+	if assert.Error(t, err, "时间格式错误时应该返回错误") {
+		assert.Contains(t, err.Error(), "API 版本 v1 的下线时间格式错误")
+	}
+}